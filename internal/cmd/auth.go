@@ -11,6 +11,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/alecthomas/kong"
+
 	"github.com/steipete/gogcli/internal/authclient"
 	"github.com/steipete/gogcli/internal/config"
 	"github.com/steipete/gogcli/internal/googleauth"
@@ -54,11 +56,14 @@ type AuthCmd struct {
 	Add         AuthAddCmd            `cmd:"" name:"add" help:"Authorize and store a refresh token"`
 	Services    AuthServicesCmd       `cmd:"" name:"services" help:"List supported auth services and scopes"`
 	List        AuthListCmd           `cmd:"" name:"list" help:"List stored accounts"`
+	Check       AuthCheckCmd          `cmd:"" name:"check" help:"Check refresh-token health (validity, Testing-mode 7-day expiry)"`
 	Aliases     AuthAliasCmd          `cmd:"" name:"alias" help:"Manage account aliases"`
 	Status      AuthStatusCmd         `cmd:"" name:"status" help:"Show auth configuration and keyring backend"`
-	Keyring     AuthKeyringCmd        `cmd:"" name:"keyring" help:"Configure keyring backend"`
-	Remove      AuthRemoveCmd         `cmd:"" name:"remove" help:"Remove a stored refresh token"`
+	Keyring     AuthKeyringCmd        `cmd:"" name:"keyring" help:"Configure keyring backend, rotate its password, or migrate items"`
+	Remove      AuthRemoveCmd         `cmd:"" name:"remove" help:"Revoke and remove a stored refresh token"`
+	RevokeAll   AuthRevokeAllCmd      `cmd:"" name:"revoke-all" help:"Revoke every stored token for a client at Google (incident response)"`
 	Tokens      AuthTokensCmd         `cmd:"" name:"tokens" help:"Manage stored refresh tokens"`
+	Bundle      AuthBundleCmd         `cmd:"" name:"bundle" help:"Export/import an encrypted bundle of all auth state (machine migration)"`
 	Manage      AuthManageCmd         `cmd:"" name:"manage" help:"Open accounts manager in browser" aliases:"login"`
 	ServiceAcct AuthServiceAccountCmd `cmd:"" name:"service-account" help:"Configure service account (Workspace only; domain-wide delegation)"`
 	Keep        AuthKeepCmd           `cmd:"" name:"keep" help:"Configure service account for Google Keep (Workspace only)"`
@@ -72,9 +77,10 @@ type AuthCredentialsCmd struct {
 type AuthCredentialsSetCmd struct {
 	Path    string `arg:"" name:"credentials" help:"Path to credentials.json or '-' for stdin"`
 	Domains string `name:"domain" help:"Comma-separated domains to map to this client (e.g. example.com)"`
+	Testing bool   `name:"testing" negatable:"" help:"OAuth consent screen is in Testing status (refresh tokens expire after 7 days; enables expiry warnings). Unchanged unless given; --no-testing clears it"`
 }
 
-func (c *AuthCredentialsSetCmd) Run(ctx context.Context) error {
+func (c *AuthCredentialsSetCmd) Run(ctx context.Context, kctx *kong.Context) error {
 	u := ui.FromContext(ctx)
 	client, err := normalizeClientForFlag(authclient.ClientOverrideFromContext(ctx))
 	if err != nil {
@@ -105,29 +111,42 @@ func (c *AuthCredentialsSetCmd) Run(ctx context.Context) error {
 	}
 
 	outPath, _ := config.ClientCredentialsPathFor(client)
-	if strings.TrimSpace(c.Domains) != "" {
-		cfg, err := config.ReadConfig()
-		if err != nil {
-			return err
-		}
+	cfg, err := config.ReadConfig()
+	if err != nil {
+		return err
+	}
+	// The testing flag only changes stored state when given, so re-running
+	// credentials set for rotated secrets keeps it.
+	setTesting := flagProvided(kctx, "testing")
+	if strings.TrimSpace(c.Domains) != "" || setTesting {
 		for _, domain := range splitCommaList(c.Domains) {
 			if err := config.SetClientDomain(&cfg, domain, client); err != nil {
 				return err
 			}
 		}
+		if setTesting {
+			if err := config.SetClientTesting(&cfg, client, c.Testing); err != nil {
+				return err
+			}
+		}
 		if err := config.WriteConfig(cfg); err != nil {
 			return err
 		}
 	}
+	isTesting := config.ClientIsTesting(cfg, client)
 	if outfmt.IsJSON(ctx) {
 		return outfmt.WriteJSON(os.Stdout, map[string]any{
-			"saved":  true,
-			"path":   outPath,
-			"client": client,
+			"saved":   true,
+			"path":    outPath,
+			"client":  client,
+			"testing": isTesting,
 		})
 	}
 	u.Out().Printf("path\t%s", outPath)
 	u.Out().Printf("client\t%s", client)
+	if isTesting {
+		u.Out().Printf("testing\ttrue")
+	}
 	return nil
 }
 
@@ -256,7 +275,8 @@ func (c *AuthTokensListCmd) Run(ctx context.Context) error {
 }
 
 type AuthTokensDeleteCmd struct {
-	Email string `arg:"" name:"email" help:"Email"`
+	Email  string `arg:"" name:"email" help:"Email"`
+	Revoke bool   `name:"revoke" negatable:"" help:"Revoke the token at Google before deleting it"`
 }
 
 func (c *AuthTokensDeleteCmd) Run(ctx context.Context, flags *RootFlags) error {
@@ -269,16 +289,12 @@ func (c *AuthTokensDeleteCmd) Run(ctx context.Context, flags *RootFlags) error {
 	if err := confirmDestructive(ctx, flags, fmt.Sprintf("delete stored token for %s", email)); err != nil {
 		return err
 	}
-
-	store, err := openSecretsStore()
-	if err != nil {
-		return err
-	}
 	client, err := resolveClientForEmail(email, flags, "")
 	if err != nil {
 		return err
 	}
-	if err := store.DeleteToken(client, email); err != nil {
+	revoked, err := deleteStoredToken(ctx, client, email, c.Revoke)
+	if err != nil {
 		return err
 	}
 	if outfmt.IsJSON(ctx) {
@@ -286,11 +302,13 @@ func (c *AuthTokensDeleteCmd) Run(ctx context.Context, flags *RootFlags) error {
 			"deleted": true,
 			"email":   email,
 			"client":  client,
+			"revoked": revoked,
 		})
 	}
 	u.Out().Printf("deleted\ttrue")
 	u.Out().Printf("email\t%s", email)
 	u.Out().Printf("client\t%s", client)
+	u.Out().Printf("revoked\t%s", revoked)
 	return nil
 }
 
@@ -758,6 +776,7 @@ func (c *AuthListCmd) Run(ctx context.Context) error {
 			Services  []string `json:"services,omitempty"`
 			Scopes    []string `json:"scopes,omitempty"`
 			CreatedAt string   `json:"created_at,omitempty"`
+			Refreshed string   `json:"last_refreshed_at,omitempty"`
 			Auth      string   `json:"auth"`
 			Valid     *bool    `json:"valid,omitempty"`
 			Error     string   `json:"error,omitempty"`
@@ -800,6 +819,9 @@ func (c *AuthListCmd) Run(ctx context.Context) error {
 			}
 			if e.Token != nil {
 				it.Client = e.Token.Client
+				if !e.Token.LastRefreshedAt.IsZero() {
+					it.Refreshed = e.Token.LastRefreshedAt.UTC().Format("2006-01-02T15:04:05Z07:00")
+				}
 			}
 			if c.Check {
 				if e.Token == nil {
@@ -889,6 +911,15 @@ func bestServiceAccountPathAndMtime(email string) (string, time.Time, bool) {
 			return p, st.ModTime(), true
 		}
 	}
+	if cfg, err := config.ReadConfig(); err == nil {
+		if key, ok := config.ServiceAccountKeyForAccount(cfg, email, ""); ok {
+			if p, err := config.ServiceAccountKeyPath(key); err == nil {
+				if st, err := os.Stat(p); err == nil {
+					return p, st.ModTime(), true
+				}
+			}
+		}
+	}
 	return "", time.Time{}, false
 }
 
@@ -925,7 +956,8 @@ func (c *AuthServicesCmd) Run(ctx context.Context) error {
 }
 
 type AuthRemoveCmd struct {
-	Email string `arg:"" name:"email" help:"Email"`
+	Email  string `arg:"" name:"email" help:"Email"`
+	Revoke bool   `name:"revoke" negatable:"" default:"true" help:"Revoke the token at Google before removing it (--no-revoke to only delete locally)"`
 }
 
 func (c *AuthRemoveCmd) Run(ctx context.Context, flags *RootFlags) error {
@@ -938,15 +970,12 @@ func (c *AuthRemoveCmd) Run(ctx context.Context, flags *RootFlags) error {
 	if err := confirmDestructive(ctx, flags, fmt.Sprintf("remove stored token for %s", email)); err != nil {
 		return err
 	}
-	store, err := openSecretsStore()
-	if err != nil {
-		return err
-	}
 	client, err := resolveClientForEmail(email, flags, "")
 	if err != nil {
 		return err
 	}
-	if err := store.DeleteToken(client, email); err != nil {
+	revoked, err := deleteStoredToken(ctx, client, email, c.Revoke)
+	if err != nil {
 		return err
 	}
 	if outfmt.IsJSON(ctx) {
@@ -954,11 +983,13 @@ func (c *AuthRemoveCmd) Run(ctx context.Context, flags *RootFlags) error {
 			"deleted": true,
 			"email":   email,
 			"client":  client,
+			"revoked": revoked,
 		})
 	}
 	u.Out().Printf("deleted\ttrue")
 	u.Out().Printf("email\t%s", email)
 	u.Out().Printf("client\t%s", client)
+	u.Out().Printf("revoked\t%s", revoked)
 	return nil
 }
 
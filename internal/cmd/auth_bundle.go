@@ -0,0 +1,653 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+
+	"github.com/steipete/gogcli/internal/config"
+	"github.com/steipete/gogcli/internal/outfmt"
+	"github.com/steipete/gogcli/internal/secrets"
+	"github.com/steipete/gogcli/internal/tracking"
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+const (
+	authBundleVersion       = 1
+	authBundlePassphraseEnv = "GOG_BUNDLE_PASSPHRASE" //nolint:gosec // env var name, not a credential
+
+	bundleKindSA      = "service_account"
+	bundleKindKeepSA  = "keep_service_account"
+	bundleKindNamedSA = "named_service_account"
+)
+
+var readBundlePassphrase = readPassphraseFromTTY
+
+type AuthBundleCmd struct {
+	Export AuthBundleExportCmd `cmd:"" name:"export" help:"Export credentials, config, service accounts, tracking secrets and tokens to an encrypted bundle"`
+	Import AuthBundleImportCmd `cmd:"" name:"import" help:"Restore an encrypted bundle into the current config dir and keyring"`
+}
+
+// authBundle is the plaintext payload sealed inside a bundle file.
+type authBundle struct {
+	Version         int                  `json:"version"`
+	CreatedAt       time.Time            `json:"created_at"`
+	Config          authBundleConfig     `json:"config"`
+	Clients         []authBundleClient   `json:"clients,omitempty"`
+	ServiceAccounts []authBundleSA       `json:"service_accounts,omitempty"`
+	Tracking        []authBundleTracking `json:"tracking,omitempty"`
+	Tokens          []authBundleToken    `json:"tokens,omitempty"`
+	DefaultAccounts map[string]string    `json:"default_accounts,omitempty"`
+}
+
+// authBundleConfig carries the portable parts of config.json. The keyring
+// backend is deliberately left out: it describes the source machine.
+type authBundleConfig struct {
+	DefaultTimezone string            `json:"default_timezone,omitempty"`
+	AccountAliases  map[string]string `json:"account_aliases,omitempty"`
+	AccountClients  map[string]string `json:"account_clients,omitempty"`
+	ClientDomains   map[string]string `json:"client_domains,omitempty"`
+	TestingClients  []string          `json:"testing_clients,omitempty"`
+
+	ServiceAccountDomains map[string]config.ServiceAccountRoute `json:"service_account_domains,omitempty"`
+}
+
+type authBundleClient struct {
+	Client       string `json:"client"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+}
+
+type authBundleSA struct {
+	Email string          `json:"email,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Kind  string          `json:"kind"`
+	Key   json.RawMessage `json:"key"`
+}
+
+type authBundleTracking struct {
+	Account string          `json:"account"`
+	Config  tracking.Config `json:"config"`
+}
+
+type authBundleToken struct {
+	Client          string    `json:"client"`
+	Email           string    `json:"email"`
+	Services        []string  `json:"services,omitempty"`
+	Scopes          []string  `json:"scopes,omitempty"`
+	CreatedAt       time.Time `json:"created_at,omitempty"`
+	LastRefreshedAt time.Time `json:"last_refreshed_at,omitempty"`
+	RefreshToken    string    `json:"refresh_token"`
+}
+
+type authBundleItem struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+func (b authBundle) items() []authBundleItem {
+	out := make([]authBundleItem, 0)
+	for alias, email := range b.Config.AccountAliases {
+		out = append(out, authBundleItem{Kind: "alias", Name: alias + " -> " + email})
+	}
+	for email, client := range b.Config.AccountClients {
+		out = append(out, authBundleItem{Kind: "account_client", Name: email + " -> " + client})
+	}
+	for domain, client := range b.Config.ClientDomains {
+		out = append(out, authBundleItem{Kind: "client_domain", Name: domain + " -> " + client})
+	}
+	if b.Config.DefaultTimezone != "" {
+		out = append(out, authBundleItem{Kind: "default_timezone", Name: b.Config.DefaultTimezone})
+	}
+	for _, c := range b.Clients {
+		out = append(out, authBundleItem{Kind: "credentials", Name: c.Client})
+	}
+	for domain, route := range b.Config.ServiceAccountDomains {
+		if route.Key != "" {
+			out = append(out, authBundleItem{Kind: "service_account_route", Name: domain + " -> " + route.Key})
+		}
+		for service, key := range route.Services {
+			out = append(out, authBundleItem{Kind: "service_account_route", Name: domain + "/" + service + " -> " + key})
+		}
+	}
+	for _, sa := range b.ServiceAccounts {
+		name := sa.Email
+		if sa.Kind == bundleKindNamedSA {
+			name = sa.Name
+		}
+		out = append(out, authBundleItem{Kind: sa.Kind, Name: name})
+	}
+	for _, t := range b.Tracking {
+		out = append(out, authBundleItem{Kind: "tracking", Name: t.Account})
+	}
+	for _, t := range b.Tokens {
+		out = append(out, authBundleItem{Kind: "token", Name: secrets.TokenKey(t.Client, t.Email)})
+	}
+	for client, email := range b.DefaultAccounts {
+		out = append(out, authBundleItem{Kind: "default_account", Name: client + " -> " + email})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Kind != out[j].Kind {
+			return out[i].Kind < out[j].Kind
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+type AuthBundleExportCmd struct {
+	Output         OutputPathRequiredFlag `embed:""`
+	Overwrite      bool                   `name:"overwrite" help:"Overwrite output file if it exists"`
+	PassphraseFile string                 `name:"passphrase-file" help:"Read bundle passphrase from file (default: $GOG_BUNDLE_PASSPHRASE or TTY prompt)"`
+}
+
+func (c *AuthBundleExportCmd) Run(ctx context.Context) error {
+	u := ui.FromContext(ctx)
+	outPath := strings.TrimSpace(c.Output.Path)
+	if outPath == "" {
+		return usage("empty outPath")
+	}
+	outPath, err := config.ExpandPath(outPath)
+	if err != nil {
+		return err
+	}
+
+	passphrase, err := resolveBundlePassphrase(c.PassphraseFile, true)
+	if err != nil {
+		return err
+	}
+
+	bundle, err := collectAuthBundle()
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := json.Marshal(bundle)
+	if err != nil {
+		return fmt.Errorf("encode bundle: %w", err)
+	}
+	sealed, err := secrets.SealWithPassphrase(plaintext, passphrase)
+	if err != nil {
+		return fmt.Errorf("encrypt bundle: %w", err)
+	}
+
+	if mkErr := os.MkdirAll(filepath.Dir(outPath), 0o700); mkErr != nil {
+		return mkErr
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !c.Overwrite {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	}
+	f, err := os.OpenFile(outPath, flags, 0o600) //nolint:gosec // user-provided path
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(sealed); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	items := bundle.items()
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(os.Stdout, map[string]any{
+			"exported": true,
+			"path":     outPath,
+			"items":    items,
+		})
+	}
+	u.Out().Printf("exported\ttrue")
+	u.Out().Printf("path\t%s", outPath)
+	u.Out().Printf("items\t%d", len(items))
+	u.Out().Printf("tokens\t%d", len(bundle.Tokens))
+	return nil
+}
+
+type AuthBundleImportCmd struct {
+	InPath         string `arg:"" name:"inPath" help:"Bundle path or '-' for stdin"`
+	DryRun         bool   `name:"dry-run" help:"List what would be written without changing anything"`
+	PassphraseFile string `name:"passphrase-file" help:"Read bundle passphrase from file (default: $GOG_BUNDLE_PASSPHRASE or TTY prompt)"`
+}
+
+func (c *AuthBundleImportCmd) Run(ctx context.Context) error {
+	u := ui.FromContext(ctx)
+	inPath := c.InPath
+	var data []byte
+	var err error
+	if inPath == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		inPath, err = config.ExpandPath(inPath)
+		if err != nil {
+			return err
+		}
+		data, err = os.ReadFile(inPath) //nolint:gosec // user-provided path
+	}
+	if err != nil {
+		return err
+	}
+
+	passphrase, err := resolveBundlePassphrase(c.PassphraseFile, false)
+	if err != nil {
+		return err
+	}
+	plaintext, err := secrets.OpenWithPassphrase(data, passphrase)
+	if err != nil {
+		return fmt.Errorf("decrypt bundle: %w", err)
+	}
+
+	var bundle authBundle
+	if err := json.Unmarshal(plaintext, &bundle); err != nil {
+		return fmt.Errorf("decode bundle: %w", err)
+	}
+	if bundle.Version != authBundleVersion {
+		return fmt.Errorf("unsupported bundle version %d", bundle.Version)
+	}
+
+	items := bundle.items()
+	if c.DryRun {
+		if outfmt.IsJSON(ctx) {
+			return outfmt.WriteJSON(os.Stdout, map[string]any{
+				"dry_run": true,
+				"items":   items,
+			})
+		}
+		u.Err().Println("Dry run: nothing written")
+		w, done := tableWriter(ctx)
+		defer done()
+		_, _ = fmt.Fprintln(w, "KIND\tNAME")
+		for _, it := range items {
+			_, _ = fmt.Fprintf(w, "%s\t%s\n", it.Kind, it.Name)
+		}
+		return nil
+	}
+
+	if len(bundle.Tokens) > 0 || len(bundle.Tracking) > 0 {
+		if keychainErr := ensureKeychainAccessIfNeeded(); keychainErr != nil {
+			return fmt.Errorf("keychain access: %w", keychainErr)
+		}
+	}
+
+	if err := applyAuthBundle(bundle); err != nil {
+		return err
+	}
+
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(os.Stdout, map[string]any{
+			"imported": true,
+			"items":    items,
+		})
+	}
+	u.Out().Printf("imported\ttrue")
+	u.Out().Printf("items\t%d", len(items))
+	u.Out().Printf("tokens\t%d", len(bundle.Tokens))
+	return nil
+}
+
+func collectAuthBundle() (authBundle, error) {
+	bundle := authBundle{
+		Version:   authBundleVersion,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	cfg, err := config.ReadConfig()
+	if err != nil {
+		return authBundle{}, err
+	}
+	bundle.Config = authBundleConfig{
+		DefaultTimezone: cfg.DefaultTimezone,
+		AccountAliases:  cfg.AccountAliases,
+		AccountClients:  cfg.AccountClients,
+		ClientDomains:   cfg.ClientDomains,
+		TestingClients:  cfg.TestingClients,
+
+		ServiceAccountDomains: cfg.ServiceAccountDomains,
+	}
+
+	creds, err := config.ListClientCredentials()
+	if err != nil {
+		return authBundle{}, err
+	}
+	for _, info := range creds {
+		cc, readErr := config.ReadClientCredentialsFor(info.Client)
+		if readErr != nil {
+			return authBundle{}, fmt.Errorf("read credentials for client %s: %w", info.Client, readErr)
+		}
+		bundle.Clients = append(bundle.Clients, authBundleClient{
+			Client:       info.Client,
+			ClientID:     cc.ClientID,
+			ClientSecret: cc.ClientSecret,
+		})
+	}
+
+	saEmails, err := config.ListServiceAccountEmails()
+	if err != nil {
+		return authBundle{}, err
+	}
+	for _, email := range saEmails {
+		sas, saErr := collectBundleServiceAccounts(email)
+		if saErr != nil {
+			return authBundle{}, saErr
+		}
+		bundle.ServiceAccounts = append(bundle.ServiceAccounts, sas...)
+	}
+
+	namedKeys, err := config.ListServiceAccountKeys()
+	if err != nil {
+		return authBundle{}, err
+	}
+	for _, k := range namedKeys {
+		data, readErr := os.ReadFile(k.Path) //nolint:gosec // stored in user config dir
+		if readErr != nil {
+			return authBundle{}, fmt.Errorf("read service account key %s: %w", k.Name, readErr)
+		}
+		if _, parseErr := parseServiceAccountJSON(data); parseErr != nil {
+			return authBundle{}, fmt.Errorf("service account key %s: %w", k.Name, parseErr)
+		}
+		bundle.ServiceAccounts = append(bundle.ServiceAccounts, authBundleSA{Name: k.Name, Kind: bundleKindNamedSA, Key: json.RawMessage(data)})
+	}
+
+	trackingAccounts, err := tracking.ListAccounts()
+	if err != nil {
+		return authBundle{}, err
+	}
+	for _, account := range trackingAccounts {
+		tcfg, loadErr := tracking.LoadConfig(account)
+		if loadErr != nil {
+			return authBundle{}, fmt.Errorf("load tracking config for %s: %w", account, loadErr)
+		}
+		bundle.Tracking = append(bundle.Tracking, authBundleTracking{Account: account, Config: *tcfg})
+	}
+
+	store, err := openSecretsStore()
+	if err != nil {
+		return authBundle{}, err
+	}
+	tokens, err := store.ListTokens()
+	if err != nil {
+		return authBundle{}, err
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		return secrets.TokenKey(tokens[i].Client, tokens[i].Email) < secrets.TokenKey(tokens[j].Client, tokens[j].Email)
+	})
+	clients := make(map[string]struct{})
+	for _, tok := range tokens {
+		if strings.TrimSpace(tok.Email) == "" {
+			continue
+		}
+		client, normErr := config.NormalizeClientNameOrDefault(tok.Client)
+		if normErr != nil {
+			return authBundle{}, normErr
+		}
+		clients[client] = struct{}{}
+		bundle.Tokens = append(bundle.Tokens, authBundleToken{
+			Client:          client,
+			Email:           tok.Email,
+			Services:        tok.Services,
+			Scopes:          tok.Scopes,
+			CreatedAt:       tok.CreatedAt,
+			LastRefreshedAt: tok.LastRefreshedAt,
+			RefreshToken:    tok.RefreshToken,
+		})
+	}
+	for client := range clients {
+		email, defErr := store.GetDefaultAccount(client)
+		if defErr != nil {
+			return authBundle{}, defErr
+		}
+		if strings.TrimSpace(email) == "" {
+			continue
+		}
+		if bundle.DefaultAccounts == nil {
+			bundle.DefaultAccounts = make(map[string]string)
+		}
+		bundle.DefaultAccounts[client] = email
+	}
+
+	return bundle, nil
+}
+
+func collectBundleServiceAccounts(email string) ([]authBundleSA, error) {
+	out := make([]authBundleSA, 0, 2)
+	genericPath, err := config.ServiceAccountPath(email)
+	if err != nil {
+		return nil, err
+	}
+	keepPath, err := config.KeepServiceAccountPath(email)
+	if err != nil {
+		return nil, err
+	}
+	legacyKeepPath, err := config.KeepServiceAccountLegacyPath(email)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := []struct {
+		kind string
+		path string
+	}{
+		{bundleKindSA, genericPath},
+		{bundleKindKeepSA, keepPath},
+		{bundleKindKeepSA, legacyKeepPath},
+	}
+	seenKeep := false
+	for _, cand := range candidates {
+		if cand.kind == bundleKindKeepSA && seenKeep {
+			continue
+		}
+		data, readErr := os.ReadFile(cand.path) //nolint:gosec // stored in user config dir
+		if readErr != nil {
+			if os.IsNotExist(readErr) {
+				continue
+			}
+			return nil, fmt.Errorf("read service account: %w", readErr)
+		}
+		if _, parseErr := parseServiceAccountJSON(data); parseErr != nil {
+			return nil, fmt.Errorf("%s: %w", cand.path, parseErr)
+		}
+		if cand.kind == bundleKindKeepSA {
+			seenKeep = true
+		}
+		out = append(out, authBundleSA{Email: email, Kind: cand.kind, Key: json.RawMessage(data)})
+	}
+	return out, nil
+}
+
+func applyAuthBundle(bundle authBundle) error {
+	cfg, err := config.ReadConfig()
+	if err != nil {
+		return err
+	}
+	if bundle.Config.DefaultTimezone != "" && cfg.DefaultTimezone == "" {
+		cfg.DefaultTimezone = bundle.Config.DefaultTimezone
+	}
+	for alias, email := range bundle.Config.AccountAliases {
+		alias = config.NormalizeAccountAlias(alias)
+		if alias == "" {
+			continue
+		}
+		if cfg.AccountAliases == nil {
+			cfg.AccountAliases = map[string]string{}
+		}
+		cfg.AccountAliases[alias] = normalizeEmail(email)
+	}
+	for email, client := range bundle.Config.AccountClients {
+		if err := config.SetAccountClient(&cfg, email, client); err != nil {
+			return err
+		}
+	}
+	for domain, client := range bundle.Config.ClientDomains {
+		if err := config.SetClientDomain(&cfg, domain, client); err != nil {
+			return err
+		}
+	}
+	for _, client := range bundle.Config.TestingClients {
+		if err := config.SetClientTesting(&cfg, client, true); err != nil {
+			return err
+		}
+	}
+	for domain, route := range bundle.Config.ServiceAccountDomains {
+		if route.Key != "" {
+			if err := config.SetServiceAccountRoute(&cfg, domain, "", route.Key); err != nil {
+				return err
+			}
+		}
+		for service, key := range route.Services {
+			if err := config.SetServiceAccountRoute(&cfg, domain, service, key); err != nil {
+				return err
+			}
+		}
+	}
+	if err := config.WriteConfig(cfg); err != nil {
+		return err
+	}
+
+	for _, c := range bundle.Clients {
+		if err := config.WriteClientCredentialsFor(c.Client, config.ClientCredentials{
+			ClientID:     c.ClientID,
+			ClientSecret: c.ClientSecret,
+		}); err != nil {
+			return err
+		}
+	}
+
+	for _, sa := range bundle.ServiceAccounts {
+		var path string
+		switch sa.Kind {
+		case bundleKindSA:
+			path, err = config.ServiceAccountPath(sa.Email)
+		case bundleKindKeepSA:
+			path, err = config.KeepServiceAccountPath(sa.Email)
+		case bundleKindNamedSA:
+			path, err = config.ServiceAccountKeyPath(sa.Name)
+		default:
+			return fmt.Errorf("unknown service account kind %q", sa.Kind)
+		}
+		if err != nil {
+			return err
+		}
+		if _, parseErr := parseServiceAccountJSON(sa.Key); parseErr != nil {
+			return fmt.Errorf("service account %s%s: %w", sa.Email, sa.Name, parseErr)
+		}
+		if err := os.WriteFile(path, sa.Key, 0o600); err != nil {
+			return fmt.Errorf("write service account: %w", err)
+		}
+	}
+
+	for _, t := range bundle.Tracking {
+		tcfg := t.Config
+		if tcfg.SecretsInKeyring && tcfg.TrackingKey != "" && tcfg.AdminKey != "" {
+			if err := tracking.SaveSecrets(t.Account, tcfg.TrackingKey, tcfg.AdminKey); err != nil {
+				return err
+			}
+		}
+		if err := tracking.SaveConfig(t.Account, &tcfg); err != nil {
+			return err
+		}
+	}
+
+	if len(bundle.Tokens) == 0 && len(bundle.DefaultAccounts) == 0 {
+		return nil
+	}
+	store, err := openSecretsStore()
+	if err != nil {
+		return err
+	}
+	for _, t := range bundle.Tokens {
+		if err := store.SetToken(t.Client, t.Email, secrets.Token{
+			Client:          t.Client,
+			Email:           t.Email,
+			Services:        t.Services,
+			Scopes:          t.Scopes,
+			CreatedAt:       t.CreatedAt,
+			LastRefreshedAt: t.LastRefreshedAt,
+			RefreshToken:    t.RefreshToken,
+		}); err != nil {
+			return fmt.Errorf("store token for %s: %w", t.Email, err)
+		}
+	}
+	clients := make([]string, 0, len(bundle.DefaultAccounts))
+	for client := range bundle.DefaultAccounts {
+		clients = append(clients, client)
+	}
+	sort.Strings(clients)
+	for _, client := range clients {
+		if err := store.SetDefaultAccount(client, bundle.DefaultAccounts[client]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func resolveBundlePassphrase(passphraseFile string, confirm bool) (string, error) {
+	if path := strings.TrimSpace(passphraseFile); path != "" {
+		path, err := config.ExpandPath(path)
+		if err != nil {
+			return "", err
+		}
+		b, err := os.ReadFile(path) //nolint:gosec // user-provided path
+		if err != nil {
+			return "", fmt.Errorf("read passphrase file: %w", err)
+		}
+		pass := strings.TrimRight(string(b), "\r\n")
+		if pass == "" {
+			return "", usage("empty passphrase file")
+		}
+		return pass, nil
+	}
+	if v := os.Getenv(authBundlePassphraseEnv); v != "" {
+		return v, nil
+	}
+
+	pass, err := readBundlePassphrase("Bundle passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if pass == "" {
+		return "", usage("empty passphrase")
+	}
+	if confirm {
+		again, err := readBundlePassphrase("Repeat passphrase: ")
+		if err != nil {
+			return "", err
+		}
+		if again != pass {
+			return "", usage("passphrases do not match")
+		}
+	}
+	return pass, nil
+}
+
+func readPassphraseFromTTY(prompt string) (string, error) {
+	return readSecretFromTTY(prompt, fmt.Sprintf("use --passphrase-file or set %s", authBundlePassphraseEnv))
+}
+
+// readSecretFromTTY prompts on stderr and reads a line without echo. hint
+// explains the non-interactive alternative when stdin is not a terminal.
+func readSecretFromTTY(prompt string, hint string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", usagef("no TTY for passphrase prompt; %s", hint)
+	}
+	_, _ = fmt.Fprint(os.Stderr, prompt)
+	b, err := term.ReadPassword(fd)
+	_, _ = fmt.Fprintln(os.Stderr)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return "", &ExitError{Code: 1, Err: errors.New("cancelled")}
+		}
+		return "", fmt.Errorf("read passphrase: %w", err)
+	}
+	return string(b), nil
+}
@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steipete/gogcli/internal/config"
+	"github.com/steipete/gogcli/internal/outfmt"
+	"github.com/steipete/gogcli/internal/secrets"
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+func TestAuthBundleExportImport_RoundTrip(t *testing.T) {
+	origOpen := openSecretsStore
+	origEnsure := ensureKeychainAccess
+	t.Cleanup(func() {
+		openSecretsStore = origOpen
+		ensureKeychainAccess = origEnsure
+	})
+	ensureKeychainAccess = func() error { return nil }
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+	t.Setenv("GOG_KEYRING_BACKEND", "file")
+	t.Setenv(authBundlePassphraseEnv, "bundle-pass")
+
+	if err := config.WriteConfig(config.File{
+		AccountAliases: map[string]string{"work": "a@b.com"},
+		AccountClients: map[string]string{"a@b.com": "work"},
+		ClientDomains:  map[string]string{"b.com": "work"},
+		ServiceAccountDomains: map[string]config.ServiceAccountRoute{
+			"c.com": {Key: "tenant-c"},
+		},
+	}); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if err := config.WriteClientCredentialsFor("work", config.ClientCredentials{ClientID: "id", ClientSecret: "secret"}); err != nil {
+		t.Fatalf("write creds: %v", err)
+	}
+	saPath, err := config.ServiceAccountPath("sa@b.com")
+	if err != nil {
+		t.Fatalf("sa path: %v", err)
+	}
+	if err := os.WriteFile(saPath, []byte(`{"type":"service_account","client_email":"bot@x.iam.gserviceaccount.com"}`), 0o600); err != nil {
+		t.Fatalf("write sa: %v", err)
+	}
+	namedPath, err := config.ServiceAccountKeyPath("tenant-c")
+	if err != nil {
+		t.Fatalf("named key path: %v", err)
+	}
+	if err := os.WriteFile(namedPath, []byte(`{"type":"service_account","client_email":"dwd@c.iam.gserviceaccount.com"}`), 0o600); err != nil {
+		t.Fatalf("write named key: %v", err)
+	}
+
+	store := newMemStore()
+	openSecretsStore = func() (secrets.Store, error) { return store, nil }
+	if err := store.SetToken("work", "a@b.com", secrets.Token{
+		Email:        "a@b.com",
+		RefreshToken: "rt",
+		Services:     []string{"gmail"},
+		CreatedAt:    time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+	}); err != nil {
+		t.Fatalf("SetToken: %v", err)
+	}
+
+	u, uiErr := ui.New(ui.Options{Stdout: os.Stdout, Stderr: os.Stderr, Color: "never"})
+	if uiErr != nil {
+		t.Fatalf("ui.New: %v", uiErr)
+	}
+	ctx := outfmt.WithMode(ui.WithUI(context.Background(), u), outfmt.Mode{JSON: true})
+
+	bundlePath := filepath.Join(t.TempDir(), "gog.bundle")
+	_ = captureStdout(t, func() {
+		exportCmd := AuthBundleExportCmd{Output: OutputPathRequiredFlag{Path: bundlePath}}
+		if err := exportCmd.Run(ctx); err != nil {
+			t.Fatalf("export: %v", err)
+		}
+	})
+	raw, err := os.ReadFile(bundlePath)
+	if err != nil {
+		t.Fatalf("read bundle: %v", err)
+	}
+	if strings.Contains(string(raw), "secret") || strings.Contains(string(raw), "a@b.com") {
+		t.Fatalf("bundle not encrypted: %s", raw)
+	}
+
+	// Fresh machine: empty config dir and keyring.
+	home2 := t.TempDir()
+	t.Setenv("HOME", home2)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home2, "xdg-config"))
+	newStore := newMemStore()
+	openSecretsStore = func() (secrets.Store, error) { return newStore, nil }
+
+	out := captureStdout(t, func() {
+		importCmd := AuthBundleImportCmd{InPath: bundlePath, DryRun: true}
+		if err := importCmd.Run(ctx); err != nil {
+			t.Fatalf("dry-run import: %v", err)
+		}
+	})
+	var plan struct {
+		DryRun bool             `json:"dry_run"`
+		Items  []authBundleItem `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(out), &plan); err != nil {
+		t.Fatalf("parse dry-run: %v (%q)", err, out)
+	}
+	if !plan.DryRun || len(plan.Items) != 8 {
+		t.Fatalf("unexpected plan: %#v", plan)
+	}
+	if len(newStore.tokens) != 0 {
+		t.Fatalf("dry-run wrote tokens")
+	}
+	if ok, _ := config.ClientCredentialsExists("work"); ok {
+		t.Fatalf("dry-run wrote credentials")
+	}
+
+	_ = captureStdout(t, func() {
+		importCmd := AuthBundleImportCmd{InPath: bundlePath}
+		if err := importCmd.Run(ctx); err != nil {
+			t.Fatalf("import: %v", err)
+		}
+	})
+
+	tok, err := newStore.GetToken("work", "a@b.com")
+	if err != nil || tok.RefreshToken != "rt" || tok.Services[0] != "gmail" {
+		t.Fatalf("unexpected token: %#v err=%v", tok, err)
+	}
+	creds, err := config.ReadClientCredentialsFor("work")
+	if err != nil || creds.ClientSecret != "secret" {
+		t.Fatalf("unexpected creds: %#v err=%v", creds, err)
+	}
+	cfg, err := config.ReadConfig()
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+	if cfg.AccountAliases["work"] != "a@b.com" || cfg.ClientDomains["b.com"] != "work" {
+		t.Fatalf("unexpected config: %#v", cfg)
+	}
+	emails, err := config.ListServiceAccountEmails()
+	if err != nil || len(emails) != 1 || emails[0] != "sa@b.com" {
+		t.Fatalf("unexpected service accounts: %v err=%v", emails, err)
+	}
+	if key, ok := config.ServiceAccountKeyForAccount(cfg, "x@c.com", "gmail"); !ok || key != "tenant-c" {
+		t.Fatalf("service account route not restored: %#v", cfg.ServiceAccountDomains)
+	}
+	if keys, keysErr := config.ListServiceAccountKeys(); keysErr != nil || len(keys) != 1 || keys[0].Name != "tenant-c" {
+		t.Fatalf("named keys not restored: %v err=%v", keys, keysErr)
+	}
+}
+
+func TestAuthBundleImport_WrongPassphrase(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	sealed, err := secrets.SealWithPassphrase([]byte(`{"version":1}`), "right")
+	if err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+	path := filepath.Join(home, "gog.bundle")
+	if err := os.WriteFile(path, sealed, 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	passFile := filepath.Join(home, "pass")
+	if err := os.WriteFile(passFile, []byte("wrong\n"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	cmd := AuthBundleImportCmd{InPath: path, PassphraseFile: passFile}
+	if err := cmd.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "decrypt bundle") {
+		t.Fatalf("expected decrypt error, got %v", err)
+	}
+}
+
+func TestResolveBundlePassphrase_Confirm(t *testing.T) {
+	orig := readBundlePassphrase
+	t.Cleanup(func() { readBundlePassphrase = orig })
+	t.Setenv(authBundlePassphraseEnv, "")
+
+	answers := []string{"a", "b"}
+	readBundlePassphrase = func(string) (string, error) {
+		v := answers[0]
+		answers = answers[1:]
+		return v, nil
+	}
+	if _, err := resolveBundlePassphrase("", true); err == nil || !strings.Contains(err.Error(), "do not match") {
+		t.Fatalf("expected mismatch error, got %v", err)
+	}
+}
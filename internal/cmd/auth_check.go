@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/steipete/gogcli/internal/config"
+	"github.com/steipete/gogcli/internal/googleauth"
+	"github.com/steipete/gogcli/internal/outfmt"
+	"github.com/steipete/gogcli/internal/secrets"
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+const (
+	tokenHealthOK          = "ok"
+	tokenHealthExpiring    = "expiring"
+	tokenHealthNeedsReauth = "needs_reauth"
+	// tokenHealthError means the check itself failed (network, timeout,
+	// credentials file), so the token's state is unknown.
+	tokenHealthError = "error"
+)
+
+type AuthCheckCmd struct {
+	ExitCode bool          `name:"exit-code" help:"Exit non-zero if any account needs re-auth (for cron monitors)"`
+	Timeout  time.Duration `name:"timeout" help:"Per-token check timeout" default:"15s"`
+}
+
+type authCheckResult struct {
+	Email           string `json:"email"`
+	Client          string `json:"client"`
+	Status          string `json:"status"`
+	Testing         bool   `json:"testing,omitempty"`
+	CreatedAt       string `json:"created_at,omitempty"`
+	LastRefreshedAt string `json:"last_refreshed_at,omitempty"`
+	ExpiresAt       string `json:"expires_at,omitempty"`
+	Error           string `json:"error,omitempty"`
+}
+
+func (c *AuthCheckCmd) Run(ctx context.Context) error {
+	u := ui.FromContext(ctx)
+	cfg, err := config.ReadConfig()
+	if err != nil {
+		return err
+	}
+	store, err := openSecretsStore()
+	if err != nil {
+		return err
+	}
+	tokens, err := store.ListTokens()
+	if err != nil {
+		return err
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		return secrets.TokenKey(tokens[i].Client, tokens[i].Email) < secrets.TokenKey(tokens[j].Client, tokens[j].Email)
+	})
+
+	results := make([]authCheckResult, 0, len(tokens))
+	needsReauth := 0
+	for _, tok := range tokens {
+		if strings.TrimSpace(tok.Email) == "" {
+			continue
+		}
+		res := checkTokenHealth(ctx, store, cfg, tok, c.Timeout)
+		if res.Status == tokenHealthNeedsReauth {
+			needsReauth++
+		}
+		results = append(results, res)
+	}
+
+	if outfmt.IsJSON(ctx) {
+		if err := outfmt.WriteJSON(os.Stdout, map[string]any{
+			"accounts":     results,
+			"needs_reauth": needsReauth,
+		}); err != nil {
+			return err
+		}
+	} else if len(results) == 0 {
+		u.Err().Println("No tokens stored")
+	} else {
+		w, done := tableWriter(ctx)
+		_, _ = fmt.Fprintln(w, "EMAIL\tCLIENT\tSTATUS\tEXPIRES\tLAST_REFRESHED\tERROR")
+		for _, r := range results {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", r.Email, r.Client, r.Status, r.ExpiresAt, r.LastRefreshedAt, r.Error)
+		}
+		done()
+	}
+
+	for _, r := range results {
+		switch r.Status {
+		case tokenHealthExpiring:
+			u.Err().Printf("WARNING: %s (Testing mode) expires at %s; re-run: gog auth add %s --force-consent", r.Email, r.ExpiresAt, r.Email)
+		case tokenHealthNeedsReauth:
+			u.Err().Printf("WARNING: %s needs re-auth; run: gog auth add %s --force-consent", r.Email, r.Email)
+		case tokenHealthError:
+			u.Err().Printf("WARNING: could not check %s: %s", r.Email, r.Error)
+		}
+	}
+
+	if c.ExitCode && needsReauth > 0 {
+		return &ExitError{Code: 1, Err: fmt.Errorf("%d account(s) need re-auth", needsReauth)}
+	}
+	return nil
+}
+
+// checkTokenHealth exchanges the refresh token and combines the result with
+// the Testing-mode 7-day limit. Successful checks are recorded in the token's
+// LastRefreshedAt metadata.
+func checkTokenHealth(ctx context.Context, store secrets.Store, cfg config.File, tok secrets.Token, timeout time.Duration) authCheckResult {
+	client, err := config.NormalizeClientNameOrDefault(tok.Client)
+	if err != nil {
+		client = tok.Client
+	}
+	res := authCheckResult{
+		Email:   tok.Email,
+		Client:  client,
+		Status:  tokenHealthOK,
+		Testing: config.ClientIsTesting(cfg, client),
+	}
+	if !tok.CreatedAt.IsZero() {
+		res.CreatedAt = tok.CreatedAt.UTC().Format(time.RFC3339)
+	}
+
+	now := time.Now()
+	if checkErr := checkRefreshToken(ctx, client, tok.RefreshToken, tok.Scopes, timeout); checkErr != nil {
+		res.Status = tokenHealthError
+		if isInvalidGrant(checkErr) {
+			res.Status = tokenHealthNeedsReauth
+		}
+		res.Error = checkErr.Error()
+	} else {
+		tok.LastRefreshedAt = now.UTC()
+		if setErr := store.SetToken(client, tok.Email, tok); setErr != nil {
+			res.Error = fmt.Sprintf("record refresh: %v", setErr)
+		}
+	}
+	if !tok.LastRefreshedAt.IsZero() {
+		res.LastRefreshedAt = tok.LastRefreshedAt.UTC().Format(time.RFC3339)
+	}
+
+	if res.Testing {
+		exp := googleauth.TestingTokenExpiryAt(tok.CreatedAt, now)
+		if !exp.ExpiresAt.IsZero() {
+			res.ExpiresAt = exp.ExpiresAt.UTC().Format(time.RFC3339)
+		}
+		switch {
+		case exp.Expired:
+			res.Status = tokenHealthNeedsReauth
+		case exp.ExpiringSoon && res.Status == tokenHealthOK:
+			res.Status = tokenHealthExpiring
+		}
+	}
+	return res
+}
+
+// isInvalidGrant reports whether Google rejected the refresh token itself, as
+// opposed to the check failing on the way there.
+func isInvalidGrant(err error) bool {
+	var retrieveErr *oauth2.RetrieveError
+	return errors.As(err, &retrieveErr) && retrieveErr.ErrorCode == "invalid_grant"
+}
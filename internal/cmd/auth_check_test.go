@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/steipete/gogcli/internal/config"
+	"github.com/steipete/gogcli/internal/secrets"
+)
+
+func TestAuthCheck_ExitCodeAndTestingExpiry(t *testing.T) {
+	origOpen := openSecretsStore
+	origCheck := checkRefreshToken
+	t.Cleanup(func() {
+		openSecretsStore = origOpen
+		checkRefreshToken = origCheck
+	})
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+	if err := config.WriteConfig(config.File{TestingClients: []string{"default"}}); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	store := newMemSecretsStore()
+	openSecretsStore = func() (secrets.Store, error) { return store, nil }
+	now := time.Now().UTC()
+	tokens := map[string]secrets.Token{
+		"fresh@b.com":    {RefreshToken: "ok", CreatedAt: now.Add(-24 * time.Hour)},
+		"expiring@b.com": {RefreshToken: "ok", CreatedAt: now.Add(-6 * 24 * time.Hour)},
+		"invalid@b.com":  {RefreshToken: "bad", CreatedAt: now.Add(-time.Hour)},
+		"offline@b.com":  {RefreshToken: "net", CreatedAt: now.Add(-time.Hour)},
+	}
+	for email, tok := range tokens {
+		if err := store.SetToken(config.DefaultClientName, email, tok); err != nil {
+			t.Fatalf("SetToken: %v", err)
+		}
+	}
+	checkRefreshToken = func(_ context.Context, _ string, refreshToken string, _ []string, _ time.Duration) error {
+		switch refreshToken {
+		case "bad":
+			return fmt.Errorf("refresh access token: %w", &oauth2.RetrieveError{ErrorCode: "invalid_grant"})
+		case "net":
+			return fmt.Errorf("refresh access token: %w", errors.New("dial tcp: lookup oauth2.googleapis.com: no such host"))
+		}
+		return nil
+	}
+
+	var runErr error
+	out := captureStdout(t, func() {
+		_ = captureStderr(t, func() {
+			runErr = Execute([]string{"--json", "auth", "check", "--exit-code"})
+		})
+	})
+	var exitErr *ExitError
+	if !errors.As(runErr, &exitErr) || exitErr.Code != 1 {
+		t.Fatalf("expected exit 1, got %v", runErr)
+	}
+
+	var resp struct {
+		Accounts []authCheckResult `json:"accounts"`
+		Needs    int               `json:"needs_reauth"`
+	}
+	if err := json.Unmarshal([]byte(out), &resp); err != nil {
+		t.Fatalf("json: %v (%q)", err, out)
+	}
+	want := map[string]string{
+		"fresh@b.com":    tokenHealthOK,
+		"expiring@b.com": tokenHealthExpiring,
+		"invalid@b.com":  tokenHealthNeedsReauth,
+		"offline@b.com":  tokenHealthError,
+	}
+	if resp.Needs != 1 || len(resp.Accounts) != 4 {
+		t.Fatalf("unexpected resp: %#v", resp)
+	}
+	for _, a := range resp.Accounts {
+		if want[a.Email] != a.Status {
+			t.Fatalf("unexpected status for %s: %s", a.Email, a.Status)
+		}
+		if !a.Testing || a.ExpiresAt == "" {
+			t.Fatalf("expected testing expiry for %s: %#v", a.Email, a)
+		}
+	}
+
+	tok, err := store.GetToken(config.DefaultClientName, "fresh@b.com")
+	if err != nil || tok.LastRefreshedAt.IsZero() {
+		t.Fatalf("expected last refresh recorded: %#v err=%v", tok, err)
+	}
+	tok, err = store.GetToken(config.DefaultClientName, "invalid@b.com")
+	if err != nil || !tok.LastRefreshedAt.IsZero() {
+		t.Fatalf("expected no refresh recorded for invalid token: %#v err=%v", tok, err)
+	}
+}
+
+func TestAuthCheck_NoExitCodeByDefault(t *testing.T) {
+	origOpen := openSecretsStore
+	origCheck := checkRefreshToken
+	t.Cleanup(func() {
+		openSecretsStore = origOpen
+		checkRefreshToken = origCheck
+	})
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	store := newMemSecretsStore()
+	openSecretsStore = func() (secrets.Store, error) { return store, nil }
+	if err := store.SetToken(config.DefaultClientName, "a@b.com", secrets.Token{RefreshToken: "rt"}); err != nil {
+		t.Fatalf("SetToken: %v", err)
+	}
+	checkRefreshToken = func(context.Context, string, string, []string, time.Duration) error {
+		return &oauth2.RetrieveError{ErrorCode: "invalid_grant"}
+	}
+
+	var errOut string
+	out := captureStdout(t, func() {
+		errOut = captureStderr(t, func() {
+			if err := Execute([]string{"auth", "check"}); err != nil {
+				t.Fatalf("check: %v", err)
+			}
+		})
+	})
+	if !strings.Contains(out, tokenHealthNeedsReauth) || !strings.Contains(errOut, "needs re-auth") {
+		t.Fatalf("unexpected output: out=%q err=%q", out, errOut)
+	}
+}
+
+func TestAuthCheck_NetworkErrorIsNotReauth(t *testing.T) {
+	origOpen := openSecretsStore
+	origCheck := checkRefreshToken
+	t.Cleanup(func() {
+		openSecretsStore = origOpen
+		checkRefreshToken = origCheck
+	})
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	store := newMemSecretsStore()
+	openSecretsStore = func() (secrets.Store, error) { return store, nil }
+	if err := store.SetToken(config.DefaultClientName, "a@b.com", secrets.Token{RefreshToken: "rt"}); err != nil {
+		t.Fatalf("SetToken: %v", err)
+	}
+	checkRefreshToken = func(context.Context, string, string, []string, time.Duration) error {
+		return context.DeadlineExceeded
+	}
+
+	var runErr error
+	var errOut string
+	out := captureStdout(t, func() {
+		errOut = captureStderr(t, func() {
+			runErr = Execute([]string{"auth", "check", "--exit-code"})
+		})
+	})
+	if runErr != nil {
+		t.Fatalf("a failed check must not report re-auth: %v", runErr)
+	}
+	if !strings.Contains(out, tokenHealthError) || strings.Contains(out, tokenHealthNeedsReauth) || !strings.Contains(errOut, "could not check a@b.com") {
+		t.Fatalf("unexpected output: out=%q err=%q", out, errOut)
+	}
+}
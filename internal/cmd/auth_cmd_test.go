@@ -350,13 +350,16 @@ func TestAuthTokensImport_FileBackendSkipsKeychain(t *testing.T) {
 func TestAuthListRemoveTokensListDelete_JSON(t *testing.T) {
 	origOpen := openSecretsStore
 	origCheck := checkRefreshToken
+	origRevoke := revokeRefreshToken
 	t.Cleanup(func() {
 		openSecretsStore = origOpen
 		checkRefreshToken = origCheck
+		revokeRefreshToken = origRevoke
 	})
 
 	store := newMemSecretsStore()
 	openSecretsStore = func() (secrets.Store, error) { return store, nil }
+	revokeRefreshToken = func(context.Context, string, time.Duration) error { return nil }
 
 	checkRefreshToken = func(_ context.Context, _ string, refreshToken string, _ []string, _ time.Duration) error {
 		if refreshToken == "rt2" {
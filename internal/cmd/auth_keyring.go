@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"strings"
 
@@ -14,23 +15,21 @@ import (
 )
 
 type AuthKeyringCmd struct {
-	Backend  string `arg:"" optional:"" name:"backend" help:"Keyring backend: auto|keychain|file"`
-	Backend2 string `arg:"" optional:"" name:"backend2" help:"(compat) Use: gog auth keyring set <backend>"`
+	Set     AuthKeyringSetCmd     `cmd:"" name:"set" default:"withargs" help:"Show or set keyring backend"`
+	Rekey   AuthKeyringRekeyCmd   `cmd:"" name:"rekey" help:"Change the file keyring password (re-encrypts every item)"`
+	Migrate AuthKeyringMigrateCmd `cmd:"" name:"migrate" help:"Move all keyring items to another backend"`
 }
 
-func (c *AuthKeyringCmd) Run(ctx context.Context) error {
+type AuthKeyringSetCmd struct {
+	Backend string `arg:"" optional:"" name:"backend" help:"Keyring backend: auto|keychain|file"`
+}
+
+func (c *AuthKeyringSetCmd) Run(ctx context.Context) error {
 	u := ui.FromContext(ctx)
 
 	const keyringPasswordEnv = "GOG_KEYRING_PASSWORD" //nolint:gosec // env var name, not a credential
 
 	backend := strings.ToLower(strings.TrimSpace(c.Backend))
-	backend2 := strings.ToLower(strings.TrimSpace(c.Backend2))
-
-	// Backwards compat for earlier suggestion: `gog auth keyring set <backend>`.
-	if backend == "set" {
-		backend = backend2
-		backend2 = ""
-	}
 
 	// No args: show current config.
 	if backend == "" {
@@ -58,10 +57,6 @@ func (c *AuthKeyringCmd) Run(ctx context.Context) error {
 		return nil
 	}
 
-	if backend2 != "" {
-		return usagef("too many args: %q %q", c.Backend, c.Backend2)
-	}
-
 	if backend == "default" {
 		backend = "auto"
 	}
@@ -124,3 +119,159 @@ func (c *AuthKeyringCmd) Run(ctx context.Context) error {
 	u.Out().Printf("keyring_backend\t%s", backend)
 	return nil
 }
+
+const keyringNewPasswordEnv = "GOG_KEYRING_NEW_PASSWORD" //nolint:gosec // env var name, not a credential
+
+var (
+	rekeyFileKeyring    = secrets.RekeyFileKeyring
+	usesFileKeyring     = secrets.UsesFileKeyring
+	migrateKeyring      = secrets.MigrateKeyring
+	readKeyringPassword = readSecretFromTTY
+)
+
+type AuthKeyringRekeyCmd struct {
+	NewPasswordFile string `name:"new-password-file" help:"Read the new password from a file (default: $GOG_KEYRING_NEW_PASSWORD or prompt)"`
+}
+
+func (c *AuthKeyringRekeyCmd) Run(ctx context.Context) error {
+	u := ui.FromContext(ctx)
+
+	fileBackend, err := usesFileKeyring()
+	if err != nil {
+		return err
+	}
+	if !fileBackend {
+		return usage("auth keyring rekey only works with the file keyring backend (see gog auth keyring)")
+	}
+
+	oldPassword := os.Getenv("GOG_KEYRING_PASSWORD")
+	if oldPassword == "" {
+		p, err := readKeyringPassword("Current keyring password: ", "set GOG_KEYRING_PASSWORD")
+		if err != nil {
+			return err
+		}
+		oldPassword = p
+	}
+	if oldPassword == "" {
+		return usage("empty current password")
+	}
+
+	newPassword, err := c.newPassword()
+	if err != nil {
+		return err
+	}
+	if newPassword == oldPassword {
+		return usage("new password matches the current one")
+	}
+
+	n, err := rekeyFileKeyring(oldPassword, newPassword)
+	if err != nil {
+		return err
+	}
+
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(os.Stdout, map[string]any{
+			"rekeyed": true,
+			"items":   n,
+		})
+	}
+	u.Out().Printf("rekeyed\ttrue")
+	u.Out().Printf("items\t%d", n)
+	if os.Getenv("GOG_KEYRING_PASSWORD") != "" {
+		u.Err().Println("NOTE: update GOG_KEYRING_PASSWORD to the new password")
+	}
+	return nil
+}
+
+func (c *AuthKeyringRekeyCmd) newPassword() (string, error) {
+	if path := strings.TrimSpace(c.NewPasswordFile); path != "" {
+		path, err := config.ExpandPath(path)
+		if err != nil {
+			return "", err
+		}
+		b, err := os.ReadFile(path) //nolint:gosec // user-provided path
+		if err != nil {
+			return "", fmt.Errorf("read password file: %w", err)
+		}
+		pass := strings.TrimRight(string(b), "\r\n")
+		if pass == "" {
+			return "", usage("empty password file")
+		}
+		return pass, nil
+	}
+	if v := os.Getenv(keyringNewPasswordEnv); v != "" {
+		return v, nil
+	}
+
+	hint := fmt.Sprintf("use --new-password-file or set %s", keyringNewPasswordEnv)
+	pass, err := readKeyringPassword("New keyring password: ", hint)
+	if err != nil {
+		return "", err
+	}
+	if pass == "" {
+		return "", usage("empty password")
+	}
+	again, err := readKeyringPassword("Repeat new password: ", hint)
+	if err != nil {
+		return "", err
+	}
+	if again != pass {
+		return "", usage("passwords do not match")
+	}
+	return pass, nil
+}
+
+type AuthKeyringMigrateCmd struct {
+	To         string `name:"to" required:"" enum:"keychain,file" help:"Destination backend: keychain|file"`
+	KeepSource bool   `name:"keep-source" help:"Copy only; leave items in the current backend"`
+}
+
+func (c *AuthKeyringMigrateCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+
+	if !c.KeepSource {
+		if err := confirmDestructive(ctx, flags, fmt.Sprintf("move all keyring items to the %s backend (removing them from the current one)", c.To)); err != nil {
+			return err
+		}
+	}
+
+	res, err := migrateKeyring(c.To, c.KeepSource)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.ReadConfig()
+	if err != nil {
+		return err
+	}
+	cfg.KeyringBackend = c.To
+	if err := config.WriteConfig(cfg); err != nil {
+		return err
+	}
+
+	if u != nil {
+		if v := strings.TrimSpace(os.Getenv("GOG_KEYRING_BACKEND")); v != "" {
+			u.Err().Printf("NOTE: GOG_KEYRING_BACKEND=%s overrides config.json; unset it to use %s", v, c.To)
+		}
+		if len(res.RemoveFailed) > 0 {
+			u.Err().Printf("WARNING: %d item(s) could not be removed from the old backend: %s", len(res.RemoveFailed), strings.Join(res.RemoveFailed, ", "))
+		}
+	}
+
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(os.Stdout, map[string]any{
+			"keyring_backend": c.To,
+			"copied":          res.Copied,
+			"removed":         res.Removed,
+			"source_removed":  res.SourceRemoved,
+			"remove_failed":   res.RemoveFailed,
+		})
+	}
+	if u == nil {
+		return nil
+	}
+	u.Out().Printf("keyring_backend\t%s", c.To)
+	u.Out().Printf("copied\t%d", res.Copied)
+	u.Out().Printf("removed\t%d", res.Removed)
+	return nil
+}
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/steipete/gogcli/internal/config"
+	"github.com/steipete/gogcli/internal/outfmt"
+	"github.com/steipete/gogcli/internal/secrets"
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+func TestAuthKeyringRekey_FileBackend(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+	t.Setenv("GOG_KEYRING_BACKEND", "file")
+	t.Setenv("GOG_KEYRING_PASSWORD", "old-pass")
+	t.Setenv(keyringNewPasswordEnv, "")
+
+	store, err := secrets.OpenDefault()
+	if err != nil {
+		t.Fatalf("OpenDefault: %v", err)
+	}
+	if err := store.SetToken("default", "a@b.com", secrets.Token{Email: "a@b.com", RefreshToken: "rt"}); err != nil {
+		t.Fatalf("SetToken: %v", err)
+	}
+
+	passFile := filepath.Join(t.TempDir(), "new-pass")
+	if err := os.WriteFile(passFile, []byte("new-pass\n"), 0o600); err != nil {
+		t.Fatalf("write pass file: %v", err)
+	}
+
+	u, err := ui.New(ui.Options{Stdout: os.Stdout, Stderr: os.Stderr, Color: "never"})
+	if err != nil {
+		t.Fatalf("ui.New: %v", err)
+	}
+	ctx := outfmt.WithMode(ui.WithUI(context.Background(), u), outfmt.Mode{JSON: true})
+
+	out := captureStdout(t, func() {
+		if err := runKong(t, &AuthKeyringCmd{}, []string{"rekey", "--new-password-file", passFile}, ctx, nil); err != nil {
+			t.Fatalf("rekey: %v", err)
+		}
+	})
+	var parsed struct {
+		Rekeyed bool `json:"rekeyed"`
+		Items   int  `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(out), &parsed); err != nil {
+		t.Fatalf("json: %v (%q)", err, out)
+	}
+	// Token plus its legacy default-client copy.
+	if !parsed.Rekeyed || parsed.Items != 2 {
+		t.Fatalf("unexpected output: %q", out)
+	}
+
+	t.Setenv("GOG_KEYRING_PASSWORD", "new-pass")
+	store, err = secrets.OpenDefault()
+	if err != nil {
+		t.Fatalf("OpenDefault: %v", err)
+	}
+	tok, err := store.GetToken("default", "a@b.com")
+	if err != nil || tok.RefreshToken != "rt" {
+		t.Fatalf("token after rekey: %#v err=%v", tok, err)
+	}
+
+	t.Setenv(keyringNewPasswordEnv, "new-pass")
+	if err := runKong(t, &AuthKeyringCmd{}, []string{"rekey"}, ctx, nil); err == nil {
+		t.Fatalf("expected error when new password matches current")
+	}
+}
+
+func TestAuthKeyringRekey_RequiresFileBackend(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+	t.Setenv("GOG_KEYRING_BACKEND", "keychain")
+	t.Setenv("GOG_KEYRING_PASSWORD", "old-pass")
+	t.Setenv(keyringNewPasswordEnv, "new-pass")
+
+	origRekey := rekeyFileKeyring
+	t.Cleanup(func() { rekeyFileKeyring = origRekey })
+	rekeyFileKeyring = func(string, string) (int, error) {
+		t.Fatalf("rekey should not run for the keychain backend")
+		return 0, nil
+	}
+
+	u, err := ui.New(ui.Options{Stdout: os.Stdout, Stderr: os.Stderr, Color: "never"})
+	if err != nil {
+		t.Fatalf("ui.New: %v", err)
+	}
+	err = runKong(t, &AuthKeyringCmd{}, []string{"rekey"}, ui.WithUI(context.Background(), u), nil)
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 2 {
+		t.Fatalf("expected usage error, got %v", err)
+	}
+}
+
+func TestAuthKeyringMigrate_WritesBackend(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+	t.Setenv("GOG_KEYRING_BACKEND", "")
+
+	origMigrate := migrateKeyring
+	t.Cleanup(func() { migrateKeyring = origMigrate })
+
+	var gotTo string
+	var gotKeep bool
+	migrateKeyring = func(to string, keepSource bool) (secrets.MigrateResult, error) {
+		gotTo, gotKeep = to, keepSource
+		return secrets.MigrateResult{Copied: 3, Removed: 3, SourceRemoved: true}, nil
+	}
+
+	u, err := ui.New(ui.Options{Stdout: os.Stdout, Stderr: os.Stderr, Color: "never"})
+	if err != nil {
+		t.Fatalf("ui.New: %v", err)
+	}
+	ctx := ui.WithUI(context.Background(), u)
+
+	if err := runKong(t, &AuthKeyringCmd{}, []string{"migrate", "--to", "keychain"}, ctx, &RootFlags{NoInput: true}); err == nil {
+		t.Fatalf("expected confirmation to be required")
+	}
+	if gotTo != "" {
+		t.Fatalf("migrated without confirmation")
+	}
+
+	_ = captureStdout(t, func() {
+		if err := runKong(t, &AuthKeyringCmd{}, []string{"migrate", "--to", "file", "--keep-source"}, ctx, &RootFlags{NoInput: true}); err != nil {
+			t.Fatalf("migrate: %v", err)
+		}
+	})
+	if gotTo != "file" || !gotKeep {
+		t.Fatalf("unexpected migrate args: %q keep=%v", gotTo, gotKeep)
+	}
+
+	cfg, err := config.ReadConfig()
+	if err != nil {
+		t.Fatalf("ReadConfig: %v", err)
+	}
+	if cfg.KeyringBackend != "file" {
+		t.Fatalf("keyring_backend = %q", cfg.KeyringBackend)
+	}
+
+	if err := runKong(t, &AuthKeyringCmd{}, []string{"migrate", "--to", "auto"}, ctx, &RootFlags{Force: true}); err == nil {
+		t.Fatalf("expected enum error for --to auto")
+	}
+}
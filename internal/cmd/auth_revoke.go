@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/99designs/keyring"
+
+	"github.com/steipete/gogcli/internal/authclient"
+	"github.com/steipete/gogcli/internal/config"
+	"github.com/steipete/gogcli/internal/googleauth"
+	"github.com/steipete/gogcli/internal/outfmt"
+	"github.com/steipete/gogcli/internal/secrets"
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+var revokeRefreshToken = googleauth.RevokeToken
+
+const (
+	revokeStatusRevoked        = "revoked"
+	revokeStatusAlreadyInvalid = "already_invalid"
+	revokeStatusNoToken        = "no_token"
+	revokeStatusSkipped        = "skipped"
+	revokeStatusFailed         = "failed"
+)
+
+const revokeTimeout = 15 * time.Second
+
+// revokeStoredToken revokes the stored refresh token for client/email at Google.
+// A missing token or one Google already considers invalid is not an error;
+// other keyring failures (locked keyring, wrong password) are.
+func revokeStoredToken(ctx context.Context, store secrets.Store, client string, email string) (string, error) {
+	tok, err := store.GetToken(client, email)
+	if errors.Is(err, keyring.ErrKeyNotFound) {
+		return revokeStatusNoToken, nil
+	}
+	if err != nil {
+		return revokeStatusFailed, fmt.Errorf("read token for %s: %w", email, err)
+	}
+	if strings.TrimSpace(tok.RefreshToken) == "" {
+		return revokeStatusNoToken, nil
+	}
+	if err := revokeRefreshToken(ctx, tok.RefreshToken, revokeTimeout); err != nil {
+		if errors.Is(err, googleauth.ErrTokenAlreadyInvalid) {
+			return revokeStatusAlreadyInvalid, nil
+		}
+		return revokeStatusFailed, err
+	}
+	return revokeStatusRevoked, nil
+}
+
+// deleteStoredToken optionally revokes, then removes, a stored refresh token.
+// A failed revocation leaves the keyring entry in place so the still-valid
+// token isn't orphaned at Google.
+func deleteStoredToken(ctx context.Context, client string, email string, revoke bool) (string, error) {
+	store, err := openSecretsStore()
+	if err != nil {
+		return "", err
+	}
+	status := revokeStatusSkipped
+	if revoke {
+		status, err = revokeStoredToken(ctx, store, client, email)
+		if err != nil {
+			return status, fmt.Errorf("%w (token kept; retry, or use --no-revoke to delete locally only)", err)
+		}
+	}
+	if err := store.DeleteToken(client, email); err != nil {
+		return status, err
+	}
+	return status, nil
+}
+
+type AuthRevokeAllCmd struct {
+	KeepLocal bool `name:"keep-local" help:"Revoke at Google but keep keyring entries"`
+}
+
+func (c *AuthRevokeAllCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	client, err := config.NormalizeClientNameOrDefault(authclient.ClientOverrideFromContext(ctx))
+	if err != nil {
+		return err
+	}
+
+	if err := confirmDestructive(ctx, flags, fmt.Sprintf("revoke every stored token for client %s", client)); err != nil {
+		return err
+	}
+
+	store, err := openSecretsStore()
+	if err != nil {
+		return err
+	}
+	tokens, err := store.ListTokens()
+	if err != nil {
+		return err
+	}
+
+	type result struct {
+		Email   string `json:"email"`
+		Client  string `json:"client"`
+		Status  string `json:"status"`
+		Deleted bool   `json:"deleted"`
+		Error   string `json:"error,omitempty"`
+	}
+	results := make([]result, 0, len(tokens))
+	failed := 0
+	for _, tok := range tokens {
+		tokClient, normErr := config.NormalizeClientNameOrDefault(tok.Client)
+		if normErr != nil || tokClient != client || strings.TrimSpace(tok.Email) == "" {
+			continue
+		}
+		res := result{Email: tok.Email, Client: client, Status: revokeStatusRevoked}
+		if revokeErr := revokeRefreshToken(ctx, tok.RefreshToken, revokeTimeout); revokeErr != nil {
+			if errors.Is(revokeErr, googleauth.ErrTokenAlreadyInvalid) {
+				res.Status = revokeStatusAlreadyInvalid
+			} else {
+				res.Status = revokeStatusFailed
+				res.Error = revokeErr.Error()
+				failed++
+			}
+		}
+		if res.Status != revokeStatusFailed && !c.KeepLocal {
+			if delErr := store.DeleteToken(client, tok.Email); delErr != nil {
+				res.Error = delErr.Error()
+				failed++
+			} else {
+				res.Deleted = true
+			}
+		}
+		results = append(results, res)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Email < results[j].Email })
+
+	if outfmt.IsJSON(ctx) {
+		if err := outfmt.WriteJSON(os.Stdout, map[string]any{
+			"client":  client,
+			"results": results,
+			"failed":  failed,
+		}); err != nil {
+			return err
+		}
+	} else if len(results) == 0 {
+		u.Err().Printf("No tokens stored for client %s", client)
+	} else {
+		w, done := tableWriter(ctx)
+		_, _ = fmt.Fprintln(w, "EMAIL\tSTATUS\tDELETED\tERROR")
+		for _, r := range results {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%t\t%s\n", r.Email, r.Status, r.Deleted, r.Error)
+		}
+		done()
+	}
+
+	if failed > 0 {
+		return &ExitError{Code: 1, Err: fmt.Errorf("%d of %d tokens could not be revoked", failed, len(results))}
+	}
+	return nil
+}
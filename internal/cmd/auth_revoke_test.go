@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steipete/gogcli/internal/config"
+	"github.com/steipete/gogcli/internal/googleauth"
+	"github.com/steipete/gogcli/internal/secrets"
+)
+
+func stubRevoke(t *testing.T, fn func(token string) error) *[]string {
+	t.Helper()
+	orig := revokeRefreshToken
+	t.Cleanup(func() { revokeRefreshToken = orig })
+	var calls []string
+	revokeRefreshToken = func(_ context.Context, token string, _ time.Duration) error {
+		calls = append(calls, token)
+		return fn(token)
+	}
+	return &calls
+}
+
+func TestAuthRemove_RevokesByDefault(t *testing.T) {
+	origOpen := openSecretsStore
+	t.Cleanup(func() { openSecretsStore = origOpen })
+	store := newMemSecretsStore()
+	openSecretsStore = func() (secrets.Store, error) { return store, nil }
+	if err := store.SetToken(config.DefaultClientName, "a@b.com", secrets.Token{RefreshToken: "rt"}); err != nil {
+		t.Fatalf("SetToken: %v", err)
+	}
+	calls := stubRevoke(t, func(string) error { return nil })
+
+	out := captureStdout(t, func() {
+		if err := Execute([]string{"--json", "--force", "auth", "remove", "a@b.com"}); err != nil {
+			t.Fatalf("remove: %v", err)
+		}
+	})
+	if len(*calls) != 1 || (*calls)[0] != "rt" {
+		t.Fatalf("unexpected revoke calls: %v", *calls)
+	}
+	var resp struct {
+		Deleted bool   `json:"deleted"`
+		Revoked string `json:"revoked"`
+	}
+	if err := json.Unmarshal([]byte(out), &resp); err != nil {
+		t.Fatalf("json: %v (%q)", err, out)
+	}
+	if !resp.Deleted || resp.Revoked != revokeStatusRevoked {
+		t.Fatalf("unexpected resp: %#v", resp)
+	}
+	if _, err := store.GetToken(config.DefaultClientName, "a@b.com"); err == nil {
+		t.Fatalf("expected token deleted")
+	}
+}
+
+func TestAuthRemove_RevokeFailureKeepsToken(t *testing.T) {
+	origOpen := openSecretsStore
+	t.Cleanup(func() { openSecretsStore = origOpen })
+	store := newMemSecretsStore()
+	openSecretsStore = func() (secrets.Store, error) { return store, nil }
+	if err := store.SetToken(config.DefaultClientName, "a@b.com", secrets.Token{RefreshToken: "rt"}); err != nil {
+		t.Fatalf("SetToken: %v", err)
+	}
+	stubRevoke(t, func(string) error { return errors.New("network down") })
+
+	err := Execute([]string{"--force", "auth", "remove", "a@b.com"})
+	if err == nil || !strings.Contains(err.Error(), "--no-revoke") {
+		t.Fatalf("expected revoke error, got %v", err)
+	}
+	if _, getErr := store.GetToken(config.DefaultClientName, "a@b.com"); getErr != nil {
+		t.Fatalf("token should be kept: %v", getErr)
+	}
+
+	_ = captureStdout(t, func() {
+		if err := Execute([]string{"--force", "auth", "remove", "--no-revoke", "a@b.com"}); err != nil {
+			t.Fatalf("remove --no-revoke: %v", err)
+		}
+	})
+	if _, getErr := store.GetToken(config.DefaultClientName, "a@b.com"); getErr == nil {
+		t.Fatalf("expected token deleted")
+	}
+}
+
+func TestAuthTokensDelete_NoRevokeByDefault(t *testing.T) {
+	origOpen := openSecretsStore
+	t.Cleanup(func() { openSecretsStore = origOpen })
+	store := newMemSecretsStore()
+	openSecretsStore = func() (secrets.Store, error) { return store, nil }
+	if err := store.SetToken(config.DefaultClientName, "a@b.com", secrets.Token{RefreshToken: "rt"}); err != nil {
+		t.Fatalf("SetToken: %v", err)
+	}
+	calls := stubRevoke(t, func(string) error { return nil })
+
+	_ = captureStdout(t, func() {
+		if err := Execute([]string{"--force", "auth", "tokens", "delete", "a@b.com"}); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+	})
+	if len(*calls) != 0 {
+		t.Fatalf("unexpected revoke calls: %v", *calls)
+	}
+}
+
+func TestAuthRevokeAll_ReportsPerAccount(t *testing.T) {
+	origOpen := openSecretsStore
+	t.Cleanup(func() { openSecretsStore = origOpen })
+	store := newMemSecretsStore()
+	openSecretsStore = func() (secrets.Store, error) { return store, nil }
+	for email, rt := range map[string]string{"a@b.com": "ok", "b@b.com": "gone", "c@b.com": "fail"} {
+		if err := store.SetToken(config.DefaultClientName, email, secrets.Token{RefreshToken: rt}); err != nil {
+			t.Fatalf("SetToken: %v", err)
+		}
+	}
+	if err := store.SetToken("work", "d@b.com", secrets.Token{RefreshToken: "other"}); err != nil {
+		t.Fatalf("SetToken: %v", err)
+	}
+	calls := stubRevoke(t, func(token string) error {
+		switch token {
+		case "gone":
+			return googleauth.ErrTokenAlreadyInvalid
+		case "fail":
+			return errors.New("boom")
+		}
+		return nil
+	})
+
+	var runErr error
+	out := captureStdout(t, func() {
+		runErr = Execute([]string{"--json", "--force", "auth", "revoke-all"})
+	})
+	var exitErr *ExitError
+	if !errors.As(runErr, &exitErr) || exitErr.Code != 1 {
+		t.Fatalf("expected exit 1, got %v", runErr)
+	}
+	if len(*calls) != 3 {
+		t.Fatalf("expected only default-client tokens revoked: %v", *calls)
+	}
+	var resp struct {
+		Results []struct {
+			Email   string `json:"email"`
+			Status  string `json:"status"`
+			Deleted bool   `json:"deleted"`
+		} `json:"results"`
+		Failed int `json:"failed"`
+	}
+	if err := json.Unmarshal([]byte(out), &resp); err != nil {
+		t.Fatalf("json: %v (%q)", err, out)
+	}
+	if resp.Failed != 1 || len(resp.Results) != 3 {
+		t.Fatalf("unexpected resp: %#v", resp)
+	}
+	want := map[string]string{"a@b.com": revokeStatusRevoked, "b@b.com": revokeStatusAlreadyInvalid, "c@b.com": revokeStatusFailed}
+	for _, r := range resp.Results {
+		if want[r.Email] != r.Status {
+			t.Fatalf("unexpected status for %s: %s", r.Email, r.Status)
+		}
+		if r.Deleted == (r.Status == revokeStatusFailed) {
+			t.Fatalf("unexpected deleted for %s: %t", r.Email, r.Deleted)
+		}
+	}
+	if _, err := store.GetToken(config.DefaultClientName, "c@b.com"); err != nil {
+		t.Fatalf("failed revocation should keep token: %v", err)
+	}
+	if _, err := store.GetToken("work", "d@b.com"); err != nil {
+		t.Fatalf("other client token should be untouched: %v", err)
+	}
+}
+
+func TestRevokeStoredToken_KeyringErrors(t *testing.T) {
+	calls := stubRevoke(t, func(string) error { return nil })
+
+	status, err := revokeStoredToken(context.Background(), newMemSecretsStore(), config.DefaultClientName, "a@b.com")
+	if err != nil || status != revokeStatusNoToken {
+		t.Fatalf("missing token should be no_token, got %q %v", status, err)
+	}
+	status, err = revokeStoredToken(context.Background(), &memStoreErr{}, config.DefaultClientName, "a@b.com")
+	if err == nil || status != revokeStatusFailed {
+		t.Fatalf("keyring errors should surface, got %q %v", status, err)
+	}
+	if len(*calls) != 0 {
+		t.Fatalf("unexpected revoke calls: %v", *calls)
+	}
+}
@@ -16,6 +16,9 @@ type AuthServiceAccountCmd struct {
 	Set    AuthServiceAccountSetCmd    `cmd:"" name:"set" help:"Store a service account key for impersonation"`
 	Unset  AuthServiceAccountUnsetCmd  `cmd:"" name:"unset" help:"Remove stored service account key"`
 	Status AuthServiceAccountStatusCmd `cmd:"" name:"status" help:"Show stored service account key status"`
+	List   AuthServiceAccountListCmd   `cmd:"" name:"list" help:"List service account keys, domain routes, and scopes"`
+	Keys   AuthServiceAccountKeysCmd   `cmd:"" name:"keys" help:"Manage named service account keys for domain routing"`
+	Route  AuthServiceAccountRouteCmd  `cmd:"" name:"route" help:"Route a Workspace domain (or one service) to a named key"`
 }
 
 type serviceAccountJSONInfo struct {
@@ -43,39 +46,49 @@ func parseServiceAccountJSON(data []byte) (serviceAccountJSONInfo, error) {
 }
 
 func storeServiceAccountKey(impersonateEmail string, keyPath string) (string, serviceAccountJSONInfo, error) {
+	destPath, err := config.ServiceAccountPath(impersonateEmail)
+	if err != nil {
+		return "", serviceAccountJSONInfo{}, err
+	}
+
+	info, err := copyServiceAccountKey(keyPath, destPath)
+	if err != nil {
+		return "", serviceAccountJSONInfo{}, err
+	}
+
+	return destPath, info, nil
+}
+
+// copyServiceAccountKey validates the JSON key at keyPath and stores it at destPath.
+func copyServiceAccountKey(keyPath string, destPath string) (serviceAccountJSONInfo, error) {
 	keyPath = strings.TrimSpace(keyPath)
 	if keyPath == "" {
-		return "", serviceAccountJSONInfo{}, usage("empty key path")
+		return serviceAccountJSONInfo{}, usage("empty key path")
 	}
 	keyPath, err := config.ExpandPath(keyPath)
 	if err != nil {
-		return "", serviceAccountJSONInfo{}, err
+		return serviceAccountJSONInfo{}, err
 	}
 
 	data, err := os.ReadFile(keyPath) //nolint:gosec // user-provided path
 	if err != nil {
-		return "", serviceAccountJSONInfo{}, fmt.Errorf("read service account key: %w", err)
+		return serviceAccountJSONInfo{}, fmt.Errorf("read service account key: %w", err)
 	}
 
 	info, err := parseServiceAccountJSON(data)
 	if err != nil {
-		return "", serviceAccountJSONInfo{}, err
-	}
-
-	destPath, err := config.ServiceAccountPath(impersonateEmail)
-	if err != nil {
-		return "", serviceAccountJSONInfo{}, err
+		return serviceAccountJSONInfo{}, err
 	}
 
 	if _, err := config.EnsureDir(); err != nil {
-		return "", serviceAccountJSONInfo{}, err
+		return serviceAccountJSONInfo{}, err
 	}
 
 	if err := os.WriteFile(destPath, data, 0o600); err != nil {
-		return "", serviceAccountJSONInfo{}, fmt.Errorf("write service account: %w", err)
+		return serviceAccountJSONInfo{}, fmt.Errorf("write service account: %w", err)
 	}
 
-	return destPath, info, nil
+	return info, nil
 }
 
 type AuthServiceAccountSetCmd struct {
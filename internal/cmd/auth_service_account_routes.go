@@ -0,0 +1,487 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/steipete/gogcli/internal/config"
+	"github.com/steipete/gogcli/internal/googleapi"
+	"github.com/steipete/gogcli/internal/googleauth"
+	"github.com/steipete/gogcli/internal/outfmt"
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+var checkServiceAccountScopes = googleapi.CheckServiceAccountScopes
+
+type AuthServiceAccountKeysCmd struct {
+	Add    AuthServiceAccountKeysAddCmd    `cmd:"" name:"add" help:"Store a named service account key"`
+	Remove AuthServiceAccountKeysRemoveCmd `cmd:"" name:"remove" help:"Remove a named key and the routes using it"`
+}
+
+type AuthServiceAccountKeysAddCmd struct {
+	Name string `arg:"" name:"name" help:"Key name (e.g. tenant-a)"`
+	Key  string `name:"key" required:"" help:"Path to service account JSON key file"`
+}
+
+func (c *AuthServiceAccountKeysAddCmd) Run(ctx context.Context) error {
+	u := ui.FromContext(ctx)
+
+	name, err := config.NormalizeServiceAccountKeyName(c.Name)
+	if err != nil {
+		return usage(err.Error())
+	}
+	destPath, err := config.ServiceAccountKeyPath(name)
+	if err != nil {
+		return err
+	}
+
+	info, err := copyServiceAccountKey(c.Key, destPath)
+	if err != nil {
+		return err
+	}
+
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(os.Stdout, map[string]any{
+			"stored":       true,
+			"name":         name,
+			"path":         destPath,
+			"client_email": info.ClientEmail,
+			"client_id":    info.ClientID,
+		})
+	}
+	u.Out().Printf("name\t%s", name)
+	u.Out().Printf("path\t%s", destPath)
+	if info.ClientEmail != "" {
+		u.Out().Printf("client_email\t%s", info.ClientEmail)
+	}
+	if info.ClientID != "" {
+		u.Out().Printf("client_id\t%s", info.ClientID)
+	}
+	u.Out().Printf("Key stored. Route a domain to it with: gog auth service-account route set <domain> %s", name)
+	return nil
+}
+
+type AuthServiceAccountKeysRemoveCmd struct {
+	Name string `arg:"" name:"name" help:"Key name"`
+}
+
+func (c *AuthServiceAccountKeysRemoveCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+
+	name, err := config.NormalizeServiceAccountKeyName(c.Name)
+	if err != nil {
+		return usage(err.Error())
+	}
+	path, err := config.ServiceAccountKeyPath(name)
+	if err != nil {
+		return err
+	}
+
+	if err := confirmDestructive(ctx, flags, fmt.Sprintf("remove service account key %s and its routes", name)); err != nil {
+		return err
+	}
+
+	deleted := true
+	if err := os.Remove(path); err != nil {
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("remove service account key: %w", err)
+		}
+		deleted = false
+	}
+
+	cfg, err := config.ReadConfig()
+	if err != nil {
+		return err
+	}
+	routesRemoved := config.RemoveServiceAccountKeyRoutes(&cfg, name)
+	if routesRemoved > 0 {
+		if err := config.WriteConfig(cfg); err != nil {
+			return err
+		}
+	}
+
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(os.Stdout, map[string]any{
+			"deleted":        deleted,
+			"name":           name,
+			"path":           path,
+			"routes_removed": routesRemoved,
+		})
+	}
+	u.Out().Printf("deleted\t%t", deleted)
+	u.Out().Printf("name\t%s", name)
+	u.Out().Printf("path\t%s", path)
+	u.Out().Printf("routes_removed\t%d", routesRemoved)
+	return nil
+}
+
+type AuthServiceAccountRouteCmd struct {
+	Set   AuthServiceAccountRouteSetCmd   `cmd:"" name:"set" help:"Impersonate users of a domain through a named key"`
+	Unset AuthServiceAccountRouteUnsetCmd `cmd:"" name:"unset" help:"Remove a domain route (or a service override)"`
+}
+
+type AuthServiceAccountRouteSetCmd struct {
+	Domain  string `arg:"" name:"domain" help:"Workspace domain (e.g. example.com)"`
+	Key     string `arg:"" name:"key" help:"Key name (from: gog auth service-account keys add)"`
+	Service string `name:"service" help:"Only route this service (e.g. keep); default routes every service"`
+}
+
+func (c *AuthServiceAccountRouteSetCmd) Run(ctx context.Context) error {
+	u := ui.FromContext(ctx)
+
+	domain, err := config.NormalizeDomain(c.Domain)
+	if err != nil {
+		return usage(err.Error())
+	}
+	name, err := config.NormalizeServiceAccountKeyName(c.Key)
+	if err != nil {
+		return usage(err.Error())
+	}
+	service, err := parseRouteService(c.Service)
+	if err != nil {
+		return err
+	}
+
+	path, err := config.ServiceAccountKeyPath(name)
+	if err != nil {
+		return err
+	}
+	if _, statErr := os.Stat(path); statErr != nil {
+		if os.IsNotExist(statErr) {
+			return usage(fmt.Sprintf("unknown service account key %q (add it with: gog auth service-account keys add %s --key <file>)", name, name))
+		}
+		return statErr
+	}
+
+	cfg, err := config.ReadConfig()
+	if err != nil {
+		return err
+	}
+	if err := config.SetServiceAccountRoute(&cfg, domain, service, name); err != nil {
+		return err
+	}
+	if err := config.WriteConfig(cfg); err != nil {
+		return err
+	}
+
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(os.Stdout, map[string]any{
+			"domain":  domain,
+			"service": service,
+			"key":     name,
+		})
+	}
+	u.Out().Printf("domain\t%s", domain)
+	if service != "" {
+		u.Out().Printf("service\t%s", service)
+	}
+	u.Out().Printf("key\t%s", name)
+	return nil
+}
+
+type AuthServiceAccountRouteUnsetCmd struct {
+	Domain  string `arg:"" name:"domain" help:"Workspace domain"`
+	Service string `name:"service" help:"Only remove this service override"`
+}
+
+func (c *AuthServiceAccountRouteUnsetCmd) Run(ctx context.Context) error {
+	u := ui.FromContext(ctx)
+
+	domain, err := config.NormalizeDomain(c.Domain)
+	if err != nil {
+		return usage(err.Error())
+	}
+	service, err := parseRouteService(c.Service)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.ReadConfig()
+	if err != nil {
+		return err
+	}
+	removed, err := config.UnsetServiceAccountRoute(&cfg, domain, service)
+	if err != nil {
+		return err
+	}
+	if removed {
+		if err := config.WriteConfig(cfg); err != nil {
+			return err
+		}
+	}
+
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(os.Stdout, map[string]any{
+			"domain":  domain,
+			"service": service,
+			"removed": removed,
+		})
+	}
+	u.Out().Printf("domain\t%s", domain)
+	if service != "" {
+		u.Out().Printf("service\t%s", service)
+	}
+	u.Out().Printf("removed\t%t", removed)
+	return nil
+}
+
+func parseRouteService(raw string) (string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return "", nil
+	}
+	svc, err := googleauth.ParseService(raw)
+	if err != nil {
+		return "", usage(err.Error())
+	}
+	return string(svc), nil
+}
+
+type AuthServiceAccountListCmd struct {
+	ProbeAs []string      `name:"probe-as" sep:"," help:"Impersonate these users to check which scopes their domain's keys are authorized for"`
+	Timeout time.Duration `name:"timeout" help:"Per-service probe timeout" default:"15s"`
+}
+
+type serviceAccountRouteItem struct {
+	Domain  string `json:"domain"`
+	Service string `json:"service,omitempty"`
+	Key     string `json:"key"`
+}
+
+type serviceAccountProbe struct {
+	Subject            string            `json:"subject"`
+	AuthorizedServices []string          `json:"authorized_services"`
+	AuthorizedScopes   []string          `json:"authorized_scopes"`
+	Denied             map[string]string `json:"denied,omitempty"`
+}
+
+type serviceAccountKeyItem struct {
+	Name           string                `json:"name"`
+	Path           string                `json:"path"`
+	ClientEmail    string                `json:"client_email,omitempty"`
+	ClientID       string                `json:"client_id,omitempty"`
+	Routes         []string              `json:"routes"`
+	Services       []string              `json:"services"`
+	RequiredScopes []string              `json:"required_scopes"`
+	Probes         []serviceAccountProbe `json:"probes,omitempty"`
+	Error          string                `json:"error,omitempty"`
+
+	data []byte
+}
+
+type serviceAccountAccountItem struct {
+	Email string `json:"email"`
+	Path  string `json:"path"`
+}
+
+func (c *AuthServiceAccountListCmd) Run(ctx context.Context) error {
+	u := ui.FromContext(ctx)
+
+	cfg, err := config.ReadConfig()
+	if err != nil {
+		return err
+	}
+	stored, err := config.ListServiceAccountKeys()
+	if err != nil {
+		return err
+	}
+	routes := serviceAccountRoutes(cfg)
+
+	keys := make([]*serviceAccountKeyItem, 0, len(stored))
+	byName := make(map[string]*serviceAccountKeyItem, len(stored))
+	for _, k := range stored {
+		item := &serviceAccountKeyItem{Name: k.Name, Path: k.Path, Routes: []string{}, Services: []string{}, RequiredScopes: []string{}}
+		if data, readErr := os.ReadFile(k.Path); readErr != nil { //nolint:gosec // stored in user config dir
+			item.Error = readErr.Error()
+		} else if info, parseErr := parseServiceAccountJSON(data); parseErr != nil {
+			item.Error = parseErr.Error()
+		} else {
+			item.ClientEmail = info.ClientEmail
+			item.ClientID = info.ClientID
+			item.data = data
+		}
+		keys = append(keys, item)
+		byName[k.Name] = item
+	}
+
+	for _, r := range routes {
+		item, ok := byName[r.Key]
+		if !ok {
+			item = &serviceAccountKeyItem{Name: r.Key, Routes: []string{}, Services: []string{}, RequiredScopes: []string{}, Error: "key file missing"}
+			keys = append(keys, item)
+			byName[r.Key] = item
+		}
+		item.Routes = append(item.Routes, routeLabel(r))
+	}
+
+	for _, item := range keys {
+		services := make(map[string]struct{})
+		for _, domain := range routedDomains(routes, item.Name) {
+			for _, svc := range routedServices(cfg, domain, item.Name) {
+				services[string(svc)] = struct{}{}
+			}
+		}
+		item.Services = sortedKeys(services)
+		item.RequiredScopes = scopesForServiceNames(item.Services)
+	}
+
+	for _, subject := range c.ProbeAs {
+		subject = normalizeEmail(subject)
+		domain := config.DomainFromEmail(subject)
+		if domain == "" {
+			return usage(fmt.Sprintf("invalid --probe-as %q", subject))
+		}
+		for _, item := range keys {
+			services := routedServices(cfg, domain, item.Name)
+			if len(services) == 0 || item.data == nil {
+				continue
+			}
+			item.Probes = append(item.Probes, c.probe(ctx, item.data, subject, services))
+		}
+	}
+
+	accounts := make([]serviceAccountAccountItem, 0)
+	if emails, listErr := config.ListServiceAccountEmails(); listErr == nil {
+		for _, email := range emails {
+			if p, _, ok := bestServiceAccountPathAndMtime(email); ok {
+				accounts = append(accounts, serviceAccountAccountItem{Email: email, Path: p})
+			}
+		}
+	}
+
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(os.Stdout, map[string]any{
+			"keys":     keys,
+			"routes":   routes,
+			"accounts": accounts,
+		})
+	}
+
+	if len(keys) == 0 && len(accounts) == 0 {
+		u.Err().Println("No service account keys configured")
+		return nil
+	}
+
+	w, done := tableWriter(ctx)
+	_, _ = fmt.Fprintln(w, "KEY\tCLIENT_EMAIL\tCLIENT_ID\tROUTES\tSCOPES\tERROR")
+	for _, item := range keys {
+		scopes := item.RequiredScopes
+		if len(item.Probes) > 0 {
+			scopes = item.Probes[0].AuthorizedScopes
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", item.Name, item.ClientEmail, item.ClientID, strings.Join(item.Routes, ","), strings.Join(scopes, ","), item.Error)
+	}
+	for _, a := range accounts {
+		_, _ = fmt.Fprintf(w, "%s\t\t\t%s\t\t\n", "(account)", a.Email)
+	}
+	done()
+
+	for _, item := range keys {
+		for _, p := range item.Probes {
+			for _, svc := range sortedKeys(p.Denied) {
+				u.Err().Printf("%s: %s not authorized for %s (%s)", item.Name, svc, p.Subject, p.Denied[svc])
+			}
+		}
+	}
+	return nil
+}
+
+func (c *AuthServiceAccountListCmd) probe(ctx context.Context, keyJSON []byte, subject string, services []googleauth.Service) serviceAccountProbe {
+	probe := serviceAccountProbe{Subject: subject, AuthorizedServices: []string{}, AuthorizedScopes: []string{}}
+	authorized := make([]string, 0, len(services))
+	for _, svc := range services {
+		scopes, err := googleauth.Scopes(svc)
+		if err != nil {
+			continue
+		}
+		probeCtx, cancel := context.WithTimeout(ctx, c.Timeout)
+		err = checkServiceAccountScopes(probeCtx, keyJSON, subject, scopes)
+		cancel()
+		if err != nil {
+			if probe.Denied == nil {
+				probe.Denied = make(map[string]string)
+			}
+			probe.Denied[string(svc)] = err.Error()
+			continue
+		}
+		authorized = append(authorized, string(svc))
+	}
+	sort.Strings(authorized)
+	probe.AuthorizedServices = authorized
+	probe.AuthorizedScopes = scopesForServiceNames(authorized)
+	return probe
+}
+
+func serviceAccountRoutes(cfg config.File) []serviceAccountRouteItem {
+	out := make([]serviceAccountRouteItem, 0, len(cfg.ServiceAccountDomains))
+	for domain, route := range cfg.ServiceAccountDomains {
+		if key, err := config.NormalizeServiceAccountKeyName(route.Key); err == nil {
+			out = append(out, serviceAccountRouteItem{Domain: domain, Key: key})
+		}
+		for service, k := range route.Services {
+			if key, err := config.NormalizeServiceAccountKeyName(k); err == nil {
+				out = append(out, serviceAccountRouteItem{Domain: domain, Service: service, Key: key})
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Domain != out[j].Domain {
+			return out[i].Domain < out[j].Domain
+		}
+		return out[i].Service < out[j].Service
+	})
+	return out
+}
+
+func routeLabel(r serviceAccountRouteItem) string {
+	if r.Service == "" {
+		return r.Domain
+	}
+	return r.Domain + "/" + r.Service
+}
+
+func routedDomains(routes []serviceAccountRouteItem, key string) []string {
+	seen := make(map[string]struct{})
+	for _, r := range routes {
+		if r.Key == key {
+			seen[r.Domain] = struct{}{}
+		}
+	}
+	return sortedKeys(seen)
+}
+
+// routedServices lists the services that resolve to key for users of domain.
+func routedServices(cfg config.File, domain string, key string) []googleauth.Service {
+	probeEmail := "user@" + domain
+	out := make([]googleauth.Service, 0)
+	for _, svc := range googleauth.AllServices() {
+		if k, ok := config.ServiceAccountKeyForAccount(cfg, probeEmail, string(svc)); ok && k == key {
+			out = append(out, svc)
+		}
+	}
+	return out
+}
+
+func scopesForServiceNames(names []string) []string {
+	services := make([]googleauth.Service, 0, len(names))
+	for _, name := range names {
+		services = append(services, googleauth.Service(name))
+	}
+	scopes, err := googleauth.ScopesForServices(services)
+	if err != nil || scopes == nil {
+		return []string{}
+	}
+	return scopes
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
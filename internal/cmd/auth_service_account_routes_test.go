@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/steipete/gogcli/internal/config"
+)
+
+func TestAuthServiceAccountRoutes_AddRouteListRemove(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	keyPath := filepath.Join(t.TempDir(), "sa.json")
+	if err := os.WriteFile(keyPath, []byte(`{"type":"service_account","client_email":"dwd@tenant.iam.gserviceaccount.com","client_id":"123"}`), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+
+	origCheck := checkServiceAccountScopes
+	t.Cleanup(func() { checkServiceAccountScopes = origCheck })
+	checkServiceAccountScopes = func(_ context.Context, _ []byte, subject string, scopes []string) error {
+		if subject != "probe@example.com" {
+			t.Fatalf("unexpected subject %q", subject)
+		}
+		for _, s := range scopes {
+			if strings.Contains(s, "keep") {
+				return errors.New("unauthorized_client")
+			}
+		}
+		return nil
+	}
+
+	run := func(args ...string) string {
+		t.Helper()
+		return captureStdout(t, func() {
+			_ = captureStderr(t, func() {
+				if err := Execute(args); err != nil {
+					t.Fatalf("%v: %v", args, err)
+				}
+			})
+		})
+	}
+
+	run("auth", "service-account", "keys", "add", "Tenant", "--key", keyPath)
+
+	if err := Execute([]string{"auth", "service-account", "route", "set", "example.com", "missing"}); err == nil {
+		t.Fatalf("expected error for unknown key")
+	}
+	if err := Execute([]string{"auth", "service-account", "route", "set", "example.com", "tenant", "--service", "nope"}); err == nil {
+		t.Fatalf("expected error for unknown service")
+	}
+
+	run("auth", "service-account", "route", "set", "example.com", "tenant")
+
+	cfg, err := config.ReadConfig()
+	if err != nil {
+		t.Fatalf("ReadConfig: %v", err)
+	}
+	if key, ok := config.ServiceAccountKeyForAccount(cfg, "anyone@example.com", "gmail"); !ok || key != "tenant" {
+		t.Fatalf("route not stored: %#v", cfg.ServiceAccountDomains)
+	}
+
+	out := run("--json", "auth", "service-account", "list", "--probe-as", "probe@example.com")
+	var parsed struct {
+		Keys []struct {
+			Name           string   `json:"name"`
+			ClientID       string   `json:"client_id"`
+			Routes         []string `json:"routes"`
+			RequiredScopes []string `json:"required_scopes"`
+			Probes         []struct {
+				AuthorizedServices []string          `json:"authorized_services"`
+				Denied             map[string]string `json:"denied"`
+			} `json:"probes"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal([]byte(out), &parsed); err != nil {
+		t.Fatalf("json: %v\n%s", err, out)
+	}
+	if len(parsed.Keys) != 1 {
+		t.Fatalf("unexpected keys: %s", out)
+	}
+	key := parsed.Keys[0]
+	if key.Name != "tenant" || key.ClientID != "123" || len(key.Routes) != 1 || key.Routes[0] != "example.com" {
+		t.Fatalf("unexpected key: %#v", key)
+	}
+	if len(key.RequiredScopes) == 0 || len(key.Probes) != 1 {
+		t.Fatalf("expected scopes and one probe: %#v", key)
+	}
+	if _, denied := key.Probes[0].Denied["keep"]; !denied {
+		t.Fatalf("expected keep denied: %#v", key.Probes[0])
+	}
+	for _, svc := range key.Probes[0].AuthorizedServices {
+		if svc == "keep" {
+			t.Fatalf("keep should not be authorized")
+		}
+	}
+
+	run("--force", "auth", "service-account", "keys", "remove", "tenant")
+
+	cfg, err = config.ReadConfig()
+	if err != nil {
+		t.Fatalf("ReadConfig: %v", err)
+	}
+	if cfg.ServiceAccountDomains != nil {
+		t.Fatalf("expected routes removed with key: %#v", cfg.ServiceAccountDomains)
+	}
+}
@@ -15,10 +15,15 @@ import (
 
 func TestAuthTextOutputs(t *testing.T) {
 	origOpen := openSecretsStore
-	t.Cleanup(func() { openSecretsStore = origOpen })
+	origRevoke := revokeRefreshToken
+	t.Cleanup(func() {
+		openSecretsStore = origOpen
+		revokeRefreshToken = origRevoke
+	})
 
 	store := newMemSecretsStore()
 	openSecretsStore = func() (secrets.Store, error) { return store, nil }
+	revokeRefreshToken = func(context.Context, string, time.Duration) error { return nil }
 
 	if err := store.SetToken(config.DefaultClientName, "a@b.com", secrets.Token{
 		Services:     []string{"gmail"},
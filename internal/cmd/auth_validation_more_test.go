@@ -25,7 +25,7 @@ func TestAuthCredentialsCmd_ErrorsAndStdin(t *testing.T) {
 	}
 	ctx := outfmt.WithMode(ui.WithUI(context.Background(), u), outfmt.Mode{JSON: true})
 
-	if err := (&AuthCredentialsSetCmd{Path: "/nope/credentials.json"}).Run(ctx); err == nil {
+	if err := (&AuthCredentialsSetCmd{Path: "/nope/credentials.json"}).Run(ctx, nil); err == nil {
 		t.Fatalf("expected read error")
 	}
 
@@ -33,7 +33,7 @@ func TestAuthCredentialsCmd_ErrorsAndStdin(t *testing.T) {
 	if err := os.WriteFile(tmp, []byte("nope"), 0o600); err != nil {
 		t.Fatalf("write: %v", err)
 	}
-	if err := (&AuthCredentialsSetCmd{Path: tmp}).Run(ctx); err == nil {
+	if err := (&AuthCredentialsSetCmd{Path: tmp}).Run(ctx, nil); err == nil {
 		t.Fatalf("expected parse error")
 	}
 
@@ -42,7 +42,7 @@ func TestAuthCredentialsCmd_ErrorsAndStdin(t *testing.T) {
 	creds := `{"installed":{"client_id":"id","client_secret":"secret"}}`
 	out := captureStdout(t, func() {
 		withStdin(t, creds, func() {
-			if err := (&AuthCredentialsSetCmd{Path: "-"}).Run(ctx); err != nil {
+			if err := (&AuthCredentialsSetCmd{Path: "-"}).Run(ctx, nil); err != nil {
 				t.Fatalf("stdin run: %v", err)
 			}
 		})
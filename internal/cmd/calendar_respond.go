@@ -7,6 +7,8 @@ import (
 	"os"
 	"strings"
 
+	"google.golang.org/api/calendar/v3"
+
 	"github.com/steipete/gogcli/internal/outfmt"
 	"github.com/steipete/gogcli/internal/ui"
 )
@@ -54,13 +56,38 @@ func (c *CalendarRespondCmd) Run(ctx context.Context, flags *RootFlags) error {
 		return err
 	}
 
-	event, err := svc.Events.Get(calendarID, eventID).Do()
+	updated, err := respondToEvent(ctx, svc, calendarID, eventID, status, c.Comment)
 	if err != nil {
 		return err
 	}
 
+	if outfmt.IsJSON(ctx) {
+		tz, loc, _ := getCalendarLocation(ctx, svc, calendarID)
+		return outfmt.WriteJSON(os.Stdout, map[string]any{"event": wrapEventWithDaysWithTimezone(updated, tz, loc)})
+	}
+
+	u.Out().Printf("id\t%s", updated.Id)
+	u.Out().Printf("summary\t%s", orEmpty(updated.Summary, "(no title)"))
+	u.Out().Printf("response_status\t%s", status)
+	if strings.TrimSpace(c.Comment) != "" {
+		u.Out().Printf("comment\t%s", strings.TrimSpace(c.Comment))
+	}
+	if updated.HtmlLink != "" {
+		u.Out().Printf("link\t%s", updated.HtmlLink)
+	}
+	return nil
+}
+
+// respondToEvent sets the authenticated user's response status (and optional
+// comment) on an event they were invited to.
+func respondToEvent(ctx context.Context, svc *calendar.Service, calendarID, eventID, status, comment string) (*calendar.Event, error) {
+	event, err := svc.Events.Get(calendarID, eventID).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+
 	if len(event.Attendees) == 0 {
-		return errors.New("event has no attendees")
+		return nil, errors.New("event has no attendees")
 	}
 
 	var selfAttendee *int
@@ -72,36 +99,17 @@ func (c *CalendarRespondCmd) Run(ctx context.Context, flags *RootFlags) error {
 	}
 
 	if selfAttendee == nil {
-		return errors.New("you are not an attendee of this event")
+		return nil, errors.New("you are not an attendee of this event")
 	}
 
 	if event.Attendees[*selfAttendee].Organizer {
-		return errors.New("cannot respond to your own event (you are the organizer)")
+		return nil, errors.New("cannot respond to your own event (you are the organizer)")
 	}
 
 	event.Attendees[*selfAttendee].ResponseStatus = status
-	if strings.TrimSpace(c.Comment) != "" {
-		event.Attendees[*selfAttendee].Comment = strings.TrimSpace(c.Comment)
-	}
-
-	updated, err := svc.Events.Patch(calendarID, eventID, event).Do()
-	if err != nil {
-		return err
-	}
-
-	if outfmt.IsJSON(ctx) {
-		tz, loc, _ := getCalendarLocation(ctx, svc, calendarID)
-		return outfmt.WriteJSON(os.Stdout, map[string]any{"event": wrapEventWithDaysWithTimezone(updated, tz, loc)})
+	if strings.TrimSpace(comment) != "" {
+		event.Attendees[*selfAttendee].Comment = strings.TrimSpace(comment)
 	}
 
-	u.Out().Printf("id\t%s", updated.Id)
-	u.Out().Printf("summary\t%s", orEmpty(updated.Summary, "(no title)"))
-	u.Out().Printf("response_status\t%s", status)
-	if strings.TrimSpace(c.Comment) != "" {
-		u.Out().Printf("comment\t%s", strings.TrimSpace(c.Comment))
-	}
-	if updated.HtmlLink != "" {
-		u.Out().Printf("link\t%s", updated.HtmlLink)
-	}
-	return nil
+	return svc.Events.Patch(calendarID, eventID, event).Context(ctx).Do()
 }
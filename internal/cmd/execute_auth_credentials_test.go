@@ -150,3 +150,38 @@ func TestExecute_AuthCredentialsList_JSON(t *testing.T) {
 		t.Fatalf("missing expected entries: %#v", seen)
 	}
 }
+
+func TestExecute_AuthCredentials_TestingFlagIsSticky(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	in := filepath.Join(t.TempDir(), "creds.json")
+	if err := os.WriteFile(in, []byte(`{"installed":{"client_id":"id","client_secret":"sec"}}`), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	isTesting := func(args ...string) bool {
+		t.Helper()
+		_ = captureStdout(t, func() {
+			_ = captureStderr(t, func() {
+				if err := Execute(append([]string{"--json", "auth", "credentials", in}, args...)); err != nil {
+					t.Fatalf("Execute: %v", err)
+				}
+			})
+		})
+		cfg, err := config.ReadConfig()
+		if err != nil {
+			t.Fatalf("ReadConfig: %v", err)
+		}
+		return config.ClientIsTesting(cfg, config.DefaultClientName)
+	}
+
+	if !isTesting("--testing") {
+		t.Fatalf("--testing should mark the client")
+	}
+	if !isTesting() {
+		t.Fatalf("re-running without --testing should keep the flag")
+	}
+	if isTesting("--no-testing") {
+		t.Fatalf("--no-testing should clear the flag")
+	}
+}
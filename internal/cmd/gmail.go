@@ -19,20 +19,35 @@ import (
 var newGmailService = googleapi.NewGmail
 
 type GmailCmd struct {
-	Search     GmailSearchCmd     `cmd:"" name:"search" group:"Read" help:"Search threads using Gmail query syntax"`
-	Messages   GmailMessagesCmd   `cmd:"" name:"messages" group:"Read" help:"Message operations"`
-	Thread     GmailThreadCmd     `cmd:"" name:"thread" aliases:"read" group:"Organize" help:"Thread operations (get, modify)"`
-	Get        GmailGetCmd        `cmd:"" name:"get" group:"Read" help:"Get a message (full|metadata|raw)"`
-	Attachment GmailAttachmentCmd `cmd:"" name:"attachment" group:"Read" help:"Download a single attachment"`
-	URL        GmailURLCmd        `cmd:"" name:"url" group:"Read" help:"Print Gmail web URLs for threads"`
-	History    GmailHistoryCmd    `cmd:"" name:"history" group:"Read" help:"Gmail history"`
+	Search      GmailSearchCmd      `cmd:"" name:"search" group:"Read" help:"Search threads using Gmail query syntax"`
+	Messages    GmailMessagesCmd    `cmd:"" name:"messages" group:"Read" help:"Message operations"`
+	Thread      GmailThreadCmd      `cmd:"" name:"thread" aliases:"read" group:"Organize" help:"Thread operations (get, modify)"`
+	Get         GmailGetCmd         `cmd:"" name:"get" group:"Read" help:"Get a message (full|metadata|raw)"`
+	Attachment  GmailAttachmentCmd  `cmd:"" name:"attachment" group:"Read" help:"Download a single attachment"`
+	Attachments GmailAttachmentsCmd `cmd:"" name:"attachments" group:"Read" help:"Bulk attachment operations (export)"`
+	URL         GmailURLCmd         `cmd:"" name:"url" group:"Read" help:"Print Gmail web URLs for threads"`
+	History     GmailHistoryCmd     `cmd:"" name:"history" group:"Read" help:"Gmail history"`
+	Export      GmailExportCmd      `cmd:"" name:"export" group:"Read" help:"Export messages to mbox or Maildir (resumable)"`
+	Sync        GmailSyncCmd        `cmd:"" name:"sync" group:"Read" help:"Keep a local Maildir in sync using history IDs"`
+	Stats       GmailStatsCmd       `cmd:"" name:"stats" group:"Read" help:"Mailbox analytics: top senders, volume, threads, size of messages with attachments by label, reply latency"`
+	Invite      GmailInviteCmd      `cmd:"" name:"invite" group:"Read" help:"Show a calendar invitation from an email and accept, decline, or tentatively accept it"`
 
 	Labels GmailLabelsCmd `cmd:"" name:"labels" group:"Organize" help:"Label operations"`
 	Batch  GmailBatchCmd  `cmd:"" name:"batch" group:"Organize" help:"Batch operations"`
-
-	Send   GmailSendCmd   `cmd:"" name:"send" group:"Write" help:"Send an email"`
-	Track  GmailTrackCmd  `cmd:"" name:"track" group:"Write" help:"Email open tracking"`
-	Drafts GmailDraftsCmd `cmd:"" name:"drafts" group:"Write" help:"Draft operations"`
+	Apply  GmailApplyCmd  `cmd:"" name:"apply" group:"Organize" help:"Archive, trash, label, or mark every message matching a query"`
+
+	Unsubscribe   GmailUnsubscribeCmd   `cmd:"" name:"unsubscribe" group:"Organize" help:"Unsubscribe using List-Unsubscribe headers (RFC 8058 one-click or mailto)"`
+	Subscriptions GmailSubscriptionsCmd `cmd:"" name:"subscriptions" group:"Organize" help:"Report mailing lists by message volume"`
+
+	Send    GmailSendCmd    `cmd:"" name:"send" group:"Write" help:"Send an email"`
+	Compose GmailComposeCmd `cmd:"" name:"compose" group:"Write" help:"Write an email in $EDITOR, then send or save as draft"`
+	Reply   GmailReplyCmd   `cmd:"" name:"reply" group:"Write" help:"Reply to a message, quoting the original"`
+	Forward GmailForwardCmd `cmd:"" name:"forward" group:"Write" help:"Forward a message with its attachments"`
+	Track   GmailTrackCmd   `cmd:"" name:"track" group:"Write" help:"Email open tracking"`
+	Drafts  GmailDraftsCmd  `cmd:"" name:"drafts" group:"Write" help:"Draft operations"`
+	Import  GmailImportCmd  `cmd:"" name:"import" group:"Write" help:"Import .eml, mbox, or Maildir messages (resumable)"`
+	Merge   GmailMergeCmd   `cmd:"" name:"merge" group:"Write" help:"Send personalized emails from a template and CSV/Sheet rows"`
+	Outbox  GmailOutboxCmd  `cmd:"" name:"outbox" group:"Write" help:"Scheduled sends (send --at)"`
 
 	Settings GmailSettingsCmd `cmd:"" name:"settings" group:"Admin" help:"Settings and admin"`
 
@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"google.golang.org/api/gmail/v1"
+
+	"github.com/steipete/gogcli/internal/outfmt"
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+// gmailBatchModifyMax is the most IDs users.messages.batchModify accepts.
+const gmailBatchModifyMax = 1000
+
+type GmailApplyCmd struct {
+	Query       string   `name:"query" short:"q" help:"Gmail search query selecting the messages" required:""`
+	Archive     bool     `name:"archive" help:"Remove from Inbox"`
+	Trash       bool     `name:"trash" help:"Move to Trash"`
+	MarkRead    bool     `name:"mark-read" help:"Mark as read"`
+	MarkUnread  bool     `name:"mark-unread" help:"Mark as unread"`
+	Star        bool     `name:"star" help:"Add a star"`
+	Unstar      bool     `name:"unstar" help:"Remove the star"`
+	AddLabel    []string `name:"add-label" help:"Label to add (name or ID; created if missing; repeatable or comma-separated)" sep:","`
+	RemoveLabel []string `name:"remove-label" help:"Label to remove (name or ID; repeatable or comma-separated)" sep:","`
+	Limit       int64    `name:"limit" help:"Stop after this many matching messages (0 = no cap)" default:"0"`
+	DryRun      bool     `name:"dry-run" help:"Only count matches; change nothing"`
+}
+
+func (c *GmailApplyCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+	query := strings.TrimSpace(c.Query)
+	if query == "" {
+		return usage("empty --query")
+	}
+	if c.Limit < 0 {
+		return usage("--limit must be >= 0")
+	}
+	if c.MarkRead && c.MarkUnread {
+		return usage("use only one of --mark-read or --mark-unread")
+	}
+	if c.Star && c.Unstar {
+		return usage("use only one of --star or --unstar")
+	}
+
+	add, remove := c.systemLabelChanges()
+	addNames := nonEmpty(c.AddLabel)
+	removeNames := nonEmpty(c.RemoveLabel)
+	if len(add)+len(remove)+len(addNames)+len(removeNames) == 0 {
+		return usage("nothing to do: pass --archive, --trash, --mark-read, --mark-unread, --star, --unstar, --add-label, or --remove-label")
+	}
+
+	svc, err := newGmailService(ctx, account)
+	if err != nil {
+		return err
+	}
+
+	ids, err := listGmailMessageIDs(ctx, svc, query, c.Limit)
+	if err != nil {
+		return err
+	}
+	limited := c.Limit > 0 && int64(len(ids)) >= c.Limit
+
+	if c.DryRun || len(ids) == 0 {
+		return c.writeResult(ctx, u, query, len(ids), 0, limited, append(add, addNames...), append(remove, removeNames...))
+	}
+
+	if c.Trash {
+		if confirmErr := confirmDestructive(ctx, flags, fmt.Sprintf("move %d messages to trash", len(ids))); confirmErr != nil {
+			return confirmErr
+		}
+	}
+
+	addIDs, err := resolveOrCreateLabelIDs(ctx, svc, addNames)
+	if err != nil {
+		return err
+	}
+	removeIDs, err := resolveExistingLabelIDs(svc, removeNames)
+	if err != nil {
+		return err
+	}
+	addIDs = append(add, addIDs...)
+	removeIDs = append(remove, removeIDs...)
+
+	modified := 0
+	for start := 0; start < len(ids); start += gmailBatchModifyMax {
+		end := min(start+gmailBatchModifyMax, len(ids))
+		err := svc.Users.Messages.BatchModify("me", &gmail.BatchModifyMessagesRequest{
+			Ids:            ids[start:end],
+			AddLabelIds:    addIDs,
+			RemoveLabelIds: removeIDs,
+		}).Context(ctx).Do()
+		if err != nil {
+			return fmt.Errorf("batchModify after %d of %d messages: %w", modified, len(ids), err)
+		}
+		modified = end
+		u.Err().Printf("Modified %d/%d", modified, len(ids))
+	}
+
+	return c.writeResult(ctx, u, query, len(ids), modified, limited, addIDs, removeIDs)
+}
+
+// systemLabelChanges maps the action flags onto system label IDs.
+func (c *GmailApplyCmd) systemLabelChanges() (add []string, remove []string) {
+	if c.Archive {
+		remove = append(remove, "INBOX")
+	}
+	if c.Trash {
+		add = append(add, "TRASH")
+	}
+	if c.MarkRead {
+		remove = append(remove, "UNREAD")
+	}
+	if c.MarkUnread {
+		add = append(add, "UNREAD")
+	}
+	if c.Star {
+		add = append(add, "STARRED")
+	}
+	if c.Unstar {
+		remove = append(remove, "STARRED")
+	}
+	return add, remove
+}
+
+func (c *GmailApplyCmd) writeResult(ctx context.Context, u *ui.UI, query string, matched, modified int, limited bool, add, remove []string) error {
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(os.Stdout, map[string]any{
+			"query":         query,
+			"matched":       matched,
+			"modified":      modified,
+			"dryRun":        c.DryRun,
+			"limited":       limited,
+			"addedLabels":   add,
+			"removedLabels": remove,
+		})
+	}
+	u.Out().Printf("query\t%s", query)
+	u.Out().Printf("matched\t%d", matched)
+	if c.DryRun {
+		u.Out().Printf("dry_run\ttrue")
+	} else {
+		u.Out().Printf("modified\t%d", modified)
+	}
+	if len(add) > 0 {
+		u.Out().Printf("add\t%s", strings.Join(add, ","))
+	}
+	if len(remove) > 0 {
+		u.Out().Printf("remove\t%s", strings.Join(remove, ","))
+	}
+	if limited {
+		u.Err().Printf("Stopped at --limit %d; more messages may match", c.Limit)
+	}
+	return nil
+}
+
+// resolveExistingLabelIDs maps label names or IDs to IDs, failing on labels
+// that do not exist rather than passing them through.
+func resolveExistingLabelIDs(svc *gmail.Service, labels []string) ([]string, error) {
+	if len(labels) == 0 {
+		return nil, nil
+	}
+	idMap, err := fetchLabelNameToID(svc)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, 0, len(labels))
+	for _, l := range labels {
+		id, ok := idMap[strings.ToLower(l)]
+		if !ok {
+			return nil, usagef("unknown label %q", l)
+		}
+		out = append(out, id)
+	}
+	return out, nil
+}
+
+func nonEmpty(values []string) []string {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if trimmed := strings.TrimSpace(v); trimmed != "" {
+			out = append(out, trimmed)
+		}
+	}
+	return out
+}
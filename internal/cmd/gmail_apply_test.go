@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
+)
+
+func newApplyTestService(t *testing.T, total int) (*[]gmail.BatchModifyMessagesRequest, *[]string) {
+	t.Helper()
+
+	var modified []gmail.BatchModifyMessagesRequest
+	var created []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/users/me/messages") && r.Method == http.MethodGet:
+			start, _ := strconv.Atoi(r.URL.Query().Get("pageToken"))
+			end := min(start+500, total)
+			msgs := make([]map[string]any, 0, end-start)
+			for i := start; i < end; i++ {
+				msgs = append(msgs, map[string]any{"id": fmt.Sprintf("m%d", i)})
+			}
+			resp := map[string]any{"messages": msgs}
+			if end < total {
+				resp["nextPageToken"] = strconv.Itoa(end)
+			}
+			_ = json.NewEncoder(w).Encode(resp)
+		case strings.HasSuffix(r.URL.Path, "/users/me/labels") && r.Method == http.MethodGet:
+			_ = json.NewEncoder(w).Encode(map[string]any{"labels": []map[string]any{
+				{"id": "INBOX", "name": "INBOX"},
+				{"id": "Label_1", "name": "Newsletters"},
+			}})
+		case strings.HasSuffix(r.URL.Path, "/users/me/labels") && r.Method == http.MethodPost:
+			var l gmail.Label
+			_ = json.NewDecoder(r.Body).Decode(&l)
+			created = append(created, l.Name)
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": "Label_new", "name": l.Name})
+		case strings.HasSuffix(r.URL.Path, "/users/me/messages/batchModify"):
+			var req gmail.BatchModifyMessagesRequest
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			modified = append(modified, req)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	svc, err := gmail.NewService(context.Background(), option.WithoutAuthentication(), option.WithHTTPClient(srv.Client()), option.WithEndpoint(srv.URL+"/"))
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	origNew := newGmailService
+	t.Cleanup(func() { newGmailService = origNew })
+	newGmailService = func(context.Context, string) (*gmail.Service, error) { return svc, nil }
+	return &modified, &created
+}
+
+func TestGmailApply_ChunksBatchModify(t *testing.T) {
+	modified, created := newApplyTestService(t, 1500)
+
+	out := runSendLike(t, &GmailApplyCmd{}, "--query", "from:noreply older_than:30d", "--archive", "--mark-read", "--add-label", "Old", "--remove-label", "newsletters")
+	if out["matched"] != float64(1500) || out["modified"] != float64(1500) {
+		t.Fatalf("unexpected output: %#v", out)
+	}
+	if len(*modified) != 2 || len((*modified)[0].Ids) != 1000 || len((*modified)[1].Ids) != 500 || (*modified)[1].Ids[0] != "m1000" {
+		t.Fatalf("unexpected chunks: %d", len(*modified))
+	}
+	req := (*modified)[0]
+	if !reflect.DeepEqual(req.AddLabelIds, []string{"Label_new"}) || !reflect.DeepEqual(req.RemoveLabelIds, []string{"INBOX", "UNREAD", "Label_1"}) {
+		t.Fatalf("unexpected labels: add=%v remove=%v", req.AddLabelIds, req.RemoveLabelIds)
+	}
+	if !reflect.DeepEqual(*created, []string{"Old"}) {
+		t.Fatalf("expected missing label to be created, got %v", *created)
+	}
+}
+
+func TestGmailApply_DryRunAndLimit(t *testing.T) {
+	modified, created := newApplyTestService(t, 1500)
+
+	out := runSendLike(t, &GmailApplyCmd{}, "--query", "in:inbox", "--archive", "--add-label", "Old", "--dry-run")
+	if out["matched"] != float64(1500) || out["modified"] != float64(0) || out["dryRun"] != true {
+		t.Fatalf("unexpected dry run output: %#v", out)
+	}
+	if len(*modified) != 0 || len(*created) != 0 {
+		t.Fatalf("dry run must not change anything")
+	}
+
+	out = runSendLike(t, &GmailApplyCmd{}, "--query", "in:inbox", "--star", "--limit", "600")
+	if out["matched"] != float64(600) || out["limited"] != true || len(*modified) != 1 || len((*modified)[0].Ids) != 600 {
+		t.Fatalf("unexpected limited run: %#v", out)
+	}
+}
+
+func TestGmailApply_Validation(t *testing.T) {
+	for _, args := range [][]string{
+		{"--query", "x"},
+		{"--query", "x", "--mark-read", "--mark-unread"},
+		{"--query", "x", "--archive", "--limit", "-1"},
+	} {
+		if err := runKong(t, &GmailApplyCmd{}, args, context.Background(), &RootFlags{Account: "a@b.com"}); err == nil {
+			t.Fatalf("expected usage error for %v", args)
+		}
+	}
+}
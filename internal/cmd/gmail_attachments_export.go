@@ -0,0 +1,480 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"google.golang.org/api/gmail/v1"
+
+	"github.com/steipete/gogcli/internal/config"
+	"github.com/steipete/gogcli/internal/outfmt"
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+const (
+	attachmentsManifestName       = "manifest.json"
+	attachmentsManifestSaveEvery  = 50
+	attachmentsDefaultNamePattern = "{{.Date}}_{{.Filename}}"
+)
+
+type GmailAttachmentsCmd struct {
+	Export GmailAttachmentsExportCmd `cmd:"" name:"export" help:"Download every attachment matching a query, deduplicated by content"`
+}
+
+type GmailAttachmentsExportCmd struct {
+	Query       string   `name:"query" short:"q" required:"" help:"Gmail search query (e.g. 'has:attachment from:billing@x.com after:2026/01/01')"`
+	Mime        []string `name:"mime" help:"Only these MIME types (repeatable or comma-separated; 'image/*' wildcards)" sep:","`
+	Out         string   `name:"out" aliases:"output" required:"" help:"Output directory (created if missing)"`
+	Name        string   `name:"name" help:"File name template: {{.Date}} {{.DateTime}} {{.From}} {{.FromName}} {{.Subject}} {{.Filename}} {{.Base}} {{.Ext}} {{.MessageID}} {{.ThreadID}}; '/' makes subdirectories" default:"{{.Date}}_{{.Filename}}"`
+	Max         int64    `name:"max" aliases:"limit" help:"Stop after this many messages (0 = all)" default:"0"`
+	Concurrency int      `name:"concurrency" help:"Parallel message downloads" default:"8"`
+	Manifest    string   `name:"manifest" help:"Manifest path (default: <out>/manifest.json)"`
+}
+
+// attachmentsManifest maps exported files back to the messages they came
+// from. Done lists processed messages so a re-run only fetches new ones.
+type attachmentsManifest struct {
+	Query     string                    `json:"query"`
+	Files     []*attachmentManifestFile `json:"files"`
+	Done      []string                  `json:"done"`
+	UpdatedAt time.Time                 `json:"updated_at"`
+}
+
+type attachmentManifestFile struct {
+	Path     string                     `json:"path"`
+	SHA256   string                     `json:"sha256"`
+	Size     int64                      `json:"size"`
+	MimeType string                     `json:"mimeType"`
+	Sources  []attachmentManifestSource `json:"sources"`
+}
+
+type attachmentManifestSource struct {
+	MessageID string `json:"messageId"`
+	ThreadID  string `json:"threadId,omitempty"`
+	Filename  string `json:"filename"`
+	From      string `json:"from,omitempty"`
+	Subject   string `json:"subject,omitempty"`
+	Date      string `json:"date,omitempty"`
+}
+
+// attachmentNameData is what --name templates see. Every field is already
+// safe to use as a path element.
+type attachmentNameData struct {
+	Date      string
+	DateTime  string
+	From      string
+	FromName  string
+	Subject   string
+	Filename  string
+	Base      string
+	Ext       string
+	MessageID string
+	ThreadID  string
+}
+
+type exportedAttachment struct {
+	Filename string
+	MimeType string
+	Data     []byte
+}
+
+type attachmentMessageResult struct {
+	id          string
+	threadID    string
+	from        string
+	subject     string
+	date        time.Time
+	attachments []exportedAttachment
+	err         error
+}
+
+func (c *GmailAttachmentsExportCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+	query := strings.TrimSpace(c.Query)
+	if query == "" {
+		return usage("empty --query")
+	}
+	if c.Concurrency < 1 {
+		return usage("--concurrency must be >= 1")
+	}
+	if c.Max < 0 {
+		return usage("--max must be >= 0")
+	}
+	tmpl, err := parseAttachmentNameTemplate(c.Name)
+	if err != nil {
+		return err
+	}
+	mimes := nonEmpty(c.Mime)
+
+	outDir, err := config.ExpandPath(c.Out)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(outDir, 0o700); err != nil {
+		return err
+	}
+	manifestPath := filepath.Join(outDir, attachmentsManifestName)
+	if strings.TrimSpace(c.Manifest) != "" {
+		if manifestPath, err = config.ExpandPath(c.Manifest); err != nil {
+			return err
+		}
+	}
+	manifest, err := loadAttachmentsManifest(manifestPath, query)
+	if err != nil {
+		return err
+	}
+
+	svc, err := newGmailService(ctx, account)
+	if err != nil {
+		return err
+	}
+	ids, err := listGmailMessageIDs(ctx, svc, query, c.Max)
+	if err != nil {
+		return err
+	}
+	done := make(map[string]bool, len(manifest.Done))
+	for _, id := range manifest.Done {
+		done[id] = true
+	}
+	pending := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if !done[id] {
+			pending = append(pending, id)
+		}
+	}
+	u.Err().Printf("%d messages match; %d already exported", len(ids), len(ids)-len(pending))
+
+	byHash := map[string]*attachmentManifestFile{}
+	byPath := map[string]*attachmentManifestFile{}
+	for _, f := range manifest.Files {
+		byHash[f.SHA256] = f
+		byPath[strings.ToLower(f.Path)] = f
+	}
+	if rel, relErr := filepath.Rel(outDir, manifestPath); relErr == nil {
+		byPath[strings.ToLower(filepath.ToSlash(rel))] = nil
+	}
+
+	var written, duplicates, failed, processed int
+	var newFiles []*attachmentManifestFile
+	// abort saves what was written so far, so a re-run dedupes against those
+	// files instead of writing them again as name-2.ext.
+	abort := func(err error) error {
+		_ = writeAttachmentsManifest(manifestPath, manifest)
+		return err
+	}
+	for res := range fetchAttachmentMessages(ctx, svc, pending, mimes, c.Concurrency) {
+		if res.err != nil {
+			failed++
+			u.Err().Errorf("%s: %v", res.id, res.err)
+			continue
+		}
+		for _, a := range res.attachments {
+			sum := sha256.Sum256(a.Data)
+			hash := hex.EncodeToString(sum[:])
+			source := attachmentManifestSource{
+				MessageID: res.id,
+				ThreadID:  res.threadID,
+				Filename:  a.Filename,
+				From:      res.from,
+				Subject:   res.subject,
+				Date:      res.date.Format(time.RFC3339),
+			}
+			if existing, ok := byHash[hash]; ok {
+				if !hasAttachmentSource(existing, source) {
+					existing.Sources = append(existing.Sources, source)
+				}
+				duplicates++
+				continue
+			}
+
+			rel, nameErr := attachmentFilePath(tmpl, res, a.Filename)
+			if nameErr != nil {
+				return abort(nameErr)
+			}
+			rel = uniqueAttachmentPath(outDir, rel, byPath)
+			path := filepath.Join(outDir, filepath.FromSlash(rel))
+			if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+				return abort(err)
+			}
+			if err := os.WriteFile(path, a.Data, 0o600); err != nil {
+				return abort(err)
+			}
+			f := &attachmentManifestFile{Path: rel, SHA256: hash, Size: int64(len(a.Data)), MimeType: a.MimeType, Sources: []attachmentManifestSource{source}}
+			manifest.Files = append(manifest.Files, f)
+			byHash[hash] = f
+			byPath[strings.ToLower(rel)] = f
+			newFiles = append(newFiles, f)
+			written++
+			if !outfmt.IsJSON(ctx) {
+				u.Out().Printf("%s\t%s", path, res.id)
+			}
+		}
+		manifest.Done = append(manifest.Done, res.id)
+		processed++
+		if processed%attachmentsManifestSaveEvery == 0 {
+			if err := writeAttachmentsManifest(manifestPath, manifest); err != nil {
+				return err
+			}
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return abort(err)
+	}
+	if err := writeAttachmentsManifest(manifestPath, manifest); err != nil {
+		return err
+	}
+
+	if outfmt.IsJSON(ctx) {
+		if newFiles == nil {
+			newFiles = []*attachmentManifestFile{}
+		}
+		if err := outfmt.WriteJSON(os.Stdout, map[string]any{
+			"out":        outDir,
+			"manifest":   manifestPath,
+			"matched":    len(ids),
+			"processed":  processed,
+			"written":    written,
+			"duplicates": duplicates,
+			"failed":     failed,
+			"files":      newFiles,
+		}); err != nil {
+			return err
+		}
+	} else {
+		u.Err().Printf("Wrote %d files (%d duplicates skipped) from %d messages; manifest: %s", written, duplicates, processed, manifestPath)
+	}
+	if failed > 0 {
+		return &ExitError{Code: 1, Err: fmt.Errorf("%d messages failed; re-run to retry", failed)}
+	}
+	return nil
+}
+
+// fetchAttachmentMessages fetches messages and their matching attachments with
+// bounded parallelism; results stream back for sequential dedupe and writes.
+func fetchAttachmentMessages(ctx context.Context, svc *gmail.Service, ids []string, mimes []string, concurrency int) <-chan attachmentMessageResult {
+	results := make(chan attachmentMessageResult, concurrency)
+	jobs := make(chan string)
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range jobs {
+				results <- fetchAttachmentMessage(ctx, svc, id, mimes)
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for _, id := range ids {
+			select {
+			case jobs <- id:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	return results
+}
+
+func fetchAttachmentMessage(ctx context.Context, svc *gmail.Service, id string, mimes []string) attachmentMessageResult {
+	msg, err := svc.Users.Messages.Get("me", id).
+		Format("full").
+		Fields("id,threadId,internalDate,payload").
+		Context(ctx).
+		Do()
+	if err != nil {
+		return attachmentMessageResult{id: id, err: err}
+	}
+	res := attachmentMessageResult{
+		id:       id,
+		threadID: msg.ThreadId,
+		from:     headerValue(msg.Payload, "From"),
+		subject:  headerValue(msg.Payload, "Subject"),
+		date:     time.UnixMilli(msg.InternalDate),
+	}
+	for _, a := range collectAttachments(msg.Payload) {
+		if !mimeMatches(a.MimeType, mimes) {
+			continue
+		}
+		body, getErr := svc.Users.Messages.Attachments.Get("me", id, a.AttachmentID).Context(ctx).Do()
+		if getErr != nil {
+			return attachmentMessageResult{id: id, err: fmt.Errorf("attachment %s: %w", a.Filename, getErr)}
+		}
+		data, decodeErr := decodeBase64URLBytes(body.Data)
+		if decodeErr != nil {
+			return attachmentMessageResult{id: id, err: fmt.Errorf("attachment %s: %w", a.Filename, decodeErr)}
+		}
+		res.attachments = append(res.attachments, exportedAttachment{Filename: a.Filename, MimeType: a.MimeType, Data: data})
+	}
+	return res
+}
+
+// mimeMatches reports whether mimeType matches any filter ("type/*" allowed);
+// no filters match everything.
+func mimeMatches(mimeType string, filters []string) bool {
+	if len(filters) == 0 {
+		return true
+	}
+	mimeType = strings.ToLower(strings.TrimSpace(mimeType))
+	for _, f := range filters {
+		f = strings.ToLower(strings.TrimSpace(f))
+		if f == mimeType || (strings.HasSuffix(f, "/*") && strings.HasPrefix(mimeType, strings.TrimSuffix(f, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseAttachmentNameTemplate(pattern string) (*template.Template, error) {
+	if strings.TrimSpace(pattern) == "" {
+		pattern = attachmentsDefaultNamePattern
+	}
+	tmpl, err := template.New("name").Option("missingkey=error").Parse(pattern)
+	if err != nil {
+		return nil, usagef("invalid --name template: %v", err)
+	}
+	if err := tmpl.Execute(&bytes.Buffer{}, attachmentNameData{}); err != nil {
+		return nil, usagef("invalid --name template: %v", err)
+	}
+	return tmpl, nil
+}
+
+// attachmentFilePath renders the name template to a slash-separated path
+// relative to the output directory.
+func attachmentFilePath(tmpl *template.Template, res attachmentMessageResult, filename string) (string, error) {
+	ext := filepath.Ext(filename)
+	data := attachmentNameData{
+		Date:      res.date.Format("2006-01-02"),
+		DateTime:  res.date.Format("2006-01-02_150405"),
+		From:      safePathElement(senderAddress(res.from)),
+		Subject:   safePathElement(res.subject),
+		Filename:  safePathElement(filename),
+		Base:      safePathElement(strings.TrimSuffix(filename, ext)),
+		Ext:       safePathElement(strings.TrimPrefix(ext, ".")),
+		MessageID: res.id,
+		ThreadID:  res.threadID,
+	}
+	if addr, err := mail.ParseAddress(res.from); err == nil {
+		data.FromName = safePathElement(addr.Name)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", usagef("--name template: %v", err)
+	}
+	var parts []string
+	for _, p := range strings.Split(filepath.ToSlash(buf.String()), "/") {
+		p = strings.TrimSpace(p)
+		if p == "" || p == "." || p == ".." {
+			continue
+		}
+		parts = append(parts, p)
+	}
+	if len(parts) == 0 {
+		return "", usagef("--name template produced an empty file name for %s", filename)
+	}
+	return strings.Join(parts, "/"), nil
+}
+
+// safePathElement makes s usable as a single file name component.
+func safePathElement(s string) string {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(s) {
+		switch {
+		case r < 0x20 || r == 0x7f:
+			continue
+		case strings.ContainsRune(`/\:*?"<>|`, r):
+			b.WriteRune('_')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	out := strings.Trim(b.String(), " .")
+	if r := []rune(out); len(r) > 120 {
+		out = strings.TrimSpace(string(r[:120]))
+	}
+	return out
+}
+
+// uniqueAttachmentPath appends -2, -3, ... before the extension until rel is
+// neither in the manifest nor on disk.
+func uniqueAttachmentPath(outDir, rel string, taken map[string]*attachmentManifestFile) string {
+	ext := filepath.Ext(rel)
+	base := strings.TrimSuffix(rel, ext)
+	candidate := rel
+	for i := 2; ; i++ {
+		_, inManifest := taken[strings.ToLower(candidate)]
+		_, statErr := os.Stat(filepath.Join(outDir, filepath.FromSlash(candidate)))
+		if !inManifest && errors.Is(statErr, os.ErrNotExist) {
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s-%d%s", base, i, ext)
+	}
+}
+
+func loadAttachmentsManifest(path, query string) (*attachmentsManifest, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // user-provided path
+	if errors.Is(err, os.ErrNotExist) {
+		return &attachmentsManifest{Query: query, Files: []*attachmentManifestFile{}, Done: []string{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var m attachmentsManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("read manifest %s: %w", path, err)
+	}
+	if m.Query != query {
+		return nil, usagef("%s belongs to a different export (%q); use another --out or --manifest", path, m.Query)
+	}
+	return &m, nil
+}
+
+func writeAttachmentsManifest(path string, m *attachmentsManifest) error {
+	m.UpdatedAt = time.Now().UTC()
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode manifest: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("write manifest: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("commit manifest: %w", err)
+	}
+	return nil
+}
+
+// hasAttachmentSource reports whether f already lists the attachment, which
+// happens when a message whose export was interrupted is fetched again.
+func hasAttachmentSource(f *attachmentManifestFile, src attachmentManifestSource) bool {
+	for _, s := range f.Sources {
+		if s.MessageID == src.MessageID && s.Filename == src.Filename {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
+
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+func newAttachmentsExportTestService(t *testing.T) *int {
+	t.Helper()
+
+	attachments := map[string]string{"a1": "%PDF invoice 1", "a2": "%PDF invoice 1", "a3": "%PDF invoice 2", "img": "PNG"}
+	messages := map[string]map[string]any{
+		"m1": {"from": "Billing <billing@x.com>", "date": "1767261600000", "parts": []map[string]any{
+			{"mimeType": "application/pdf", "filename": "invoice.pdf", "body": map[string]any{"attachmentId": "a1", "size": 14}},
+			{"mimeType": "image/png", "filename": "logo.png", "body": map[string]any{"attachmentId": "img", "size": 3}},
+		}},
+		"m2": {"from": "billing@x.com", "date": "1767261600000", "parts": []map[string]any{
+			{"mimeType": "application/pdf", "filename": "invoice.pdf", "body": map[string]any{"attachmentId": "a2", "size": 14}},
+		}},
+		"m3": {"from": "billing@x.com", "date": "1767261600000", "parts": []map[string]any{
+			{"mimeType": "application/pdf", "filename": "invoice.pdf", "body": map[string]any{"attachmentId": "a3", "size": 14}},
+		}},
+	}
+	gets := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/users/me/messages") && r.Method == http.MethodGet:
+			_ = json.NewEncoder(w).Encode(map[string]any{"messages": []map[string]any{{"id": "m1"}, {"id": "m2"}, {"id": "m3"}}})
+		case strings.Contains(r.URL.Path, "/attachments/"):
+			id := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+			_ = json.NewEncoder(w).Encode(map[string]any{"data": b64url(attachments[id])})
+		case strings.Contains(r.URL.Path, "/users/me/messages/"):
+			id := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+			m := messages[id]
+			gets++
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"id": id, "threadId": "t" + id, "internalDate": m["date"],
+				"payload": map[string]any{
+					"mimeType": "multipart/mixed",
+					"headers":  []map[string]any{{"name": "From", "value": m["from"]}, {"name": "Subject", "value": "Invoice"}},
+					"parts":    m["parts"],
+				},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	svc, err := gmail.NewService(context.Background(), option.WithoutAuthentication(), option.WithHTTPClient(srv.Client()), option.WithEndpoint(srv.URL+"/"))
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	origNew := newGmailService
+	t.Cleanup(func() { newGmailService = origNew })
+	newGmailService = func(context.Context, string) (*gmail.Service, error) { return svc, nil }
+	return &gets
+}
+
+func TestGmailAttachmentsExport_DedupesAndResumes(t *testing.T) {
+	gets := newAttachmentsExportTestService(t)
+	dir := t.TempDir()
+
+	out := runSendLike(t, &GmailAttachmentsExportCmd{}, "--query", "has:attachment from:billing@x.com", "--mime", "application/pdf", "--out", dir, "--name", "{{.From}}/{{.Base}}.{{.Ext}}")
+	if out["written"] != float64(2) || out["duplicates"] != float64(1) || out["processed"] != float64(3) {
+		t.Fatalf("unexpected output: %#v", out)
+	}
+	for _, name := range []string{"billing@x.com/invoice.pdf", "billing@x.com/invoice-2.pdf"} {
+		if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+			t.Fatalf("expected %s: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "billing@x.com", "logo.png")); err == nil {
+		t.Fatalf("--mime filter should skip the PNG")
+	}
+
+	manifest, err := loadAttachmentsManifest(filepath.Join(dir, attachmentsManifestName), "has:attachment from:billing@x.com")
+	if err != nil {
+		t.Fatalf("load manifest: %v", err)
+	}
+	if len(manifest.Files) != 2 || len(manifest.Done) != 3 {
+		t.Fatalf("unexpected manifest: %#v", manifest)
+	}
+	var shared *attachmentManifestFile
+	for _, f := range manifest.Files {
+		if len(f.Sources) == 2 {
+			shared = f
+		}
+	}
+	if shared == nil || shared.Size != 14 || shared.Sources[0].MessageID == shared.Sources[1].MessageID {
+		t.Fatalf("duplicate content should map to one file with both messages: %#v", manifest.Files)
+	}
+
+	before := *gets
+	out = runSendLike(t, &GmailAttachmentsExportCmd{}, "--query", "has:attachment from:billing@x.com", "--mime", "application/pdf", "--out", dir)
+	if out["processed"] != float64(0) || *gets != before {
+		t.Fatalf("re-run should skip exported messages: %#v", out)
+	}
+
+	if err := runKong(t, &GmailAttachmentsExportCmd{}, []string{"--query", "other", "--out", dir}, context.Background(), &RootFlags{Account: "a@b.com"}); err == nil {
+		t.Fatalf("expected manifest mismatch error")
+	}
+}
+
+func TestAttachmentNameHelpers(t *testing.T) {
+	if got := safePathElement(` ../Re: "Q1" <report>/x.pdf `); got != `_Re_ _Q1_ _report__x.pdf` {
+		t.Fatalf("unexpected sanitized name: %q", got)
+	}
+	if !mimeMatches("image/PNG", []string{"application/pdf", "image/*"}) || mimeMatches("text/plain", []string{"image/*"}) || !mimeMatches("x/y", nil) {
+		t.Fatalf("unexpected mime matching")
+	}
+	if _, err := parseAttachmentNameTemplate("{{.Nope}}"); err == nil {
+		t.Fatalf("expected unknown field error")
+	}
+}
+
+func TestGmailAttachmentsExport_SavesManifestOnError(t *testing.T) {
+	newAttachmentsExportTestService(t)
+	dir := t.TempDir()
+	query := "has:attachment from:billing@x.com"
+
+	// m3's file name fails to render after m1's invoice was written.
+	broken := `{{.From}}/{{if eq .MessageID "m3"}}{{.Nope}}{{end}}{{.Base}}.{{.Ext}}`
+	u, err := ui.New(ui.Options{Stdout: io.Discard, Stderr: io.Discard, Color: "never"})
+	if err != nil {
+		t.Fatalf("ui.New: %v", err)
+	}
+	err = runKong(t, &GmailAttachmentsExportCmd{}, []string{"--query", query, "--mime", "application/pdf", "--out", dir, "--concurrency", "1", "--name", broken}, ui.WithUI(context.Background(), u), &RootFlags{Account: "a@b.com"})
+	if err == nil {
+		t.Fatalf("expected --name template error")
+	}
+	manifest, err := loadAttachmentsManifest(filepath.Join(dir, attachmentsManifestName), query)
+	if err != nil || len(manifest.Files) != 1 || manifest.Files[0].Path != "billing@x.com/invoice.pdf" {
+		t.Fatalf("expected manifest with the written file: %#v %v", manifest, err)
+	}
+
+	out := runSendLike(t, &GmailAttachmentsExportCmd{}, "--query", query, "--mime", "application/pdf", "--out", dir, "--name", "{{.From}}/{{.Base}}.{{.Ext}}")
+	if out["written"] != float64(1) {
+		t.Fatalf("re-run should only write m3's invoice: %#v", out)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "billing@x.com", "invoice-3.pdf")); err == nil {
+		t.Fatalf("files written before the error must not be written again")
+	}
+	manifest, err = loadAttachmentsManifest(filepath.Join(dir, attachmentsManifestName), query)
+	if err != nil || len(manifest.Files) != 2 {
+		t.Fatalf("unexpected manifest: %#v %v", manifest, err)
+	}
+	for _, f := range manifest.Files {
+		if f.Path == "billing@x.com/invoice.pdf" && len(f.Sources) != 2 {
+			t.Fatalf("shared invoice should list m1 and m2 once each: %#v", f.Sources)
+		}
+	}
+}
@@ -0,0 +1,454 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"google.golang.org/api/gmail/v1"
+
+	"github.com/steipete/gogcli/internal/outfmt"
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+// draftAttachmentPrefix marks an Attach: line that refers to an attachment
+// already on the draft being edited rather than a local file.
+const draftAttachmentPrefix = "draft:"
+
+var errComposeAborted = errors.New("empty message")
+
+// runEditor opens path in the user's editor and waits for it to exit.
+var runEditor = func(ctx context.Context, path string) error {
+	args := strings.Fields(composeEditor())
+	cmd := exec.CommandContext(ctx, args[0], append(args[1:], path)...) //nolint:gosec // user-configured editor
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor %q: %w", args[0], err)
+	}
+	return nil
+}
+
+func composeEditor() string {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if v := strings.TrimSpace(os.Getenv(env)); v != "" {
+			return v
+		}
+	}
+	if runtime.GOOS == "windows" {
+		return "notepad"
+	}
+	return "vi"
+}
+
+// composeDocument is the editable form of a message: a header block, a blank
+// line, then the plain-text body.
+type composeDocument struct {
+	From    string
+	To      string
+	Cc      string
+	Bcc     string
+	Subject string
+	Attach  []string
+	Body    string
+}
+
+const composeHelp = `# Edit the headers above and write the message below the blank line.
+# Attach: takes one file path per line; remove a line to drop an attachment.
+# Lines starting with '#' in this block are ignored. Save an empty file to abort.`
+
+func (d composeDocument) format() string {
+	var b strings.Builder
+	if d.From != "" {
+		b.WriteString("From: " + d.From + "\n")
+	}
+	b.WriteString("To: " + d.To + "\n")
+	b.WriteString("Cc: " + d.Cc + "\n")
+	b.WriteString("Bcc: " + d.Bcc + "\n")
+	b.WriteString("Subject: " + d.Subject + "\n")
+	for _, a := range d.Attach {
+		b.WriteString("Attach: " + a + "\n")
+	}
+	b.WriteString(composeHelp + "\n\n")
+	if body := strings.TrimRight(normalizeNewlines(d.Body), "\n"); body != "" {
+		b.WriteString(body + "\n")
+	}
+	return b.String()
+}
+
+// parseComposeDocument parses an edited document. It returns errComposeAborted
+// when nothing but comments and whitespace is left.
+func parseComposeDocument(s string) (composeDocument, error) {
+	s = normalizeNewlines(s)
+	var doc composeDocument
+
+	meaningful := false
+	for _, line := range strings.Split(s, "\n") {
+		if strings.TrimSpace(line) != "" && !strings.HasPrefix(line, "#") {
+			meaningful = true
+			break
+		}
+	}
+	if !meaningful {
+		return doc, errComposeAborted
+	}
+
+	head, body, found := strings.Cut(s, "\n\n")
+	if !found {
+		head, body = strings.TrimRight(s, "\n"), ""
+	}
+
+	var last *string
+	sc := bufio.NewScanner(strings.NewReader(head))
+	for n := 1; sc.Scan(); n++ {
+		line := sc.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && last != nil {
+			*last = strings.TrimSpace(*last + " " + strings.TrimSpace(line))
+			continue
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return doc, fmt.Errorf("line %d: expected 'Header: value' (separate the body from the headers with a blank line)", n)
+		}
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "from":
+			doc.From, last = value, &doc.From
+		case "to":
+			doc.To, last = value, &doc.To
+		case "cc":
+			doc.Cc, last = value, &doc.Cc
+		case "bcc":
+			doc.Bcc, last = value, &doc.Bcc
+		case "subject":
+			doc.Subject, last = value, &doc.Subject
+		case "attach":
+			last = nil
+			if value != "" {
+				doc.Attach = append(doc.Attach, value)
+			}
+		default:
+			return doc, fmt.Errorf("line %d: unknown header %q (allowed: From, To, Cc, Bcc, Subject, Attach)", n, strings.TrimSpace(name))
+		}
+	}
+	doc.Body = strings.TrimRight(body, "\n")
+	return doc, nil
+}
+
+// editComposeDocument round-trips doc through the editor. When parsing or
+// check fails the temp file is kept so nothing typed is lost.
+func editComposeDocument(ctx context.Context, doc composeDocument, check func(composeDocument) error) (composeDocument, error) {
+	f, err := os.CreateTemp("", "gog-compose-*.eml")
+	if err != nil {
+		return doc, fmt.Errorf("create temp file: %w", err)
+	}
+	path := f.Name()
+	_, writeErr := f.WriteString(doc.format())
+	closeErr := f.Close()
+	if writeErr != nil || closeErr != nil {
+		_ = os.Remove(path)
+		return doc, fmt.Errorf("write temp file: %w", errors.Join(writeErr, closeErr))
+	}
+
+	if err := runEditor(ctx, path); err != nil {
+		return doc, fmt.Errorf("%w (message kept in %s)", err, path)
+	}
+	data, err := os.ReadFile(path) //nolint:gosec // our temp file
+	if err != nil {
+		return doc, err
+	}
+
+	edited, err := parseComposeDocument(string(data))
+	if errors.Is(err, errComposeAborted) {
+		_ = os.Remove(path)
+		return edited, err
+	}
+	if err == nil && check != nil {
+		err = check(edited)
+	}
+	if err != nil {
+		return edited, fmt.Errorf("%w (message kept in %s)", err, path)
+	}
+	_ = os.Remove(path)
+	return edited, nil
+}
+
+type GmailComposeCmd struct {
+	To      string   `name:"to" help:"Initial recipients (comma-separated)"`
+	Cc      string   `name:"cc" help:"Initial CC recipients (comma-separated)"`
+	Bcc     string   `name:"bcc" help:"Initial BCC recipients (comma-separated)"`
+	Subject string   `name:"subject" help:"Initial subject"`
+	Body    string   `name:"body" help:"Initial body text"`
+	Attach  []string `name:"attach" help:"Initial attachment file path (repeatable)"`
+	From    string   `name:"from" help:"Send from this email address (must be a verified send-as alias)"`
+	Draft   bool     `name:"draft" help:"Save as a draft instead of sending"`
+}
+
+func (c *GmailComposeCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+	if flags != nil && flags.NoInput {
+		return usage("compose opens an editor; use 'gmail send' or 'gmail drafts create' with --no-input")
+	}
+
+	doc, err := editComposeDocument(ctx, composeDocument{
+		From:    c.From,
+		To:      c.To,
+		Cc:      c.Cc,
+		Bcc:     c.Bcc,
+		Subject: c.Subject,
+		Attach:  c.Attach,
+		Body:    c.Body,
+	}, func(d composeDocument) error {
+		if !c.Draft && len(splitCSV(d.To)) == 0 {
+			return usage("required: To")
+		}
+		return d.draftInput().validate()
+	})
+	if errors.Is(err, errComposeAborted) {
+		u.Err().Println("Aborted: empty message; nothing sent")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	svc, err := newGmailService(ctx, account)
+	if err != nil {
+		return err
+	}
+
+	if c.Draft {
+		input := doc.draftInput()
+		msg, threadID, buildErr := buildDraftMessage(ctx, svc, account, input)
+		if buildErr != nil {
+			return buildErr
+		}
+		draft, createErr := svc.Users.Drafts.Create("me", &gmail.Draft{Message: msg}).Context(ctx).Do()
+		if createErr != nil {
+			return createErr
+		}
+		return writeDraftResult(ctx, u, draft, threadID)
+	}
+
+	fromAddr, _, err := resolveSendFrom(ctx, svc, account, doc.From)
+	if err != nil {
+		return err
+	}
+	atts, err := attachmentsFromPaths(doc.Attach)
+	if err != nil {
+		return err
+	}
+	results, err := sendGmailBatches(ctx, svc, sendMessageOptions{
+		FromAddr:    fromAddr,
+		Subject:     doc.Subject,
+		Body:        doc.Body,
+		Attachments: atts,
+	}, []sendBatch{{To: splitCSV(doc.To), Cc: splitCSV(doc.Cc), Bcc: splitCSV(doc.Bcc)}})
+	if err != nil {
+		return err
+	}
+	return writeSendResults(ctx, u, fromAddr, results)
+}
+
+func (d composeDocument) draftInput() draftComposeInput {
+	input := draftComposeInput{From: d.From, To: d.To, Cc: d.Cc, Bcc: d.Bcc, Subject: d.Subject, Body: d.Body}
+	for _, a := range d.Attach {
+		if !strings.HasPrefix(a, draftAttachmentPrefix) {
+			input.Attach = append(input.Attach, a)
+		}
+	}
+	return input
+}
+
+type GmailDraftsEditCmd struct {
+	DraftID string `arg:"" name:"draftId" help:"Draft ID"`
+	Send    bool   `name:"send" help:"Send the draft after saving"`
+}
+
+func (c *GmailDraftsEditCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+	draftID := strings.TrimSpace(c.DraftID)
+	if draftID == "" {
+		return usage("empty draftId")
+	}
+	if flags != nil && flags.NoInput {
+		return usage("drafts edit opens an editor; use 'gmail drafts update' with --no-input")
+	}
+
+	svc, err := newGmailService(ctx, account)
+	if err != nil {
+		return err
+	}
+	existing, err := svc.Users.Drafts.Get("me", draftID).Format("full").Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+	if existing.Message == nil {
+		return fmt.Errorf("draft %s has no message", draftID)
+	}
+
+	original, attachments := composeDocumentFromMessage(existing.Message)
+	// The editor only shows plain text, so an HTML draft keeps its HTML part
+	// as long as the body is not touched.
+	originalHTML := findPartBody(existing.Message.Payload, "text/html")
+	doc, err := editComposeDocument(ctx, original, func(d composeDocument) error {
+		if _, refErr := matchDraftAttachments(d.Attach, attachments); refErr != nil {
+			return refErr
+		}
+		if originalHTML != "" && d.Body != original.Body {
+			return usage("draft has an HTML body that editing the text would drop; change only headers and attachments here, or use 'gmail drafts update --body-html'")
+		}
+		return d.draftInput().validate()
+	})
+	if errors.Is(err, errComposeAborted) {
+		u.Err().Println("Aborted: empty message; draft unchanged")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	threadID := existing.Message.ThreadId
+	if !composeDocumentsEqual(doc, original, account) {
+		kept, _ := matchDraftAttachments(doc.Attach, attachments)
+		carried, carryErr := fetchOriginalAttachments(ctx, svc, existing.Message.Id, &gmail.MessagePart{Parts: partsForAttachments(kept)})
+		if carryErr != nil {
+			return carryErr
+		}
+
+		input := doc.draftInput()
+		if strings.EqualFold(input.From, account) {
+			input.From = ""
+		}
+		input.BodyHTML = originalHTML
+		input.Threading = &replyInfo{
+			InReplyTo:  headerValue(existing.Message.Payload, "In-Reply-To"),
+			References: headerValue(existing.Message.Payload, "References"),
+			ThreadID:   strings.TrimSpace(existing.Message.ThreadId),
+		}
+		input.ExtraAttachments = carried
+		msg, newThreadID, buildErr := buildDraftMessage(ctx, svc, account, input)
+		if buildErr != nil {
+			return buildErr
+		}
+		existing, err = svc.Users.Drafts.Update("me", draftID, &gmail.Draft{Id: draftID, Message: msg}).Context(ctx).Do()
+		if err != nil {
+			return err
+		}
+		threadID = newThreadID
+	} else {
+		u.Err().Println("No changes")
+	}
+
+	if !c.Send {
+		return writeDraftResult(ctx, u, existing, threadID)
+	}
+	sent, err := svc.Users.Drafts.Send("me", &gmail.Draft{Id: draftID}).Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(os.Stdout, map[string]any{"messageId": sent.Id, "threadId": sent.ThreadId})
+	}
+	u.Out().Printf("message_id\t%s", sent.Id)
+	if sent.ThreadId != "" {
+		u.Out().Printf("thread_id\t%s", sent.ThreadId)
+	}
+	return nil
+}
+
+// composeDocumentFromMessage is the editable form of an existing draft. From
+// is the bare sender address; attachments appear as "Attach: draft:<filename>"
+// lines.
+func composeDocumentFromMessage(msg *gmail.Message) (composeDocument, []attachmentInfo) {
+	q := quotedMessageFromPart(msg.Payload)
+	from := q.From
+	if addr, err := mail.ParseAddress(from); err == nil {
+		from = addr.Address
+	}
+	doc := composeDocument{
+		From:    from,
+		To:      q.To,
+		Cc:      q.Cc,
+		Bcc:     headerValue(msg.Payload, "Bcc"),
+		Subject: q.Subject,
+		Body:    strings.TrimRight(normalizeNewlines(q.Plain), "\n"),
+	}
+	attachments := collectAttachments(msg.Payload)
+	for _, a := range attachments {
+		doc.Attach = append(doc.Attach, draftAttachmentPrefix+a.Filename)
+	}
+	return doc, attachments
+}
+
+// matchDraftAttachments maps draft: references back to the draft's
+// attachments; duplicate filenames are matched in order.
+func matchDraftAttachments(refs []string, attachments []attachmentInfo) ([]attachmentInfo, error) {
+	used := make([]bool, len(attachments))
+	var out []attachmentInfo
+	for _, ref := range refs {
+		name, ok := strings.CutPrefix(ref, draftAttachmentPrefix)
+		if !ok {
+			continue
+		}
+		found := false
+		for i, a := range attachments {
+			if !used[i] && a.Filename == strings.TrimSpace(name) {
+				used[i] = true
+				out = append(out, a)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, usagef("Attach: %s does not match an attachment on the draft", ref)
+		}
+	}
+	return out, nil
+}
+
+func partsForAttachments(infos []attachmentInfo) []*gmail.MessagePart {
+	parts := make([]*gmail.MessagePart, 0, len(infos))
+	for _, a := range infos {
+		parts = append(parts, &gmail.MessagePart{
+			Filename: a.Filename,
+			MimeType: a.MimeType,
+			Body:     &gmail.MessagePartBody{AttachmentId: a.AttachmentID, Size: a.Size},
+		})
+	}
+	return parts
+}
+
+func composeDocumentsEqual(a, b composeDocument, account string) bool {
+	return composeSender(a.From, account) == composeSender(b.From, account) &&
+		a.To == b.To && a.Cc == b.Cc && a.Bcc == b.Bcc && a.Subject == b.Subject &&
+		a.Body == b.Body && strings.Join(a.Attach, "\n") == strings.Join(b.Attach, "\n")
+}
+
+// composeSender normalises a From line for comparison; an empty one means the
+// account itself.
+func composeSender(from, account string) string {
+	from = strings.TrimSpace(from)
+	if from == "" {
+		from = account
+	}
+	return strings.ToLower(from)
+}
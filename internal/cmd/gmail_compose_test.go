@@ -0,0 +1,299 @@
+package cmd
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
+
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+func stubEditor(t *testing.T, edit func(string) string) {
+	t.Helper()
+	orig := runEditor
+	t.Cleanup(func() { runEditor = orig })
+	runEditor = func(_ context.Context, path string) error {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(path, []byte(edit(string(data))), 0o600)
+	}
+}
+
+func TestComposeDocument_RoundTripsDraft(t *testing.T) {
+	msg := &gmail.Message{Payload: &gmail.MessagePart{
+		MimeType: "multipart/mixed",
+		Headers: []*gmail.MessagePartHeader{
+			{Name: "From", Value: "Me <me@example.com>"},
+			{Name: "To", Value: "Ada <ada@example.com>, bob@example.com"},
+			{Name: "Cc", Value: "cy@example.com"},
+			{Name: "Bcc", Value: "dee@example.com"},
+			{Name: "Subject", Value: "Quarterly: plan"},
+		},
+		Parts: []*gmail.MessagePart{
+			{MimeType: "text/plain", Body: &gmail.MessagePartBody{Data: b64url("Hi,\r\n\r\nSee attached.\r\n# not a comment\r\n")}},
+			{MimeType: "application/pdf", Filename: "plan.pdf", Body: &gmail.MessagePartBody{AttachmentId: "att1"}},
+		},
+	}}
+
+	doc, _ := composeDocumentFromMessage(msg)
+	parsed, err := parseComposeDocument(doc.format())
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if !reflect.DeepEqual(parsed, doc) {
+		t.Fatalf("round trip mismatch:\n got %#v\nwant %#v", parsed, doc)
+	}
+	if doc.From != "me@example.com" || doc.Body != "Hi,\n\nSee attached.\n# not a comment" || !reflect.DeepEqual(doc.Attach, []string{"draft:plan.pdf"}) {
+		t.Fatalf("unexpected document: %#v", doc)
+	}
+}
+
+func TestParseComposeDocument(t *testing.T) {
+	doc, err := parseComposeDocument("# help\nto: a@example.com,\n  b@example.com\nSubject: Hi\nAttach: ./x.pdf\nAttach:\n\nBody\n\n")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	want := composeDocument{To: "a@example.com, b@example.com", Subject: "Hi", Attach: []string{"./x.pdf"}, Body: "Body"}
+	if !reflect.DeepEqual(doc, want) {
+		t.Fatalf("got %#v, want %#v", doc, want)
+	}
+
+	if _, err := parseComposeDocument("# only comments\n\n  \n"); !errors.Is(err, errComposeAborted) {
+		t.Fatalf("expected abort, got %v", err)
+	}
+	if _, err := parseComposeDocument("X-Priority: 1\n\nbody"); err == nil || !strings.Contains(err.Error(), "unknown header") {
+		t.Fatalf("expected unknown header error, got %v", err)
+	}
+}
+
+func TestGmailCompose_SendsEditedMessageOrAborts(t *testing.T) {
+	var sent []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.Contains(r.URL.Path, "/settings/sendAs/"):
+			http.NotFound(w, r)
+		case strings.HasSuffix(r.URL.Path, "/users/me/messages/send"):
+			var msg gmail.Message
+			_ = json.NewDecoder(r.Body).Decode(&msg)
+			raw, _ := base64.RawURLEncoding.DecodeString(msg.Raw)
+			sent = append(sent, string(raw))
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": "m1", "threadId": "t1"})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	svc, err := gmail.NewService(context.Background(), option.WithoutAuthentication(), option.WithHTTPClient(srv.Client()), option.WithEndpoint(srv.URL+"/"))
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	origNew := newGmailService
+	t.Cleanup(func() { newGmailService = origNew })
+	newGmailService = func(context.Context, string) (*gmail.Service, error) { return svc, nil }
+
+	stubEditor(t, func(s string) string {
+		s = strings.Replace(s, "Subject: \n", "Subject: Notes\n", 1)
+		return s + "Long body\nwritten in the editor.\n"
+	})
+	out := runSendLike(t, &GmailComposeCmd{}, "--to", "zed@example.com")
+	if out["messageId"] != "m1" || len(sent) != 1 {
+		t.Fatalf("unexpected output: %#v (sent %d)", out, len(sent))
+	}
+	raw := strings.ReplaceAll(sent[0], "\r\n", "\n")
+	if !strings.Contains(raw, "To: zed@example.com\n") || !strings.Contains(raw, "Subject: Notes\n") || !strings.Contains(raw, "\n\nLong body\nwritten in the editor.\n") {
+		t.Fatalf("unexpected message:\n%s", raw)
+	}
+
+	stubEditor(t, func(string) string { return "" })
+	u, err := ui.New(ui.Options{Stdout: os.Stdout, Stderr: os.Stderr, Color: "never"})
+	if err != nil {
+		t.Fatalf("ui.New: %v", err)
+	}
+	if err := runKong(t, &GmailComposeCmd{}, []string{"--to", "zed@example.com"}, ui.WithUI(context.Background(), u), &RootFlags{Account: "a@b.com"}); err != nil {
+		t.Fatalf("abort should not fail: %v", err)
+	}
+	if len(sent) != 1 {
+		t.Fatalf("aborted compose must not send")
+	}
+}
+
+func TestGmailDraftsEdit_UpdatesAndKeepsAttachments(t *testing.T) {
+	var updated string
+	var updatedThread string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/users/me/drafts/d1") && r.Method == http.MethodGet:
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"id": "d1",
+				"message": map[string]any{
+					"id":       "dm1",
+					"threadId": "t1",
+					"payload": map[string]any{
+						"mimeType": "multipart/mixed",
+						"headers": []map[string]any{
+							{"name": "From", "value": "Alias <alias@example.com>"},
+							{"name": "To", "value": "ada@example.com"},
+							{"name": "Subject", "value": "Draft"},
+							{"name": "Message-ID", "value": "<draft@example.com>"},
+							{"name": "In-Reply-To", "value": "<orig@example.com>"},
+							{"name": "References", "value": "<root@example.com> <orig@example.com>"},
+						},
+						"parts": []map[string]any{
+							{"mimeType": "text/plain", "body": map[string]any{"data": b64url("Old body")}},
+							{"mimeType": "application/pdf", "filename": "plan.pdf", "body": map[string]any{"attachmentId": "att1"}},
+						},
+					},
+				},
+			})
+		case strings.Contains(r.URL.Path, "/settings/sendAs/alias@example.com"):
+			_ = json.NewEncoder(w).Encode(map[string]any{"sendAsEmail": "alias@example.com", "displayName": "Alias", "verificationStatus": "accepted"})
+		case strings.HasSuffix(r.URL.Path, "/users/me/messages/dm1/attachments/att1"):
+			_ = json.NewEncoder(w).Encode(map[string]any{"data": b64url("%PDF-1.4")})
+		case strings.HasSuffix(r.URL.Path, "/users/me/threads/t1"):
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": "t1", "messages": []map[string]any{
+				{"id": "orig", "threadId": "t1", "internalDate": "1", "payload": map[string]any{"headers": []map[string]any{{"name": "Message-ID", "value": "<orig@example.com>"}}}},
+				{"id": "dm1", "threadId": "t1", "internalDate": "2", "payload": map[string]any{"headers": []map[string]any{{"name": "Message-ID", "value": "<draft@example.com>"}}}},
+			}})
+		case strings.HasSuffix(r.URL.Path, "/users/me/drafts/d1") && r.Method == http.MethodPut:
+			var d gmail.Draft
+			_ = json.NewDecoder(r.Body).Decode(&d)
+			raw, _ := base64.RawURLEncoding.DecodeString(d.Message.Raw)
+			updated = string(raw)
+			updatedThread = d.Message.ThreadId
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": "d1", "message": map[string]any{"id": "dm2", "threadId": "t1"}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	svc, err := gmail.NewService(context.Background(), option.WithoutAuthentication(), option.WithHTTPClient(srv.Client()), option.WithEndpoint(srv.URL+"/"))
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	origNew := newGmailService
+	t.Cleanup(func() { newGmailService = origNew })
+	newGmailService = func(context.Context, string) (*gmail.Service, error) { return svc, nil }
+
+	var seen string
+	stubEditor(t, func(s string) string {
+		seen = s
+		return strings.Replace(s, "Old body", "New body", 1)
+	})
+	out := runSendLike(t, &GmailDraftsEditCmd{}, "d1")
+	if out["draftId"] != "d1" {
+		t.Fatalf("unexpected output: %#v", out)
+	}
+	if !strings.Contains(seen, "From: alias@example.com\n") || !strings.Contains(seen, "To: ada@example.com\n") || !strings.Contains(seen, "Attach: draft:plan.pdf\n") {
+		t.Fatalf("editor did not get the draft contents:\n%s", seen)
+	}
+	raw := strings.ReplaceAll(updated, "\r\n", "\n")
+	if updatedThread != "t1" || !strings.Contains(raw, "New body") || !strings.Contains(raw, `filename="plan.pdf"`) || !strings.Contains(raw, base64.StdEncoding.EncodeToString([]byte("%PDF-1.4"))) {
+		t.Fatalf("unexpected update (thread %q):\n%s", updatedThread, raw)
+	}
+	if !strings.Contains(raw, "In-Reply-To: <orig@example.com>\n") || !strings.Contains(raw, "References: <root@example.com> <orig@example.com>\n") || strings.Contains(raw, "<draft@example.com>") {
+		t.Fatalf("edit must keep the draft's own threading headers:\n%s", raw)
+	}
+	if !strings.HasPrefix(raw, "From: Alias <alias@example.com>\n") && !strings.Contains(raw, "\nFrom: Alias <alias@example.com>\n") {
+		t.Fatalf("edit must keep the send-as alias:\n%s", raw)
+	}
+
+	// Changing only the sender is an edit; a case-only change is not.
+	updated = ""
+	stubEditor(t, func(s string) string {
+		return strings.Replace(s, "From: alias@example.com", "From: ALIAS@example.com", 1)
+	})
+	runSendLike(t, &GmailDraftsEditCmd{}, "d1")
+	if updated != "" {
+		t.Fatalf("case-only From change should not update the draft:\n%s", updated)
+	}
+	stubEditor(t, func(s string) string { return strings.Replace(s, "From: alias@example.com", "From: a@b.com", 1) })
+	runSendLike(t, &GmailDraftsEditCmd{}, "d1")
+	if updated == "" || strings.Contains(updated, "alias@example.com") {
+		t.Fatalf("expected the draft to move off the alias:\n%s", updated)
+	}
+
+	stubEditor(t, func(s string) string { return strings.Replace(s, "draft:plan.pdf", "draft:other.pdf", 1) })
+	err = runKong(t, &GmailDraftsEditCmd{}, []string{"d1"}, context.Background(), &RootFlags{Account: "a@b.com"})
+	if err == nil || !strings.Contains(err.Error(), "does not match an attachment") || !strings.Contains(err.Error(), "message kept in") {
+		t.Fatalf("expected attachment reference error, got %v", err)
+	}
+	if path := err.Error()[strings.LastIndex(err.Error(), "kept in ")+len("kept in "):]; path != "" {
+		_ = os.Remove(strings.TrimSuffix(path, ")"))
+	}
+}
+
+func TestGmailDraftsEdit_KeepsHTMLUnlessBodyChanges(t *testing.T) {
+	var updated string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/users/me/drafts/d1") && r.Method == http.MethodGet:
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"id": "d1",
+				"message": map[string]any{
+					"id":       "dm1",
+					"threadId": "t1",
+					"payload": map[string]any{
+						"mimeType": "multipart/alternative",
+						"headers": []map[string]any{
+							{"name": "From", "value": "a@b.com"},
+							{"name": "To", "value": "ada@example.com"},
+							{"name": "Subject", "value": "Draft"},
+						},
+						"parts": []map[string]any{
+							{"mimeType": "text/plain", "body": map[string]any{"data": b64url("Hello")}},
+							{"mimeType": "text/html", "body": map[string]any{"data": b64url("<p><b>Hello</b></p>")}},
+						},
+					},
+				},
+			})
+		case strings.HasSuffix(r.URL.Path, "/users/me/drafts/d1") && r.Method == http.MethodPut:
+			var d gmail.Draft
+			_ = json.NewDecoder(r.Body).Decode(&d)
+			raw, _ := base64.RawURLEncoding.DecodeString(d.Message.Raw)
+			updated = string(raw)
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": "d1", "message": map[string]any{"id": "dm2", "threadId": "t1"}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	svc, err := gmail.NewService(context.Background(), option.WithoutAuthentication(), option.WithHTTPClient(srv.Client()), option.WithEndpoint(srv.URL+"/"))
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	origNew := newGmailService
+	t.Cleanup(func() { newGmailService = origNew })
+	newGmailService = func(context.Context, string) (*gmail.Service, error) { return svc, nil }
+
+	stubEditor(t, func(s string) string { return strings.Replace(s, "Subject: Draft", "Subject: Renamed", 1) })
+	runSendLike(t, &GmailDraftsEditCmd{}, "d1")
+	raw := strings.ReplaceAll(updated, "\r\n", "\n")
+	if !strings.Contains(raw, "Subject: Renamed\n") || !strings.Contains(raw, "multipart/alternative") || !strings.Contains(raw, "<b>Hello</b>") {
+		t.Fatalf("expected HTML part to be kept:\n%s", raw)
+	}
+
+	updated = ""
+	stubEditor(t, func(s string) string { return strings.Replace(s, "Hello", "Bye", 1) })
+	err = runKong(t, &GmailDraftsEditCmd{}, []string{"d1"}, context.Background(), &RootFlags{Account: "a@b.com"})
+	if err == nil || !strings.Contains(err.Error(), "HTML body") || updated != "" {
+		t.Fatalf("expected HTML body edit to be refused, got %v", err)
+	}
+	if path := err.Error()[strings.LastIndex(err.Error(), "kept in ")+len("kept in "):]; path != "" {
+		_ = os.Remove(strings.TrimSuffix(path, ")"))
+	}
+}
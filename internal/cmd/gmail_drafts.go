@@ -21,6 +21,7 @@ type GmailDraftsCmd struct {
 	Send   GmailDraftsSendCmd   `cmd:"" name:"send" help:"Send a draft"`
 	Create GmailDraftsCreateCmd `cmd:"" name:"create" help:"Create a draft"`
 	Update GmailDraftsUpdateCmd `cmd:"" name:"update" help:"Update a draft"`
+	Edit   GmailDraftsEditCmd   `cmd:"" name:"edit" help:"Edit a draft in $EDITOR"`
 }
 
 type GmailDraftsListCmd struct {
@@ -253,6 +254,7 @@ type GmailDraftsCreateCmd struct {
 	Body             string   `name:"body" help:"Body (plain text; required unless --body-html is set)"`
 	BodyFile         string   `name:"body-file" help:"Body file path (plain text; '-' for stdin)"`
 	BodyHTML         string   `name:"body-html" help:"Body (HTML; optional)"`
+	BodyMD           string   `name:"body-md" help:"Body from a Markdown file ('-' for stdin); rendered to HTML with a plain-text fallback, local images inlined"`
 	ReplyToMessageID string   `name:"reply-to-message-id" help:"Reply to Gmail message ID (sets In-Reply-To/References and thread)"`
 	ReplyTo          string   `name:"reply-to" help:"Reply-To header address"`
 	Attach           []string `name:"attach" help:"Attachment file path (repeatable)"`
@@ -271,6 +273,12 @@ type draftComposeInput struct {
 	ReplyTo          string
 	Attach           []string
 	From             string
+	InlineImages     []mailAttachment
+	ExtraAttachments []mailAttachment
+	// Threading, when set, is used as is instead of looking up
+	// ReplyToMessageID/ReplyToThreadID; drafts edit keeps the draft's own
+	// In-Reply-To/References this way.
+	Threading *replyInfo
 }
 
 func (c draftComposeInput) validate() error {
@@ -278,7 +286,7 @@ func (c draftComposeInput) validate() error {
 		return usage("required: --subject")
 	}
 	if strings.TrimSpace(c.Body) == "" && strings.TrimSpace(c.BodyHTML) == "" {
-		return usage("required: --body, --body-file, or --body-html")
+		return usage("required: --body, --body-file, --body-html, or --body-md")
 	}
 	return nil
 }
@@ -299,9 +307,13 @@ func buildDraftMessage(ctx context.Context, svc *gmail.Service, account string,
 		}
 	}
 
-	info, err := fetchReplyInfo(ctx, svc, input.ReplyToMessageID, input.ReplyToThreadID)
-	if err != nil {
-		return nil, "", err
+	info := input.Threading
+	if info == nil {
+		var err error
+		info, err = fetchReplyInfo(ctx, svc, input.ReplyToMessageID, input.ReplyToThreadID)
+		if err != nil {
+			return nil, "", err
+		}
 	}
 	inReplyTo := info.InReplyTo
 	references := info.References
@@ -315,19 +327,21 @@ func buildDraftMessage(ctx context.Context, svc *gmail.Service, account string,
 		}
 		atts = append(atts, mailAttachment{Path: expanded})
 	}
+	atts = append(append([]mailAttachment{}, input.ExtraAttachments...), atts...)
 
 	raw, err := buildRFC822(mailOptions{
-		From:        fromAddr,
-		To:          splitCSV(input.To),
-		Cc:          splitCSV(input.Cc),
-		Bcc:         splitCSV(input.Bcc),
-		ReplyTo:     input.ReplyTo,
-		Subject:     input.Subject,
-		Body:        input.Body,
-		BodyHTML:    input.BodyHTML,
-		InReplyTo:   inReplyTo,
-		References:  references,
-		Attachments: atts,
+		From:         fromAddr,
+		To:           splitCSV(input.To),
+		Cc:           splitCSV(input.Cc),
+		Bcc:          splitCSV(input.Bcc),
+		ReplyTo:      input.ReplyTo,
+		Subject:      input.Subject,
+		Body:         input.Body,
+		BodyHTML:     input.BodyHTML,
+		InlineImages: input.InlineImages,
+		InReplyTo:    inReplyTo,
+		References:   references,
+		Attachments:  atts,
 	}, &rfc822Config{allowMissingTo: true})
 	if err != nil {
 		return nil, "", err
@@ -375,6 +389,10 @@ func (c *GmailDraftsCreateCmd) Run(ctx context.Context, flags *RootFlags) error
 	if err != nil {
 		return err
 	}
+	body, bodyHTML, inline, err := resolveComposeBodies(body, c.BodyHTML, c.BodyMD)
+	if err != nil {
+		return err
+	}
 
 	input := draftComposeInput{
 		To:               c.To,
@@ -382,12 +400,13 @@ func (c *GmailDraftsCreateCmd) Run(ctx context.Context, flags *RootFlags) error
 		Bcc:              c.Bcc,
 		Subject:          c.Subject,
 		Body:             body,
-		BodyHTML:         c.BodyHTML,
+		BodyHTML:         bodyHTML,
 		ReplyToMessageID: c.ReplyToMessageID,
 		ReplyToThreadID:  "",
 		ReplyTo:          c.ReplyTo,
 		Attach:           c.Attach,
 		From:             c.From,
+		InlineImages:     inline,
 	}
 	if validateErr := input.validate(); validateErr != nil {
 		return validateErr
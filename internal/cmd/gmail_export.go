@@ -0,0 +1,566 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/api/gmail/v1"
+
+	"github.com/steipete/gogcli/internal/config"
+	"github.com/steipete/gogcli/internal/outfmt"
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+const (
+	gmailExportFormatMbox    = "mbox"
+	gmailExportFormatMaildir = "maildir"
+
+	gmailExportCheckpointName  = ".gog-export.json"
+	gmailExportMboxName        = "mailbox.mbox"
+	gmailExportCheckpointEvery = 50
+)
+
+type GmailExportCmd struct {
+	Query       string `name:"query" short:"q" required:"" help:"Gmail search query (e.g. 'label:clients after:2025/01/01')"`
+	Format      string `name:"format" help:"Output format: mbox|maildir" enum:"mbox,maildir" default:"mbox"`
+	Out         string `name:"out" aliases:"output" required:"" help:"Output directory (created if missing)"`
+	Max         int64  `name:"max" aliases:"limit" help:"Stop after this many messages (0 = all)" default:"0"`
+	Concurrency int    `name:"concurrency" help:"Parallel message fetches" default:"8"`
+	Restart     bool   `name:"restart" help:"Ignore an existing checkpoint and start over (overwrites an existing mailbox.mbox)"`
+}
+
+// gmailExportCheckpoint records progress so an interrupted export can resume.
+// MboxSize is the mailbox length covered by Done; anything past it is a
+// partially written tail and is truncated on resume.
+type gmailExportCheckpoint struct {
+	Account   string    `json:"account"`
+	Query     string    `json:"query"`
+	Format    string    `json:"format"`
+	Done      []string  `json:"done"`
+	MboxSize  int64     `json:"mbox_size,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// resumed is set when the checkpoint was read from disk.
+	resumed bool
+}
+
+type gmailExportMessage struct {
+	ID           string
+	ThreadID     string
+	Labels       []string
+	LabelIDs     []string
+	InternalDate time.Time
+	Raw          []byte
+}
+
+type gmailExportWriter interface {
+	Write(msg gmailExportMessage) error
+	Size() int64
+	Close() error
+}
+
+func (c *GmailExportCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+
+	query := strings.TrimSpace(c.Query)
+	if query == "" {
+		return usage("missing --query")
+	}
+	if c.Concurrency < 1 {
+		return usage("--concurrency must be >= 1")
+	}
+
+	outDir, err := config.ExpandPath(strings.TrimSpace(c.Out))
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(outDir, 0o700); err != nil {
+		return fmt.Errorf("create output dir: %w", err)
+	}
+
+	checkpointPath := filepath.Join(outDir, gmailExportCheckpointName)
+	cp, err := loadGmailExportCheckpoint(checkpointPath, account, query, c.Format, c.Restart)
+	if err != nil {
+		return err
+	}
+
+	svc, err := newGmailService(ctx, account)
+	if err != nil {
+		return err
+	}
+
+	ids, err := listGmailMessageIDs(ctx, svc, query, c.Max)
+	if err != nil {
+		return err
+	}
+
+	done := make(map[string]struct{}, len(cp.Done))
+	for _, id := range cp.Done {
+		done[id] = struct{}{}
+	}
+
+	var writer gmailExportWriter
+	switch c.Format {
+	case gmailExportFormatMaildir:
+		mw, mwErr := newMaildirExportWriter(outDir)
+		if mwErr != nil {
+			return mwErr
+		}
+		for id := range mw.existing {
+			if _, ok := done[id]; !ok && !c.Restart {
+				done[id] = struct{}{}
+				cp.Done = append(cp.Done, id)
+			}
+		}
+		writer = mw
+	default:
+		mboxPath := filepath.Join(outDir, gmailExportMboxName)
+		// Without a checkpoint the mailbox is not ours to truncate.
+		if !cp.resumed && !c.Restart {
+			if st, statErr := os.Stat(mboxPath); statErr == nil && st.Size() > 0 {
+				return usagef("%s already exists without an export checkpoint; use another --out or --restart to overwrite it", mboxPath)
+			}
+		}
+		mw, mwErr := newMboxExportWriter(mboxPath, cp.MboxSize)
+		if mwErr != nil {
+			return mwErr
+		}
+		writer = mw
+	}
+
+	pending := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := done[id]; !ok {
+			pending = append(pending, id)
+		}
+	}
+	skipped := len(ids) - len(pending)
+
+	idToName := map[string]string{}
+	if len(pending) > 0 {
+		idToName, err = fetchLabelIDToName(svc)
+		if err != nil {
+			_ = writer.Close()
+			return err
+		}
+	}
+
+	if skipped > 0 {
+		u.Err().Printf("Resuming: %d of %d messages already exported", skipped, len(ids))
+	}
+
+	saveCheckpoint := func() error {
+		cp.MboxSize = writer.Size()
+		cp.UpdatedAt = time.Now().UTC()
+		return writeGmailExportCheckpoint(checkpointPath, cp)
+	}
+
+	exported := 0
+	failed := make(map[string]string)
+	var writeErr error
+	fetchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := fetchRawMessages(fetchCtx, svc, pending, c.Concurrency)
+	for res := range results {
+		if writeErr != nil {
+			continue
+		}
+		if res.err != nil {
+			failed[res.id] = res.err.Error()
+			continue
+		}
+		msg := res.msg
+		msg.Labels = labelNames(msg.LabelIDs, idToName)
+		if err := writer.Write(msg); err != nil {
+			writeErr = fmt.Errorf("write %s: %w", msg.ID, err)
+			cancel()
+			continue
+		}
+		cp.Done = append(cp.Done, msg.ID)
+		exported++
+		if exported%gmailExportCheckpointEvery == 0 {
+			if err := saveCheckpoint(); err != nil {
+				writeErr = err
+				cancel()
+				continue
+			}
+			u.Err().Printf("Exported %d/%d", skipped+exported, len(ids))
+		}
+	}
+
+	closeErr := writer.Close()
+	if err := saveCheckpoint(); err != nil && writeErr == nil {
+		writeErr = err
+	}
+	if writeErr != nil {
+		return writeErr
+	}
+	if closeErr != nil {
+		return closeErr
+	}
+
+	path := outDir
+	if c.Format == gmailExportFormatMbox {
+		path = filepath.Join(outDir, gmailExportMboxName)
+	}
+
+	if outfmt.IsJSON(ctx) {
+		if err := outfmt.WriteJSON(os.Stdout, map[string]any{
+			"format":     c.Format,
+			"path":       path,
+			"total":      len(ids),
+			"exported":   exported,
+			"skipped":    skipped,
+			"failed":     len(failed),
+			"failures":   failed,
+			"checkpoint": checkpointPath,
+		}); err != nil {
+			return err
+		}
+	} else {
+		u.Out().Printf("format\t%s", c.Format)
+		u.Out().Printf("path\t%s", path)
+		u.Out().Printf("total\t%d", len(ids))
+		u.Out().Printf("exported\t%d", exported)
+		u.Out().Printf("skipped\t%d", skipped)
+		u.Out().Printf("failed\t%d", len(failed))
+	}
+
+	if len(failed) > 0 {
+		for _, id := range sortedKeys(failed) {
+			u.Err().Printf("failed %s: %s", id, failed[id])
+		}
+		return &ExitError{Code: 1, Err: fmt.Errorf("%d message(s) failed; re-run the same command to retry", len(failed))}
+	}
+	return nil
+}
+
+func loadGmailExportCheckpoint(path string, account string, query string, format string, restart bool) (*gmailExportCheckpoint, error) {
+	fresh := &gmailExportCheckpoint{Account: account, Query: query, Format: format}
+	if restart {
+		return fresh, nil
+	}
+
+	data, err := os.ReadFile(path) //nolint:gosec // user-provided output dir
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fresh, nil
+		}
+		return nil, fmt.Errorf("read checkpoint: %w", err)
+	}
+
+	var cp gmailExportCheckpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("parse checkpoint %s: %w", path, err)
+	}
+	if !strings.EqualFold(cp.Account, account) || cp.Query != query || cp.Format != format {
+		return nil, usagef("%s belongs to a different export (%s, %q, %s); use another --out or --restart", path, cp.Account, cp.Query, cp.Format)
+	}
+	cp.resumed = true
+	return &cp, nil
+}
+
+func writeGmailExportCheckpoint(path string, cp *gmailExportCheckpoint) error {
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode checkpoint: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("write checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("commit checkpoint: %w", err)
+	}
+	return nil
+}
+
+// listGmailMessageIDs pages through messages.list for query, oldest page last.
+func listGmailMessageIDs(ctx context.Context, svc *gmail.Service, query string, limit int64) ([]string, error) {
+	ids := make([]string, 0)
+	pageToken := ""
+	for {
+		call := svc.Users.Messages.List("me").
+			Q(query).
+			MaxResults(500).
+			Fields("messages(id),nextPageToken").
+			Context(ctx)
+		if pageToken != "" {
+			call = call.PageToken(pageToken)
+		}
+		resp, err := call.Do()
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range resp.Messages {
+			if m == nil || m.Id == "" {
+				continue
+			}
+			ids = append(ids, m.Id)
+			if limit > 0 && int64(len(ids)) >= limit {
+				return ids, nil
+			}
+		}
+		if resp.NextPageToken == "" {
+			return ids, nil
+		}
+		pageToken = resp.NextPageToken
+	}
+}
+
+type rawMessageResult struct {
+	id  string
+	msg gmailExportMessage
+	err error
+}
+
+// fetchRawMessages fetches format=raw messages with bounded parallelism and
+// streams results as they complete.
+func fetchRawMessages(ctx context.Context, svc *gmail.Service, ids []string, concurrency int) <-chan rawMessageResult {
+	return fetchMessagesParallel(ctx, ids, concurrency, func(id string) rawMessageResult {
+		return fetchRawMessage(ctx, svc, id)
+	})
+}
+
+func fetchMessagesParallel(ctx context.Context, ids []string, concurrency int, fetch func(id string) rawMessageResult) <-chan rawMessageResult {
+	results := make(chan rawMessageResult, concurrency)
+	jobs := make(chan string)
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range jobs {
+				results <- fetch(id)
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for _, id := range ids {
+			select {
+			case jobs <- id:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	return results
+}
+
+func fetchRawMessage(ctx context.Context, svc *gmail.Service, id string) rawMessageResult {
+	msg, err := svc.Users.Messages.Get("me", id).Format(gmailFormatRaw).Context(ctx).Do()
+	if err != nil {
+		return rawMessageResult{id: id, err: err}
+	}
+	raw, err := decodeGmailRaw(msg.Raw)
+	if err != nil {
+		return rawMessageResult{id: id, err: fmt.Errorf("decode raw: %w", err)}
+	}
+	return rawMessageResult{id: id, msg: gmailExportMessage{
+		ID:           msg.Id,
+		ThreadID:     msg.ThreadId,
+		LabelIDs:     msg.LabelIds,
+		InternalDate: time.UnixMilli(msg.InternalDate).UTC(),
+		Raw:          raw,
+	}}
+}
+
+func decodeGmailRaw(raw string) ([]byte, error) {
+	raw = strings.TrimRight(strings.TrimSpace(raw), "=")
+	if raw == "" {
+		return nil, errors.New("empty raw message")
+	}
+	return base64.RawURLEncoding.DecodeString(raw)
+}
+
+func labelNames(ids []string, idToName map[string]string) []string {
+	out := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if name, ok := idToName[id]; ok {
+			out = append(out, name)
+		} else {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// exportMessageBytes prepends the Gmail metadata headers and normalizes line
+// endings to LF (the convention for both mbox and Maildir on disk).
+func exportMessageBytes(msg gmailExportMessage) []byte {
+	var b bytes.Buffer
+	if msg.ThreadID != "" {
+		fmt.Fprintf(&b, "X-GM-THRID: %s\n", msg.ThreadID)
+	}
+	fmt.Fprintf(&b, "X-Gmail-Labels: %s\n", strings.Join(msg.Labels, ","))
+	b.Write(bytes.ReplaceAll(msg.Raw, []byte("\r\n"), []byte("\n")))
+	return b.Bytes()
+}
+
+type mboxExportWriter struct {
+	f    *os.File
+	w    *bufio.Writer
+	size int64
+}
+
+// newMboxExportWriter opens path for appending after truncating it to size,
+// dropping any tail written after the last checkpoint.
+func newMboxExportWriter(path string, size int64) (*mboxExportWriter, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600) //nolint:gosec // user-provided output dir
+	if err != nil {
+		return nil, fmt.Errorf("open mbox: %w", err)
+	}
+	if err := f.Truncate(size); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("truncate mbox: %w", err)
+	}
+	if _, err := f.Seek(size, 0); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("seek mbox: %w", err)
+	}
+	return &mboxExportWriter{f: f, w: bufio.NewWriter(f), size: size}, nil
+}
+
+// Write appends msg in mboxrd format: "From " separator line, body lines
+// matching ^>*From  get one more '>', and a trailing blank line.
+func (m *mboxExportWriter) Write(msg gmailExportMessage) error {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From %s@gmail %s\n", msg.ID, msg.InternalDate.Format(time.ANSIC))
+	body := exportMessageBytes(msg)
+	for _, line := range bytes.SplitAfter(body, []byte("\n")) {
+		if isMboxFromLine(line) {
+			b.WriteByte('>')
+		}
+		b.Write(line)
+	}
+	if !bytes.HasSuffix(body, []byte("\n")) {
+		b.WriteByte('\n')
+	}
+	b.WriteByte('\n')
+
+	n, err := m.w.Write(b.Bytes())
+	m.size += int64(n)
+	if err != nil {
+		return err
+	}
+	return m.w.Flush()
+}
+
+func isMboxFromLine(line []byte) bool {
+	return bytes.HasPrefix(bytes.TrimLeft(line, ">"), []byte("From "))
+}
+
+func (m *mboxExportWriter) Size() int64 { return m.size }
+
+func (m *mboxExportWriter) Close() error {
+	flushErr := m.w.Flush()
+	syncErr := m.f.Sync()
+	closeErr := m.f.Close()
+	return errors.Join(flushErr, syncErr, closeErr)
+}
+
+type maildirExportWriter struct {
+	dir      string
+	existing map[string]string // message ID -> filename in cur/
+}
+
+func newMaildirExportWriter(dir string) (*maildirExportWriter, error) {
+	for _, sub := range []string{"cur", "new", "tmp"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o700); err != nil {
+			return nil, fmt.Errorf("create maildir: %w", err)
+		}
+	}
+
+	existing := make(map[string]string)
+	entries, err := os.ReadDir(filepath.Join(dir, "cur"))
+	if err != nil {
+		return nil, fmt.Errorf("read maildir: %w", err)
+	}
+	for _, e := range entries {
+		if id := maildirMessageID(e.Name()); id != "" {
+			existing[id] = e.Name()
+		}
+	}
+	return &maildirExportWriter{dir: dir, existing: existing}, nil
+}
+
+// maildirFilename is "<unix>.<messageId>.gog:2,<flags>". Flags are kept in
+// ASCII order as the Maildir spec requires: F (starred), S (read).
+func maildirFilename(msg gmailExportMessage) string {
+	unread, starred := false, false
+	for _, id := range msg.LabelIDs {
+		switch id {
+		case "UNREAD":
+			unread = true
+		case "STARRED":
+			starred = true
+		}
+	}
+	flags := make([]string, 0, 2)
+	if starred {
+		flags = append(flags, "F")
+	}
+	if !unread {
+		flags = append(flags, "S")
+	}
+	sort.Strings(flags)
+	return fmt.Sprintf("%d.%s.gog:2,%s", msg.InternalDate.Unix(), msg.ID, strings.Join(flags, ""))
+}
+
+func maildirMessageID(name string) string {
+	base, _, ok := strings.Cut(name, ":2,")
+	if !ok {
+		return ""
+	}
+	parts := strings.Split(base, ".")
+	if len(parts) != 3 || parts[2] != "gog" {
+		return ""
+	}
+	return parts[1]
+}
+
+// Write delivers msg via tmp/ then renames it into cur/, replacing an earlier
+// copy whose flags have since changed.
+func (m *maildirExportWriter) Write(msg gmailExportMessage) error {
+	name := maildirFilename(msg)
+	tmp := filepath.Join(m.dir, "tmp", name)
+	if err := os.WriteFile(tmp, exportMessageBytes(msg), 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(m.dir, "cur", name)); err != nil {
+		return err
+	}
+	if old, ok := m.existing[msg.ID]; ok && old != name {
+		_ = os.Remove(filepath.Join(m.dir, "cur", old))
+	}
+	m.existing[msg.ID] = name
+	return nil
+}
+
+func (m *maildirExportWriter) Size() int64 { return 0 }
+
+func (m *maildirExportWriter) Close() error { return nil }
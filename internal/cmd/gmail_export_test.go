@@ -0,0 +1,224 @@
+package cmd
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
+
+	"github.com/steipete/gogcli/internal/outfmt"
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+func newGmailExportTestService(t *testing.T, fetches *int32) {
+	t.Helper()
+
+	raw := map[string]string{
+		"m1": "From: a@example.com\r\nSubject: One\r\n\r\nhello\r\nFrom the top\r\n",
+		"m2": "From: b@example.com\r\nSubject: Two\r\n\r\nbye\r\n",
+	}
+	labels := map[string][]string{
+		"m1": {"INBOX", "Label_1", "UNREAD"},
+		"m2": {"STARRED"},
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		path := r.URL.Path
+		switch {
+		case strings.HasSuffix(path, "/users/me/labels"):
+			_ = json.NewEncoder(w).Encode(map[string]any{"labels": []map[string]any{
+				{"id": "INBOX", "name": "INBOX"},
+				{"id": "Label_1", "name": "Clients"},
+			}})
+		case strings.HasSuffix(path, "/users/me/messages"):
+			if r.URL.Query().Get("pageToken") == "" {
+				_ = json.NewEncoder(w).Encode(map[string]any{"messages": []map[string]any{{"id": "m1"}}, "nextPageToken": "p2"})
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"messages": []map[string]any{{"id": "m2"}}})
+		case strings.Contains(path, "/users/me/messages/"):
+			id := filepath.Base(path)
+			if r.URL.Query().Get("format") != "raw" {
+				t.Errorf("expected format=raw, got %q", r.URL.RawQuery)
+			}
+			atomic.AddInt32(fetches, 1)
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"id":           id,
+				"threadId":     "t-" + id,
+				"labelIds":     labels[id],
+				"internalDate": "1735689600000",
+				"raw":          base64.URLEncoding.EncodeToString([]byte(raw[id])),
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	svc, err := gmail.NewService(context.Background(),
+		option.WithoutAuthentication(),
+		option.WithHTTPClient(srv.Client()),
+		option.WithEndpoint(srv.URL+"/"),
+	)
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+
+	origNew := newGmailService
+	t.Cleanup(func() { newGmailService = origNew })
+	newGmailService = func(context.Context, string) (*gmail.Service, error) { return svc, nil }
+}
+
+func runGmailExport(t *testing.T, args ...string) map[string]any {
+	t.Helper()
+
+	u, err := ui.New(ui.Options{Stdout: os.Stdout, Stderr: os.Stderr, Color: "never"})
+	if err != nil {
+		t.Fatalf("ui.New: %v", err)
+	}
+	ctx := outfmt.WithMode(ui.WithUI(context.Background(), u), outfmt.Mode{JSON: true})
+
+	var out string
+	out = captureStdout(t, func() {
+		_ = captureStderr(t, func() {
+			if err := runKong(t, &GmailExportCmd{}, args, ctx, &RootFlags{Account: "a@b.com"}); err != nil {
+				t.Fatalf("export: %v", err)
+			}
+		})
+	})
+
+	var parsed map[string]any
+	if err := json.Unmarshal([]byte(out), &parsed); err != nil {
+		t.Fatalf("json: %v (%q)", err, out)
+	}
+	return parsed
+}
+
+func TestGmailExport_MboxResumes(t *testing.T) {
+	var fetches int32
+	newGmailExportTestService(t, &fetches)
+	dir := t.TempDir()
+
+	res := runGmailExport(t, "--query", "label:clients", "--out", dir)
+	if res["exported"] != float64(2) || res["total"] != float64(2) {
+		t.Fatalf("unexpected result: %v", res)
+	}
+
+	mboxPath := filepath.Join(dir, gmailExportMboxName)
+	data, err := os.ReadFile(mboxPath)
+	if err != nil {
+		t.Fatalf("read mbox: %v", err)
+	}
+	mbox := string(data)
+	if strings.Count(mbox, "\nFrom m") != 1 || !strings.HasPrefix(mbox, "From m") {
+		t.Fatalf("expected two mbox separators:\n%s", mbox)
+	}
+	if !strings.Contains(mbox, "X-Gmail-Labels: INBOX,Clients,UNREAD\n") || !strings.Contains(mbox, "X-GM-THRID: t-m1\n") {
+		t.Fatalf("missing Gmail headers:\n%s", mbox)
+	}
+	if !strings.Contains(mbox, "\n>From the top\n") || strings.Contains(mbox, "\r\n") {
+		t.Fatalf("body not mboxrd-escaped/LF-normalized:\n%s", mbox)
+	}
+
+	// Simulate a crash after a partial append: the tail past the checkpoint is dropped.
+	f, err := os.OpenFile(mboxPath, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	_, _ = f.WriteString("From partial")
+	_ = f.Close()
+
+	atomic.StoreInt32(&fetches, 0)
+	res = runGmailExport(t, "--query", "label:clients", "--out", dir)
+	if res["skipped"] != float64(2) || res["exported"] != float64(0) || atomic.LoadInt32(&fetches) != 0 {
+		t.Fatalf("expected resume to skip everything: %v (fetches=%d)", res, fetches)
+	}
+	again, err := os.ReadFile(mboxPath)
+	if err != nil {
+		t.Fatalf("read mbox: %v", err)
+	}
+	if string(again) != mbox {
+		t.Fatalf("mbox tail not truncated on resume")
+	}
+
+	u, _ := ui.New(ui.Options{Stdout: os.Stdout, Stderr: os.Stderr, Color: "never"})
+	ctx := ui.WithUI(context.Background(), u)
+	if err := runKong(t, &GmailExportCmd{}, []string{"--query", "other", "--out", dir}, ctx, &RootFlags{Account: "a@b.com"}); err == nil {
+		t.Fatalf("expected checkpoint mismatch error")
+	}
+}
+
+func TestGmailExport_MboxRefusesForeignMailbox(t *testing.T) {
+	var fetches int32
+	newGmailExportTestService(t, &fetches)
+	dir := t.TempDir()
+	mboxPath := filepath.Join(dir, gmailExportMboxName)
+	if err := os.WriteFile(mboxPath, []byte("From someone-else\n\nkeep me\n"), 0o600); err != nil {
+		t.Fatalf("write mbox: %v", err)
+	}
+
+	u, _ := ui.New(ui.Options{Stdout: os.Stdout, Stderr: os.Stderr, Color: "never"})
+	ctx := ui.WithUI(context.Background(), u)
+	if err := runKong(t, &GmailExportCmd{}, []string{"--query", "label:clients", "--out", dir}, ctx, &RootFlags{Account: "a@b.com"}); err == nil {
+		t.Fatalf("expected refusal to overwrite an existing mbox")
+	}
+	if data, _ := os.ReadFile(mboxPath); string(data) != "From someone-else\n\nkeep me\n" || atomic.LoadInt32(&fetches) != 0 {
+		t.Fatalf("existing mbox was modified: %q", data)
+	}
+
+	res := runGmailExport(t, "--query", "label:clients", "--out", dir, "--restart")
+	if res["exported"] != float64(2) {
+		t.Fatalf("unexpected result: %v", res)
+	}
+	if data, _ := os.ReadFile(mboxPath); strings.Contains(string(data), "keep me") {
+		t.Fatalf("--restart should overwrite the mbox")
+	}
+}
+
+func TestGmailExport_MaildirFlags(t *testing.T) {
+	var fetches int32
+	newGmailExportTestService(t, &fetches)
+	dir := t.TempDir()
+
+	res := runGmailExport(t, "--query", "in:anywhere", "--format", "maildir", "--out", dir, "--concurrency", "2")
+	if res["exported"] != float64(2) {
+		t.Fatalf("unexpected result: %v", res)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(dir, "cur"))
+	if err != nil {
+		t.Fatalf("read cur: %v", err)
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	want := []string{"1735689600.m1.gog:2,", "1735689600.m2.gog:2,FS"}
+	if strings.Join(names, " ") != strings.Join(want, " ") {
+		t.Fatalf("unexpected maildir names: %v", names)
+	}
+
+	if tmp, _ := os.ReadDir(filepath.Join(dir, "tmp")); len(tmp) != 0 {
+		t.Fatalf("tmp not empty: %v", tmp)
+	}
+
+	// Without a checkpoint, existing Maildir files still count as done.
+	if err := os.Remove(filepath.Join(dir, gmailExportCheckpointName)); err != nil {
+		t.Fatalf("remove checkpoint: %v", err)
+	}
+	atomic.StoreInt32(&fetches, 0)
+	res = runGmailExport(t, "--query", "in:anywhere", "--format", "maildir", "--out", dir)
+	if res["skipped"] != float64(2) || atomic.LoadInt32(&fetches) != 0 {
+		t.Fatalf("expected maildir resume: %v", res)
+	}
+}
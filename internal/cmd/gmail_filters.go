@@ -19,6 +19,8 @@ type GmailFiltersCmd struct {
 	Get    GmailFiltersGetCmd    `cmd:"" name:"get" help:"Get a specific filter"`
 	Create GmailFiltersCreateCmd `cmd:"" name:"create" help:"Create a new email filter"`
 	Delete GmailFiltersDeleteCmd `cmd:"" name:"delete" help:"Delete a filter"`
+	Export GmailFiltersExportCmd `cmd:"" name:"export" help:"Export all filters as YAML or Gmail XML"`
+	Apply  GmailFiltersApplyCmd  `cmd:"" name:"apply" help:"Converge filters to a YAML or Gmail XML file"`
 }
 
 type GmailFiltersListCmd struct{}
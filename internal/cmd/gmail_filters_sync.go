@@ -0,0 +1,754 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/kong"
+	"google.golang.org/api/gmail/v1"
+	"gopkg.in/yaml.v3"
+
+	"github.com/steipete/gogcli/internal/config"
+	"github.com/steipete/gogcli/internal/outfmt"
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+const (
+	filterFormatYAML     = "yaml"
+	filterFormatGmailXML = "gmail-xml"
+)
+
+// filterSpec is the declarative, label-name based form of a Gmail filter used
+// by filters export/apply. System label changes are spelled as flags.
+type filterSpec struct {
+	From           string   `yaml:"from,omitempty" json:"from,omitempty"`
+	To             string   `yaml:"to,omitempty" json:"to,omitempty"`
+	Subject        string   `yaml:"subject,omitempty" json:"subject,omitempty"`
+	Query          string   `yaml:"query,omitempty" json:"query,omitempty"`
+	NegatedQuery   string   `yaml:"negatedQuery,omitempty" json:"negatedQuery,omitempty"`
+	HasAttachment  bool     `yaml:"hasAttachment,omitempty" json:"hasAttachment,omitempty"`
+	ExcludeChats   bool     `yaml:"excludeChats,omitempty" json:"excludeChats,omitempty"`
+	Size           int64    `yaml:"size,omitempty" json:"size,omitempty"`
+	SizeComparison string   `yaml:"sizeComparison,omitempty" json:"sizeComparison,omitempty"`
+	AddLabels      []string `yaml:"addLabels,omitempty" json:"addLabels,omitempty"`
+	RemoveLabels   []string `yaml:"removeLabels,omitempty" json:"removeLabels,omitempty"`
+	Archive        bool     `yaml:"archive,omitempty" json:"archive,omitempty"`
+	MarkRead       bool     `yaml:"markRead,omitempty" json:"markRead,omitempty"`
+	Star           bool     `yaml:"star,omitempty" json:"star,omitempty"`
+	Trash          bool     `yaml:"trash,omitempty" json:"trash,omitempty"`
+	NeverSpam      bool     `yaml:"neverSpam,omitempty" json:"neverSpam,omitempty"`
+	Important      bool     `yaml:"important,omitempty" json:"important,omitempty"`
+	NeverImportant bool     `yaml:"neverImportant,omitempty" json:"neverImportant,omitempty"`
+	Forward        string   `yaml:"forward,omitempty" json:"forward,omitempty"`
+}
+
+type filterSpecFile struct {
+	Filters []filterSpec `yaml:"filters"`
+}
+
+// System labels that filterSpec expresses as flags.
+var (
+	filterAddFlagLabels    = []string{"STARRED", "TRASH", "IMPORTANT"}
+	filterRemoveFlagLabels = []string{"INBOX", "UNREAD", "SPAM", "IMPORTANT"}
+)
+
+func (s *filterSpec) addFlag(id string) *bool {
+	switch id {
+	case "STARRED":
+		return &s.Star
+	case "TRASH":
+		return &s.Trash
+	case "IMPORTANT":
+		return &s.Important
+	}
+	return nil
+}
+
+func (s *filterSpec) removeFlag(id string) *bool {
+	switch id {
+	case "INBOX":
+		return &s.Archive
+	case "UNREAD":
+		return &s.MarkRead
+	case "SPAM":
+		return &s.NeverSpam
+	case "IMPORTANT":
+		return &s.NeverImportant
+	}
+	return nil
+}
+
+func filterSpecFromGmail(f *gmail.Filter, idToName map[string]string) filterSpec {
+	var s filterSpec
+	if c := f.Criteria; c != nil {
+		s.From, s.To, s.Subject, s.Query, s.NegatedQuery = c.From, c.To, c.Subject, c.Query, c.NegatedQuery
+		s.HasAttachment, s.ExcludeChats = c.HasAttachment, c.ExcludeChats
+		s.Size = c.Size
+		if c.Size != 0 && c.SizeComparison != "unspecified" {
+			s.SizeComparison = c.SizeComparison
+		}
+	}
+	if a := f.Action; a != nil {
+		for _, id := range a.AddLabelIds {
+			if flag := s.addFlag(id); flag != nil {
+				*flag = true
+				continue
+			}
+			s.AddLabels = append(s.AddLabels, labelNameOrID(id, idToName))
+		}
+		for _, id := range a.RemoveLabelIds {
+			if flag := s.removeFlag(id); flag != nil {
+				*flag = true
+				continue
+			}
+			s.RemoveLabels = append(s.RemoveLabels, labelNameOrID(id, idToName))
+		}
+		s.Forward = a.Forward
+	}
+	return s
+}
+
+func labelNameOrID(id string, idToName map[string]string) string {
+	if name, ok := idToName[id]; ok && name != "" {
+		return name
+	}
+	return id
+}
+
+// normalized folds system labels listed by name into their flags and sorts
+// label lists, so equivalent specs compare equal.
+func (s filterSpec) normalized() filterSpec {
+	out := s
+	out.AddLabels, out.RemoveLabels = nil, nil
+	for _, l := range s.AddLabels {
+		if flag := out.addFlag(strings.ToUpper(strings.TrimSpace(l))); flag != nil {
+			*flag = true
+		} else if strings.TrimSpace(l) != "" {
+			out.AddLabels = append(out.AddLabels, strings.TrimSpace(l))
+		}
+	}
+	for _, l := range s.RemoveLabels {
+		if flag := out.removeFlag(strings.ToUpper(strings.TrimSpace(l))); flag != nil {
+			*flag = true
+		} else if strings.TrimSpace(l) != "" {
+			out.RemoveLabels = append(out.RemoveLabels, strings.TrimSpace(l))
+		}
+	}
+	sort.Slice(out.AddLabels, func(i, j int) bool { return strings.ToLower(out.AddLabels[i]) < strings.ToLower(out.AddLabels[j]) })
+	sort.Slice(out.RemoveLabels, func(i, j int) bool {
+		return strings.ToLower(out.RemoveLabels[i]) < strings.ToLower(out.RemoveLabels[j])
+	})
+	if out.Size == 0 {
+		out.SizeComparison = ""
+	}
+	return out
+}
+
+// key identifies a filter for diffing; label names compare case-insensitively
+// like Gmail does.
+func (s filterSpec) key() string {
+	n := s.normalized()
+	for i := range n.AddLabels {
+		n.AddLabels[i] = strings.ToLower(n.AddLabels[i])
+	}
+	for i := range n.RemoveLabels {
+		n.RemoveLabels[i] = strings.ToLower(n.RemoveLabels[i])
+	}
+	data, _ := json.Marshal(n)
+	return string(data)
+}
+
+func (s filterSpec) validate() error {
+	if s.From == "" && s.To == "" && s.Subject == "" && s.Query == "" && s.NegatedQuery == "" && !s.HasAttachment && s.Size == 0 {
+		return errors.New("no criteria (from, to, subject, query, negatedQuery, hasAttachment, or size)")
+	}
+	n := s.normalized()
+	if len(n.AddLabels) == 0 && len(n.RemoveLabels) == 0 && !n.Archive && !n.MarkRead && !n.Star && !n.Trash &&
+		!n.NeverSpam && !n.Important && !n.NeverImportant && n.Forward == "" {
+		return errors.New("no action")
+	}
+	if s.Size != 0 && s.SizeComparison != "larger" && s.SizeComparison != "smaller" {
+		return errors.New("size needs sizeComparison: larger or smaller")
+	}
+	return nil
+}
+
+// toGmail builds the API filter; every user label must already exist.
+func (s filterSpec) toGmail(nameToID map[string]string) (*gmail.Filter, error) {
+	n := s.normalized()
+	criteria := &gmail.FilterCriteria{
+		From: n.From, To: n.To, Subject: n.Subject, Query: n.Query, NegatedQuery: n.NegatedQuery,
+		HasAttachment: n.HasAttachment, ExcludeChats: n.ExcludeChats, Size: n.Size, SizeComparison: n.SizeComparison,
+	}
+	action := &gmail.FilterAction{Forward: n.Forward}
+	for _, id := range filterAddFlagLabels {
+		if *n.addFlag(id) {
+			action.AddLabelIds = append(action.AddLabelIds, id)
+		}
+	}
+	for _, id := range filterRemoveFlagLabels {
+		if *n.removeFlag(id) {
+			action.RemoveLabelIds = append(action.RemoveLabelIds, id)
+		}
+	}
+	for _, name := range n.AddLabels {
+		id, ok := nameToID[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown label %q", name)
+		}
+		action.AddLabelIds = append(action.AddLabelIds, id)
+	}
+	for _, name := range n.RemoveLabels {
+		id, ok := nameToID[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown label %q", name)
+		}
+		action.RemoveLabelIds = append(action.RemoveLabelIds, id)
+	}
+	return &gmail.Filter{Criteria: criteria, Action: action}, nil
+}
+
+// describe is a one-line summary for plans.
+func (s filterSpec) describe() string {
+	n := s.normalized()
+	var crit, acts []string
+	add := func(list *[]string, cond bool, v string) {
+		if cond {
+			*list = append(*list, v)
+		}
+	}
+	add(&crit, n.From != "", "from:"+n.From)
+	add(&crit, n.To != "", "to:"+n.To)
+	add(&crit, n.Subject != "", "subject:"+n.Subject)
+	add(&crit, n.Query != "", n.Query)
+	add(&crit, n.NegatedQuery != "", "-("+n.NegatedQuery+")")
+	add(&crit, n.HasAttachment, "has:attachment")
+	add(&crit, n.Size != 0, n.SizeComparison+":"+strconv.FormatInt(n.Size, 10))
+	for _, l := range n.AddLabels {
+		acts = append(acts, "+"+l)
+	}
+	for _, l := range n.RemoveLabels {
+		acts = append(acts, "-"+l)
+	}
+	add(&acts, n.Archive, "archive")
+	add(&acts, n.MarkRead, "mark-read")
+	add(&acts, n.Star, "star")
+	add(&acts, n.Trash, "trash")
+	add(&acts, n.NeverSpam, "never-spam")
+	add(&acts, n.Important, "important")
+	add(&acts, n.NeverImportant, "never-important")
+	add(&acts, n.Forward != "", "forward:"+n.Forward)
+	return strings.Join(crit, " ") + " -> " + strings.Join(acts, ", ")
+}
+
+type filterDeletion struct {
+	ID   string     `json:"id"`
+	Spec filterSpec `json:"filter"`
+}
+
+type filterPlan struct {
+	Create       []filterSpec     `json:"create"`
+	Delete       []filterDeletion `json:"delete"`
+	Unchanged    int              `json:"unchanged"`
+	CreateLabels []string         `json:"createLabels"`
+}
+
+// planFilterSync diffs desired specs against the account's filters. Gmail
+// filters cannot be edited, so a changed filter is a delete plus a create.
+func planFilterSync(desired []filterSpec, actual []*gmail.Filter, idToName, nameToID map[string]string) (filterPlan, error) {
+	plan := filterPlan{Create: []filterSpec{}, Delete: []filterDeletion{}, CreateLabels: []string{}}
+
+	existing := map[string][]*gmail.Filter{}
+	for _, f := range actual {
+		if f == nil {
+			continue
+		}
+		k := filterSpecFromGmail(f, idToName).key()
+		existing[k] = append(existing[k], f)
+	}
+
+	newLabels := map[string]bool{}
+	for _, s := range desired {
+		k := s.key()
+		if len(existing[k]) > 0 {
+			existing[k] = existing[k][1:]
+			plan.Unchanged++
+			continue
+		}
+		n := s.normalized()
+		for _, name := range n.RemoveLabels {
+			if _, ok := nameToID[strings.ToLower(name)]; !ok {
+				return plan, usagef("filter %q removes unknown label %q", s.describe(), name)
+			}
+		}
+		for _, name := range n.AddLabels {
+			if _, ok := nameToID[strings.ToLower(name)]; !ok && !newLabels[strings.ToLower(name)] {
+				newLabels[strings.ToLower(name)] = true
+				plan.CreateLabels = append(plan.CreateLabels, name)
+			}
+		}
+		plan.Create = append(plan.Create, n)
+	}
+
+	for _, f := range actual {
+		if f == nil {
+			continue
+		}
+		spec := filterSpecFromGmail(f, idToName)
+		for _, left := range existing[spec.key()] {
+			if left == f {
+				plan.Delete = append(plan.Delete, filterDeletion{ID: f.Id, Spec: spec.normalized()})
+			}
+		}
+	}
+	return plan, nil
+}
+
+type GmailFiltersExportCmd struct {
+	Format string `name:"format" help:"Output format" enum:"yaml,gmail-xml" default:"yaml"`
+	Out    string `name:"out" short:"o" help:"Write to this file instead of stdout"`
+}
+
+func (c *GmailFiltersExportCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+	svc, err := newGmailService(ctx, account)
+	if err != nil {
+		return err
+	}
+
+	resp, err := svc.Users.Settings.Filters.List("me").Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+	idToName, err := fetchLabelIDToName(svc)
+	if err != nil {
+		return err
+	}
+	specs := make([]filterSpec, 0, len(resp.Filter))
+	for _, f := range resp.Filter {
+		if f != nil {
+			specs = append(specs, filterSpecFromGmail(f, idToName).normalized())
+		}
+	}
+
+	var data []byte
+	if c.Format == filterFormatGmailXML {
+		var skipped []string
+		data, skipped, err = encodeGmailFilterXML(specs, account, time.Now())
+		for _, s := range skipped {
+			u.Err().Printf("Gmail XML cannot remove user labels; dropped from: %s", s)
+		}
+	} else {
+		data, err = yaml.Marshal(filterSpecFile{Filters: specs})
+	}
+	if err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(c.Out) == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	path, err := config.ExpandPath(c.Out)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return err
+	}
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(os.Stdout, map[string]any{"path": path, "format": c.Format, "filters": len(specs)})
+	}
+	u.Out().Printf("path\t%s", path)
+	u.Out().Printf("filters\t%d", len(specs))
+	return nil
+}
+
+type GmailFiltersApplyCmd struct {
+	File   string `arg:"" name:"file" help:"Filters file (YAML from 'filters export', or Gmail's mailFilters.xml); '-' for stdin"`
+	Format string `name:"format" help:"Input format (auto detects by extension/content)" enum:"auto,yaml,gmail-xml" default:"auto"`
+	DryRun bool   `name:"dry-run" help:"Show the plan without changing anything"`
+	Delete bool   `name:"delete" help:"Delete filters that are not in the file (a file with no filters needs an explicit --delete)" default:"true" negatable:""`
+}
+
+func (c *GmailFiltersApplyCmd) Run(ctx context.Context, kctx *kong.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+
+	desired, err := c.readSpecs()
+	if err != nil {
+		return err
+	}
+	for i, s := range desired {
+		if err := s.validate(); err != nil {
+			return usagef("filter %d: %v", i+1, err)
+		}
+	}
+	// An empty file (or a truncated export) would otherwise plan deleting
+	// every filter on the account.
+	if len(desired) == 0 && c.Delete && !c.DryRun && !flagProvided(kctx, "delete") {
+		return usage("file has no filters; pass --delete to remove every filter on the account, or --no-delete")
+	}
+
+	svc, err := newGmailService(ctx, account)
+	if err != nil {
+		return err
+	}
+	resp, err := svc.Users.Settings.Filters.List("me").Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+	labels, err := svc.Users.Labels.List("me").Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+	idToName := map[string]string{}
+	nameToID := map[string]string{}
+	for _, l := range labels.Labels {
+		idToName[l.Id] = l.Name
+		nameToID[strings.ToLower(l.Id)] = l.Id
+		nameToID[strings.ToLower(l.Name)] = l.Id
+	}
+
+	plan, err := planFilterSync(desired, resp.Filter, idToName, nameToID)
+	if err != nil {
+		return err
+	}
+	if !c.Delete {
+		plan.Delete = []filterDeletion{}
+	}
+
+	if c.DryRun || (len(plan.Create) == 0 && len(plan.Delete) == 0) {
+		return writeFilterPlan(ctx, u, plan, c.DryRun)
+	}
+
+	if len(plan.Delete) > 0 {
+		if u != nil {
+			u.Err().Printf("%d gmail filter(s) not in the file will be deleted", len(plan.Delete))
+		}
+		if err := confirmDestructive(ctx, flags, fmt.Sprintf("delete %d gmail filters", len(plan.Delete))); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range plan.CreateLabels {
+		created, createErr := createLabel(ctx, svc, name)
+		if createErr != nil {
+			return mapLabelCreateError(createErr, name)
+		}
+		nameToID[strings.ToLower(name)] = created.Id
+	}
+	// Create before deleting so a failure never leaves the mailbox unfiltered.
+	for i, s := range plan.Create {
+		f, buildErr := s.toGmail(nameToID)
+		if buildErr != nil {
+			return buildErr
+		}
+		if _, createErr := svc.Users.Settings.Filters.Create("me", f).Context(ctx).Do(); createErr != nil {
+			return fmt.Errorf("create filter %q (%d of %d created): %w", s.describe(), i, len(plan.Create), createErr)
+		}
+	}
+	for i, d := range plan.Delete {
+		if delErr := svc.Users.Settings.Filters.Delete("me", d.ID).Context(ctx).Do(); delErr != nil && !isNotFoundAPIError(delErr) {
+			return fmt.Errorf("delete filter %s (%d of %d deleted): %w", d.ID, i, len(plan.Delete), delErr)
+		}
+	}
+	return writeFilterPlan(ctx, u, plan, false)
+}
+
+func (c *GmailFiltersApplyCmd) readSpecs() ([]filterSpec, error) {
+	var (
+		data []byte
+		err  error
+	)
+	path := strings.TrimSpace(c.File)
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		path, err = config.ExpandPath(path)
+		if err == nil {
+			data, err = os.ReadFile(path) //nolint:gosec // user-provided path
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	format := c.Format
+	if format == "" || format == "auto" {
+		format = filterFormatYAML
+		if strings.EqualFold(filepath.Ext(path), ".xml") || bytes.HasPrefix(bytes.TrimSpace(data), []byte("<")) {
+			format = filterFormatGmailXML
+		}
+	}
+	if format == filterFormatGmailXML {
+		return decodeGmailFilterXML(data)
+	}
+
+	var file filterSpecFile
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, usagef("parse %s: %v", c.File, err)
+	}
+	return file.Filters, nil
+}
+
+func writeFilterPlan(ctx context.Context, u *ui.UI, plan filterPlan, dryRun bool) error {
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(os.Stdout, map[string]any{
+			"dryRun":       dryRun,
+			"create":       plan.Create,
+			"delete":       plan.Delete,
+			"unchanged":    plan.Unchanged,
+			"createLabels": plan.CreateLabels,
+		})
+	}
+	for _, l := range plan.CreateLabels {
+		u.Out().Printf("+ label\t%s", l)
+	}
+	for _, s := range plan.Create {
+		u.Out().Printf("+ filter\t%s", s.describe())
+	}
+	for _, d := range plan.Delete {
+		u.Out().Printf("- filter\t%s\t%s", d.ID, d.Spec.describe())
+	}
+	verb := "Applied"
+	if dryRun {
+		verb = "Plan"
+	}
+	u.Err().Printf("%s: %d to create, %d to delete, %d unchanged", verb, len(plan.Create), len(plan.Delete), plan.Unchanged)
+	return nil
+}
+
+// Gmail's filter export/import format (Settings > Filters > Export) is an
+// Atom feed of entries with apps:property name/value pairs. It carries one
+// label per entry, so a filter adding several labels becomes several entries.
+
+type gmailFilterFeedOut struct {
+	XMLName   xml.Name              `xml:"feed"`
+	Xmlns     string                `xml:"xmlns,attr"`
+	XmlnsApps string                `xml:"xmlns:apps,attr"`
+	Title     string                `xml:"title"`
+	ID        string                `xml:"id"`
+	Updated   string                `xml:"updated"`
+	Author    gmailFilterFeedAuthor `xml:"author"`
+	Entries   []gmailFilterEntryOut `xml:"entry"`
+}
+
+type gmailFilterFeedAuthor struct {
+	Name  string `xml:"name"`
+	Email string `xml:"email"`
+}
+
+type gmailFilterEntryOut struct {
+	Category   gmailFilterCategory   `xml:"category"`
+	Title      string                `xml:"title"`
+	ID         string                `xml:"id"`
+	Updated    string                `xml:"updated"`
+	Content    string                `xml:"content"`
+	Properties []gmailFilterProperty `xml:"apps:property"`
+}
+
+type gmailFilterCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type gmailFilterProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type gmailFilterFeedIn struct {
+	Entries []struct {
+		Properties []gmailFilterProperty `xml:"property"`
+	} `xml:"entry"`
+}
+
+var gmailFilterCategoryLabels = map[string]string{
+	"CATEGORY_PERSONAL":   "^smartlabel_personal",
+	"CATEGORY_SOCIAL":     "^smartlabel_social",
+	"CATEGORY_PROMOTIONS": "^smartlabel_promo",
+	"CATEGORY_UPDATES":    "^smartlabel_notification",
+	"CATEGORY_FORUMS":     "^smartlabel_group",
+}
+
+func encodeGmailFilterXML(specs []filterSpec, account string, now time.Time) ([]byte, []string, error) {
+	updated := now.UTC().Format(time.RFC3339)
+	feed := gmailFilterFeedOut{
+		Xmlns:     "http://www.w3.org/2005/Atom",
+		XmlnsApps: "http://schemas.google.com/apps/2006",
+		Title:     "Mail Filters",
+		ID:        fmt.Sprintf("tag:mail.google.com,2008:filters:%d", now.Unix()),
+		Updated:   updated,
+		Author:    gmailFilterFeedAuthor{Email: account},
+	}
+	var skipped []string
+
+	for i, s := range specs {
+		n := s.normalized()
+		criteria := []gmailFilterProperty{}
+		prop := func(list *[]gmailFilterProperty, cond bool, name, value string) {
+			if cond {
+				*list = append(*list, gmailFilterProperty{Name: name, Value: value})
+			}
+		}
+		prop(&criteria, n.From != "", "from", n.From)
+		prop(&criteria, n.To != "", "to", n.To)
+		prop(&criteria, n.Subject != "", "subject", n.Subject)
+		prop(&criteria, n.Query != "", "hasTheWord", n.Query)
+		prop(&criteria, n.NegatedQuery != "", "doesNotHaveTheWord", n.NegatedQuery)
+		prop(&criteria, n.HasAttachment, "hasAttachment", "true")
+		prop(&criteria, n.ExcludeChats, "excludeChats", "true")
+		if n.Size != 0 {
+			op := "s_sl"
+			if n.SizeComparison == "smaller" {
+				op = "s_ss"
+			}
+			criteria = append(criteria,
+				gmailFilterProperty{Name: "size", Value: strconv.FormatInt(n.Size, 10)},
+				gmailFilterProperty{Name: "sizeOperator", Value: op},
+				gmailFilterProperty{Name: "sizeUnit", Value: "s_sb"})
+		}
+
+		actions := []gmailFilterProperty{}
+		prop(&actions, n.Archive, "shouldArchive", "true")
+		prop(&actions, n.MarkRead, "shouldMarkAsRead", "true")
+		prop(&actions, n.Star, "shouldStar", "true")
+		prop(&actions, n.Trash, "shouldTrash", "true")
+		prop(&actions, n.NeverSpam, "shouldNeverSpam", "true")
+		prop(&actions, n.Important, "shouldAlwaysMarkAsImportant", "true")
+		prop(&actions, n.NeverImportant, "shouldNeverMarkAsImportant", "true")
+		prop(&actions, n.Forward != "", "forwardTo", n.Forward)
+		if len(n.RemoveLabels) > 0 {
+			skipped = append(skipped, n.describe())
+		}
+
+		var labelActions [][]gmailFilterProperty
+		for _, l := range n.AddLabels {
+			if smart, ok := gmailFilterCategoryLabels[strings.ToUpper(l)]; ok {
+				actions = append(actions, gmailFilterProperty{Name: "smartLabelToApply", Value: smart})
+				continue
+			}
+			labelActions = append(labelActions, []gmailFilterProperty{{Name: "label", Value: l}})
+		}
+		if len(labelActions) == 0 {
+			labelActions = [][]gmailFilterProperty{nil}
+		}
+		labelActions[0] = append(labelActions[0], actions...)
+
+		for j, la := range labelActions {
+			props := append(append([]gmailFilterProperty{}, criteria...), la...)
+			feed.Entries = append(feed.Entries, gmailFilterEntryOut{
+				Category:   gmailFilterCategory{Term: "filter"},
+				Title:      "Mail Filter",
+				ID:         fmt.Sprintf("tag:mail.google.com,2008:filter:%d%03d%02d", now.Unix(), i, j),
+				Updated:    updated,
+				Properties: props,
+			})
+		}
+	}
+
+	out, err := xml.MarshalIndent(feed, "", "\t")
+	if err != nil {
+		return nil, nil, err
+	}
+	return append([]byte("<?xml version='1.0' encoding='UTF-8'?>"+"\n"), append(out, '\n')...), skipped, nil
+}
+
+func decodeGmailFilterXML(data []byte) ([]filterSpec, error) {
+	var feed gmailFilterFeedIn
+	if err := xml.Unmarshal(data, &feed); err != nil {
+		return nil, usagef("parse Gmail filter XML: %v", err)
+	}
+	smartToLabel := map[string]string{}
+	for label, smart := range gmailFilterCategoryLabels {
+		smartToLabel[smart] = label
+	}
+
+	specs := make([]filterSpec, 0, len(feed.Entries))
+	for i, e := range feed.Entries {
+		var s filterSpec
+		unit := int64(1)
+		for _, p := range e.Properties {
+			isTrue := p.Value == "true"
+			switch p.Name {
+			case "from":
+				s.From = p.Value
+			case "to":
+				s.To = p.Value
+			case "subject":
+				s.Subject = p.Value
+			case "hasTheWord":
+				s.Query = p.Value
+			case "doesNotHaveTheWord":
+				s.NegatedQuery = p.Value
+			case "hasAttachment":
+				s.HasAttachment = isTrue
+			case "excludeChats":
+				s.ExcludeChats = isTrue
+			case "size":
+				size, err := strconv.ParseInt(p.Value, 10, 64)
+				if err != nil {
+					return nil, usagef("entry %d: invalid size %q", i+1, p.Value)
+				}
+				s.Size = size
+			case "sizeOperator":
+				s.SizeComparison = "larger"
+				if p.Value == "s_ss" {
+					s.SizeComparison = "smaller"
+				}
+			case "sizeUnit":
+				switch p.Value {
+				case "s_skb":
+					unit = 1 << 10
+				case "s_smb":
+					unit = 1 << 20
+				}
+			case "label":
+				s.AddLabels = append(s.AddLabels, p.Value)
+			case "smartLabelToApply":
+				if label, ok := smartToLabel[p.Value]; ok {
+					s.AddLabels = append(s.AddLabels, label)
+				}
+			case "shouldArchive":
+				s.Archive = isTrue
+			case "shouldMarkAsRead":
+				s.MarkRead = isTrue
+			case "shouldStar":
+				s.Star = isTrue
+			case "shouldTrash":
+				s.Trash = isTrue
+			case "shouldNeverSpam":
+				s.NeverSpam = isTrue
+			case "shouldAlwaysMarkAsImportant":
+				s.Important = isTrue
+			case "shouldNeverMarkAsImportant":
+				s.NeverImportant = isTrue
+			case "forwardTo":
+				s.Forward = p.Value
+			}
+		}
+		s.Size *= unit
+		specs = append(specs, s.normalized())
+	}
+	return specs, nil
+}
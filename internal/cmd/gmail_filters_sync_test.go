@@ -0,0 +1,239 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
+	"gopkg.in/yaml.v3"
+
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+type fakeFilterServer struct {
+	filters       []*gmail.Filter
+	created       []*gmail.Filter
+	deleted       []string
+	createdLabels []string
+}
+
+func newFilterSyncTestService(t *testing.T, filters []*gmail.Filter) *fakeFilterServer {
+	t.Helper()
+
+	fake := &fakeFilterServer{filters: filters}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/settings/filters") && r.Method == http.MethodGet:
+			_ = json.NewEncoder(w).Encode(map[string]any{"filter": fake.filters})
+		case strings.HasSuffix(r.URL.Path, "/settings/filters") && r.Method == http.MethodPost:
+			var f gmail.Filter
+			_ = json.NewDecoder(r.Body).Decode(&f)
+			f.Id = "new"
+			fake.created = append(fake.created, &f)
+			_ = json.NewEncoder(w).Encode(f)
+		case strings.Contains(r.URL.Path, "/settings/filters/") && r.Method == http.MethodDelete:
+			fake.deleted = append(fake.deleted, r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:])
+			w.WriteHeader(http.StatusNoContent)
+		case strings.HasSuffix(r.URL.Path, "/users/me/labels") && r.Method == http.MethodGet:
+			_ = json.NewEncoder(w).Encode(map[string]any{"labels": []map[string]any{
+				{"id": "INBOX", "name": "INBOX"},
+				{"id": "UNREAD", "name": "UNREAD"},
+				{"id": "CATEGORY_PROMOTIONS", "name": "CATEGORY_PROMOTIONS"},
+				{"id": "Label_1", "name": "Newsletters"},
+			}})
+		case strings.HasSuffix(r.URL.Path, "/users/me/labels") && r.Method == http.MethodPost:
+			var l gmail.Label
+			_ = json.NewDecoder(r.Body).Decode(&l)
+			fake.createdLabels = append(fake.createdLabels, l.Name)
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": "Label_new", "name": l.Name})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	svc, err := gmail.NewService(context.Background(), option.WithoutAuthentication(), option.WithHTTPClient(srv.Client()), option.WithEndpoint(srv.URL+"/"))
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	origNew := newGmailService
+	t.Cleanup(func() { newGmailService = origNew })
+	newGmailService = func(context.Context, string) (*gmail.Service, error) { return svc, nil }
+	return fake
+}
+
+var syncTestFilters = []*gmail.Filter{
+	{
+		Id:       "f1",
+		Criteria: &gmail.FilterCriteria{From: "news@example.com"},
+		Action:   &gmail.FilterAction{AddLabelIds: []string{"Label_1"}, RemoveLabelIds: []string{"INBOX", "UNREAD"}},
+	},
+	{
+		Id:       "f2",
+		Criteria: &gmail.FilterCriteria{Query: "unsubscribe", Size: 1000, SizeComparison: "larger"},
+		Action:   &gmail.FilterAction{AddLabelIds: []string{"STARRED"}},
+	},
+}
+
+func TestFilterSpec_GmailRoundTrip(t *testing.T) {
+	idToName := map[string]string{"Label_1": "Newsletters"}
+	spec := filterSpecFromGmail(syncTestFilters[0], idToName)
+	want := filterSpec{From: "news@example.com", AddLabels: []string{"Newsletters"}, Archive: true, MarkRead: true}
+	if !reflect.DeepEqual(spec, want) {
+		t.Fatalf("got %#v", spec)
+	}
+	back, err := spec.toGmail(map[string]string{"newsletters": "Label_1"})
+	if err != nil {
+		t.Fatalf("toGmail: %v", err)
+	}
+	if !reflect.DeepEqual(back.Action.AddLabelIds, []string{"Label_1"}) || !reflect.DeepEqual(back.Action.RemoveLabelIds, []string{"INBOX", "UNREAD"}) {
+		t.Fatalf("unexpected action: %#v", back.Action)
+	}
+
+	spelled := filterSpec{From: "news@example.com", AddLabels: []string{"newsletters"}, RemoveLabels: []string{"inbox", "UNREAD"}}
+	if spelled.key() != spec.key() {
+		t.Fatalf("label spellings should compare equal:\n%s\n%s", spelled.key(), spec.key())
+	}
+}
+
+func TestGmailFiltersExport_YAMLThenApplyIsNoop(t *testing.T) {
+	fake := newFilterSyncTestService(t, syncTestFilters)
+	path := filepath.Join(t.TempDir(), "filters.yaml")
+
+	runSendLike(t, &GmailFiltersExportCmd{}, "--out", path)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read export: %v", err)
+	}
+	var file filterSpecFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		t.Fatalf("parse export: %v\n%s", err, data)
+	}
+	if len(file.Filters) != 2 || file.Filters[1].SizeComparison != "larger" || !file.Filters[1].Star {
+		t.Fatalf("unexpected export:\n%s", data)
+	}
+
+	out := runSendLike(t, &GmailFiltersApplyCmd{}, path)
+	if out["unchanged"] != float64(2) || len(fake.created) != 0 || len(fake.deleted) != 0 {
+		t.Fatalf("re-applying an export must be a no-op: %#v", out)
+	}
+}
+
+func TestGmailFiltersApply_Converges(t *testing.T) {
+	fake := newFilterSyncTestService(t, syncTestFilters)
+	path := filepath.Join(t.TempDir(), "filters.yaml")
+	yamlSrc := `filters:
+  - from: news@example.com
+    addLabels: [Newsletters]
+    archive: true
+    markRead: true
+  - from: boss@example.com
+    addLabels: [Work/Boss]
+    important: true
+`
+	if err := os.WriteFile(path, []byte(yamlSrc), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	out := runSendLike(t, &GmailFiltersApplyCmd{}, path, "--dry-run")
+	if out["unchanged"] != float64(1) || len(out["create"].([]any)) != 1 || len(out["delete"].([]any)) != 1 {
+		t.Fatalf("unexpected plan: %#v", out)
+	}
+	if len(fake.created)+len(fake.deleted)+len(fake.createdLabels) != 0 {
+		t.Fatalf("dry run must not change anything")
+	}
+
+	if err := runKong(t, &GmailFiltersApplyCmd{}, []string{path}, context.Background(), &RootFlags{Account: "a@b.com"}); err == nil || len(fake.deleted) != 0 {
+		t.Fatalf("deleting filters must need confirmation, got %v", err)
+	}
+	u, err := ui.New(ui.Options{Stdout: os.Stdout, Stderr: os.Stderr, Color: "never"})
+	if err != nil {
+		t.Fatalf("ui.New: %v", err)
+	}
+	fake.createdLabels, fake.created = nil, nil
+	if err := runKong(t, &GmailFiltersApplyCmd{}, []string{path}, ui.WithUI(context.Background(), u), &RootFlags{Account: "a@b.com", Force: true}); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if !reflect.DeepEqual(fake.createdLabels, []string{"Work/Boss"}) || !reflect.DeepEqual(fake.deleted, []string{"f2"}) || len(fake.created) != 1 {
+		t.Fatalf("unexpected changes: labels=%v deleted=%v created=%d", fake.createdLabels, fake.deleted, len(fake.created))
+	}
+	act := fake.created[0].Action
+	if fake.created[0].Criteria.From != "boss@example.com" || !reflect.DeepEqual(act.AddLabelIds, []string{"IMPORTANT", "Label_new"}) {
+		t.Fatalf("unexpected created filter: %#v %#v", fake.created[0].Criteria, act)
+	}
+
+	// An empty file must not silently delete every filter, even with --force.
+	if err := os.WriteFile(path, []byte("filters: []\n"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	fake.deleted = nil
+	forced := &RootFlags{Account: "a@b.com", Force: true}
+	if err := runKong(t, &GmailFiltersApplyCmd{}, []string{path}, ui.WithUI(context.Background(), u), forced); err == nil || !strings.Contains(err.Error(), "no filters") || len(fake.deleted) != 0 {
+		t.Fatalf("expected empty file to be refused, got %v (deleted %v)", err, fake.deleted)
+	}
+	stderr := captureStderr(t, func() {
+		eu, uiErr := ui.New(ui.Options{Stdout: io.Discard, Stderr: os.Stderr, Color: "never"})
+		if uiErr != nil {
+			t.Fatalf("ui.New: %v", uiErr)
+		}
+		if err := runKong(t, &GmailFiltersApplyCmd{}, []string{path, "--delete"}, ui.WithUI(context.Background(), eu), forced); err != nil {
+			t.Fatalf("explicit --delete: %v", err)
+		}
+	})
+	if len(fake.deleted) == 0 || !strings.Contains(stderr, "will be deleted") {
+		t.Fatalf("expected explicit prune with a delete count, deleted=%v stderr=%q", fake.deleted, stderr)
+	}
+
+	if err := os.WriteFile(path, []byte("filters:\n  - form: typo@example.com\n"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := runKong(t, &GmailFiltersApplyCmd{}, []string{path}, context.Background(), &RootFlags{Account: "a@b.com"}); err == nil {
+		t.Fatalf("expected unknown field error")
+	}
+}
+
+func TestGmailFilterXML_RoundTrip(t *testing.T) {
+	specs := []filterSpec{
+		{From: "shop@example.com", AddLabels: []string{"Shopping", "Receipts", "CATEGORY_PROMOTIONS"}, Archive: true},
+		{Query: "big", Size: 5000, SizeComparison: "smaller", Forward: "me@example.org", RemoveLabels: []string{"Old"}},
+	}
+	data, skipped, err := encodeGmailFilterXML(specs, "a@b.com", time.Unix(1700000000, 0))
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	xmlSrc := string(data)
+	if !strings.Contains(xmlSrc, `<apps:property name="label" value="Receipts"></apps:property>`) ||
+		!strings.Contains(xmlSrc, `value="^smartlabel_promo"`) || len(skipped) != 1 {
+		t.Fatalf("unexpected XML (skipped %v):\n%s", skipped, xmlSrc)
+	}
+
+	parsed, err := decodeGmailFilterXML(data)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	// One entry per user label; the first carries the other actions.
+	if len(parsed) != 3 {
+		t.Fatalf("expected 3 entries, got %#v", parsed)
+	}
+	first := parsed[0]
+	if first.From != "shop@example.com" || !first.Archive || !reflect.DeepEqual(first.AddLabels, []string{"CATEGORY_PROMOTIONS", "Receipts"}) {
+		t.Fatalf("unexpected first entry: %#v", first)
+	}
+	if parsed[1].Archive || !reflect.DeepEqual(parsed[1].AddLabels, []string{"Shopping"}) {
+		t.Fatalf("unexpected second entry: %#v", parsed[1])
+	}
+	if parsed[2].Size != 5000 || parsed[2].SizeComparison != "smaller" || parsed[2].Forward != "me@example.org" {
+		t.Fatalf("unexpected third entry: %#v", parsed[2])
+	}
+}
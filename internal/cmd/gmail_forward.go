@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/api/gmail/v1"
+
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+type GmailForwardCmd struct {
+	MessageID    string   `arg:"" name:"messageId" help:"Message ID to forward"`
+	To           string   `name:"to" help:"Recipients (comma-separated)" required:""`
+	Cc           string   `name:"cc" help:"CC recipients (comma-separated)"`
+	Bcc          string   `name:"bcc" help:"BCC recipients (comma-separated)"`
+	Body         string   `name:"body" help:"Note above the forwarded message (plain text)"`
+	BodyFile     string   `name:"body-file" help:"Note file path (plain text; '-' for stdin)"`
+	BodyHTML     string   `name:"body-html" help:"Note above the forwarded message (HTML)"`
+	Attach       []string `name:"attach" help:"Additional attachment file path (repeatable)"`
+	From         string   `name:"from" help:"Send from this email address (must be a verified send-as alias)"`
+	AsAttachment bool     `name:"as-attachment" help:"Attach the original as message/rfc822 instead of inlining it"`
+	Attachments  bool     `name:"attachments" help:"Carry over the original's attachments (inline forwards)" default:"true" negatable:""`
+}
+
+func (c *GmailForwardCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+	messageID := strings.TrimSpace(c.MessageID)
+	if messageID == "" {
+		return usage("empty messageId")
+	}
+	to := splitCSV(c.To)
+	if len(to) == 0 {
+		return usage("required: --to")
+	}
+	body, err := resolveBodyInput(c.Body, c.BodyFile)
+	if err != nil {
+		return err
+	}
+	atts, err := attachmentsFromPaths(c.Attach)
+	if err != nil {
+		return err
+	}
+
+	svc, err := newGmailService(ctx, account)
+	if err != nil {
+		return err
+	}
+	fromAddr, _, err := resolveSendFrom(ctx, svc, account, c.From)
+	if err != nil {
+		return err
+	}
+
+	orig, err := svc.Users.Messages.Get("me", messageID).Format("full").Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+	q := quotedMessageFromPart(orig.Payload)
+
+	plain, htmlBody := body, c.BodyHTML
+	if c.AsAttachment {
+		rawMsg, getErr := svc.Users.Messages.Get("me", messageID).Format("raw").Context(ctx).Do()
+		if getErr != nil {
+			return getErr
+		}
+		raw, decodeErr := decodeGmailRaw(rawMsg.Raw)
+		if decodeErr != nil {
+			return fmt.Errorf("decode message %s: %w", messageID, decodeErr)
+		}
+		atts = append([]mailAttachment{{
+			Filename: forwardAttachmentName(q.Subject),
+			MIMEType: "message/rfc822",
+			Data:     raw,
+		}}, atts...)
+	} else {
+		plain, htmlBody = q.forwardBodies(body, c.BodyHTML)
+		if c.Attachments {
+			carried, carryErr := fetchOriginalAttachments(ctx, svc, messageID, orig.Payload)
+			if carryErr != nil {
+				return carryErr
+			}
+			atts = append(carried, atts...)
+		}
+	}
+
+	results, err := sendGmailBatches(ctx, svc, sendMessageOptions{
+		FromAddr:    fromAddr,
+		Subject:     prefixedSubject("Fwd:", q.Subject, "fwd:", "fw:"),
+		Body:        plain,
+		BodyHTML:    htmlBody,
+		ReplyInfo:   replyInfoFromMessage(orig),
+		Attachments: atts,
+	}, []sendBatch{{To: to, Cc: splitCSV(c.Cc), Bcc: splitCSV(c.Bcc)}})
+	if err != nil {
+		return err
+	}
+	return writeSendResults(ctx, u, fromAddr, results)
+}
+
+// forwardBodies builds the "Forwarded message" block Gmail uses, in plain text
+// and HTML, below the optional note.
+func (q quotedMessage) forwardBodies(body, bodyHTML string) (string, string) {
+	const marker = "---------- Forwarded message ---------"
+	fields := [][2]string{{"From", q.From}, {"Date", q.Date}, {"Subject", q.Subject}, {"To", q.To}, {"Cc", q.Cc}}
+
+	var plain strings.Builder
+	if strings.TrimSpace(body) != "" {
+		plain.WriteString(strings.TrimRight(body, "\r\n"))
+		plain.WriteString("\n\n")
+	}
+	plain.WriteString(marker + "\n")
+	for _, f := range fields {
+		if strings.TrimSpace(f[1]) != "" {
+			plain.WriteString(f[0] + ": " + f[1] + "\n")
+		}
+	}
+	plain.WriteString("\n")
+	plain.WriteString(strings.TrimRight(normalizeNewlines(q.Plain), "\n"))
+	plain.WriteString("\n")
+
+	if strings.TrimSpace(bodyHTML) == "" && strings.TrimSpace(body) != "" {
+		bodyHTML = plainToHTML(body)
+	}
+	var header strings.Builder
+	header.WriteString(marker + "<br>")
+	for _, f := range fields {
+		if strings.TrimSpace(f[1]) != "" {
+			header.WriteString(f[0] + ": " + html.EscapeString(f[1]) + "<br>")
+		}
+	}
+	htmlBody := fmt.Sprintf("%s<br><div class=\"gmail_quote\"><div dir=\"ltr\" class=\"gmail_attr\">%s</div><br>%s</div>",
+		bodyHTML, header.String(), q.htmlOrPlain())
+	return plain.String(), htmlBody
+}
+
+// fetchOriginalAttachments downloads the original's attachments so an inline
+// forward carries them.
+func fetchOriginalAttachments(ctx context.Context, svc *gmail.Service, messageID string, payload *gmail.MessagePart) ([]mailAttachment, error) {
+	infos := collectAttachments(payload)
+	out := make([]mailAttachment, 0, len(infos))
+	for _, a := range infos {
+		body, err := svc.Users.Messages.Attachments.Get("me", messageID, a.AttachmentID).Context(ctx).Do()
+		if err != nil {
+			return nil, fmt.Errorf("fetch attachment %s: %w", a.Filename, err)
+		}
+		data, err := decodeBase64URLBytes(body.Data)
+		if err != nil {
+			return nil, fmt.Errorf("decode attachment %s: %w", a.Filename, err)
+		}
+		out = append(out, mailAttachment{
+			Filename: filepath.Base(a.Filename),
+			MIMEType: a.MimeType,
+			Data:     data,
+		})
+	}
+	return out, nil
+}
+
+func forwardAttachmentName(subject string) string {
+	name := strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|':
+			return '_'
+		}
+		if r < 0x20 {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(subject))
+	if name == "" {
+		name = "message"
+	}
+	return name + ".eml"
+}
@@ -0,0 +1,511 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/api/gmail/v1"
+	gapi "google.golang.org/api/googleapi"
+
+	"github.com/steipete/gogcli/internal/config"
+	"github.com/steipete/gogcli/internal/outfmt"
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+const (
+	gmailImportLedgerName  = ".gog-import.json"
+	gmailImportLedgerEvery = 50
+)
+
+type GmailImportCmd struct {
+	Path               string   `arg:"" name:"path" help:"Message source: .eml file, mbox file, or Maildir/.eml directory"`
+	Label              []string `name:"label" help:"Label(s) to apply (names or IDs, comma-separated; missing labels are created)" sep:","`
+	Insert             bool     `name:"insert" help:"Use messages.insert (no spam scanning, like IMAP APPEND) instead of messages.import"`
+	NeverMarkSpam      bool     `name:"never-mark-spam" help:"Never send imported messages to spam"`
+	ProcessForCalendar bool     `name:"process-for-calendar" help:"Add calendar invites in imported messages to Calendar"`
+	SkipExisting       bool     `name:"skip-existing" negatable:"" default:"true" help:"Skip messages whose Message-ID already exists in the mailbox"`
+	Concurrency        int      `name:"concurrency" help:"Parallel uploads" default:"4"`
+	Ledger             string   `name:"ledger" help:"Progress ledger path (default: next to the source)"`
+}
+
+// gmailImportLedger records every message already uploaded, keyed by
+// Message-ID (or a content hash when the header is missing), so re-runs
+// resume and never create duplicates.
+type gmailImportLedger struct {
+	Account   string                            `json:"account"`
+	Imported  map[string]gmailImportLedgerEntry `json:"imported"`
+	UpdatedAt time.Time                         `json:"updated_at"`
+}
+
+type gmailImportLedgerEntry struct {
+	ID       string `json:"id"`
+	Source   string `json:"source"`
+	Existing bool   `json:"existing,omitempty"`
+}
+
+type importSourceMessage struct {
+	Source string
+	Raw    []byte
+}
+
+type gmailImportJob struct {
+	src       importSourceMessage
+	key       string
+	messageID string
+}
+
+type gmailImportResult struct {
+	job      gmailImportJob
+	id       string
+	existing bool
+	err      error
+}
+
+func (c *GmailImportCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+	if c.Concurrency < 1 {
+		return usage("--concurrency must be >= 1")
+	}
+	if c.Insert && (c.NeverMarkSpam || c.ProcessForCalendar) {
+		return usage("--never-mark-spam and --process-for-calendar only apply to import (drop --insert)")
+	}
+
+	path, err := config.ExpandPath(strings.TrimSpace(c.Path))
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	ledgerPath := strings.TrimSpace(c.Ledger)
+	switch {
+	case ledgerPath != "":
+		ledgerPath, err = config.ExpandPath(ledgerPath)
+		if err != nil {
+			return err
+		}
+	case info.IsDir():
+		ledgerPath = filepath.Join(path, gmailImportLedgerName)
+	default:
+		ledgerPath = path + gmailImportLedgerName
+	}
+
+	ledger, err := loadGmailImportLedger(ledgerPath, account)
+	if err != nil {
+		return err
+	}
+
+	svc, err := newGmailService(ctx, account)
+	if err != nil {
+		return err
+	}
+
+	labelIDs, err := resolveOrCreateLabelIDs(ctx, svc, c.Label)
+	if err != nil {
+		return err
+	}
+
+	saveLedger := func() error {
+		ledger.UpdatedAt = time.Now().UTC()
+		return writeGmailImportLedger(ledgerPath, ledger)
+	}
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan gmailImportJob)
+	results := make(chan gmailImportResult, c.Concurrency)
+
+	var wg sync.WaitGroup
+	for i := 0; i < c.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				results <- c.upload(jobCtx, svc, labelIDs, job)
+			}
+		}()
+	}
+
+	// The producer only sees this snapshot; the ledger itself is updated by
+	// the result loop below.
+	done := make(map[string]struct{}, len(ledger.Imported))
+	for key := range ledger.Imported {
+		done[key] = struct{}{}
+	}
+
+	total, skipped := 0, 0
+	var readErr error
+	go func() {
+		defer close(jobs)
+		seen := make(map[string]struct{})
+		readErr = readImportSource(path, info, func(src importSourceMessage) error {
+			total++
+			messageID := importMessageID(src.Raw)
+			key := messageID
+			if key == "" {
+				sum := sha256.Sum256(src.Raw)
+				key = "sha256:" + hex.EncodeToString(sum[:])
+			}
+			if _, ok := done[key]; ok {
+				skipped++
+				return nil
+			}
+			if _, dup := seen[key]; dup {
+				skipped++
+				return nil
+			}
+			seen[key] = struct{}{}
+			select {
+			case jobs <- gmailImportJob{src: src, key: key, messageID: messageID}:
+				return nil
+			case <-jobCtx.Done():
+				return jobCtx.Err()
+			}
+		})
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	imported, existing := 0, 0
+	failed := make(map[string]string)
+	var writeErr error
+	for res := range results {
+		if writeErr != nil {
+			continue
+		}
+		if res.err != nil {
+			failed[res.job.src.Source] = res.err.Error()
+			continue
+		}
+		ledger.Imported[res.job.key] = gmailImportLedgerEntry{ID: res.id, Source: res.job.src.Source, Existing: res.existing}
+		if res.existing {
+			existing++
+		} else {
+			imported++
+		}
+		if (imported+existing)%gmailImportLedgerEvery == 0 {
+			if err := saveLedger(); err != nil {
+				writeErr = err
+				cancel()
+				continue
+			}
+			u.Err().Printf("Imported %d (%d already in mailbox)", imported, existing)
+		}
+	}
+
+	if err := saveLedger(); err != nil && writeErr == nil {
+		writeErr = err
+	}
+	if writeErr != nil {
+		return writeErr
+	}
+	if readErr != nil {
+		return readErr
+	}
+
+	if outfmt.IsJSON(ctx) {
+		if err := outfmt.WriteJSON(os.Stdout, map[string]any{
+			"source":   path,
+			"total":    total,
+			"imported": imported,
+			"skipped":  skipped,
+			"existing": existing,
+			"failed":   len(failed),
+			"failures": failed,
+			"ledger":   ledgerPath,
+		}); err != nil {
+			return err
+		}
+	} else {
+		u.Out().Printf("source\t%s", path)
+		u.Out().Printf("total\t%d", total)
+		u.Out().Printf("imported\t%d", imported)
+		u.Out().Printf("skipped\t%d", skipped)
+		u.Out().Printf("existing\t%d", existing)
+		u.Out().Printf("failed\t%d", len(failed))
+	}
+
+	if len(failed) > 0 {
+		for _, src := range sortedKeys(failed) {
+			u.Err().Printf("failed %s: %s", src, failed[src])
+		}
+		return &ExitError{Code: 1, Err: fmt.Errorf("%d message(s) failed; re-run the same command to retry", len(failed))}
+	}
+	return nil
+}
+
+// upload sends one message through the media upload endpoint, which switches
+// to resumable uploads for large messages.
+func (c *GmailImportCmd) upload(ctx context.Context, svc *gmail.Service, labelIDs []string, job gmailImportJob) gmailImportResult {
+	if c.SkipExisting && job.messageID != "" {
+		resp, err := svc.Users.Messages.List("me").
+			Q("rfc822msgid:" + strings.Trim(job.messageID, "<>")).
+			IncludeSpamTrash(true).
+			MaxResults(1).
+			Fields("messages(id)").
+			Context(ctx).
+			Do()
+		if err != nil {
+			return gmailImportResult{job: job, err: err}
+		}
+		if len(resp.Messages) > 0 && resp.Messages[0] != nil {
+			return gmailImportResult{job: job, id: resp.Messages[0].Id, existing: true}
+		}
+	}
+
+	meta := &gmail.Message{LabelIds: labelIDs}
+	media := bytes.NewReader(job.src.Raw)
+	var (
+		msg *gmail.Message
+		err error
+	)
+	if c.Insert {
+		msg, err = svc.Users.Messages.Insert("me", meta).
+			InternalDateSource("dateHeader").
+			Media(media, gapi.ContentType("message/rfc822")).
+			Context(ctx).
+			Do()
+	} else {
+		msg, err = svc.Users.Messages.Import("me", meta).
+			InternalDateSource("dateHeader").
+			NeverMarkSpam(c.NeverMarkSpam).
+			ProcessForCalendar(c.ProcessForCalendar).
+			Media(media, gapi.ContentType("message/rfc822")).
+			Context(ctx).
+			Do()
+	}
+	if err != nil {
+		return gmailImportResult{job: job, err: err}
+	}
+	return gmailImportResult{job: job, id: msg.Id}
+}
+
+func resolveOrCreateLabelIDs(ctx context.Context, svc *gmail.Service, labels []string) ([]string, error) {
+	names := make([]string, 0, len(labels))
+	for _, l := range labels {
+		if trimmed := strings.TrimSpace(l); trimmed != "" {
+			names = append(names, trimmed)
+		}
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	idMap, err := fetchLabelNameToID(svc)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, 0, len(names))
+	for _, name := range names {
+		if id, ok := idMap[strings.ToLower(name)]; ok {
+			out = append(out, id)
+			continue
+		}
+		created, err := createLabel(ctx, svc, name)
+		if err != nil {
+			return nil, mapLabelCreateError(err, name)
+		}
+		idMap[strings.ToLower(name)] = created.Id
+		out = append(out, created.Id)
+	}
+	return out, nil
+}
+
+func loadGmailImportLedger(path string, account string) (*gmailImportLedger, error) {
+	fresh := &gmailImportLedger{Account: account, Imported: map[string]gmailImportLedgerEntry{}}
+
+	data, err := os.ReadFile(path) //nolint:gosec // user-provided ledger path
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fresh, nil
+		}
+		return nil, fmt.Errorf("read ledger: %w", err)
+	}
+
+	var ledger gmailImportLedger
+	if err := json.Unmarshal(data, &ledger); err != nil {
+		return nil, fmt.Errorf("parse ledger %s: %w", path, err)
+	}
+	if !strings.EqualFold(ledger.Account, account) {
+		return nil, usagef("%s records an import into %s; use --ledger to start a separate one", path, ledger.Account)
+	}
+	if ledger.Imported == nil {
+		ledger.Imported = map[string]gmailImportLedgerEntry{}
+	}
+	return &ledger, nil
+}
+
+func writeGmailImportLedger(path string, ledger *gmailImportLedger) error {
+	data, err := json.MarshalIndent(ledger, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode ledger: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("write ledger: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("commit ledger: %w", err)
+	}
+	return nil
+}
+
+func importMessageID(raw []byte) string {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(msg.Header.Get("Message-Id"))
+}
+
+// readImportSource streams messages from a single .eml file, an mbox file
+// (detected by a leading "From " line), a Maildir (cur/ and new/), or a
+// directory of .eml files.
+func readImportSource(path string, info os.FileInfo, fn func(importSourceMessage) error) error {
+	if info.IsDir() {
+		return readImportDir(path, fn)
+	}
+
+	f, err := os.Open(path) //nolint:gosec // user-provided path
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReaderSize(f, 64*1024)
+	head, err := r.Peek(5)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	if string(head) == "From " {
+		return readMbox(r, filepath.Base(path), fn)
+	}
+
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return fn(importSourceMessage{Source: filepath.Base(path), Raw: raw})
+}
+
+func readImportDir(dir string, fn func(importSourceMessage) error) error {
+	var files []string
+	maildir := false
+	for _, sub := range []string{"cur", "new"} {
+		entries, err := os.ReadDir(filepath.Join(dir, sub))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return err
+		}
+		maildir = true
+		for _, e := range entries {
+			if e.Type().IsRegular() && !strings.HasPrefix(e.Name(), ".") {
+				files = append(files, filepath.Join(sub, e.Name()))
+			}
+		}
+	}
+	if !maildir {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if e.Type().IsRegular() && strings.EqualFold(filepath.Ext(e.Name()), ".eml") {
+				files = append(files, e.Name())
+			}
+		}
+	}
+	if len(files) == 0 {
+		return usagef("no messages found in %s (expected a Maildir or .eml files)", dir)
+	}
+	sort.Strings(files)
+
+	for _, name := range files {
+		raw, err := os.ReadFile(filepath.Join(dir, name)) //nolint:gosec // user-provided dir
+		if err != nil {
+			return err
+		}
+		if err := fn(importSourceMessage{Source: name, Raw: raw}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readMbox splits an mbox on "From " separator lines that follow a blank
+// line (or start the file) and undoes mboxrd ">From " quoting.
+func readMbox(r *bufio.Reader, name string, fn func(importSourceMessage) error) error {
+	var cur bytes.Buffer
+	started := false
+	prevBlank := true
+	n := 0
+
+	flush := func() error {
+		if !started {
+			return nil
+		}
+		n++
+		data := bytes.Clone(cur.Bytes())
+		cur.Reset()
+		if bytes.HasSuffix(data, []byte("\n\n")) {
+			data = data[:len(data)-1]
+		} else if bytes.HasSuffix(data, []byte("\r\n\r\n")) {
+			data = data[:len(data)-2]
+		}
+		return fn(importSourceMessage{Source: fmt.Sprintf("%s#%d", name, n), Raw: data})
+	}
+
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 {
+			switch {
+			case prevBlank && bytes.HasPrefix(line, []byte("From ")):
+				if flushErr := flush(); flushErr != nil {
+					return flushErr
+				}
+				started = true
+				prevBlank = false
+				continue
+			case bytes.HasPrefix(line, []byte(">")) && isMboxFromLine(line):
+				line = line[1:]
+			}
+			if started {
+				cur.Write(line)
+			}
+			prevBlank = len(bytes.TrimRight(line, "\r\n")) == 0
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return flush()
+}
@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
+
+	"github.com/steipete/gogcli/internal/outfmt"
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+func TestGmailImport_MboxLedgerAndDedupe(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		uploads []string
+		created []string
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		path := r.URL.Path
+		switch {
+		case strings.HasSuffix(path, "/users/me/labels") && r.Method == http.MethodGet:
+			_ = json.NewEncoder(w).Encode(map[string]any{"labels": []map[string]any{{"id": "INBOX", "name": "INBOX"}}})
+		case strings.HasSuffix(path, "/users/me/labels") && r.Method == http.MethodPost:
+			var body map[string]any
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			created = append(created, body["name"].(string))
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": "Label_9", "name": body["name"]})
+		case strings.HasSuffix(path, "/users/me/messages") && r.Method == http.MethodGet:
+			if r.URL.Query().Get("q") == "rfc822msgid:b@x" {
+				_ = json.NewEncoder(w).Encode(map[string]any{"messages": []map[string]any{{"id": "existing-b"}}})
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{})
+		case strings.HasPrefix(path, "/upload/") && strings.HasSuffix(path, "/users/me/messages/import"):
+			if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/related") {
+				t.Errorf("expected media upload, got %q", r.Header.Get("Content-Type"))
+			}
+			if r.URL.Query().Get("internalDateSource") != "dateHeader" || r.URL.Query().Get("neverMarkSpam") != "true" {
+				t.Errorf("unexpected query: %s", r.URL.RawQuery)
+			}
+			body, _ := io.ReadAll(r.Body)
+			if !strings.Contains(string(body), "Label_9") {
+				t.Errorf("missing label in upload metadata: %s", body)
+			}
+			uploads = append(uploads, string(body))
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": "new-" + strconv.Itoa(len(uploads))})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	svc, err := gmail.NewService(context.Background(),
+		option.WithoutAuthentication(),
+		option.WithHTTPClient(srv.Client()),
+		option.WithEndpoint(srv.URL+"/"),
+	)
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	origNew := newGmailService
+	t.Cleanup(func() { newGmailService = origNew })
+	newGmailService = func(context.Context, string) (*gmail.Service, error) { return svc, nil }
+
+	mbox := strings.Join([]string{
+		"From a@gmail Wed Jan  1 00:00:00 2025",
+		"Message-ID: <a@x>",
+		"Subject: A",
+		"",
+		">From the archive",
+		"",
+		"From b@gmail Wed Jan  1 00:00:00 2025",
+		"Message-ID: <b@x>",
+		"Subject: B",
+		"",
+		"bee",
+		"",
+		"From a2@gmail Wed Jan  1 00:00:00 2025",
+		"Message-ID: <a@x>",
+		"Subject: A again",
+		"",
+		"dup",
+		"",
+		"From c@gmail Wed Jan  1 00:00:00 2025",
+		"Subject: no id",
+		"",
+		"sea",
+		"",
+	}, "\n")
+	path := filepath.Join(t.TempDir(), "archive.mbox")
+	if err := os.WriteFile(path, []byte(mbox), 0o600); err != nil {
+		t.Fatalf("write mbox: %v", err)
+	}
+
+	run := func() map[string]any {
+		u, uiErr := ui.New(ui.Options{Stdout: os.Stdout, Stderr: os.Stderr, Color: "never"})
+		if uiErr != nil {
+			t.Fatalf("ui.New: %v", uiErr)
+		}
+		ctx := outfmt.WithMode(ui.WithUI(context.Background(), u), outfmt.Mode{JSON: true})
+		out := captureStdout(t, func() {
+			if err := runKong(t, &GmailImportCmd{}, []string{path, "--label", "Migrated", "--never-mark-spam", "--concurrency", "2"}, ctx, &RootFlags{Account: "a@b.com"}); err != nil {
+				t.Fatalf("import: %v", err)
+			}
+		})
+		var parsed map[string]any
+		if err := json.Unmarshal([]byte(out), &parsed); err != nil {
+			t.Fatalf("json parse: %v\nout=%q", err, out)
+		}
+		return parsed
+	}
+
+	first := run()
+	if first["total"] != float64(4) || first["imported"] != float64(2) || first["existing"] != float64(1) || first["skipped"] != float64(1) {
+		t.Fatalf("unexpected first import: %#v", first)
+	}
+	if len(created) != 1 || created[0] != "Migrated" {
+		t.Fatalf("expected Migrated label to be created, got %v", created)
+	}
+	joined := strings.Join(uploads, "\n")
+	if !strings.Contains(joined, "From the archive") || strings.Contains(joined, ">From the archive") {
+		t.Fatalf("mboxrd quoting not undone: %s", joined)
+	}
+
+	ledgerData, err := os.ReadFile(path + gmailImportLedgerName)
+	if err != nil {
+		t.Fatalf("read ledger: %v", err)
+	}
+	var ledger gmailImportLedger
+	if err := json.Unmarshal(ledgerData, &ledger); err != nil {
+		t.Fatalf("parse ledger: %v", err)
+	}
+	if len(ledger.Imported) != 3 || ledger.Imported["<b@x>"].ID != "existing-b" || !ledger.Imported["<b@x>"].Existing {
+		t.Fatalf("unexpected ledger: %#v", ledger.Imported)
+	}
+
+	uploadsBefore := len(uploads)
+	second := run()
+	if second["skipped"] != float64(4) || second["imported"] != float64(0) || len(uploads) != uploadsBefore {
+		t.Fatalf("rerun should be a no-op: %#v uploads=%d", second, len(uploads))
+	}
+}
+
+func TestReadMbox_SplitsAndUnquotes(t *testing.T) {
+	src := "From x Mon Jan  1 00:00:00 2024\nSubject: one\n\nhello\nFrom inside without blank\n>>From quoted\n\nFrom y Mon Jan  1 00:00:00 2024\nSubject: two\n\nbody\n"
+
+	var got []importSourceMessage
+	err := readMbox(bufio.NewReader(strings.NewReader(src)), "box", func(m importSourceMessage) error {
+		got = append(got, m)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("readMbox: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(got))
+	}
+	if string(got[0].Raw) != "Subject: one\n\nhello\nFrom inside without blank\n>From quoted\n" || got[0].Source != "box#1" {
+		t.Fatalf("unexpected first message: %q (%s)", got[0].Raw, got[0].Source)
+	}
+	if string(got[1].Raw) != "Subject: two\n\nbody\n" {
+		t.Fatalf("unexpected second message: %q", got[1].Raw)
+	}
+}
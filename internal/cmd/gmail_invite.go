@@ -0,0 +1,554 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/gmail/v1"
+
+	"github.com/steipete/gogcli/internal/outfmt"
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+type GmailInviteCmd struct {
+	MessageID string `arg:"" name:"messageId" help:"Message ID of the invitation email"`
+	Accept    bool   `name:"accept" help:"Accept the invitation"`
+	Decline   bool   `name:"decline" help:"Decline the invitation"`
+	Tentative bool   `name:"tentative" help:"Respond tentatively"`
+	Comment   string `name:"comment" help:"Optional comment/note to include with response"`
+	Calendar  string `name:"calendar" help:"Calendar to look the event up in" default:"primary"`
+}
+
+type icsAttendee struct {
+	Email    string `json:"email"`
+	Name     string `json:"name,omitempty"`
+	PartStat string `json:"partstat,omitempty"`
+	Role     string `json:"role,omitempty"`
+	RSVP     bool   `json:"rsvp,omitempty"`
+}
+
+// calendarInvite is the first VEVENT of a text/calendar part, plus the
+// calendar-level METHOD (REQUEST, CANCEL, REPLY, ...).
+type calendarInvite struct {
+	Method       string        `json:"method,omitempty"`
+	UID          string        `json:"uid"`
+	Sequence     int           `json:"sequence,omitempty"`
+	Status       string        `json:"status,omitempty"`
+	Summary      string        `json:"summary,omitempty"`
+	Location     string        `json:"location,omitempty"`
+	Start        string        `json:"start,omitempty"`
+	End          string        `json:"end,omitempty"`
+	AllDay       bool          `json:"allDay,omitempty"`
+	RecurrenceID string        `json:"recurrenceId,omitempty"`
+	Organizer    *icsAttendee  `json:"organizer,omitempty"`
+	Attendees    []icsAttendee `json:"attendees,omitempty"`
+}
+
+func (c *GmailInviteCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+	messageID := strings.TrimSpace(c.MessageID)
+	if messageID == "" {
+		return usage("empty messageId")
+	}
+
+	status := ""
+	for _, opt := range []struct {
+		set    bool
+		status string
+	}{{c.Accept, "accepted"}, {c.Decline, "declined"}, {c.Tentative, "tentative"}} {
+		if !opt.set {
+			continue
+		}
+		if status != "" {
+			return usage("use only one of --accept, --decline, --tentative")
+		}
+		status = opt.status
+	}
+	if status == "" && strings.TrimSpace(c.Comment) != "" {
+		return usage("--comment requires --accept, --decline, or --tentative")
+	}
+	calendarID := strings.TrimSpace(c.Calendar)
+	if calendarID == "" {
+		return usage("empty --calendar")
+	}
+
+	svc, err := newGmailService(ctx, account)
+	if err != nil {
+		return err
+	}
+	msg, err := svc.Users.Messages.Get("me", messageID).Format("full").Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+	data, err := calendarPartData(ctx, svc, messageID, msg.Payload)
+	if err != nil {
+		return err
+	}
+	invite, err := parseICalendar(data)
+	if err != nil {
+		return err
+	}
+	if status != "" && strings.EqualFold(invite.Method, "CANCEL") {
+		return usage("the invitation was cancelled by the organizer; nothing to respond to")
+	}
+
+	calSvc, err := newCalendarService(ctx, account)
+	if err != nil {
+		return err
+	}
+	event, err := findInviteEvent(ctx, calSvc, calendarID, invite, status != "")
+	if err != nil {
+		if status != "" {
+			return err
+		}
+		u.Err().Printf("Warning: calendar lookup failed: %v", err)
+	}
+	if status != "" {
+		if event == nil {
+			return fmt.Errorf("no event with iCalUID %s in calendar %s (it may not have been added to the calendar yet)", invite.UID, calendarID)
+		}
+		event, err = respondToEvent(ctx, calSvc, calendarID, event.Id, status, c.Comment)
+		if err != nil {
+			return err
+		}
+	}
+
+	if outfmt.IsJSON(ctx) {
+		out := map[string]any{"messageId": messageID, "calendarId": calendarID, "invite": invite, "event": nil}
+		if event != nil {
+			tz, loc, _ := getCalendarLocation(ctx, calSvc, calendarID)
+			out["event"] = wrapEventWithDaysWithTimezone(event, tz, loc)
+		}
+		if status != "" {
+			out["responseStatus"] = status
+		}
+		return outfmt.WriteJSON(os.Stdout, out)
+	}
+
+	u.Out().Printf("method\t%s", orEmpty(invite.Method, "-"))
+	u.Out().Printf("uid\t%s", invite.UID)
+	u.Out().Printf("summary\t%s", orEmpty(invite.Summary, "(no title)"))
+	u.Out().Printf("start\t%s", invite.Start)
+	if invite.End != "" {
+		u.Out().Printf("end\t%s", invite.End)
+	}
+	if invite.Location != "" {
+		u.Out().Printf("location\t%s", invite.Location)
+	}
+	if invite.Organizer != nil {
+		u.Out().Printf("organizer\t%s", formatICSAttendee(*invite.Organizer))
+	}
+	for _, a := range invite.Attendees {
+		u.Out().Printf("attendee\t%s\t%s", formatICSAttendee(a), orEmpty(strings.ToLower(a.PartStat), "-"))
+	}
+	if event == nil {
+		u.Out().Printf("event_id\t-")
+		return nil
+	}
+	u.Out().Printf("event_id\t%s", event.Id)
+	if self := selfAttendee(event); self != nil {
+		u.Out().Printf("response_status\t%s", self.ResponseStatus)
+	}
+	if status != "" && strings.TrimSpace(c.Comment) != "" {
+		u.Out().Printf("comment\t%s", strings.TrimSpace(c.Comment))
+	}
+	if event.HtmlLink != "" {
+		u.Out().Printf("link\t%s", event.HtmlLink)
+	}
+	return nil
+}
+
+func selfAttendee(event *calendar.Event) *calendar.EventAttendee {
+	for _, a := range event.Attendees {
+		if a != nil && a.Self {
+			return a
+		}
+	}
+	return nil
+}
+
+func formatICSAttendee(a icsAttendee) string {
+	if a.Name == "" {
+		return a.Email
+	}
+	return fmt.Sprintf("%s <%s>", a.Name, a.Email)
+}
+
+// findCalendarPart returns the iCalendar part of a message: a text/calendar
+// part if there is one, else an .ics attachment.
+func findCalendarPart(p *gmail.MessagePart) *gmail.MessagePart {
+	if p == nil {
+		return nil
+	}
+	if mimeTypeMatches(p.MimeType, "text/calendar") {
+		return p
+	}
+	var fallback *gmail.MessagePart
+	if mimeTypeMatches(p.MimeType, "application/ics") || strings.HasSuffix(strings.ToLower(p.Filename), ".ics") {
+		fallback = p
+	}
+	for _, part := range p.Parts {
+		found := findCalendarPart(part)
+		if found != nil && mimeTypeMatches(found.MimeType, "text/calendar") {
+			return found
+		}
+		if found != nil && fallback == nil {
+			fallback = found
+		}
+	}
+	return fallback
+}
+
+func calendarPartData(ctx context.Context, svc *gmail.Service, messageID string, payload *gmail.MessagePart) (string, error) {
+	part := findCalendarPart(payload)
+	if part == nil || part.Body == nil {
+		return "", errors.New("message has no calendar invitation (text/calendar part)")
+	}
+	if part.Body.Data != "" {
+		return decodePartBody(part)
+	}
+	if part.Body.AttachmentId == "" {
+		return "", errors.New("calendar part is empty")
+	}
+	att, err := svc.Users.Messages.Attachments.Get("me", messageID, part.Body.AttachmentId).Context(ctx).Do()
+	if err != nil {
+		return "", err
+	}
+	data, err := decodeBase64URLBytes(att.Data)
+	if err != nil {
+		return "", fmt.Errorf("decode calendar attachment: %w", err)
+	}
+	return string(data), nil
+}
+
+// parseICalendar extracts the invitation from an RFC 5545 calendar object.
+// Only the first VEVENT is read; alarms and time zone definitions are skipped.
+func parseICalendar(data string) (*calendarInvite, error) {
+	inv := &calendarInvite{}
+	var depth []string
+	seenEvent := false
+	for _, line := range unfoldICSLines(data) {
+		name, params, value := parseICSLine(line)
+		switch name {
+		case "":
+			continue
+		case "BEGIN":
+			depth = append(depth, strings.ToUpper(value))
+			continue
+		case "END":
+			if len(depth) > 0 {
+				if depth[len(depth)-1] == "VEVENT" {
+					seenEvent = true
+				}
+				depth = depth[:len(depth)-1]
+			}
+			continue
+		}
+		if len(depth) == 1 && depth[0] == "VCALENDAR" && name == "METHOD" {
+			inv.Method = strings.ToUpper(value)
+			continue
+		}
+		if seenEvent || len(depth) != 2 || depth[1] != "VEVENT" {
+			continue
+		}
+		switch name {
+		case "UID":
+			inv.UID = value
+		case "SEQUENCE":
+			inv.Sequence, _ = strconv.Atoi(value)
+		case "STATUS":
+			inv.Status = strings.ToUpper(value)
+		case "SUMMARY":
+			inv.Summary = unescapeICSText(value)
+		case "LOCATION":
+			inv.Location = unescapeICSText(value)
+		case "DTSTART":
+			inv.Start, inv.AllDay = icsTime(value, params)
+		case "DTEND":
+			inv.End, _ = icsTime(value, params)
+		case "RECURRENCE-ID":
+			inv.RecurrenceID, _ = icsTime(value, params)
+		case "ORGANIZER":
+			org := icsPerson(value, params)
+			inv.Organizer = &org
+		case "ATTENDEE":
+			inv.Attendees = append(inv.Attendees, icsPerson(value, params))
+		}
+	}
+	if strings.TrimSpace(inv.UID) == "" {
+		return nil, errors.New("calendar part has no VEVENT with a UID")
+	}
+	return inv, nil
+}
+
+func unfoldICSLines(data string) []string {
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	var lines []string
+	for _, l := range strings.Split(data, "\n") {
+		if (strings.HasPrefix(l, " ") || strings.HasPrefix(l, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += l[1:]
+			continue
+		}
+		lines = append(lines, strings.TrimRight(l, "\r"))
+	}
+	return lines
+}
+
+// parseICSLine splits "NAME;PARAM=a;PARAM2="b:c":value", honoring quoted
+// parameter values.
+func parseICSLine(line string) (string, map[string]string, string) {
+	inQuote := false
+	colon := -1
+	for i, r := range line {
+		if r == '"' {
+			inQuote = !inQuote
+		} else if r == ':' && !inQuote {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return "", nil, ""
+	}
+	head, value := line[:colon], line[colon+1:]
+
+	var fields []string
+	start := 0
+	inQuote = false
+	for i, r := range head {
+		if r == '"' {
+			inQuote = !inQuote
+		} else if r == ';' && !inQuote {
+			fields = append(fields, head[start:i])
+			start = i + 1
+		}
+	}
+	fields = append(fields, head[start:])
+
+	params := map[string]string{}
+	for _, f := range fields[1:] {
+		k, v, _ := strings.Cut(f, "=")
+		params[strings.ToUpper(strings.TrimSpace(k))] = strings.Trim(v, `"`)
+	}
+	return strings.ToUpper(strings.TrimSpace(fields[0])), params, value
+}
+
+func unescapeICSText(s string) string {
+	r := strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`)
+	return r.Replace(s)
+}
+
+func icsPerson(value string, params map[string]string) icsAttendee {
+	email := value
+	if len(email) >= 7 && strings.EqualFold(email[:7], "mailto:") {
+		email = email[7:]
+	}
+	return icsAttendee{
+		Email:    strings.TrimSpace(email),
+		Name:     unescapeICSText(params["CN"]),
+		PartStat: strings.ToUpper(params["PARTSTAT"]),
+		Role:     strings.ToUpper(params["ROLE"]),
+		RSVP:     strings.EqualFold(params["RSVP"], "TRUE"),
+	}
+}
+
+// windowsZones maps the Windows zone names Outlook/Exchange put in TZID to
+// IANA names.
+var windowsZones = map[string]string{
+	"Dateline Standard Time":          "Etc/GMT+12",
+	"Hawaiian Standard Time":          "Pacific/Honolulu",
+	"Alaskan Standard Time":           "America/Anchorage",
+	"Pacific Standard Time":           "America/Los_Angeles",
+	"US Mountain Standard Time":       "America/Phoenix",
+	"Mountain Standard Time":          "America/Denver",
+	"Central Standard Time":           "America/Chicago",
+	"Central America Standard Time":   "America/Guatemala",
+	"Canada Central Standard Time":    "America/Regina",
+	"Central Standard Time (Mexico)":  "America/Mexico_City",
+	"Eastern Standard Time":           "America/New_York",
+	"US Eastern Standard Time":        "America/Indianapolis",
+	"SA Pacific Standard Time":        "America/Bogota",
+	"Atlantic Standard Time":          "America/Halifax",
+	"Newfoundland Standard Time":      "America/St_Johns",
+	"E. South America Standard Time":  "America/Sao_Paulo",
+	"Argentina Standard Time":         "America/Buenos_Aires",
+	"UTC":                             "UTC",
+	"GMT Standard Time":               "Europe/London",
+	"Greenwich Standard Time":         "Atlantic/Reykjavik",
+	"W. Europe Standard Time":         "Europe/Berlin",
+	"Central Europe Standard Time":    "Europe/Budapest",
+	"Romance Standard Time":           "Europe/Paris",
+	"Central European Standard Time":  "Europe/Warsaw",
+	"W. Central Africa Standard Time": "Africa/Lagos",
+	"GTB Standard Time":               "Europe/Bucharest",
+	"FLE Standard Time":               "Europe/Kiev",
+	"E. Europe Standard Time":         "Europe/Chisinau",
+	"Israel Standard Time":            "Asia/Jerusalem",
+	"South Africa Standard Time":      "Africa/Johannesburg",
+	"Turkey Standard Time":            "Europe/Istanbul",
+	"Russian Standard Time":           "Europe/Moscow",
+	"Arab Standard Time":              "Asia/Riyadh",
+	"Arabian Standard Time":           "Asia/Dubai",
+	"Pakistan Standard Time":          "Asia/Karachi",
+	"India Standard Time":             "Asia/Calcutta",
+	"Bangladesh Standard Time":        "Asia/Dhaka",
+	"SE Asia Standard Time":           "Asia/Bangkok",
+	"China Standard Time":             "Asia/Shanghai",
+	"Singapore Standard Time":         "Asia/Singapore",
+	"Taipei Standard Time":            "Asia/Taipei",
+	"Tokyo Standard Time":             "Asia/Tokyo",
+	"Korea Standard Time":             "Asia/Seoul",
+	"W. Australia Standard Time":      "Australia/Perth",
+	"Cen. Australia Standard Time":    "Australia/Adelaide",
+	"AUS Eastern Standard Time":       "Australia/Sydney",
+	"E. Australia Standard Time":      "Australia/Brisbane",
+	"New Zealand Standard Time":       "Pacific/Auckland",
+}
+
+// loadICSLocation resolves a TZID given as an IANA or Windows zone name.
+func loadICSLocation(tzid string) (*time.Location, bool) {
+	if l, err := time.LoadLocation(tzid); err == nil {
+		return l, true
+	}
+	if name, ok := windowsZones[tzid]; ok {
+		if l, err := time.LoadLocation(name); err == nil {
+			return l, true
+		}
+	}
+	return nil, false
+}
+
+// icsTime converts a DATE or DATE-TIME value to RFC 3339 (or YYYY-MM-DD for
+// all-day values). Floating times and TZIDs that resolve to no known zone keep
+// their wall-clock time without an offset.
+func icsTime(value string, params map[string]string) (string, bool) {
+	value = strings.TrimSpace(value)
+	if strings.EqualFold(params["VALUE"], "DATE") || len(value) == 8 {
+		if t, err := time.Parse("20060102", value); err == nil {
+			return t.Format("2006-01-02"), true
+		}
+		return value, true
+	}
+	if strings.HasSuffix(value, "Z") {
+		if t, err := time.Parse("20060102T150405Z", value); err == nil {
+			return t.Format(time.RFC3339), false
+		}
+		return value, false
+	}
+	loc := time.UTC
+	floating := true
+	if tzid := strings.TrimSpace(params["TZID"]); tzid != "" {
+		if l, ok := loadICSLocation(tzid); ok {
+			loc, floating = l, false
+		}
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, loc)
+	if err != nil {
+		return value, false
+	}
+	if floating {
+		return t.Format("2006-01-02T15:04:05"), false
+	}
+	return t.Format(time.RFC3339), false
+}
+
+// findInviteEvent maps an invitation to the calendar's copy of the event via
+// its iCalUID. For a single occurrence of a recurring series (RECURRENCE-ID)
+// it returns that instance rather than the series, or an error when the
+// occurrence can't be found; answering for the whole series would be wrong.
+// It returns nil when no matching event exists; it never falls back to some
+// other occurrence. skipCancelled ignores cancelled copies, which must not be
+// answered.
+func findInviteEvent(ctx context.Context, svc *calendar.Service, calendarID string, inv *calendarInvite, skipCancelled bool) (*calendar.Event, error) {
+	resp, err := svc.Events.List(calendarID).ICalUID(inv.UID).ShowDeleted(true).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	var master *calendar.Event
+	for _, ev := range resp.Items {
+		if ev == nil || (skipCancelled && ev.Status == "cancelled") {
+			continue
+		}
+		if inv.RecurrenceID != "" && ev.OriginalStartTime != nil && sameEventTime(ev.OriginalStartTime, inv.RecurrenceID) {
+			return ev, nil
+		}
+		if ev.RecurringEventId == "" && master == nil {
+			master = ev
+		}
+	}
+	if master == nil || inv.RecurrenceID == "" || len(master.Recurrence) == 0 {
+		return master, nil
+	}
+	call := svc.Events.Instances(calendarID, master.Id).Context(ctx)
+	want := inv.RecurrenceID
+	if day, err := time.Parse("2006-01-02", want); err == nil {
+		// All-day occurrences are matched by date within a window around it.
+		call = call.TimeMin(day.AddDate(0, 0, -1).Format(time.RFC3339)).TimeMax(day.AddDate(0, 0, 2).Format(time.RFC3339))
+	} else {
+		want, err = recurrenceStart(ctx, svc, calendarID, master, want)
+		if err != nil {
+			return nil, err
+		}
+		call = call.OriginalStart(want)
+	}
+	instances, err := call.Do()
+	if err != nil {
+		return nil, err
+	}
+	for _, ev := range instances.Items {
+		if ev == nil || (skipCancelled && ev.Status == "cancelled") {
+			continue
+		}
+		if ev.OriginalStartTime != nil && sameEventTime(ev.OriginalStartTime, want) {
+			return ev, nil
+		}
+	}
+	return nil, fmt.Errorf("occurrence %s of event %s not found in calendar %s", inv.RecurrenceID, master.Id, calendarID)
+}
+
+// recurrenceStart turns a RECURRENCE-ID into RFC 3339. A floating value (no
+// offset, e.g. from an unresolved TZID) is read as wall-clock time in the
+// series' own zone, falling back to the calendar's.
+func recurrenceStart(ctx context.Context, svc *calendar.Service, calendarID string, master *calendar.Event, value string) (string, error) {
+	if _, err := time.Parse(time.RFC3339, value); err == nil {
+		return value, nil
+	}
+	var loc *time.Location
+	if master.Start != nil && master.Start.TimeZone != "" {
+		loc, _ = loadICSLocation(master.Start.TimeZone)
+	}
+	if loc == nil {
+		_, calLoc, err := getCalendarLocation(ctx, svc, calendarID)
+		if err != nil {
+			return "", fmt.Errorf("resolve occurrence %s: %w", value, err)
+		}
+		loc = calLoc
+	}
+	t, err := time.ParseInLocation("2006-01-02T15:04:05", value, loc)
+	if err != nil {
+		return "", fmt.Errorf("unrecognized RECURRENCE-ID %q", value)
+	}
+	return t.Format(time.RFC3339), nil
+}
+
+func sameEventTime(dt *calendar.EventDateTime, want string) bool {
+	if dt.Date != "" {
+		return dt.Date == want
+	}
+	got, err1 := time.Parse(time.RFC3339, dt.DateTime)
+	exp, err2 := time.Parse(time.RFC3339, want)
+	if err1 != nil || err2 != nil {
+		return dt.DateTime == want
+	}
+	return got.Equal(exp)
+}
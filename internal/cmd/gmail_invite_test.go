@@ -0,0 +1,247 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
+)
+
+const testInviteICS = "BEGIN:VCALENDAR\r\n" +
+	"PRODID:-//Google Inc//Google Calendar 70.9054//EN\r\n" +
+	"METHOD:REQUEST\r\n" +
+	"BEGIN:VTIMEZONE\r\nTZID:Europe/Berlin\r\nBEGIN:STANDARD\r\nDTSTART:19701025T030000\r\nEND:STANDARD\r\nEND:VTIMEZONE\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;TZID=Europe/Berlin:20260310T100000\r\n" +
+	"DTEND;TZID=Europe/Berlin:20260310T110000\r\n" +
+	"UID:abc123@google.com\r\n" +
+	"ORGANIZER;CN=Ada Lovelace:mailto:ada@example.com\r\n" +
+	"ATTENDEE;CUTYPE=INDIVIDUAL;ROLE=REQ-PARTICIPANT;PARTSTAT=NEEDS-ACTION;RSVP=\r\n" +
+	" TRUE;CN=\"Bob, Jr.\":mailto:a@b.com\r\n" +
+	"SUMMARY:Design review\\, round 2\r\n" +
+	"LOCATION:Room 1\r\n" +
+	"SEQUENCE:1\r\n" +
+	"BEGIN:VALARM\r\nACTION:DISPLAY\r\nSUMMARY:alarm\r\nEND:VALARM\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParseICalendar(t *testing.T) {
+	inv, err := parseICalendar(testInviteICS)
+	if err != nil {
+		t.Fatalf("parseICalendar: %v", err)
+	}
+	if inv.Method != "REQUEST" || inv.UID != "abc123@google.com" || inv.Summary != "Design review, round 2" || inv.Sequence != 1 {
+		t.Fatalf("unexpected invite: %#v", inv)
+	}
+	if inv.Start != "2026-03-10T10:00:00+01:00" || inv.End != "2026-03-10T11:00:00+01:00" || inv.AllDay {
+		t.Fatalf("unexpected times: %q %q", inv.Start, inv.End)
+	}
+	if inv.Organizer == nil || *inv.Organizer != (icsAttendee{Email: "ada@example.com", Name: "Ada Lovelace"}) {
+		t.Fatalf("unexpected organizer: %#v", inv.Organizer)
+	}
+	want := icsAttendee{Email: "a@b.com", Name: "Bob, Jr.", PartStat: "NEEDS-ACTION", Role: "REQ-PARTICIPANT", RSVP: true}
+	if len(inv.Attendees) != 1 || inv.Attendees[0] != want {
+		t.Fatalf("unexpected attendees: %#v", inv.Attendees)
+	}
+
+	if _, err := parseICalendar("BEGIN:VCALENDAR\nEND:VCALENDAR\n"); err == nil {
+		t.Fatalf("expected error without VEVENT")
+	}
+}
+
+func TestICSTime(t *testing.T) {
+	cases := []struct {
+		value  string
+		params map[string]string
+		want   string
+		allDay bool
+	}{
+		{"20260310", map[string]string{"VALUE": "DATE"}, "2026-03-10", true},
+		{"20260310T090000Z", nil, "2026-03-10T09:00:00Z", false},
+		{"20260310T090000", map[string]string{"TZID": "W. Europe Standard Time"}, "2026-03-10T09:00:00+01:00", false},
+		{"20260310T090000", map[string]string{"TZID": "Custom Zone 1"}, "2026-03-10T09:00:00", false},
+	}
+	for _, tc := range cases {
+		got, allDay := icsTime(tc.value, tc.params)
+		if got != tc.want || allDay != tc.allDay {
+			t.Fatalf("icsTime(%q) = %q, %v; want %q, %v", tc.value, got, allDay, tc.want, tc.allDay)
+		}
+	}
+}
+
+func TestGmailInvite_Accept(t *testing.T) {
+	gmailSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/users/me/messages/m1"):
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"id": "m1",
+				"payload": map[string]any{
+					"mimeType": "multipart/mixed",
+					"parts": []map[string]any{
+						{"mimeType": "text/plain", "body": map[string]any{"data": b64url("You have been invited")}},
+						{"mimeType": "application/ics", "filename": "invite.ics", "body": map[string]any{"attachmentId": "ics1"}},
+					},
+				},
+			})
+		case strings.HasSuffix(r.URL.Path, "/attachments/ics1"):
+			_ = json.NewEncoder(w).Encode(map[string]any{"data": b64url(testInviteICS)})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(gmailSrv.Close)
+
+	var patched map[string]any
+	calSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		path := strings.TrimPrefix(r.URL.Path, "/calendar/v3")
+		event := map[string]any{
+			"id":        "evt1",
+			"iCalUID":   "abc123@google.com",
+			"summary":   "Design review, round 2",
+			"htmlLink":  "https://calendar.example/evt1",
+			"attendees": []map[string]any{{"email": "ada@example.com", "organizer": true}, {"email": "a@b.com", "self": true, "responseStatus": "needsAction"}},
+		}
+		switch {
+		case path == "/calendars/primary/events" && r.Method == http.MethodGet:
+			if r.URL.Query().Get("iCalUID") != "abc123@google.com" {
+				t.Errorf("unexpected iCalUID query: %s", r.URL.RawQuery)
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"items": []any{event}})
+		case path == "/calendars/primary/events/evt1" && r.Method == http.MethodGet:
+			_ = json.NewEncoder(w).Encode(event)
+		case path == "/calendars/primary/events/evt1" && r.Method == http.MethodPatch:
+			body, _ := io.ReadAll(r.Body)
+			_ = json.Unmarshal(body, &patched)
+			_, _ = w.Write(body)
+		case path == "/calendars/primary":
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": "primary", "timeZone": "UTC"})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(calSrv.Close)
+
+	gsvc, err := gmail.NewService(context.Background(), option.WithoutAuthentication(), option.WithHTTPClient(gmailSrv.Client()), option.WithEndpoint(gmailSrv.URL+"/"))
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	csvc, err := calendar.NewService(context.Background(), option.WithoutAuthentication(), option.WithHTTPClient(calSrv.Client()), option.WithEndpoint(calSrv.URL+"/"))
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	origGmail, origCal := newGmailService, newCalendarService
+	t.Cleanup(func() { newGmailService, newCalendarService = origGmail, origCal })
+	newGmailService = func(context.Context, string) (*gmail.Service, error) { return gsvc, nil }
+	newCalendarService = func(context.Context, string) (*calendar.Service, error) { return csvc, nil }
+
+	out := runSendLike(t, &GmailInviteCmd{}, "m1", "--tentative", "--comment", "might be late")
+	if out["responseStatus"] != "tentative" {
+		t.Fatalf("unexpected output: %#v", out)
+	}
+	invite, _ := out["invite"].(map[string]any)
+	if invite["uid"] != "abc123@google.com" || invite["method"] != "REQUEST" {
+		t.Fatalf("unexpected invite: %#v", invite)
+	}
+	attendees, _ := patched["attendees"].([]any)
+	if len(attendees) != 2 {
+		t.Fatalf("unexpected patch: %#v", patched)
+	}
+	self, _ := attendees[1].(map[string]any)
+	if self["responseStatus"] != "tentative" || self["comment"] != "might be late" {
+		t.Fatalf("unexpected self attendee: %#v", self)
+	}
+
+	if err := runKong(t, &GmailInviteCmd{}, []string{"m1", "--accept", "--decline"}, context.Background(), &RootFlags{Account: "a@b.com"}); err == nil {
+		t.Fatalf("expected error for conflicting responses")
+	}
+}
+
+func TestFindInviteEvent_Occurrence(t *testing.T) {
+	var originalStarts []string
+	calSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		path := strings.TrimPrefix(r.URL.Path, "/calendar/v3")
+		switch path {
+		case "/calendars/primary/events":
+			_ = json.NewEncoder(w).Encode(map[string]any{"items": []any{map[string]any{
+				"id":         "series",
+				"recurrence": []string{"RRULE:FREQ=WEEKLY"},
+				"start":      map[string]any{"dateTime": "2026-03-10T10:00:00+01:00", "timeZone": "Europe/Berlin"},
+			}}})
+		case "/calendars/primary/events/series/instances":
+			start := r.URL.Query().Get("originalStart")
+			originalStarts = append(originalStarts, start)
+			items := []any{}
+			if start == "2026-03-17T10:00:00+01:00" {
+				items = append(items, map[string]any{"id": "series_20260317T090000Z", "originalStartTime": map[string]any{"dateTime": "2026-03-17T09:00:00Z"}})
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"items": items})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(calSrv.Close)
+	svc, err := calendar.NewService(context.Background(), option.WithoutAuthentication(), option.WithHTTPClient(calSrv.Client()), option.WithEndpoint(calSrv.URL+"/"))
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+
+	// An unresolvable TZID leaves the RECURRENCE-ID floating; it is read in the
+	// series' zone.
+	recurrenceID, _ := icsTime("20260317T100000", map[string]string{"TZID": "Custom Zone 1"})
+	ev, err := findInviteEvent(context.Background(), svc, "primary", &calendarInvite{UID: "u1", RecurrenceID: recurrenceID}, true)
+	if err != nil || ev.Id != "series_20260317T090000Z" {
+		t.Fatalf("expected the occurrence, got %#v, %v", ev, err)
+	}
+
+	// An occurrence that can't be found must not fall back to the series.
+	ev, err = findInviteEvent(context.Background(), svc, "primary", &calendarInvite{UID: "u1", RecurrenceID: "2026-03-24T10:00:00+01:00"}, true)
+	if err == nil || ev != nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected missing occurrence error, got %#v, %v", ev, err)
+	}
+	if len(originalStarts) != 2 || originalStarts[0] != "2026-03-17T10:00:00+01:00" {
+		t.Fatalf("unexpected instance lookups: %v", originalStarts)
+	}
+}
+
+func TestFindInviteEvent_SkipsCancelledAndStrayInstances(t *testing.T) {
+	var items []any
+	calSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.TrimPrefix(r.URL.Path, "/calendar/v3") != "/calendars/primary/events" {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"items": items})
+	}))
+	t.Cleanup(calSrv.Close)
+	svc, err := calendar.NewService(context.Background(), option.WithoutAuthentication(), option.WithHTTPClient(calSrv.Client()), option.WithEndpoint(calSrv.URL+"/"))
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	inv := &calendarInvite{UID: "u1"}
+
+	// Only a modified occurrence comes back: it is not the invited event.
+	items = []any{map[string]any{"id": "series_1", "recurringEventId": "series", "originalStartTime": map[string]any{"dateTime": "2026-03-17T09:00:00Z"}}}
+	if ev, err := findInviteEvent(context.Background(), svc, "primary", inv, true); err != nil || ev != nil {
+		t.Fatalf("expected no event, got %#v, %v", ev, err)
+	}
+
+	// A cancelled copy can be shown but not answered.
+	items = []any{map[string]any{"id": "evt1", "status": "cancelled"}}
+	if ev, err := findInviteEvent(context.Background(), svc, "primary", inv, true); err != nil || ev != nil {
+		t.Fatalf("cancelled event must not be answered, got %#v, %v", ev, err)
+	}
+	if ev, err := findInviteEvent(context.Background(), svc, "primary", inv, false); err != nil || ev == nil || ev.Id != "evt1" {
+		t.Fatalf("expected cancelled event for display, got %#v, %v", ev, err)
+	}
+}
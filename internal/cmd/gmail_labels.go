@@ -13,10 +13,15 @@ import (
 )
 
 type GmailLabelsCmd struct {
-	List   GmailLabelsListCmd   `cmd:"" name:"list" help:"List labels"`
-	Get    GmailLabelsGetCmd    `cmd:"" name:"get" help:"Get label details (including counts)"`
-	Create GmailLabelsCreateCmd `cmd:"" name:"create" help:"Create a new label"`
-	Modify GmailLabelsModifyCmd `cmd:"" name:"modify" help:"Modify labels on threads"`
+	List       GmailLabelsListCmd       `cmd:"" name:"list" help:"List labels"`
+	Get        GmailLabelsGetCmd        `cmd:"" name:"get" help:"Get label details (including counts)"`
+	Create     GmailLabelsCreateCmd     `cmd:"" name:"create" help:"Create a new label"`
+	Modify     GmailLabelsModifyCmd     `cmd:"" name:"modify" help:"Modify labels on threads"`
+	Rename     GmailLabelsRenameCmd     `cmd:"" name:"rename" help:"Rename a label and its nested sub-labels"`
+	Delete     GmailLabelsDeleteCmd     `cmd:"" name:"delete" help:"Delete a label"`
+	Color      GmailLabelsColorCmd      `cmd:"" name:"color" help:"Set a label's background and text color"`
+	Visibility GmailLabelsVisibilityCmd `cmd:"" name:"visibility" help:"Show or hide a label in the label and message lists"`
+	Tree       GmailLabelsTreeCmd       `cmd:"" name:"tree" help:"Show nested labels with message and unread counts"`
 }
 
 type GmailLabelsGetCmd struct {
@@ -0,0 +1,395 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"google.golang.org/api/gmail/v1"
+
+	"github.com/steipete/gogcli/internal/outfmt"
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+// Gmail nests labels by name: "Work/Boss" is a child of "Work".
+const labelPathSep = "/"
+
+var labelColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+var labelListVisibilities = map[string]string{
+	"show":   "labelShow",
+	"hide":   "labelHide",
+	"unread": "labelShowIfUnread",
+}
+
+var messageListVisibilities = map[string]string{
+	"show": "show",
+	"hide": "hide",
+}
+
+// findLabel matches a label by ID or (case-insensitive) name.
+func findLabel(labels []*gmail.Label, raw string) *gmail.Label {
+	raw = strings.TrimSpace(raw)
+	for _, l := range labels {
+		if l.Id == raw {
+			return l
+		}
+	}
+	for _, l := range labels {
+		if strings.EqualFold(l.Name, raw) || strings.EqualFold(l.Id, raw) {
+			return l
+		}
+	}
+	return nil
+}
+
+// fetchUserLabel lists labels and resolves raw to a user label, since system
+// labels cannot be renamed, deleted, or recolored.
+func fetchUserLabel(ctx context.Context, svc *gmail.Service, raw string) (*gmail.Label, []*gmail.Label, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil, usage("empty label")
+	}
+	resp, err := svc.Users.Labels.List("me").Context(ctx).Do()
+	if err != nil {
+		return nil, nil, err
+	}
+	l := findLabel(resp.Labels, raw)
+	if l == nil {
+		return nil, nil, usagef("unknown label %q", raw)
+	}
+	if l.Type == "system" {
+		return nil, nil, usagef("%s is a system label and cannot be changed", l.Name)
+	}
+	return l, resp.Labels, nil
+}
+
+// subLabels returns the labels nested under parent, deepest first.
+func subLabels(labels []*gmail.Label, parent string) []*gmail.Label {
+	prefix := strings.ToLower(parent + labelPathSep)
+	var out []*gmail.Label
+	for _, l := range labels {
+		if strings.HasPrefix(strings.ToLower(l.Name), prefix) {
+			out = append(out, l)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return strings.Count(out[i].Name, labelPathSep) > strings.Count(out[j].Name, labelPathSep)
+	})
+	return out
+}
+
+type GmailLabelsRenameCmd struct {
+	Label   string `arg:"" name:"labelIdOrName" help:"Label ID or name"`
+	NewName string `arg:"" name:"newName" help:"New label name (use / to nest)"`
+}
+
+func (c *GmailLabelsRenameCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+	newName := strings.Trim(strings.TrimSpace(c.NewName), labelPathSep)
+	if newName == "" {
+		return usage("new label name is required")
+	}
+
+	svc, err := newGmailService(ctx, account)
+	if err != nil {
+		return err
+	}
+	label, labels, err := fetchUserLabel(ctx, svc, c.Label)
+	if err != nil {
+		return err
+	}
+	oldName := label.Name
+
+	type rename struct {
+		ID   string `json:"id"`
+		From string `json:"from"`
+		To   string `json:"to"`
+	}
+	// Parent first, then children, so every new path has its parent.
+	renames := []rename{{ID: label.Id, From: oldName, To: newName}}
+	children := subLabels(labels, oldName)
+	for i := len(children) - 1; i >= 0; i-- {
+		child := children[i]
+		renames = append(renames, rename{ID: child.Id, From: child.Name, To: newName + child.Name[len(oldName):]})
+	}
+
+	moving := make(map[string]bool, len(renames))
+	for _, r := range renames {
+		moving[r.ID] = true
+	}
+	for _, r := range renames {
+		if existing := findLabel(labels, r.To); existing != nil && !moving[existing.Id] {
+			return usagef("label already exists: %s", existing.Name)
+		}
+	}
+
+	for i, r := range renames {
+		if _, err := svc.Users.Labels.Patch("me", r.ID, &gmail.Label{Name: r.To}).Context(ctx).Do(); err != nil {
+			return fmt.Errorf("rename %q to %q (%d of %d renamed): %w", r.From, r.To, i, len(renames), mapLabelCreateError(err, r.To))
+		}
+	}
+
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(os.Stdout, map[string]any{"renamed": renames})
+	}
+	for _, r := range renames {
+		u.Out().Printf("%s\t%s\t%s", r.ID, r.From, r.To)
+	}
+	return nil
+}
+
+type GmailLabelsDeleteCmd struct {
+	Label     string `arg:"" name:"labelIdOrName" help:"Label ID or name"`
+	Recursive bool   `name:"recursive" short:"r" help:"Also delete nested sub-labels"`
+}
+
+func (c *GmailLabelsDeleteCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+
+	svc, err := newGmailService(ctx, account)
+	if err != nil {
+		return err
+	}
+	label, labels, err := fetchUserLabel(ctx, svc, c.Label)
+	if err != nil {
+		return err
+	}
+
+	targets := subLabels(labels, label.Name)
+	if len(targets) > 0 && !c.Recursive {
+		return usagef("%s has %d sub-labels; pass --recursive to delete them too", label.Name, len(targets))
+	}
+	targets = append(targets, label)
+
+	action := fmt.Sprintf("delete gmail label %s", label.Name)
+	if len(targets) > 1 {
+		action = fmt.Sprintf("delete gmail label %s and %d sub-labels", label.Name, len(targets)-1)
+	}
+	if confirmErr := confirmDestructive(ctx, flags, action); confirmErr != nil {
+		return confirmErr
+	}
+
+	deleted := make([]map[string]string, 0, len(targets))
+	for i, l := range targets {
+		if err := svc.Users.Labels.Delete("me", l.Id).Context(ctx).Do(); err != nil && !isNotFoundAPIError(err) {
+			return fmt.Errorf("delete %q (%d of %d deleted): %w", l.Name, i, len(targets), err)
+		}
+		deleted = append(deleted, map[string]string{"id": l.Id, "name": l.Name})
+	}
+
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(os.Stdout, map[string]any{"deleted": deleted})
+	}
+	for _, d := range deleted {
+		u.Out().Printf("%s\t%s\tdeleted", d["id"], d["name"])
+	}
+	return nil
+}
+
+type GmailLabelsColorCmd struct {
+	Label string `arg:"" name:"labelIdOrName" help:"Label ID or name"`
+	Bg    string `name:"bg" help:"Background color (#rrggbb from Gmail's label palette)" required:""`
+	Text  string `name:"text" help:"Text color (#rrggbb from Gmail's label palette)" required:""`
+}
+
+func (c *GmailLabelsColorCmd) Run(ctx context.Context, flags *RootFlags) error {
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+	bg, text := strings.ToLower(strings.TrimSpace(c.Bg)), strings.ToLower(strings.TrimSpace(c.Text))
+	if !labelColorPattern.MatchString(bg) || !labelColorPattern.MatchString(text) {
+		return usage("--bg and --text must be hex colors like #4a86e8")
+	}
+
+	svc, err := newGmailService(ctx, account)
+	if err != nil {
+		return err
+	}
+	label, _, err := fetchUserLabel(ctx, svc, c.Label)
+	if err != nil {
+		return err
+	}
+
+	updated, err := svc.Users.Labels.Patch("me", label.Id, &gmail.Label{
+		Color: &gmail.LabelColor{BackgroundColor: bg, TextColor: text},
+	}).Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+	return writeLabelUpdate(ctx, updated)
+}
+
+type GmailLabelsVisibilityCmd struct {
+	Label    string `arg:"" name:"labelIdOrName" help:"Label ID or name"`
+	List     string `name:"list" help:"Visibility in the label list" enum:",show,hide,unread" default:""`
+	Messages string `name:"messages" help:"Visibility in the message list" enum:",show,hide" default:""`
+}
+
+func (c *GmailLabelsVisibilityCmd) Run(ctx context.Context, flags *RootFlags) error {
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+	if c.List == "" && c.Messages == "" {
+		return usage("must specify --list and/or --messages")
+	}
+
+	svc, err := newGmailService(ctx, account)
+	if err != nil {
+		return err
+	}
+	label, _, err := fetchUserLabel(ctx, svc, c.Label)
+	if err != nil {
+		return err
+	}
+
+	updated, err := svc.Users.Labels.Patch("me", label.Id, &gmail.Label{
+		LabelListVisibility:   labelListVisibilities[c.List],
+		MessageListVisibility: messageListVisibilities[c.Messages],
+	}).Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+	return writeLabelUpdate(ctx, updated)
+}
+
+func writeLabelUpdate(ctx context.Context, l *gmail.Label) error {
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(os.Stdout, map[string]any{"label": l})
+	}
+	u := ui.FromContext(ctx)
+	u.Out().Printf("id\t%s", l.Id)
+	u.Out().Printf("name\t%s", l.Name)
+	u.Out().Printf("label_list_visibility\t%s", l.LabelListVisibility)
+	u.Out().Printf("message_list_visibility\t%s", l.MessageListVisibility)
+	if l.Color != nil {
+		u.Out().Printf("background_color\t%s", l.Color.BackgroundColor)
+		u.Out().Printf("text_color\t%s", l.Color.TextColor)
+	}
+	return nil
+}
+
+type GmailLabelsTreeCmd struct {
+	All    bool `name:"all" help:"Include system labels"`
+	Counts bool `name:"counts" help:"Fetch message and unread counts (one request per label)" default:"true" negatable:""`
+}
+
+type labelTreeNode struct {
+	Name           string           `json:"name"`
+	Path           string           `json:"path"`
+	ID             string           `json:"id,omitempty"`
+	MessagesTotal  int64            `json:"messagesTotal"`
+	MessagesUnread int64            `json:"messagesUnread"`
+	Children       []*labelTreeNode `json:"children,omitempty"`
+}
+
+func (c *GmailLabelsTreeCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+
+	svc, err := newGmailService(ctx, account)
+	if err != nil {
+		return err
+	}
+	resp, err := svc.Users.Labels.List("me").Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+
+	labels := make([]*gmail.Label, 0, len(resp.Labels))
+	for _, l := range resp.Labels {
+		if l.Type == "system" && !c.All {
+			continue
+		}
+		if c.Counts {
+			full, getErr := svc.Users.Labels.Get("me", l.Id).Context(ctx).Do()
+			if getErr != nil {
+				return getErr
+			}
+			l = full
+		}
+		labels = append(labels, l)
+	}
+	roots := buildLabelTree(labels)
+
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(os.Stdout, map[string]any{"labels": roots})
+	}
+	if len(roots) == 0 {
+		u.Err().Println("No labels")
+		return nil
+	}
+
+	w, flush := tableWriter(ctx)
+	defer flush()
+	if c.Counts {
+		fmt.Fprintln(w, "NAME\tMESSAGES\tUNREAD")
+	} else {
+		fmt.Fprintln(w, "NAME")
+	}
+	var walk func(nodes []*labelTreeNode, depth int)
+	walk = func(nodes []*labelTreeNode, depth int) {
+		for _, n := range nodes {
+			name := strings.Repeat("  ", depth) + n.Name
+			switch {
+			case !c.Counts:
+				fmt.Fprintln(w, name)
+			case n.ID == "":
+				fmt.Fprintf(w, "%s\t-\t-\n", name)
+			default:
+				fmt.Fprintf(w, "%s\t%d\t%d\n", name, n.MessagesTotal, n.MessagesUnread)
+			}
+			walk(n.Children, depth+1)
+		}
+	}
+	walk(roots, 0)
+	return nil
+}
+
+// buildLabelTree nests labels by their "/" separated names. Parents that only
+// exist implicitly (e.g. "A" for a lone "A/B") get a node without an ID.
+func buildLabelTree(labels []*gmail.Label) []*labelTreeNode {
+	var roots []*labelTreeNode
+	byPath := map[string]*labelTreeNode{}
+
+	sorted := append([]*gmail.Label(nil), labels...)
+	sort.SliceStable(sorted, func(i, j int) bool { return strings.ToLower(sorted[i].Name) < strings.ToLower(sorted[j].Name) })
+
+	for _, l := range sorted {
+		parts := strings.Split(l.Name, labelPathSep)
+		var parent *labelTreeNode
+		for i, part := range parts {
+			path := strings.Join(parts[:i+1], labelPathSep)
+			node, ok := byPath[strings.ToLower(path)]
+			if !ok {
+				node = &labelTreeNode{Name: part, Path: path}
+				byPath[strings.ToLower(path)] = node
+				if parent == nil {
+					roots = append(roots, node)
+				} else {
+					parent.Children = append(parent.Children, node)
+				}
+			}
+			parent = node
+		}
+		parent.ID = l.Id
+		parent.MessagesTotal = l.MessagesTotal
+		parent.MessagesUnread = l.MessagesUnread
+	}
+	return roots
+}
@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/gmail/v1"
+
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+type fakeLabelStore struct {
+	labels  []*gmail.Label
+	patched map[string]gmail.Label
+	deleted []string
+}
+
+func newLabelManageServer(t *testing.T) *fakeLabelStore {
+	t.Helper()
+
+	store := &fakeLabelStore{
+		labels: []*gmail.Label{
+			{Id: "INBOX", Name: "INBOX", Type: "system", MessagesTotal: 40, MessagesUnread: 4},
+			{Id: "Label_1", Name: "Work", Type: "user", MessagesTotal: 10, MessagesUnread: 2},
+			{Id: "Label_2", Name: "Work/Boss", Type: "user", MessagesTotal: 3, MessagesUnread: 1},
+			{Id: "Label_3", Name: "Work/Boss/Urgent", Type: "user", MessagesTotal: 1},
+			{Id: "Label_4", Name: "Travel/2024", Type: "user", MessagesTotal: 7},
+			{Id: "Label_5", Name: "Workshop", Type: "user"},
+		},
+		patched: map[string]gmail.Label{},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		id := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/users/me/labels") && r.Method == http.MethodGet:
+			// List omits counts, like the real API.
+			list := make([]*gmail.Label, 0, len(store.labels))
+			for _, l := range store.labels {
+				list = append(list, &gmail.Label{Id: l.Id, Name: l.Name, Type: l.Type})
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"labels": list})
+		case strings.Contains(r.URL.Path, "/users/me/labels/") && r.Method == http.MethodGet:
+			for _, l := range store.labels {
+				if l.Id == id {
+					_ = json.NewEncoder(w).Encode(l)
+					return
+				}
+			}
+			http.NotFound(w, r)
+		case strings.Contains(r.URL.Path, "/users/me/labels/") && r.Method == http.MethodPatch:
+			var l gmail.Label
+			_ = json.NewDecoder(r.Body).Decode(&l)
+			store.patched[id] = l
+			l.Id = id
+			_ = json.NewEncoder(w).Encode(l)
+		case strings.Contains(r.URL.Path, "/users/me/labels/") && r.Method == http.MethodDelete:
+			store.deleted = append(store.deleted, id)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	stubGmailService(t, srv)
+	return store
+}
+
+func TestGmailLabelsRename_CascadesToSubLabels(t *testing.T) {
+	store := newLabelManageServer(t)
+
+	out := runSendLike(t, &GmailLabelsRenameCmd{}, "work", "Jobs/Acme")
+	if len(out["renamed"].([]any)) != 3 {
+		t.Fatalf("unexpected output: %#v", out)
+	}
+	want := map[string]string{"Label_1": "Jobs/Acme", "Label_2": "Jobs/Acme/Boss", "Label_3": "Jobs/Acme/Boss/Urgent"}
+	if len(store.patched) != len(want) {
+		t.Fatalf("unexpected patches: %#v", store.patched)
+	}
+	for id, name := range want {
+		if store.patched[id].Name != name {
+			t.Fatalf("%s renamed to %q, want %q", id, store.patched[id].Name, name)
+		}
+	}
+
+	if err := runKong(t, &GmailLabelsRenameCmd{}, []string{"Workshop", "Travel/2024"}, context.Background(), &RootFlags{Account: "a@b.com"}); err == nil {
+		t.Fatalf("expected collision error")
+	}
+	if err := runKong(t, &GmailLabelsRenameCmd{}, []string{"INBOX", "Other"}, context.Background(), &RootFlags{Account: "a@b.com"}); err == nil {
+		t.Fatalf("expected system label error")
+	}
+}
+
+func TestGmailLabelsDelete_RecursiveDeepestFirst(t *testing.T) {
+	store := newLabelManageServer(t)
+
+	u, err := ui.New(ui.Options{Stdout: os.Stdout, Stderr: os.Stderr, Color: "never"})
+	if err != nil {
+		t.Fatalf("ui.New: %v", err)
+	}
+	ctx := ui.WithUI(context.Background(), u)
+	if err := runKong(t, &GmailLabelsDeleteCmd{}, []string{"Work"}, ctx, &RootFlags{Account: "a@b.com", Force: true}); err == nil || !strings.Contains(err.Error(), "--recursive") {
+		t.Fatalf("expected --recursive hint, got %v", err)
+	}
+	if err := runKong(t, &GmailLabelsDeleteCmd{}, []string{"Work", "--recursive"}, ctx, &RootFlags{Account: "a@b.com"}); err == nil {
+		t.Fatalf("expected confirmation to be required")
+	}
+	if len(store.deleted) != 0 {
+		t.Fatalf("nothing should be deleted yet: %v", store.deleted)
+	}
+
+	_ = captureStdout(t, func() {
+		if err := runKong(t, &GmailLabelsDeleteCmd{}, []string{"Work", "--recursive"}, ctx, &RootFlags{Account: "a@b.com", Force: true}); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+	})
+	if !reflect.DeepEqual(store.deleted, []string{"Label_3", "Label_2", "Label_1"}) {
+		t.Fatalf("unexpected delete order: %v", store.deleted)
+	}
+}
+
+func TestGmailLabelsColorAndVisibility(t *testing.T) {
+	store := newLabelManageServer(t)
+
+	runSendLike(t, &GmailLabelsColorCmd{}, "Work", "--bg", "#4A86E8", "--text", "#ffffff")
+	if c := store.patched["Label_1"].Color; c == nil || c.BackgroundColor != "#4a86e8" || c.TextColor != "#ffffff" {
+		t.Fatalf("unexpected color patch: %#v", store.patched["Label_1"])
+	}
+	if err := runKong(t, &GmailLabelsColorCmd{}, []string{"Work", "--bg", "blue", "--text", "#ffffff"}, context.Background(), &RootFlags{Account: "a@b.com"}); err == nil {
+		t.Fatalf("expected invalid color error")
+	}
+
+	runSendLike(t, &GmailLabelsVisibilityCmd{}, "Workshop", "--list", "unread", "--messages", "hide")
+	if p := store.patched["Label_5"]; p.LabelListVisibility != "labelShowIfUnread" || p.MessageListVisibility != "hide" {
+		t.Fatalf("unexpected visibility patch: %#v", p)
+	}
+}
+
+func TestGmailLabelsTree_NestsWithCounts(t *testing.T) {
+	newLabelManageServer(t)
+
+	out := runSendLike(t, &GmailLabelsTreeCmd{})
+	roots := out["labels"].([]any)
+	names := make([]string, 0, len(roots))
+	for _, r := range roots {
+		names = append(names, r.(map[string]any)["name"].(string))
+	}
+	if !reflect.DeepEqual(names, []string{"Travel", "Work", "Workshop"}) {
+		t.Fatalf("unexpected roots: %v", names)
+	}
+	travel := roots[0].(map[string]any)
+	if _, ok := travel["id"]; ok || travel["children"].([]any)[0].(map[string]any)["messagesTotal"] != float64(7) {
+		t.Fatalf("implicit parent should have no id: %#v", travel)
+	}
+	work := roots[1].(map[string]any)
+	boss := work["children"].([]any)[0].(map[string]any)
+	if work["messagesUnread"] != float64(2) || boss["path"] != "Work/Boss" || len(boss["children"].([]any)) != 1 {
+		t.Fatalf("unexpected work subtree: %#v", work)
+	}
+}
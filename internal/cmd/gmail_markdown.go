@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/text"
+
+	"github.com/steipete/gogcli/internal/config"
+)
+
+// markdownBody is a Markdown message rendered for sending: the Markdown source
+// doubles as the text/plain part, and local images become cid: parts.
+type markdownBody struct {
+	Plain  string
+	HTML   string
+	Images []mailAttachment
+}
+
+// resolveComposeBodies applies --body-md on top of the plain/HTML body flags.
+// Markdown replaces both, so combining them is a usage error.
+func resolveComposeBodies(body, bodyHTML, bodyMD string) (string, string, []mailAttachment, error) {
+	if strings.TrimSpace(bodyMD) == "" {
+		return body, bodyHTML, nil, nil
+	}
+	if strings.TrimSpace(body) != "" || strings.TrimSpace(bodyHTML) != "" {
+		return "", "", nil, usage("--body-md cannot be combined with --body, --body-file, or --body-html")
+	}
+	md, err := renderMarkdownFile(bodyMD)
+	if err != nil {
+		return "", "", nil, err
+	}
+	return md.Plain, md.HTML, md.Images, nil
+}
+
+func renderMarkdownFile(path string) (markdownBody, error) {
+	path = strings.TrimSpace(path)
+	var (
+		src     []byte
+		baseDir string
+		err     error
+	)
+	if path == "-" {
+		src, err = io.ReadAll(os.Stdin)
+		baseDir = "."
+	} else {
+		path, err = config.ExpandPath(path)
+		if err != nil {
+			return markdownBody{}, err
+		}
+		src, err = os.ReadFile(path) //nolint:gosec // user-provided path
+		baseDir = filepath.Dir(path)
+	}
+	if err != nil {
+		return markdownBody{}, fmt.Errorf("read markdown: %w", err)
+	}
+	return renderMarkdown(src, baseDir)
+}
+
+// renderMarkdown renders GitHub-flavored Markdown. goldmark's default renderer
+// drops raw HTML and javascript:/vbscript:/file: links, so the output is safe
+// to send as-is.
+func renderMarkdown(src []byte, baseDir string) (markdownBody, error) {
+	md := goldmark.New(goldmark.WithExtensions(extension.GFM))
+	doc := md.Parser().Parse(text.NewReader(src))
+
+	var images []mailAttachment
+	byPath := map[string]string{}
+	walkErr := ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		img, ok := n.(*ast.Image)
+		if !entering || !ok {
+			return ast.WalkContinue, nil
+		}
+		local, ok := localImagePath(string(img.Destination), baseDir)
+		if !ok {
+			return ast.WalkContinue, nil
+		}
+		cid, seen := byPath[local]
+		if !seen {
+			data, err := os.ReadFile(local) //nolint:gosec // referenced by the user's markdown
+			if err != nil {
+				return ast.WalkStop, fmt.Errorf("inline image: %w", err)
+			}
+			cid, err = inlineContentID(len(images) + 1)
+			if err != nil {
+				return ast.WalkStop, err
+			}
+			byPath[local] = cid
+			images = append(images, mailAttachment{Path: local, Data: data, ContentID: cid})
+		}
+		img.Destination = []byte("cid:" + cid)
+		return ast.WalkContinue, nil
+	})
+	if walkErr != nil {
+		return markdownBody{}, walkErr
+	}
+
+	var out bytes.Buffer
+	if err := md.Renderer().Render(&out, src, doc); err != nil {
+		return markdownBody{}, fmt.Errorf("render markdown: %w", err)
+	}
+	return markdownBody{Plain: string(src), HTML: out.String(), Images: images}, nil
+}
+
+// localImagePath resolves an image reference to a local file, or reports false
+// for remote (http:, https:, cid:, data:, …) references.
+func localImagePath(dest string, baseDir string) (string, bool) {
+	dest = strings.TrimSpace(dest)
+	if dest == "" {
+		return "", false
+	}
+	if u, err := url.Parse(dest); err == nil {
+		if len(u.Scheme) > 1 { // single letters are Windows drive names
+			return "", false
+		}
+		if unescaped, unescapeErr := url.PathUnescape(dest); unescapeErr == nil {
+			dest = unescaped
+		}
+	}
+	if strings.HasPrefix(dest, "~") {
+		expanded, err := config.ExpandPath(dest)
+		if err != nil {
+			return "", false
+		}
+		return expanded, true
+	}
+	if !filepath.IsAbs(dest) {
+		dest = filepath.Join(baseDir, filepath.FromSlash(dest))
+	}
+	return dest, true
+}
+
+func inlineContentID(n int) (string, error) {
+	var b [6]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("img%d.%s@gogcli", n, hex.EncodeToString(b[:])), nil
+}
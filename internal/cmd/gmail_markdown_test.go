@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenderMarkdown_InlinesLocalImagesAndSanitizes(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "img"), 0o700); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "img", "chart one.png"), []byte("PNG"), 0o600); err != nil {
+		t.Fatalf("write image: %v", err)
+	}
+
+	src := "# Update\n\n![chart](img/chart%20one.png) ![again](./img/chart%20one.png)\n" +
+		"![remote](https://example.com/x.png)\n\n<script>alert(1)</script>\n\n[bad](javascript:alert(1)) | ~~old~~\n"
+	md, err := renderMarkdown([]byte(src), dir)
+	if err != nil {
+		t.Fatalf("renderMarkdown: %v", err)
+	}
+
+	if len(md.Images) != 1 {
+		t.Fatalf("expected one deduplicated inline image, got %#v", md.Images)
+	}
+	cid := md.Images[0].ContentID
+	if strings.Count(md.HTML, `src="cid:`+cid+`"`) != 2 {
+		t.Fatalf("image references not rewritten to cid: %s", md.HTML)
+	}
+	if !strings.Contains(md.HTML, `src="https://example.com/x.png"`) || !strings.Contains(md.HTML, "<h1>Update</h1>") || !strings.Contains(md.HTML, "<del>old</del>") {
+		t.Fatalf("unexpected html: %s", md.HTML)
+	}
+	if strings.Contains(md.HTML, "<script") || strings.Contains(md.HTML, "javascript:") {
+		t.Fatalf("html not sanitized: %s", md.HTML)
+	}
+	if md.Plain != src {
+		t.Fatalf("plain fallback should be the markdown source")
+	}
+}
+
+func TestResolveComposeBodies(t *testing.T) {
+	if _, _, _, err := resolveComposeBodies("plain", "", "x.md"); err == nil || !strings.Contains(err.Error(), "--body-md cannot be combined") {
+		t.Fatalf("expected conflict error, got %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "msg.md")
+	if err := os.WriteFile(path, []byte("![missing](nope.png)\n"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, _, _, err := resolveComposeBodies("", "", path); err == nil || !strings.Contains(err.Error(), "inline image") {
+		t.Fatalf("expected missing image error, got %v", err)
+	}
+
+	plain, html, inline, err := resolveComposeBodies("p", "<b>h</b>", "")
+	if err != nil || plain != "p" || html != "<b>h</b>" || inline != nil {
+		t.Fatalf("passthrough failed: %q %q %v %v", plain, html, inline, err)
+	}
+}
@@ -0,0 +1,571 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/steipete/gogcli/internal/config"
+	"github.com/steipete/gogcli/internal/outfmt"
+	"github.com/steipete/gogcli/internal/tracking"
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+const (
+	mergeStatusSent   = "sent"
+	mergeStatusFailed = "failed"
+)
+
+var mergeResultsHeader = []string{"row", "to", "status", "message_id", "thread_id", "tracking_id", "error"}
+
+type GmailMergeCmd struct {
+	Template     string        `name:"template" required:"" help:"Message template: header lines (Subject, To, Cc, Bcc, Reply-To, Attach), a blank line, then the plain-text body"`
+	HTMLTemplate string        `name:"html-template" help:"Optional HTML body template"`
+	Data         string        `name:"data" help:"CSV file with a header row"`
+	Sheet        string        `name:"sheet" help:"Read rows from this spreadsheet ID instead of --data"`
+	Range        string        `name:"range" help:"Sheet range (first row is the header)" default:"A:Z"`
+	ToColumn     string        `name:"to-column" help:"Column holding the recipient when the template has no To header" default:"email"`
+	From         string        `name:"from" help:"Send from this email address (must be a verified send-as alias)"`
+	Delay        time.Duration `name:"delay" help:"Pause between sends" default:"1s"`
+	Preview      string        `name:"preview" help:"Write rendered .eml files to this directory instead of sending"`
+	Results      string        `name:"results" help:"Results CSV (rows already sent are skipped on re-run)" default:"merge-results.csv"`
+	Track        bool          `name:"track" help:"Enable open tracking per row (requires --html-template and tracking setup)"`
+}
+
+type mergeMessage struct {
+	Row         int
+	To          []string
+	Cc          []string
+	Bcc         []string
+	ReplyTo     string
+	Subject     string
+	Body        string
+	BodyHTML    string
+	Attachments []mailAttachment
+}
+
+type mergeResult struct {
+	Row        int    `json:"row"`
+	To         string `json:"to"`
+	Status     string `json:"status"`
+	MessageID  string `json:"messageId,omitempty"`
+	ThreadID   string `json:"threadId,omitempty"`
+	TrackingID string `json:"tracking_id,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+func (c *GmailMergeCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+
+	dataPath := strings.TrimSpace(c.Data)
+	sheetID := strings.TrimSpace(c.Sheet)
+	if (dataPath == "") == (sheetID == "") {
+		return usage("specify exactly one of --data or --sheet")
+	}
+	if c.Track && strings.TrimSpace(c.HTMLTemplate) == "" {
+		return usage("--track requires --html-template (pixel must be in HTML)")
+	}
+	if c.Delay < 0 {
+		return usage("--delay must be >= 0")
+	}
+
+	textTmpl, htmlTmpl, err := c.parseTemplates()
+	if err != nil {
+		return err
+	}
+
+	var rows []map[string]string
+	if dataPath != "" {
+		rows, err = readMergeCSV(dataPath)
+	} else {
+		rows, err = readMergeSheet(ctx, account, sheetID, c.Range)
+	}
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return usage("no data rows")
+	}
+
+	// Render every row up front so a template or data error never leaves a
+	// half-sent campaign.
+	messages := make([]mergeMessage, 0, len(rows))
+	for i, row := range rows {
+		msg, renderErr := renderMergeMessage(textTmpl, htmlTmpl, row, c.ToColumn)
+		if renderErr != nil {
+			return usagef("row %d: %v", i+1, renderErr)
+		}
+		msg.Row = i + 1
+		messages = append(messages, msg)
+	}
+
+	if strings.TrimSpace(c.Preview) != "" {
+		return c.writePreview(ctx, u, account, messages)
+	}
+
+	resultsPath, err := config.ExpandPath(strings.TrimSpace(c.Results))
+	if err != nil {
+		return err
+	}
+	sent, err := loadMergeResults(resultsPath)
+	if err != nil {
+		return err
+	}
+
+	svc, err := newGmailService(ctx, account)
+	if err != nil {
+		return err
+	}
+	fromAddr, _, err := resolveSendFrom(ctx, svc, account, c.From)
+	if err != nil {
+		return err
+	}
+
+	var trackingCfg *tracking.Config
+	if c.Track {
+		trackingCfg, err = tracking.LoadConfig(account)
+		if err != nil {
+			return fmt.Errorf("load tracking config: %w", err)
+		}
+		if !trackingCfg.IsConfigured() {
+			return fmt.Errorf("tracking not configured; run 'gog gmail track setup' first")
+		}
+	}
+
+	out, err := openMergeResults(resultsPath)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	results := make([]mergeResult, 0, len(messages))
+	skipped, failed := 0, 0
+	pause := false
+	for _, msg := range messages {
+		to := strings.Join(msg.To, ",")
+		if sent[mergeResultKey(msg.Row, to)] {
+			skipped++
+			continue
+		}
+
+		if pause && c.Delay > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(c.Delay):
+			}
+		}
+		pause = true
+
+		res := mergeResult{Row: msg.Row, To: to, Status: mergeStatusSent}
+		sentMsgs, sendErr := sendGmailBatches(ctx, svc, sendMessageOptions{
+			FromAddr:    fromAddr,
+			ReplyTo:     msg.ReplyTo,
+			Subject:     msg.Subject,
+			Body:        msg.Body,
+			BodyHTML:    msg.BodyHTML,
+			Attachments: msg.Attachments,
+			Track:       c.Track,
+			TrackingCfg: trackingCfg,
+		}, []sendBatch{{To: msg.To, Cc: msg.Cc, Bcc: msg.Bcc, TrackingRecipient: firstRecipient(msg.To, msg.Cc, msg.Bcc)}})
+		if sendErr != nil {
+			res.Status = mergeStatusFailed
+			res.Error = sendErr.Error()
+			failed++
+			u.Err().Printf("row %d (%s): %v", msg.Row, to, sendErr)
+		} else if len(sentMsgs) > 0 {
+			res.MessageID = sentMsgs[0].MessageID
+			res.ThreadID = sentMsgs[0].ThreadID
+			res.TrackingID = sentMsgs[0].TrackingID
+		}
+		if err := out.Append(res); err != nil {
+			return err
+		}
+		results = append(results, res)
+	}
+
+	if outfmt.IsJSON(ctx) {
+		if err := outfmt.WriteJSON(os.Stdout, map[string]any{
+			"from":    fromAddr,
+			"total":   len(messages),
+			"sent":    len(results) - failed,
+			"skipped": skipped,
+			"failed":  failed,
+			"results": resultsPath,
+			"rows":    results,
+		}); err != nil {
+			return err
+		}
+	} else {
+		u.Out().Printf("from\t%s", fromAddr)
+		u.Out().Printf("total\t%d", len(messages))
+		u.Out().Printf("sent\t%d", len(results)-failed)
+		u.Out().Printf("skipped\t%d", skipped)
+		u.Out().Printf("failed\t%d", failed)
+		u.Out().Printf("results\t%s", resultsPath)
+	}
+
+	if failed > 0 {
+		return &ExitError{Code: 1, Err: fmt.Errorf("%d row(s) failed; re-run the same command to retry them", failed)}
+	}
+	return nil
+}
+
+// mergeTemplate is the --template file split into one template per header
+// value plus the body, so row values can't add header lines or end the
+// header block early.
+type mergeTemplate struct {
+	headers []mergeHeaderTemplate
+	body    *template.Template
+}
+
+type mergeHeaderTemplate struct {
+	key   string
+	value *template.Template
+}
+
+func (c *GmailMergeCmd) parseTemplates() (*mergeTemplate, *htmltemplate.Template, error) {
+	path, err := config.ExpandPath(strings.TrimSpace(c.Template))
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := os.ReadFile(path) //nolint:gosec // user-provided path
+	if err != nil {
+		return nil, nil, err
+	}
+	textTmpl, err := parseMergeTemplate(filepath.Base(path), string(data))
+	if err != nil {
+		return nil, nil, usagef("parse --template: %v", err)
+	}
+
+	if strings.TrimSpace(c.HTMLTemplate) == "" {
+		return textTmpl, nil, nil
+	}
+	htmlPath, err := config.ExpandPath(strings.TrimSpace(c.HTMLTemplate))
+	if err != nil {
+		return nil, nil, err
+	}
+	htmlData, err := os.ReadFile(htmlPath) //nolint:gosec // user-provided path
+	if err != nil {
+		return nil, nil, err
+	}
+	htmlTmpl, err := htmltemplate.New(filepath.Base(htmlPath)).Option("missingkey=error").Parse(string(htmlData))
+	if err != nil {
+		return nil, nil, usagef("parse --html-template: %v", err)
+	}
+	return textTmpl, htmlTmpl, nil
+}
+
+// parseMergeTemplate splits the template source at the first blank line and
+// parses each header value and the body as separate templates.
+func parseMergeTemplate(name string, src string) (*mergeTemplate, error) {
+	root := template.New(name).Option("missingkey=error")
+	head, body, _ := strings.Cut(strings.ReplaceAll(src, "\r\n", "\n"), "\n\n")
+	out := &mergeTemplate{}
+	for _, line := range strings.Split(head, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid template header line %q", line)
+		}
+		key = strings.TrimSpace(key)
+		tmpl, err := root.New(name + ":" + key).Parse(strings.TrimSpace(value))
+		if err != nil {
+			return nil, err
+		}
+		out.headers = append(out.headers, mergeHeaderTemplate{key: key, value: tmpl})
+	}
+	var err error
+	if out.body, err = root.Parse(body); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// renderMergeMessage executes the templates for one row. Rendered header
+// values must stay on one line; only the body sees raw row values.
+func renderMergeMessage(textTmpl *mergeTemplate, htmlTmpl *htmltemplate.Template, row map[string]string, toColumn string) (mergeMessage, error) {
+	var rendered bytes.Buffer
+	if err := textTmpl.body.Execute(&rendered, row); err != nil {
+		return mergeMessage{}, err
+	}
+
+	msg := mergeMessage{Body: rendered.String()}
+	for _, header := range textTmpl.headers {
+		key := header.key
+		rendered.Reset()
+		if err := header.value.Execute(&rendered, row); err != nil {
+			return mergeMessage{}, err
+		}
+		value := strings.TrimSpace(rendered.String())
+		if strings.ContainsAny(value, "\r\n") {
+			return mergeMessage{}, fmt.Errorf("%s header contains a line break", key)
+		}
+		switch strings.ToLower(key) {
+		case "subject":
+			msg.Subject = value
+		case "to":
+			msg.To = append(msg.To, splitCSV(value)...)
+		case "cc":
+			msg.Cc = append(msg.Cc, splitCSV(value)...)
+		case "bcc":
+			msg.Bcc = append(msg.Bcc, splitCSV(value)...)
+		case "reply-to":
+			msg.ReplyTo = value
+		case "attach":
+			for _, p := range splitCSV(value) {
+				expanded, err := config.ExpandPath(p)
+				if err != nil {
+					return mergeMessage{}, err
+				}
+				if _, err := os.Stat(expanded); err != nil {
+					return mergeMessage{}, fmt.Errorf("attachment: %w", err)
+				}
+				msg.Attachments = append(msg.Attachments, mailAttachment{Path: expanded})
+			}
+		default:
+			return mergeMessage{}, fmt.Errorf("unknown template header %q (use Subject, To, Cc, Bcc, Reply-To, Attach)", key)
+		}
+	}
+
+	if len(msg.To) == 0 {
+		to := row[strings.TrimSpace(toColumn)]
+		if strings.ContainsAny(to, "\r\n") {
+			return mergeMessage{}, fmt.Errorf("%q column contains a line break", toColumn)
+		}
+		msg.To = splitCSV(to)
+	}
+	if len(msg.To) == 0 {
+		return mergeMessage{}, fmt.Errorf("no recipient (add a To header or fill the %q column)", toColumn)
+	}
+	if msg.Subject == "" {
+		return mergeMessage{}, errors.New("empty Subject")
+	}
+
+	if htmlTmpl != nil {
+		var html bytes.Buffer
+		if err := htmlTmpl.Execute(&html, row); err != nil {
+			return mergeMessage{}, err
+		}
+		msg.BodyHTML = html.String()
+	}
+	if strings.TrimSpace(msg.Body) == "" && strings.TrimSpace(msg.BodyHTML) == "" {
+		return mergeMessage{}, errors.New("empty body")
+	}
+	return msg, nil
+}
+
+func (c *GmailMergeCmd) writePreview(ctx context.Context, u *ui.UI, account string, messages []mergeMessage) error {
+	dir, err := config.ExpandPath(strings.TrimSpace(c.Preview))
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("create preview dir: %w", err)
+	}
+
+	from := strings.TrimSpace(c.From)
+	if from == "" {
+		from = account
+	}
+
+	files := make([]string, 0, len(messages))
+	for _, msg := range messages {
+		raw, err := buildRFC822(mailOptions{
+			From:        from,
+			To:          msg.To,
+			Cc:          msg.Cc,
+			Bcc:         msg.Bcc,
+			ReplyTo:     msg.ReplyTo,
+			Subject:     msg.Subject,
+			Body:        msg.Body,
+			BodyHTML:    msg.BodyHTML,
+			Attachments: msg.Attachments,
+		}, nil)
+		if err != nil {
+			return fmt.Errorf("row %d: %w", msg.Row, err)
+		}
+		path := filepath.Join(dir, fmt.Sprintf("row-%04d.eml", msg.Row))
+		if err := os.WriteFile(path, raw, 0o600); err != nil {
+			return err
+		}
+		files = append(files, path)
+	}
+
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(os.Stdout, map[string]any{"preview": dir, "files": files})
+	}
+	u.Out().Printf("preview\t%s", dir)
+	u.Out().Printf("files\t%d", len(files))
+	return nil
+}
+
+func readMergeCSV(path string) ([]map[string]string, error) {
+	expanded, err := config.ExpandPath(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(expanded) //nolint:gosec // user-provided path
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	return mergeRowsFromValues(records), nil
+}
+
+func readMergeSheet(ctx context.Context, account string, spreadsheetID string, rangeSpec string) ([]map[string]string, error) {
+	svc, err := newSheetsService(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := svc.Spreadsheets.Values.Get(spreadsheetID, cleanRange(rangeSpec)).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	values := make([][]string, 0, len(resp.Values))
+	for _, row := range resp.Values {
+		cells := make([]string, 0, len(row))
+		for _, cell := range row {
+			cells = append(cells, fmt.Sprint(cell))
+		}
+		values = append(values, cells)
+	}
+	return mergeRowsFromValues(values), nil
+}
+
+// mergeRowsFromValues turns a header row plus data rows into maps keyed by
+// the trimmed header; short rows get empty strings, blank rows are dropped.
+func mergeRowsFromValues(values [][]string) []map[string]string {
+	if len(values) < 2 {
+		return nil
+	}
+	header := make([]string, len(values[0]))
+	for i, h := range values[0] {
+		header[i] = strings.TrimSpace(strings.TrimPrefix(h, "\ufeff"))
+	}
+
+	rows := make([]map[string]string, 0, len(values)-1)
+	for _, record := range values[1:] {
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+		row := make(map[string]string, len(header))
+		for i, h := range header {
+			if h == "" {
+				continue
+			}
+			if i < len(record) {
+				row[h] = strings.TrimSpace(record[i])
+			} else {
+				row[h] = ""
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func mergeResultKey(row int, to string) string {
+	return strconv.Itoa(row) + "\x00" + strings.ToLower(to)
+}
+
+// loadMergeResults returns the rows already sent according to an existing
+// results file, so re-running a partially failed merge only retries the rest.
+func loadMergeResults(path string) (map[string]bool, error) {
+	sent := map[string]bool{}
+	f, err := os.Open(path) //nolint:gosec // user-provided path
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return sent, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return sent, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read results %s: %w", path, err)
+		}
+		if len(record) < 3 || record[0] == mergeResultsHeader[0] {
+			continue
+		}
+		row, err := strconv.Atoi(record[0])
+		if err != nil {
+			continue
+		}
+		if record[2] == mergeStatusSent {
+			sent[mergeResultKey(row, record[1])] = true
+		}
+	}
+}
+
+type mergeResultsWriter struct {
+	f *os.File
+	w *csv.Writer
+}
+
+func openMergeResults(path string) (*mergeResultsWriter, error) {
+	info, statErr := os.Stat(path)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600) //nolint:gosec // user-provided path
+	if err != nil {
+		return nil, fmt.Errorf("open results: %w", err)
+	}
+	w := &mergeResultsWriter{f: f, w: csv.NewWriter(f)}
+	if statErr != nil || info.Size() == 0 {
+		if err := w.write(mergeResultsHeader); err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// Append writes one result and flushes, so the file stays accurate even if
+// the merge is interrupted.
+func (m *mergeResultsWriter) Append(r mergeResult) error {
+	return m.write([]string{strconv.Itoa(r.Row), r.To, r.Status, r.MessageID, r.ThreadID, r.TrackingID, r.Error})
+}
+
+func (m *mergeResultsWriter) write(record []string) error {
+	if err := m.w.Write(record); err != nil {
+		return fmt.Errorf("write results: %w", err)
+	}
+	m.w.Flush()
+	if err := m.w.Error(); err != nil {
+		return fmt.Errorf("write results: %w", err)
+	}
+	return nil
+}
+
+func (m *mergeResultsWriter) Close() error {
+	return m.f.Close()
+}
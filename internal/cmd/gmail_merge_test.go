@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
+
+	"github.com/steipete/gogcli/internal/outfmt"
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+func writeMergeFixtures(t *testing.T) (string, string, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	invoice := filepath.Join(dir, "inv-1.pdf")
+	if err := os.WriteFile(invoice, []byte("%PDF-1.4"), 0o600); err != nil {
+		t.Fatalf("write invoice: %v", err)
+	}
+	tmpl := filepath.Join(dir, "msg.tmpl")
+	if err := os.WriteFile(tmpl, []byte("Subject: Welcome {{.name}}\nAttach: {{.invoice}}\n\nHi {{.name}},\nyour plan is {{.plan}}.\n"), 0o600); err != nil {
+		t.Fatalf("write template: %v", err)
+	}
+	data := filepath.Join(dir, "people.csv")
+	csvBody := "name,email,plan,invoice\nAda,ada@example.com,pro," + invoice + "\nBob,bob@example.com,free,\n"
+	if err := os.WriteFile(data, []byte(csvBody), 0o600); err != nil {
+		t.Fatalf("write csv: %v", err)
+	}
+	return dir, tmpl, data
+}
+
+func runMerge(t *testing.T, args []string) (map[string]any, error) {
+	t.Helper()
+
+	u, err := ui.New(ui.Options{Stdout: os.Stdout, Stderr: os.Stderr, Color: "never"})
+	if err != nil {
+		t.Fatalf("ui.New: %v", err)
+	}
+	ctx := outfmt.WithMode(ui.WithUI(context.Background(), u), outfmt.Mode{JSON: true})
+
+	var runErr error
+	out := captureStdout(t, func() {
+		runErr = runKong(t, &GmailMergeCmd{}, args, ctx, &RootFlags{Account: "a@b.com"})
+	})
+	var parsed map[string]any
+	if err := json.Unmarshal([]byte(out), &parsed); err != nil {
+		t.Fatalf("json parse: %v\nout=%q", err, out)
+	}
+	return parsed, runErr
+}
+
+func TestGmailMerge_SendsRowsAndResumesFromResults(t *testing.T) {
+	dir, tmpl, data := writeMergeFixtures(t)
+
+	var sent []string
+	failBob := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.Contains(r.URL.Path, "/settings/sendAs/"):
+			http.NotFound(w, r)
+		case strings.HasSuffix(r.URL.Path, "/users/me/messages/send"):
+			var msg gmail.Message
+			_ = json.NewDecoder(r.Body).Decode(&msg)
+			raw, _ := base64.RawURLEncoding.DecodeString(msg.Raw)
+			if strings.Contains(string(raw), "bob@example.com") && failBob {
+				w.WriteHeader(http.StatusInternalServerError)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{"code": 500, "message": "backend error"}})
+				return
+			}
+			sent = append(sent, string(raw))
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": "msg-" + strconv.Itoa(len(sent)), "threadId": "t1"})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	svc, err := gmail.NewService(context.Background(),
+		option.WithoutAuthentication(),
+		option.WithHTTPClient(srv.Client()),
+		option.WithEndpoint(srv.URL+"/"),
+	)
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	origNew := newGmailService
+	t.Cleanup(func() { newGmailService = origNew })
+	newGmailService = func(context.Context, string) (*gmail.Service, error) { return svc, nil }
+
+	results := filepath.Join(dir, "results.csv")
+	args := []string{"--template", tmpl, "--data", data, "--delay", "0s", "--results", results}
+
+	first, err := runMerge(t, args)
+	var exitErr *ExitError
+	if err == nil || !errors.As(err, &exitErr) || exitErr.Code != 1 {
+		t.Fatalf("expected exit 1 for failed row, got %v", err)
+	}
+	if first["sent"] != float64(1) || first["failed"] != float64(1) {
+		t.Fatalf("unexpected first run: %#v", first)
+	}
+	if len(sent) != 1 || !strings.Contains(sent[0], "Subject: Welcome Ada") || !strings.Contains(sent[0], "your plan is pro.") || !strings.Contains(sent[0], "inv-1.pdf") {
+		t.Fatalf("unexpected rendered message: %q", sent)
+	}
+
+	failBob = false
+	second, err := runMerge(t, args)
+	if err != nil {
+		t.Fatalf("second run: %v", err)
+	}
+	if second["sent"] != float64(1) || second["skipped"] != float64(1) || len(sent) != 2 {
+		t.Fatalf("expected only the failed row to be retried: %#v (sent %d)", second, len(sent))
+	}
+
+	f, err := os.Open(results)
+	if err != nil {
+		t.Fatalf("open results: %v", err)
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatalf("read results: %v", err)
+	}
+	if len(records) != 4 || strings.Join(records[0], ",") != strings.Join(mergeResultsHeader, ",") {
+		t.Fatalf("unexpected results file: %v", records)
+	}
+	if records[1][2] != mergeStatusSent || records[1][3] != "msg-1" || records[2][2] != mergeStatusFailed || records[3][1] != "bob@example.com" || records[3][2] != mergeStatusSent {
+		t.Fatalf("unexpected results rows: %v", records)
+	}
+}
+
+func TestGmailMerge_PreviewWritesEmlWithoutSending(t *testing.T) {
+	dir, tmpl, data := writeMergeFixtures(t)
+
+	origNew := newGmailService
+	t.Cleanup(func() { newGmailService = origNew })
+	newGmailService = func(context.Context, string) (*gmail.Service, error) {
+		t.Fatal("preview must not call Gmail")
+		return nil, nil
+	}
+
+	previewDir := filepath.Join(dir, "preview")
+	out, err := runMerge(t, []string{"--template", tmpl, "--data", data, "--preview", previewDir})
+	if err != nil {
+		t.Fatalf("preview: %v", err)
+	}
+	if files, ok := out["files"].([]any); !ok || len(files) != 2 {
+		t.Fatalf("unexpected preview output: %#v", out)
+	}
+	raw, err := os.ReadFile(filepath.Join(previewDir, "row-0002.eml"))
+	if err != nil {
+		t.Fatalf("read preview: %v", err)
+	}
+	if !strings.Contains(string(raw), "To: bob@example.com") || !strings.Contains(string(raw), "Subject: Welcome Bob") {
+		t.Fatalf("unexpected preview: %s", raw)
+	}
+}
+
+func TestRenderMergeMessage_Errors(t *testing.T) {
+	dir := t.TempDir()
+	tmpl := filepath.Join(dir, "msg.tmpl")
+
+	cases := map[string]string{
+		"missing key":    "Subject: Hi {{.nickname}}\n\nbody\n",
+		"unknown header": "Subject: Hi\nX-Custom: nope\n\nbody\n",
+		"no subject":     "To: {{.email}}\n\nbody\n",
+	}
+	for name, content := range cases {
+		if err := os.WriteFile(tmpl, []byte(content), 0o600); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		textTmpl, htmlTmpl, err := (&GmailMergeCmd{Template: tmpl}).parseTemplates()
+		if err != nil {
+			t.Fatalf("%s: parse: %v", name, err)
+		}
+		if _, err := renderMergeMessage(textTmpl, htmlTmpl, map[string]string{"email": "a@example.com"}, "email"); err == nil {
+			t.Fatalf("%s: expected render error", name)
+		}
+	}
+}
+
+func TestRenderMergeMessage_RowValuesCannotInjectHeaders(t *testing.T) {
+	tmpl, err := parseMergeTemplate("msg.tmpl", "Subject: Hi {{.name}}\nTo: {{.email}}\n\nNote: {{.note}}\n")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	injected := map[string]map[string]string{
+		"bcc":    {"name": "Ada\nBcc: evil@example.com", "email": "ada@example.com", "note": ""},
+		"attach": {"name": "Ada", "email": "ada@example.com\r\nAttach: /etc/passwd", "note": ""},
+		"body":   {"name": "Ada\n\nearly body", "email": "ada@example.com", "note": ""},
+	}
+	for name, row := range injected {
+		if _, err := renderMergeMessage(tmpl, nil, row, "email"); err == nil || !strings.Contains(err.Error(), "line break") {
+			t.Fatalf("%s: expected line break error, got %v", name, err)
+		}
+	}
+	if _, err := renderMergeMessage(&mergeTemplate{headers: tmpl.headers[:1], body: tmpl.body}, nil, map[string]string{"name": "Ada", "email": "ada@example.com\nBcc: evil@example.com", "note": ""}, "email"); err == nil {
+		t.Fatalf("expected line break error for the --to-column value")
+	}
+
+	msg, err := renderMergeMessage(tmpl, nil, map[string]string{"name": "Ada", "email": "ada@example.com", "note": "line one\nBcc: not-a-header"}, "email")
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if len(msg.Bcc) != 0 || len(msg.Attachments) != 0 || msg.Body != "Note: line one\nBcc: not-a-header\n" {
+		t.Fatalf("body values should stay in the body: %#v", msg)
+	}
+}
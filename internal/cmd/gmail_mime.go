@@ -20,6 +20,29 @@ type mailAttachment struct {
 	Filename string
 	MIMEType string
 	Data     []byte
+	// ContentID marks an inline part referenced from the HTML body as cid:<ContentID>.
+	ContentID string
+}
+
+// resolved fills in the filename, MIME type and data from Path where unset.
+func (a mailAttachment) resolved() (mailAttachment, error) {
+	if a.Filename == "" {
+		a.Filename = filepath.Base(a.Path)
+	}
+	if a.MIMEType == "" {
+		a.MIMEType = mime.TypeByExtension(strings.ToLower(filepath.Ext(a.Filename)))
+		if a.MIMEType == "" {
+			a.MIMEType = "application/octet-stream"
+		}
+	}
+	if len(a.Data) == 0 && a.Path != "" {
+		data, err := os.ReadFile(a.Path)
+		if err != nil {
+			return a, err
+		}
+		a.Data = data
+	}
+	return a, nil
 }
 
 type rfc822Config struct {
@@ -39,6 +62,8 @@ type mailOptions struct {
 	References        string
 	AdditionalHeaders map[string]string
 	Attachments       []mailAttachment
+	// InlineImages are sent next to the HTML body in multipart/related.
+	InlineImages []mailAttachment
 }
 
 func buildRFC822(opts mailOptions, cfg *rfc822Config) ([]byte, error) {
@@ -117,36 +142,12 @@ func buildRFC822(opts mailOptions, cfg *rfc822Config) ([]byte, error) {
 
 	plainBody := normalizeCRLF(opts.Body)
 	htmlBody := normalizeCRLF(opts.BodyHTML)
-	hasPlain := strings.TrimSpace(plainBody) != ""
-	hasHTML := strings.TrimSpace(htmlBody) != ""
 
 	if len(opts.Attachments) == 0 {
-		switch {
-		case hasPlain && hasHTML:
-			altBoundary, err := randomBoundary()
-			if err != nil {
-				return nil, err
-			}
-			writeHeader(&b, "Content-Type", fmt.Sprintf("multipart/alternative; boundary=%q", altBoundary))
-			b.WriteString("\r\n")
-
-			writeTextPart(&b, altBoundary, "text/plain; charset=\"utf-8\"", plainBody)
-			writeTextPart(&b, altBoundary, "text/html; charset=\"utf-8\"", htmlBody)
-			b.WriteString(fmt.Sprintf("--%s--\r\n", altBoundary))
-			return b.Bytes(), nil
-		case hasHTML && !hasPlain:
-			writeHeader(&b, "Content-Type", "text/html; charset=\"utf-8\"")
-			writeHeader(&b, "Content-Transfer-Encoding", "7bit")
-			b.WriteString("\r\n")
-			writeBodyWithTrailingCRLF(&b, htmlBody)
-			return b.Bytes(), nil
-		default:
-			writeHeader(&b, "Content-Type", "text/plain; charset=\"utf-8\"")
-			writeHeader(&b, "Content-Transfer-Encoding", "7bit")
-			b.WriteString("\r\n")
-			writeBodyWithTrailingCRLF(&b, plainBody)
-			return b.Bytes(), nil
+		if err := writeBodyEntity(&b, plainBody, htmlBody, opts.InlineImages); err != nil {
+			return nil, err
 		}
+		return b.Bytes(), nil
 	}
 
 	mixedBoundary, err := randomBoundary()
@@ -159,47 +160,26 @@ func buildRFC822(opts mailOptions, cfg *rfc822Config) ([]byte, error) {
 
 	// Body part
 	b.WriteString(fmt.Sprintf("--%s\r\n", mixedBoundary))
-	switch {
-	case hasPlain && hasHTML:
-		altBoundary, err := randomBoundary()
-		if err != nil {
-			return nil, err
-		}
-		b.WriteString(fmt.Sprintf("Content-Type: multipart/alternative; boundary=%q\r\n\r\n", altBoundary))
-		writeTextPart(&b, altBoundary, "text/plain; charset=\"utf-8\"", plainBody)
-		writeTextPart(&b, altBoundary, "text/html; charset=\"utf-8\"", htmlBody)
-		b.WriteString(fmt.Sprintf("--%s--\r\n", altBoundary))
-	case hasHTML && !hasPlain:
-		b.WriteString("Content-Type: text/html; charset=\"utf-8\"\r\n")
-		b.WriteString("Content-Transfer-Encoding: 7bit\r\n\r\n")
-		writeBodyWithTrailingCRLF(&b, htmlBody)
-	default:
-		b.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
-		b.WriteString("Content-Transfer-Encoding: 7bit\r\n\r\n")
-		writeBodyWithTrailingCRLF(&b, plainBody)
+	if err := writeBodyEntity(&b, plainBody, htmlBody, opts.InlineImages); err != nil {
+		return nil, err
 	}
 
 	// Attachments
 	for _, a := range opts.Attachments {
-		if a.Filename == "" {
-			a.Filename = filepath.Base(a.Path)
-		}
-		if a.MIMEType == "" {
-			a.MIMEType = mime.TypeByExtension(strings.ToLower(filepath.Ext(a.Filename)))
-			if a.MIMEType == "" {
-				a.MIMEType = "application/octet-stream"
-			}
-		}
-		if len(a.Data) == 0 {
-			data, err := os.ReadFile(a.Path)
-			if err != nil {
-				return nil, err
-			}
-			a.Data = data
+		a, err := a.resolved()
+		if err != nil {
+			return nil, err
 		}
 
 		b.WriteString(fmt.Sprintf("\r\n--%s\r\n", mixedBoundary))
 		b.WriteString(fmt.Sprintf("Content-Type: %s\r\n", a.MIMEType))
+		if normalizeMimeType(a.MIMEType) == "message/rfc822" {
+			// RFC 2046 5.2.1: message/rfc822 parts must not be base64-encoded.
+			b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+			b.WriteString(fmt.Sprintf("Content-Disposition: attachment; %s\r\n\r\n", contentDispositionFilename(a.Filename)))
+			writeBodyWithTrailingCRLF(&b, normalizeCRLF(string(a.Data)))
+			continue
+		}
 		b.WriteString("Content-Transfer-Encoding: base64\r\n")
 		b.WriteString(fmt.Sprintf("Content-Disposition: attachment; %s\r\n\r\n", contentDispositionFilename(a.Filename)))
 		b.WriteString(wrapBase64(a.Data))
@@ -210,6 +190,79 @@ func buildRFC822(opts mailOptions, cfg *rfc822Config) ([]byte, error) {
 	return b.Bytes(), nil
 }
 
+// writeBodyEntity writes the Content-Type headers and content of the message
+// body: a single text part, multipart/alternative for plain+HTML, and
+// multipart/related around the HTML when it references inline images.
+func writeBodyEntity(b *bytes.Buffer, plainBody, htmlBody string, inline []mailAttachment) error {
+	hasPlain := strings.TrimSpace(plainBody) != ""
+	hasHTML := strings.TrimSpace(htmlBody) != ""
+	if len(inline) > 0 && !hasHTML {
+		return errors.New("inline images require an HTML body")
+	}
+
+	switch {
+	case hasPlain && hasHTML:
+		altBoundary, err := randomBoundary()
+		if err != nil {
+			return err
+		}
+		writeHeader(b, "Content-Type", fmt.Sprintf("multipart/alternative; boundary=%q", altBoundary))
+		b.WriteString("\r\n")
+		writeTextPart(b, altBoundary, "text/plain; charset=\"utf-8\"", plainBody)
+		if len(inline) > 0 {
+			_, _ = fmt.Fprintf(b, "--%s\r\n", altBoundary)
+			if err := writeRelatedEntity(b, htmlBody, inline); err != nil {
+				return err
+			}
+		} else {
+			writeTextPart(b, altBoundary, "text/html; charset=\"utf-8\"", htmlBody)
+		}
+		_, _ = fmt.Fprintf(b, "--%s--\r\n", altBoundary)
+	case hasHTML && len(inline) > 0:
+		return writeRelatedEntity(b, htmlBody, inline)
+	case hasHTML:
+		writeHeader(b, "Content-Type", "text/html; charset=\"utf-8\"")
+		writeHeader(b, "Content-Transfer-Encoding", "7bit")
+		b.WriteString("\r\n")
+		writeBodyWithTrailingCRLF(b, htmlBody)
+	default:
+		writeHeader(b, "Content-Type", "text/plain; charset=\"utf-8\"")
+		writeHeader(b, "Content-Transfer-Encoding", "7bit")
+		b.WriteString("\r\n")
+		writeBodyWithTrailingCRLF(b, plainBody)
+	}
+	return nil
+}
+
+func writeRelatedEntity(b *bytes.Buffer, htmlBody string, inline []mailAttachment) error {
+	relBoundary, err := randomBoundary()
+	if err != nil {
+		return err
+	}
+	writeHeader(b, "Content-Type", fmt.Sprintf("multipart/related; boundary=%q; type=\"text/html\"", relBoundary))
+	b.WriteString("\r\n")
+	writeTextPart(b, relBoundary, "text/html; charset=\"utf-8\"", htmlBody)
+	for _, a := range inline {
+		a, err := a.resolved()
+		if err != nil {
+			return err
+		}
+		if err := validateHeaderValue(a.ContentID); err != nil || strings.TrimSpace(a.ContentID) == "" {
+			return fmt.Errorf("invalid Content-ID for inline image %s", a.Filename)
+		}
+		_, _ = fmt.Fprintf(b, "--%s\r\n", relBoundary)
+		writeHeader(b, "Content-Type", a.MIMEType)
+		writeHeader(b, "Content-Transfer-Encoding", "base64")
+		writeHeader(b, "Content-ID", "<"+a.ContentID+">")
+		writeHeader(b, "Content-Disposition", "inline; "+contentDispositionFilename(a.Filename))
+		b.WriteString("\r\n")
+		b.WriteString(wrapBase64(a.Data))
+		b.WriteString("\r\n")
+	}
+	_, _ = fmt.Fprintf(b, "--%s--\r\n", relBoundary)
+	return nil
+}
+
 func writeHeader(b *bytes.Buffer, name, value string) {
 	b.WriteString(name)
 	b.WriteString(": ")
@@ -1,6 +1,11 @@
 package cmd
 
 import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
 	"regexp"
 	"strings"
 	"testing"
@@ -255,3 +260,57 @@ func TestRandomMessageID(t *testing.T) {
 		t.Fatalf("unexpected: %q", id)
 	}
 }
+
+func TestBuildRFC822InlineImagesRelated(t *testing.T) {
+	raw, err := buildRFC822(mailOptions{
+		From:         "a@b.com",
+		To:           []string{"c@d.com"},
+		Subject:      "Hi",
+		Body:         "Plain",
+		BodyHTML:     `<p><img src="cid:img1@x"></p>`,
+		InlineImages: []mailAttachment{{Filename: "logo.png", Data: []byte("PNG"), ContentID: "img1@x"}},
+		Attachments:  []mailAttachment{{Filename: "x.txt", MIMEType: "text/plain", Data: []byte("abc")}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	var walk func(contentType string, body io.Reader, depth int) []string
+	walk = func(contentType string, body io.Reader, depth int) []string {
+		mediaType, params, _ := mime.ParseMediaType(contentType)
+		out := []string{strings.Repeat(" ", depth) + mediaType}
+		if !strings.HasPrefix(mediaType, "multipart/") {
+			return out
+		}
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if err != nil {
+				break
+			}
+			if cid := part.Header.Get("Content-ID"); cid != "" {
+				out = append(out, strings.Repeat(" ", depth+1)+"cid "+cid+" "+part.Header.Get("Content-Disposition"))
+			}
+			out = append(out, walk(part.Header.Get("Content-Type"), part, depth+1)...)
+		}
+		return out
+	}
+	got := strings.Join(walk(msg.Header.Get("Content-Type"), msg.Body, 0), "\n")
+	want := strings.Join([]string{
+		"multipart/mixed",
+		" multipart/alternative",
+		"  text/plain",
+		"  multipart/related",
+		"   text/html",
+		`   cid <img1@x> inline; filename="logo.png"`,
+		"   image/png",
+		" text/plain",
+	}, "\n")
+	if got != want {
+		t.Fatalf("unexpected structure:\n%s\nwant:\n%s", got, want)
+	}
+}
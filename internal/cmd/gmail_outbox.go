@@ -0,0 +1,530 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/api/gmail/v1"
+
+	"github.com/steipete/gogcli/internal/config"
+	"github.com/steipete/gogcli/internal/outfmt"
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+const (
+	outboxStatusScheduled = "scheduled"
+	outboxStatusSent      = "sent"
+	outboxStatusFailed    = "failed"
+	outboxStatusCancelled = "cancelled"
+
+	gmailOutboxStateName = "outbox.json"
+	gmailOutboxLockName  = "outbox.lock"
+
+	gmailOutboxLockStale = 15 * time.Minute
+	gmailOutboxRetryBase = time.Minute
+	gmailOutboxRetryMax  = time.Hour
+)
+
+var (
+	outboxNow           = time.Now
+	gmailOutboxLockWait = 5 * time.Second
+	errOutboxLocked     = errors.New("gmail outbox is locked by another process")
+	errOutboxNotFound   = errors.New("outbox item not found")
+)
+
+type GmailOutboxCmd struct {
+	List       GmailOutboxListCmd       `cmd:"" name:"list" default:"withargs" help:"List scheduled messages"`
+	Run        GmailOutboxRunCmd        `cmd:"" name:"run" help:"Send due messages (run from cron/systemd)"`
+	Cancel     GmailOutboxCancelCmd     `cmd:"" name:"cancel" help:"Cancel a scheduled message"`
+	Reschedule GmailOutboxRescheduleCmd `cmd:"" name:"reschedule" help:"Change when a scheduled message is sent"`
+}
+
+// gmailOutboxItem is one scheduled send. ID is the Gmail draft ID that holds
+// the message until it is due.
+type gmailOutboxItem struct {
+	ID            string     `json:"id"`
+	Account       string     `json:"account"`
+	To            string     `json:"to,omitempty"`
+	Subject       string     `json:"subject,omitempty"`
+	SendAt        time.Time  `json:"send_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts,omitempty"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	MessageID     string     `json:"message_id,omitempty"`
+	ThreadID      string     `json:"thread_id,omitempty"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+}
+
+type gmailOutboxState struct {
+	Items []gmailOutboxItem `json:"items"`
+}
+
+func (s *gmailOutboxState) find(id string) *gmailOutboxItem {
+	for i := range s.Items {
+		if s.Items[i].ID == id {
+			return &s.Items[i]
+		}
+	}
+	return nil
+}
+
+func gmailOutboxPaths() (string, string, error) {
+	dir, err := config.EnsureGmailOutboxDir()
+	if err != nil {
+		return "", "", err
+	}
+	return filepath.Join(dir, gmailOutboxStateName), filepath.Join(dir, gmailOutboxLockName), nil
+}
+
+func readGmailOutbox(path string) (gmailOutboxState, error) {
+	var state gmailOutboxState
+	data, err := os.ReadFile(path) //nolint:gosec // config dir
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return state, nil
+		}
+		return state, fmt.Errorf("read outbox: %w", err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("parse outbox %s: %w", path, err)
+	}
+	return state, nil
+}
+
+func writeGmailOutbox(path string, state gmailOutboxState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode outbox: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("write outbox: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("commit outbox: %w", err)
+	}
+	return nil
+}
+
+// updateGmailOutbox runs fn on the outbox under an exclusive lock file and
+// saves the result. Overlapping cron runs therefore never send twice; a lock
+// left behind by a crashed process is broken once it is older than
+// gmailOutboxLockStale.
+func updateGmailOutbox(fn func(*gmailOutboxState) error) error {
+	return withGmailOutbox(func(s *gmailOutboxState, _ func() error) error { return fn(s) })
+}
+
+// withGmailOutbox is updateGmailOutbox for long runs: fn also gets a
+// checkpoint func that saves the state so far and refreshes the lock, so a
+// crash loses at most the item in flight and a live lock never looks stale.
+func withGmailOutbox(fn func(state *gmailOutboxState, checkpoint func() error) error) error {
+	path, lockPath, err := gmailOutboxPaths()
+	if err != nil {
+		return err
+	}
+	unlock, err := lockGmailOutbox(lockPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	state, err := readGmailOutbox(path)
+	if err != nil {
+		return err
+	}
+	checkpoint := func() error {
+		if err := writeGmailOutbox(path, state); err != nil {
+			return err
+		}
+		now := time.Now()
+		if err := os.Chtimes(lockPath, now, now); err != nil {
+			return fmt.Errorf("refresh outbox lock: %w", err)
+		}
+		return nil
+	}
+	fnErr := fn(&state, checkpoint)
+	if err := writeGmailOutbox(path, state); err != nil {
+		return err
+	}
+	return fnErr
+}
+
+func lockGmailOutbox(lockPath string) (func(), error) {
+	deadline := time.Now().Add(gmailOutboxLockWait)
+	for {
+		f, err := os.OpenFile(lockPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600) //nolint:gosec // config dir
+		if err == nil {
+			_, _ = fmt.Fprintf(f, "%d\n", os.Getpid())
+			_ = f.Close()
+			return func() { _ = os.Remove(lockPath) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("lock outbox: %w", err)
+		}
+		if info, statErr := os.Stat(lockPath); statErr == nil && time.Since(info.ModTime()) > gmailOutboxLockStale {
+			_ = os.Remove(lockPath)
+			continue
+		}
+		if time.Now().After(deadline) {
+			holder, _ := os.ReadFile(lockPath) //nolint:gosec // config dir
+			return nil, fmt.Errorf("%w (pid %s; remove %s if it is stale)", errOutboxLocked, strings.TrimSpace(string(holder)), lockPath)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// schedule saves the message as a draft and queues it in the outbox.
+func (c *GmailSendCmd) schedule(ctx context.Context, u *ui.UI, account string, body string, inline []mailAttachment) error {
+	switch {
+	case c.ReplyAll:
+		return usage("--at cannot be combined with --reply-all")
+	case c.Track || c.TrackSplit:
+		return usage("--at cannot be combined with --track")
+	}
+
+	sendAt, err := parseTimeExpr(c.At, outboxNow(), time.Local)
+	if err != nil {
+		return usagef("invalid --at: %v", err)
+	}
+	if !sendAt.After(outboxNow()) {
+		return usagef("--at must be in the future (got %s)", sendAt.Format(time.RFC3339))
+	}
+
+	svc, err := newGmailService(ctx, account)
+	if err != nil {
+		return err
+	}
+
+	msg, threadID, err := buildDraftMessage(ctx, svc, account, draftComposeInput{
+		To:               c.To,
+		Cc:               c.Cc,
+		Bcc:              c.Bcc,
+		Subject:          c.Subject,
+		Body:             body,
+		BodyHTML:         c.BodyHTML,
+		ReplyToMessageID: c.ReplyToMessageID,
+		ReplyToThreadID:  c.ThreadID,
+		ReplyTo:          c.ReplyTo,
+		Attach:           c.Attach,
+		From:             c.From,
+		InlineImages:     inline,
+	})
+	if err != nil {
+		return err
+	}
+	draft, err := svc.Users.Drafts.Create("me", &gmail.Draft{Message: msg}).Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+
+	item := gmailOutboxItem{
+		ID:        draft.Id,
+		Account:   account,
+		To:        c.To,
+		Subject:   c.Subject,
+		SendAt:    sendAt.UTC(),
+		CreatedAt: outboxNow().UTC(),
+		Status:    outboxStatusScheduled,
+	}
+	if err := updateGmailOutbox(func(s *gmailOutboxState) error {
+		s.Items = append(s.Items, item)
+		return nil
+	}); err != nil {
+		return fmt.Errorf("draft %s created but not scheduled: %w", draft.Id, err)
+	}
+
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(os.Stdout, map[string]any{
+			"draftId":  draft.Id,
+			"threadId": threadID,
+			"sendAt":   sendAt.Format(time.RFC3339),
+			"status":   outboxStatusScheduled,
+		})
+	}
+	u.Out().Printf("draft_id\t%s", draft.Id)
+	u.Out().Printf("send_at\t%s", sendAt.Format(time.RFC3339))
+	if threadID != "" {
+		u.Out().Printf("thread_id\t%s", threadID)
+	}
+	u.Err().Println("Scheduled. Run `gog gmail outbox run` from cron or a systemd timer to deliver it.")
+	return nil
+}
+
+type GmailOutboxListCmd struct {
+	All bool `name:"all" help:"Include sent and cancelled messages"`
+}
+
+func (c *GmailOutboxListCmd) Run(ctx context.Context) error {
+	u := ui.FromContext(ctx)
+	path, _, err := gmailOutboxPaths()
+	if err != nil {
+		return err
+	}
+	state, err := readGmailOutbox(path)
+	if err != nil {
+		return err
+	}
+
+	items := make([]gmailOutboxItem, 0, len(state.Items))
+	for _, it := range state.Items {
+		if c.All || it.Status == outboxStatusScheduled || it.Status == outboxStatusFailed {
+			items = append(items, it)
+		}
+	}
+
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(os.Stdout, map[string]any{"items": items})
+	}
+	if len(items) == 0 {
+		u.Err().Println("Outbox is empty")
+		return nil
+	}
+
+	w, flush := tableWriter(ctx)
+	defer flush()
+	_, _ = fmt.Fprintln(w, "ID\tACCOUNT\tSEND_AT\tSTATUS\tATTEMPTS\tTO\tSUBJECT")
+	for _, it := range items {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			it.ID, it.Account, it.SendAt.Local().Format("2006-01-02 15:04"), it.Status, it.Attempts, it.To, it.Subject)
+	}
+	return nil
+}
+
+type GmailOutboxRunCmd struct {
+	MaxAttempts int `name:"max-attempts" help:"Give up on a message after this many failed sends" default:"5"`
+}
+
+type outboxRunResult struct {
+	ID        string `json:"id"`
+	Account   string `json:"account"`
+	Status    string `json:"status"`
+	MessageID string `json:"messageId,omitempty"`
+	Error     string `json:"error,omitempty"`
+	RetryAt   string `json:"retryAt,omitempty"`
+}
+
+func (c *GmailOutboxRunCmd) Run(ctx context.Context) error {
+	u := ui.FromContext(ctx)
+	if c.MaxAttempts < 1 {
+		return usage("--max-attempts must be >= 1")
+	}
+
+	var results []outboxRunResult
+	err := withGmailOutbox(func(s *gmailOutboxState, checkpoint func() error) error {
+		now := outboxNow()
+		services := map[string]*gmail.Service{}
+		for i := range s.Items {
+			it := &s.Items[i]
+			if it.Status != outboxStatusScheduled || it.SendAt.After(now) {
+				continue
+			}
+			if it.NextAttemptAt != nil && it.NextAttemptAt.After(now) {
+				continue
+			}
+			results = append(results, c.deliver(ctx, services, it, now))
+			if err := checkpoint(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, r := range results {
+		if r.Status == outboxStatusFailed {
+			failed++
+		}
+	}
+
+	if outfmt.IsJSON(ctx) {
+		if err := outfmt.WriteJSON(os.Stdout, map[string]any{"processed": len(results), "results": results}); err != nil {
+			return err
+		}
+	} else {
+		if len(results) == 0 {
+			u.Err().Println("Nothing due")
+		}
+		for _, r := range results {
+			switch {
+			case r.MessageID != "":
+				u.Out().Printf("%s\t%s\tsent\t%s", r.ID, r.Account, r.MessageID)
+			case r.RetryAt != "":
+				u.Out().Printf("%s\t%s\tretry at %s\t%s", r.ID, r.Account, r.RetryAt, r.Error)
+			default:
+				u.Out().Printf("%s\t%s\t%s\t%s", r.ID, r.Account, r.Status, r.Error)
+			}
+		}
+	}
+
+	if failed > 0 {
+		return &ExitError{Code: 1, Err: fmt.Errorf("%d scheduled message(s) failed permanently", failed)}
+	}
+	return nil
+}
+
+// deliver sends one due draft and updates the item in place. Failures back
+// off exponentially; a missing draft (deleted in Gmail) fails immediately.
+func (c *GmailOutboxRunCmd) deliver(ctx context.Context, services map[string]*gmail.Service, it *gmailOutboxItem, now time.Time) outboxRunResult {
+	res := outboxRunResult{ID: it.ID, Account: it.Account}
+
+	svc, ok := services[it.Account]
+	var err error
+	if !ok {
+		svc, err = newGmailService(ctx, it.Account)
+		if err == nil {
+			services[it.Account] = svc
+		}
+	}
+
+	var sent *gmail.Message
+	if err == nil {
+		sent, err = svc.Users.Drafts.Send("me", &gmail.Draft{Id: it.ID}).Context(ctx).Do()
+	}
+	if err == nil {
+		sentAt := now.UTC()
+		it.Status = outboxStatusSent
+		it.SentAt = &sentAt
+		it.MessageID = sent.Id
+		it.ThreadID = sent.ThreadId
+		it.NextAttemptAt = nil
+		it.LastError = ""
+		res.Status = outboxStatusSent
+		res.MessageID = sent.Id
+		return res
+	}
+
+	it.Attempts++
+	it.LastError = err.Error()
+	res.Error = it.LastError
+	if isNotFoundAPIError(err) || it.Attempts >= c.MaxAttempts {
+		it.Status = outboxStatusFailed
+		it.NextAttemptAt = nil
+		res.Status = outboxStatusFailed
+		return res
+	}
+
+	backoff := gmailOutboxRetryBase << (it.Attempts - 1)
+	if backoff > gmailOutboxRetryMax {
+		backoff = gmailOutboxRetryMax
+	}
+	retryAt := now.Add(backoff).UTC()
+	it.NextAttemptAt = &retryAt
+	res.Status = outboxStatusScheduled
+	res.RetryAt = retryAt.Format(time.RFC3339)
+	return res
+}
+
+type GmailOutboxCancelCmd struct {
+	ID          string `arg:"" name:"id" help:"Outbox item (draft) ID"`
+	DeleteDraft bool   `name:"delete-draft" help:"Also delete the Gmail draft (default keeps it in Drafts)"`
+}
+
+func (c *GmailOutboxCancelCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	id := strings.TrimSpace(c.ID)
+	if id == "" {
+		return usage("empty id")
+	}
+	// Ask before touching the outbox, so declining leaves the send scheduled.
+	if c.DeleteDraft {
+		if confirmErr := confirmDestructive(ctx, flags, fmt.Sprintf("cancel scheduled send %s and delete gmail draft %s", id, id)); confirmErr != nil {
+			return confirmErr
+		}
+	}
+
+	var item gmailOutboxItem
+	err := updateGmailOutbox(func(s *gmailOutboxState) error {
+		it := s.find(id)
+		if it == nil {
+			return usagef("%v: %s", errOutboxNotFound, id)
+		}
+		if it.Status != outboxStatusScheduled && it.Status != outboxStatusFailed {
+			return usagef("outbox item %s is already %s", id, it.Status)
+		}
+		it.Status = outboxStatusCancelled
+		it.NextAttemptAt = nil
+		item = *it
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	draftDeleted := false
+	if c.DeleteDraft {
+		svc, svcErr := newGmailService(ctx, item.Account)
+		if svcErr != nil {
+			return svcErr
+		}
+		if delErr := svc.Users.Drafts.Delete("me", id).Context(ctx).Do(); delErr != nil && !isNotFoundAPIError(delErr) {
+			return fmt.Errorf("cancelled, but deleting draft failed: %w", delErr)
+		}
+		draftDeleted = true
+	}
+
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(os.Stdout, map[string]any{"id": id, "status": outboxStatusCancelled, "draftDeleted": draftDeleted})
+	}
+	u.Out().Printf("id\t%s", id)
+	u.Out().Printf("status\t%s", outboxStatusCancelled)
+	u.Out().Printf("draft_deleted\t%s", strconv.FormatBool(draftDeleted))
+	return nil
+}
+
+type GmailOutboxRescheduleCmd struct {
+	ID string `arg:"" name:"id" help:"Outbox item (draft) ID"`
+	At string `arg:"" name:"at" help:"New send time (e.g. '2026-11-01 09:00', RFC3339, tomorrow)"`
+}
+
+func (c *GmailOutboxRescheduleCmd) Run(ctx context.Context) error {
+	u := ui.FromContext(ctx)
+	id := strings.TrimSpace(c.ID)
+	if id == "" {
+		return usage("empty id")
+	}
+	sendAt, err := parseTimeExpr(c.At, outboxNow(), time.Local)
+	if err != nil {
+		return usagef("invalid time: %v", err)
+	}
+	if !sendAt.After(outboxNow()) {
+		return usagef("new send time must be in the future (got %s)", sendAt.Format(time.RFC3339))
+	}
+
+	err = updateGmailOutbox(func(s *gmailOutboxState) error {
+		it := s.find(id)
+		if it == nil {
+			return usagef("%v: %s", errOutboxNotFound, id)
+		}
+		if it.Status != outboxStatusScheduled && it.Status != outboxStatusFailed {
+			return usagef("outbox item %s is already %s", id, it.Status)
+		}
+		it.SendAt = sendAt.UTC()
+		it.Status = outboxStatusScheduled
+		it.Attempts = 0
+		it.NextAttemptAt = nil
+		it.LastError = ""
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(os.Stdout, map[string]any{"id": id, "sendAt": sendAt.Format(time.RFC3339), "status": outboxStatusScheduled})
+	}
+	u.Out().Printf("id\t%s", id)
+	u.Out().Printf("send_at\t%s", sendAt.Format(time.RFC3339))
+	return nil
+}
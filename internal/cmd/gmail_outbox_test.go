@@ -0,0 +1,280 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
+
+	"github.com/steipete/gogcli/internal/outfmt"
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+func TestGmailOutbox_ScheduleRunRescheduleCancel(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	now := time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)
+	origNow := outboxNow
+	t.Cleanup(func() { outboxNow = origNow })
+	outboxNow = func() time.Time { return now }
+
+	drafts := 0
+	var sentDrafts []string
+	failSend := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/users/me/drafts/send") && r.Method == http.MethodPost:
+			var d gmail.Draft
+			_ = json.NewDecoder(r.Body).Decode(&d)
+			if failSend {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{"code": 503, "message": "backend error"}})
+				return
+			}
+			sentDrafts = append(sentDrafts, d.Id)
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": "msg-" + d.Id, "threadId": "t-" + d.Id})
+		case strings.HasSuffix(r.URL.Path, "/users/me/drafts") && r.Method == http.MethodPost:
+			drafts++
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": "d" + string(rune('0'+drafts)), "message": map[string]any{"id": "m", "threadId": "t"}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	svc, err := gmail.NewService(context.Background(),
+		option.WithoutAuthentication(),
+		option.WithHTTPClient(srv.Client()),
+		option.WithEndpoint(srv.URL+"/"),
+	)
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	origNew := newGmailService
+	t.Cleanup(func() { newGmailService = origNew })
+	newGmailService = func(context.Context, string) (*gmail.Service, error) { return svc, nil }
+
+	u, err := ui.New(ui.Options{Stdout: os.Stdout, Stderr: os.Stderr, Color: "never"})
+	if err != nil {
+		t.Fatalf("ui.New: %v", err)
+	}
+	ctx := outfmt.WithMode(ui.WithUI(context.Background(), u), outfmt.Mode{JSON: true})
+	flags := &RootFlags{Account: "a@b.com", Force: true}
+
+	run := func(cmd any, args ...string) (map[string]any, error) {
+		t.Helper()
+		var runErr error
+		out := captureStdout(t, func() {
+			runErr = runKong(t, cmd, args, ctx, flags)
+		})
+		var parsed map[string]any
+		if out != "" {
+			if err := json.Unmarshal([]byte(out), &parsed); err != nil {
+				t.Fatalf("json parse: %v\nout=%q", err, out)
+			}
+		}
+		return parsed, runErr
+	}
+
+	if _, err := run(&GmailSendCmd{}, "--to", "x@example.com", "--subject", "Hi", "--body", "b", "--at", "2026-09-30T09:00:00Z"); err == nil || !strings.Contains(err.Error(), "future") {
+		t.Fatalf("expected past --at to be rejected, got %v", err)
+	}
+
+	for _, subject := range []string{"First", "Second"} {
+		out, err := run(&GmailSendCmd{}, "--to", "x@example.com", "--subject", subject, "--body", "b", "--at", "2026-10-01T09:00:00Z")
+		if err != nil {
+			t.Fatalf("schedule: %v", err)
+		}
+		if out["status"] != outboxStatusScheduled || out["sendAt"] != "2026-10-01T09:00:00Z" {
+			t.Fatalf("unexpected schedule output: %#v", out)
+		}
+	}
+
+	listed, err := run(&GmailOutboxListCmd{})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if items, ok := listed["items"].([]any); !ok || len(items) != 2 {
+		t.Fatalf("unexpected list: %#v", listed)
+	}
+
+	// Not due yet.
+	out, err := run(&GmailOutboxRunCmd{})
+	if err != nil || out["processed"] != float64(0) {
+		t.Fatalf("expected nothing due, got %#v (%v)", out, err)
+	}
+
+	// Due, but Gmail fails: both items back off.
+	now = now.Add(2 * time.Hour)
+	out, err = run(&GmailOutboxRunCmd{})
+	if err != nil || out["processed"] != float64(2) {
+		t.Fatalf("expected retry scheduling, got %#v (%v)", out, err)
+	}
+	out, err = run(&GmailOutboxRunCmd{})
+	if err != nil || out["processed"] != float64(0) {
+		t.Fatalf("expected backoff to skip items, got %#v (%v)", out, err)
+	}
+
+	if _, err := run(&GmailOutboxRescheduleCmd{}, "d2", "2026-10-02T09:00:00Z"); err != nil {
+		t.Fatalf("reschedule: %v", err)
+	}
+
+	failSend = false
+	now = now.Add(5 * time.Minute)
+	out, err = run(&GmailOutboxRunCmd{})
+	if err != nil || out["processed"] != float64(1) || len(sentDrafts) != 1 || sentDrafts[0] != "d1" {
+		t.Fatalf("expected d1 to be sent, got %#v sent=%v (%v)", out, sentDrafts, err)
+	}
+
+	if _, err := run(&GmailOutboxCancelCmd{}, "d1"); err == nil || !strings.Contains(err.Error(), "already sent") {
+		t.Fatalf("expected cancel of sent item to fail, got %v", err)
+	}
+	// Declining --delete-draft must leave the send scheduled.
+	flags.Force, flags.NoInput = false, true
+	if _, err := run(&GmailOutboxCancelCmd{}, "d2", "--delete-draft"); err == nil || !strings.Contains(err.Error(), "refusing") {
+		t.Fatalf("expected confirmation to be required, got %v", err)
+	}
+	flags.Force, flags.NoInput = true, false
+	if _, err := run(&GmailOutboxCancelCmd{}, "d2"); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+
+	now = now.Add(48 * time.Hour)
+	out, err = run(&GmailOutboxRunCmd{})
+	if err != nil || out["processed"] != float64(0) {
+		t.Fatalf("cancelled item must not send, got %#v (%v)", out, err)
+	}
+
+	listed, err = run(&GmailOutboxListCmd{}, "--all")
+	if err != nil {
+		t.Fatalf("list --all: %v", err)
+	}
+	items, _ := listed["items"].([]any)
+	if len(items) != 2 {
+		t.Fatalf("unexpected list --all: %#v", listed)
+	}
+	first, _ := items[0].(map[string]any)
+	if first["status"] != outboxStatusSent || first["message_id"] != "msg-d1" {
+		t.Fatalf("unexpected sent item: %#v", first)
+	}
+}
+
+func TestGmailOutboxRun_FailsAfterMaxAttempts(t *testing.T) {
+	now := time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)
+	cmd := &GmailOutboxRunCmd{MaxAttempts: 2}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{"code": 500, "message": "boom"}})
+	}))
+	defer srv.Close()
+
+	svc, err := gmail.NewService(context.Background(),
+		option.WithoutAuthentication(),
+		option.WithHTTPClient(srv.Client()),
+		option.WithEndpoint(srv.URL+"/"),
+	)
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	services := map[string]*gmail.Service{"a@b.com": svc}
+	item := &gmailOutboxItem{ID: "d1", Account: "a@b.com", Status: outboxStatusScheduled}
+
+	res := cmd.deliver(context.Background(), services, item, now)
+	if res.Status != outboxStatusScheduled || item.NextAttemptAt == nil || !item.NextAttemptAt.Equal(now.Add(time.Minute)) {
+		t.Fatalf("expected first retry in 1m, got %#v / %#v", res, item)
+	}
+	res = cmd.deliver(context.Background(), services, item, now)
+	if res.Status != outboxStatusFailed || item.Status != outboxStatusFailed || item.Attempts != 2 {
+		t.Fatalf("expected permanent failure, got %#v / %#v", res, item)
+	}
+}
+
+func TestLockGmailOutbox_TimesOutWhileHeld(t *testing.T) {
+	origWait := gmailOutboxLockWait
+	t.Cleanup(func() { gmailOutboxLockWait = origWait })
+	gmailOutboxLockWait = 200 * time.Millisecond
+
+	lockPath := filepath.Join(t.TempDir(), gmailOutboxLockName)
+	unlock, err := lockGmailOutbox(lockPath)
+	if err != nil {
+		t.Fatalf("lock: %v", err)
+	}
+	defer unlock()
+
+	if _, err := lockGmailOutbox(lockPath); !errors.Is(err, errOutboxLocked) {
+		t.Fatalf("expected lock contention error, got %v", err)
+	}
+}
+
+func TestGmailOutboxRun_CheckpointsEachItem(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	statePath, lockPath, err := gmailOutboxPaths()
+	if err != nil {
+		t.Fatalf("paths: %v", err)
+	}
+	due := time.Date(2026, 10, 1, 7, 0, 0, 0, time.UTC)
+	if err := writeGmailOutbox(statePath, gmailOutboxState{Items: []gmailOutboxItem{
+		{ID: "d1", Account: "a@b.com", SendAt: due, Status: outboxStatusScheduled},
+		{ID: "d2", Account: "a@b.com", SendAt: due, Status: outboxStatusScheduled},
+	}}); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	sends := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var d gmail.Draft
+		_ = json.NewDecoder(r.Body).Decode(&d)
+		sends++
+		switch sends {
+		case 1:
+			// Age the lock as if the first send took a long time.
+			old := time.Now().Add(-time.Hour)
+			_ = os.Chtimes(lockPath, old, old)
+		case 2:
+			state, _ := readGmailOutbox(statePath)
+			if it := state.find("d1"); it == nil || it.Status != outboxStatusSent {
+				t.Errorf("d1 should be saved as sent before d2 is sent: %#v", it)
+			}
+			if info, statErr := os.Stat(lockPath); statErr != nil || time.Since(info.ModTime()) > time.Minute {
+				t.Errorf("lock should be refreshed between items: %v", statErr)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"id": "msg-" + d.Id, "threadId": "t-" + d.Id})
+	}))
+	defer srv.Close()
+
+	svc, err := gmail.NewService(context.Background(),
+		option.WithoutAuthentication(),
+		option.WithHTTPClient(srv.Client()),
+		option.WithEndpoint(srv.URL+"/"),
+	)
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	origNew, origNow := newGmailService, outboxNow
+	t.Cleanup(func() { newGmailService, outboxNow = origNew, origNow })
+	newGmailService = func(context.Context, string) (*gmail.Service, error) { return svc, nil }
+	outboxNow = func() time.Time { return due.Add(time.Hour) }
+
+	out := runSendLike(t, &GmailOutboxRunCmd{}, "--max-attempts", "3")
+	if out["processed"] != float64(2) || sends != 2 {
+		t.Fatalf("unexpected run output: %#v (sends=%d)", out, sends)
+	}
+}
@@ -0,0 +1,262 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/mail"
+	"regexp"
+	"strings"
+
+	"google.golang.org/api/gmail/v1"
+
+	"github.com/steipete/gogcli/internal/config"
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+type GmailReplyCmd struct {
+	MessageID string   `arg:"" name:"messageId" help:"Message ID to reply to"`
+	All       bool     `name:"all" help:"Reply to all recipients of the original message"`
+	Cc        string   `name:"cc" help:"Additional CC recipients (comma-separated)"`
+	Bcc       string   `name:"bcc" help:"BCC recipients (comma-separated)"`
+	Body      string   `name:"body" help:"Reply text (plain text)"`
+	BodyFile  string   `name:"body-file" help:"Reply text file path (plain text; '-' for stdin)"`
+	BodyHTML  string   `name:"body-html" help:"Reply text (HTML; optional)"`
+	Attach    []string `name:"attach" help:"Attachment file path (repeatable)"`
+	From      string   `name:"from" help:"Send from this email address (must be a verified send-as alias)"`
+	Quote     bool     `name:"quote" help:"Quote the original message below the reply" default:"true" negatable:""`
+}
+
+func (c *GmailReplyCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+	messageID := strings.TrimSpace(c.MessageID)
+	if messageID == "" {
+		return usage("empty messageId")
+	}
+	body, err := resolveBodyInput(c.Body, c.BodyFile)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(body) == "" && strings.TrimSpace(c.BodyHTML) == "" {
+		return usage("required: --body, --body-file, or --body-html")
+	}
+	atts, err := attachmentsFromPaths(c.Attach)
+	if err != nil {
+		return err
+	}
+
+	svc, err := newGmailService(ctx, account)
+	if err != nil {
+		return err
+	}
+	fromAddr, sendingEmail, err := resolveSendFrom(ctx, svc, account, c.From)
+	if err != nil {
+		return err
+	}
+
+	orig, err := svc.Users.Messages.Get("me", messageID).Format("full").Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+	info := replyInfoFromMessage(orig)
+
+	var to, cc []string
+	if c.All {
+		to, cc = buildReplyAllRecipients(info, sendingEmail)
+	} else {
+		to = replyRecipients(info, sendingEmail)
+	}
+	cc = deduplicateAddresses(append(cc, splitCSV(c.Cc)...))
+	if len(to) == 0 {
+		return usage("original message has no usable reply address")
+	}
+
+	plain, htmlBody := body, c.BodyHTML
+	if c.Quote {
+		q := quotedMessageFromPart(orig.Payload)
+		plain, htmlBody = q.replyBodies(body, c.BodyHTML)
+	}
+
+	results, err := sendGmailBatches(ctx, svc, sendMessageOptions{
+		FromAddr:    fromAddr,
+		Subject:     prefixedSubject("Re:", headerValue(orig.Payload, "Subject"), "re:"),
+		Body:        plain,
+		BodyHTML:    htmlBody,
+		ReplyInfo:   info,
+		Attachments: atts,
+	}, []sendBatch{{To: to, Cc: cc, Bcc: splitCSV(c.Bcc)}})
+	if err != nil {
+		return err
+	}
+	return writeSendResults(ctx, u, fromAddr, results)
+}
+
+// replyRecipients picks the address a plain reply goes to: Reply-To, else
+// From. Replying to a message we sent ourselves goes to its recipients
+// instead, like the Gmail web UI does.
+func replyRecipients(info *replyInfo, selfEmail string) []string {
+	addr := info.ReplyToAddr
+	if strings.TrimSpace(addr) == "" {
+		addr = info.FromAddr
+	}
+	to := filterOutSelf(parseEmailAddresses(addr), selfEmail)
+	if len(to) == 0 {
+		to = filterOutSelf(info.ToAddrs, selfEmail)
+	}
+	return deduplicateAddresses(to)
+}
+
+func attachmentsFromPaths(paths []string) ([]mailAttachment, error) {
+	atts := make([]mailAttachment, 0, len(paths))
+	for _, p := range paths {
+		expanded, err := config.ExpandPath(p)
+		if err != nil {
+			return nil, err
+		}
+		atts = append(atts, mailAttachment{Path: expanded})
+	}
+	return atts, nil
+}
+
+// prefixedSubject adds prefix to subject unless it already starts with one of
+// the (lowercase) known prefixes.
+func prefixedSubject(prefix string, subject string, known ...string) string {
+	subject = strings.TrimSpace(subject)
+	lower := strings.ToLower(subject)
+	for _, k := range known {
+		if strings.HasPrefix(lower, k) {
+			return subject
+		}
+	}
+	if subject == "" {
+		return prefix
+	}
+	return prefix + " " + subject
+}
+
+// quotedMessage is the part of an original message that replies and forwards
+// carry along.
+type quotedMessage struct {
+	From    string
+	To      string
+	Cc      string
+	Date    string
+	Subject string
+	Plain   string
+	HTML    string
+}
+
+func quotedMessageFromPart(p *gmail.MessagePart) quotedMessage {
+	q := quotedMessage{
+		From:    headerValue(p, "From"),
+		To:      headerValue(p, "To"),
+		Cc:      headerValue(p, "Cc"),
+		Date:    headerValue(p, "Date"),
+		Subject: headerValue(p, "Subject"),
+		Plain:   findPartBody(p, "text/plain"),
+		HTML:    findPartBody(p, "text/html"),
+	}
+	if strings.TrimSpace(q.Plain) == "" && q.HTML != "" {
+		q.Plain = htmlToPlainText(q.HTML)
+	}
+	return q
+}
+
+// attribution is the "On <date>, <sender> wrote:" line above a quote.
+func (q quotedMessage) attribution() string {
+	date := strings.TrimSpace(q.Date)
+	if t, err := mail.ParseDate(date); err == nil {
+		date = t.Format("Mon, Jan 2, 2006 at 3:04 PM")
+	}
+	switch {
+	case date != "" && q.From != "":
+		return fmt.Sprintf("On %s, %s wrote:", date, q.From)
+	case q.From != "":
+		return q.From + " wrote:"
+	default:
+		return "The original message:"
+	}
+}
+
+// replyBodies appends the quoted original to both the plain and HTML reply.
+// An HTML part is always produced so HTML clients get a collapsible quote.
+func (q quotedMessage) replyBodies(body, bodyHTML string) (string, string) {
+	attr := q.attribution()
+
+	var plain strings.Builder
+	plain.WriteString(strings.TrimRight(body, "\r\n"))
+	plain.WriteString("\n\n")
+	plain.WriteString(attr)
+	plain.WriteString("\n")
+	for _, line := range strings.Split(strings.TrimRight(normalizeNewlines(q.Plain), "\n"), "\n") {
+		if line == "" || strings.HasPrefix(line, ">") {
+			plain.WriteString(">" + line + "\n")
+			continue
+		}
+		plain.WriteString("> " + line + "\n")
+	}
+
+	if strings.TrimSpace(bodyHTML) == "" {
+		bodyHTML = plainToHTML(body)
+	}
+	htmlBody := fmt.Sprintf(
+		"%s<br><div class=\"gmail_quote\"><div dir=\"ltr\" class=\"gmail_attr\">%s<br></div>"+
+			"<blockquote class=\"gmail_quote\" style=\"margin:0px 0px 0px 0.8ex;border-left:1px solid rgb(204,204,204);padding-left:1ex\">%s</blockquote></div>",
+		bodyHTML, html.EscapeString(attr), q.htmlOrPlain())
+	return plain.String(), htmlBody
+}
+
+func (q quotedMessage) htmlOrPlain() string {
+	if strings.TrimSpace(q.HTML) != "" {
+		return htmlBodyContent(q.HTML)
+	}
+	return plainToHTML(q.Plain)
+}
+
+var (
+	htmlBodyPattern    = regexp.MustCompile(`(?is)<body[^>]*>(.*)</body>`)
+	htmlBreakPattern   = regexp.MustCompile(`(?i)<br\s*/?>|</(p|div|li|tr|h[1-6]|blockquote)>`)
+	blankLinesPattern  = regexp.MustCompile(`\n{3,}`)
+	trailingWSPattern  = regexp.MustCompile(`[ \t]+\n`)
+	leadingWSPattern   = regexp.MustCompile(`\n[ \t]+`)
+	inlineSpacePattern = regexp.MustCompile(`[ \t]{2,}`)
+)
+
+// htmlBodyContent returns what is inside <body>, so a quoted document does not
+// nest <html> elements.
+func htmlBodyContent(s string) string {
+	if m := htmlBodyPattern.FindStringSubmatch(s); m != nil {
+		return m[1]
+	}
+	return s
+}
+
+// htmlToPlainText is a small text rendering of an HTML body that keeps line
+// structure (unlike stripHTMLTags, which collapses everything to one line).
+func htmlToPlainText(s string) string {
+	s = scriptPattern.ReplaceAllString(s, "")
+	s = stylePattern.ReplaceAllString(s, "")
+	s = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ").Replace(s)
+	s = htmlBreakPattern.ReplaceAllString(s, "\n")
+	s = htmlTagPattern.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+	s = strings.ReplaceAll(s, " ", " ")
+	s = inlineSpacePattern.ReplaceAllString(s, " ")
+	s = trailingWSPattern.ReplaceAllString(s, "\n")
+	s = leadingWSPattern.ReplaceAllString(s, "\n")
+	s = blankLinesPattern.ReplaceAllString(s, "\n\n")
+	return strings.TrimSpace(s)
+}
+
+func plainToHTML(s string) string {
+	s = strings.TrimRight(normalizeNewlines(s), "\n")
+	return strings.ReplaceAll(html.EscapeString(s), "\n", "<br>")
+}
+
+func normalizeNewlines(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "\r\n", "\n"), "\r", "\n")
+}
@@ -0,0 +1,198 @@
+package cmd
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
+
+	"github.com/steipete/gogcli/internal/outfmt"
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+func b64url(s string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+// newReplyTestService serves one original message (m1) with an HTML body and
+// a PDF attachment, and records every sent raw message.
+func newReplyTestService(t *testing.T) (*[]string, func()) {
+	t.Helper()
+
+	var sent []string
+	original := map[string]any{
+		"id":       "m1",
+		"threadId": "t1",
+		"payload": map[string]any{
+			"mimeType": "multipart/mixed",
+			"headers": []map[string]any{
+				{"name": "From", "value": "Ada <ada@example.com>"},
+				{"name": "To", "value": "a@b.com, bob@example.com"},
+				{"name": "Cc", "value": "cy@example.com"},
+				{"name": "Subject", "value": "Plans"},
+				{"name": "Date", "value": "Mon, 5 Oct 2026 10:30:00 +0200"},
+				{"name": "Message-ID", "value": "<orig@example.com>"},
+			},
+			"parts": []map[string]any{
+				{
+					"mimeType": "multipart/alternative",
+					"parts": []map[string]any{
+						{"mimeType": "text/plain", "body": map[string]any{"data": b64url("Line one\n> earlier\n\nLine three\n")}},
+						{"mimeType": "text/html", "body": map[string]any{"data": b64url("<html><body><p>Line one</p></body></html>")}},
+					},
+				},
+				{"mimeType": "application/pdf", "filename": "plan.pdf", "body": map[string]any{"attachmentId": "att1", "size": 8}},
+			},
+		},
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.Contains(r.URL.Path, "/settings/sendAs/"):
+			http.NotFound(w, r)
+		case strings.HasSuffix(r.URL.Path, "/users/me/messages/m1/attachments/att1"):
+			_ = json.NewEncoder(w).Encode(map[string]any{"data": b64url("%PDF-1.4"), "size": 8})
+		case strings.HasSuffix(r.URL.Path, "/users/me/messages/m1"):
+			if r.URL.Query().Get("format") == "raw" {
+				_ = json.NewEncoder(w).Encode(map[string]any{"id": "m1", "raw": b64url("From: ada@example.com\r\nSubject: Plans\r\n\r\nLine one\r\n")})
+				return
+			}
+			_ = json.NewEncoder(w).Encode(original)
+		case strings.HasSuffix(r.URL.Path, "/users/me/messages/send"):
+			var msg gmail.Message
+			_ = json.NewDecoder(r.Body).Decode(&msg)
+			raw, _ := base64.RawURLEncoding.DecodeString(msg.Raw)
+			sent = append(sent, string(raw))
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": "sent", "threadId": msg.ThreadId})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	svc, err := gmail.NewService(context.Background(),
+		option.WithoutAuthentication(),
+		option.WithHTTPClient(srv.Client()),
+		option.WithEndpoint(srv.URL+"/"),
+	)
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	origNew := newGmailService
+	newGmailService = func(context.Context, string) (*gmail.Service, error) { return svc, nil }
+	return &sent, func() {
+		newGmailService = origNew
+		srv.Close()
+	}
+}
+
+func runSendLike(t *testing.T, cmd any, args ...string) map[string]any {
+	t.Helper()
+
+	u, err := ui.New(ui.Options{Stdout: os.Stdout, Stderr: os.Stderr, Color: "never"})
+	if err != nil {
+		t.Fatalf("ui.New: %v", err)
+	}
+	ctx := outfmt.WithMode(ui.WithUI(context.Background(), u), outfmt.Mode{JSON: true})
+	out := captureStdout(t, func() {
+		if err := runKong(t, cmd, args, ctx, &RootFlags{Account: "a@b.com"}); err != nil {
+			t.Fatalf("run %v: %v", args, err)
+		}
+	})
+	var parsed map[string]any
+	if err := json.Unmarshal([]byte(out), &parsed); err != nil {
+		t.Fatalf("json parse: %v\nout=%q", err, out)
+	}
+	return parsed
+}
+
+func TestGmailReply_QuotesOriginalAndThreads(t *testing.T) {
+	sent, cleanup := newReplyTestService(t)
+	defer cleanup()
+
+	out := runSendLike(t, &GmailReplyCmd{}, "m1", "--body", "Sounds good")
+	if out["threadId"] != "t1" {
+		t.Fatalf("reply not threaded: %#v", out)
+	}
+	if len(*sent) != 1 {
+		t.Fatalf("expected one send, got %d", len(*sent))
+	}
+	raw := strings.ReplaceAll((*sent)[0], "\r\n", "\n")
+	for _, want := range []string{
+		"To: ada@example.com\n",
+		"Subject: Re: Plans\n",
+		"In-Reply-To: <orig@example.com>\n",
+		"Sounds good\n\nOn Mon, Oct 5, 2026 at 10:30 AM, Ada <ada@example.com> wrote:\n> Line one\n>> earlier\n>\n> Line three\n",
+		`<blockquote class="gmail_quote"`,
+		"<p>Line one</p></blockquote>",
+	} {
+		if !strings.Contains(raw, want) {
+			t.Fatalf("reply missing %q:\n%s", want, raw)
+		}
+	}
+	if strings.Contains(raw, "Cc:") || strings.Contains(raw, "<html>") {
+		t.Fatalf("unexpected cc or nested html document:\n%s", raw)
+	}
+
+	runSendLike(t, &GmailReplyCmd{}, "m1", "--all", "--body", "All", "--no-quote")
+	raw = strings.ReplaceAll((*sent)[1], "\r\n", "\n")
+	if !strings.Contains(raw, "To: ada@example.com, bob@example.com\n") || !strings.Contains(raw, "Cc: cy@example.com\n") || strings.Contains(raw, "wrote:") {
+		t.Fatalf("unexpected reply-all:\n%s", raw)
+	}
+}
+
+func TestGmailForward_InlineCarriesAttachments(t *testing.T) {
+	sent, cleanup := newReplyTestService(t)
+	defer cleanup()
+
+	runSendLike(t, &GmailForwardCmd{}, "m1", "--to", "zed@example.com", "--body", "FYI")
+	raw := strings.ReplaceAll((*sent)[0], "\r\n", "\n")
+	for _, want := range []string{
+		"To: zed@example.com\n",
+		"Subject: Fwd: Plans\n",
+		"FYI\n\n---------- Forwarded message ---------\nFrom: Ada <ada@example.com>\n",
+		"Cc: cy@example.com\n\nLine one\n",
+		"Content-Type: application/pdf\n",
+		`filename="plan.pdf"`,
+		base64.StdEncoding.EncodeToString([]byte("%PDF-1.4")),
+	} {
+		if !strings.Contains(raw, want) {
+			t.Fatalf("forward missing %q:\n%s", want, raw)
+		}
+	}
+}
+
+func TestGmailForward_AsAttachment(t *testing.T) {
+	sent, cleanup := newReplyTestService(t)
+	defer cleanup()
+
+	runSendLike(t, &GmailForwardCmd{}, "m1", "--to", "zed@example.com", "--as-attachment")
+	raw := strings.ReplaceAll((*sent)[0], "\r\n", "\n")
+	for _, want := range []string{
+		"Content-Type: message/rfc822\nContent-Transfer-Encoding: 8bit\n",
+		`filename="Plans.eml"`,
+		"From: ada@example.com\nSubject: Plans\n\nLine one\n",
+	} {
+		if !strings.Contains(raw, want) {
+			t.Fatalf("forward missing %q:\n%s", want, raw)
+		}
+	}
+	if strings.Contains(raw, "Forwarded message") || strings.Contains(raw, "application/pdf") {
+		t.Fatalf("as-attachment must not inline the original:\n%s", raw)
+	}
+}
+
+func TestHTMLToPlainText(t *testing.T) {
+	got := htmlToPlainText("<div>Hello&nbsp;<b>there</b></div>\n<p>Second\nline</p><br><style>p{}</style>Bye")
+	want := "Hello there\nSecond line\n\nBye"
+	if got != want {
+		t.Fatalf("htmlToPlainText = %q, want %q", got, want)
+	}
+}
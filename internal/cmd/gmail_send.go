@@ -10,7 +10,6 @@ import (
 
 	"google.golang.org/api/gmail/v1"
 
-	"github.com/steipete/gogcli/internal/config"
 	"github.com/steipete/gogcli/internal/outfmt"
 	"github.com/steipete/gogcli/internal/tracking"
 	"github.com/steipete/gogcli/internal/ui"
@@ -24,6 +23,7 @@ type GmailSendCmd struct {
 	Body             string   `name:"body" help:"Body (plain text; required unless --body-html is set)"`
 	BodyFile         string   `name:"body-file" help:"Body file path (plain text; '-' for stdin)"`
 	BodyHTML         string   `name:"body-html" help:"Body (HTML; optional)"`
+	BodyMD           string   `name:"body-md" help:"Body from a Markdown file ('-' for stdin); rendered to HTML with a plain-text fallback, local images inlined"`
 	ReplyToMessageID string   `name:"reply-to-message-id" aliases:"in-reply-to" help:"Reply to Gmail message ID (sets In-Reply-To/References and thread)"`
 	ThreadID         string   `name:"thread-id" help:"Reply within a Gmail thread (uses latest message for headers)"`
 	ReplyAll         bool     `name:"reply-all" help:"Auto-populate recipients from original message (requires --reply-to-message-id or --thread-id)"`
@@ -32,6 +32,8 @@ type GmailSendCmd struct {
 	From             string   `name:"from" help:"Send from this email address (must be a verified send-as alias)"`
 	Track            bool     `name:"track" help:"Enable open tracking (requires tracking setup)"`
 	TrackSplit       bool     `name:"track-split" help:"Send tracked messages separately per recipient"`
+	Raw              string   `name:"raw" help:"Send a complete RFC 822 message from this file ('-' for stdin); only --to, --from and --thread-id apply"`
+	At               string   `name:"at" help:"Schedule instead of sending now (e.g. '2026-11-01 09:00', RFC3339, tomorrow); delivered by 'gog gmail outbox run'"`
 }
 
 type sendBatch struct {
@@ -49,15 +51,16 @@ type sendResult struct {
 }
 
 type sendMessageOptions struct {
-	FromAddr    string
-	ReplyTo     string
-	Subject     string
-	Body        string
-	BodyHTML    string
-	ReplyInfo   *replyInfo
-	Attachments []mailAttachment
-	Track       bool
-	TrackingCfg *tracking.Config
+	FromAddr     string
+	ReplyTo      string
+	Subject      string
+	Body         string
+	BodyHTML     string
+	InlineImages []mailAttachment
+	ReplyInfo    *replyInfo
+	Attachments  []mailAttachment
+	Track        bool
+	TrackingCfg  *tracking.Config
 }
 
 func (c *GmailSendCmd) Run(ctx context.Context, flags *RootFlags) error {
@@ -67,6 +70,10 @@ func (c *GmailSendCmd) Run(ctx context.Context, flags *RootFlags) error {
 		return err
 	}
 
+	if strings.TrimSpace(c.Raw) != "" {
+		return c.runRaw(ctx, u, account)
+	}
+
 	replyToMessageID := strings.TrimSpace(c.ReplyToMessageID)
 	threadID := strings.TrimSpace(c.ThreadID)
 
@@ -74,6 +81,13 @@ func (c *GmailSendCmd) Run(ctx context.Context, flags *RootFlags) error {
 	if err != nil {
 		return err
 	}
+	// Markdown renders into the plain and HTML bodies; everything downstream
+	// (tracking, scheduling) then sees a regular HTML message.
+	var inline []mailAttachment
+	body, c.BodyHTML, inline, err = resolveComposeBodies(body, c.BodyHTML, c.BodyMD)
+	if err != nil {
+		return err
+	}
 
 	if replyToMessageID != "" && threadID != "" {
 		return usage("use only one of --reply-to-message-id or --thread-id")
@@ -92,44 +106,23 @@ func (c *GmailSendCmd) Run(ctx context.Context, flags *RootFlags) error {
 		return usage("required: --subject")
 	}
 	if strings.TrimSpace(body) == "" && strings.TrimSpace(c.BodyHTML) == "" {
-		return usage("required: --body, --body-file, or --body-html")
+		return usage("required: --body, --body-file, --body-html, or --body-md")
 	}
 	if c.TrackSplit && !c.Track {
 		return usage("--track-split requires --track")
 	}
+	if strings.TrimSpace(c.At) != "" {
+		return c.schedule(ctx, u, account, body, inline)
+	}
 
 	svc, err := newGmailService(ctx, account)
 	if err != nil {
 		return err
 	}
 
-	// Determine the From address
-	fromAddr := account
-	sendingEmail := account // The email we're sending from (without display name)
-	if strings.TrimSpace(c.From) != "" {
-		// Validate that this is a configured send-as alias
-		var sa *gmail.SendAs
-		sa, err = svc.Users.Settings.SendAs.Get("me", c.From).Context(ctx).Do()
-		if err != nil {
-			return fmt.Errorf("invalid --from address %q: %w", c.From, err)
-		}
-		if sa.VerificationStatus != gmailVerificationAccepted {
-			return fmt.Errorf("--from address %q is not verified (status: %s)", c.From, sa.VerificationStatus)
-		}
-		sendingEmail = c.From
-		fromAddr = c.From
-		// Include display name if set
-		if sa.DisplayName != "" {
-			fromAddr = sa.DisplayName + " <" + c.From + ">"
-		}
-	} else {
-		// No --from specified: look up the primary account's send-as settings
-		// to get the display name
-		sa, saErr := svc.Users.Settings.SendAs.Get("me", account).Context(ctx).Do()
-		if saErr == nil && sa.DisplayName != "" {
-			fromAddr = sa.DisplayName + " <" + account + ">"
-		}
-		// If lookup fails, we just use the plain email address (no error)
+	fromAddr, sendingEmail, err := resolveSendFrom(ctx, svc, account, c.From)
+	if err != nil {
+		return err
 	}
 
 	// Fetch reply info (includes recipient headers for reply-all)
@@ -160,13 +153,9 @@ func (c *GmailSendCmd) Run(ctx context.Context, flags *RootFlags) error {
 
 	bccRecipients := splitCSV(c.Bcc)
 
-	atts := make([]mailAttachment, 0, len(c.Attach))
-	for _, p := range c.Attach {
-		expanded, expandErr := config.ExpandPath(p)
-		if expandErr != nil {
-			return expandErr
-		}
-		atts = append(atts, mailAttachment{Path: expanded})
+	atts, err := attachmentsFromPaths(c.Attach)
+	if err != nil {
+		return err
 	}
 
 	var trackingCfg *tracking.Config
@@ -179,15 +168,16 @@ func (c *GmailSendCmd) Run(ctx context.Context, flags *RootFlags) error {
 
 	batches := buildSendBatches(toRecipients, ccRecipients, bccRecipients, c.Track, c.TrackSplit)
 	results, err := sendGmailBatches(ctx, svc, sendMessageOptions{
-		FromAddr:    fromAddr,
-		ReplyTo:     c.ReplyTo,
-		Subject:     c.Subject,
-		Body:        body,
-		BodyHTML:    c.BodyHTML,
-		ReplyInfo:   replyInfo,
-		Attachments: atts,
-		Track:       c.Track,
-		TrackingCfg: trackingCfg,
+		FromAddr:     fromAddr,
+		ReplyTo:      c.ReplyTo,
+		Subject:      c.Subject,
+		Body:         body,
+		BodyHTML:     c.BodyHTML,
+		InlineImages: inline,
+		ReplyInfo:    replyInfo,
+		Attachments:  atts,
+		Track:        c.Track,
+		TrackingCfg:  trackingCfg,
 	}, batches)
 	if err != nil {
 		return err
@@ -196,6 +186,34 @@ func (c *GmailSendCmd) Run(ctx context.Context, flags *RootFlags) error {
 	return writeSendResults(ctx, u, fromAddr, results)
 }
 
+// resolveSendFrom returns the From header value (with display name when the
+// send-as entry has one) and the bare sending address. A non-empty from must
+// be a verified send-as alias.
+func resolveSendFrom(ctx context.Context, svc *gmail.Service, account string, from string) (string, string, error) {
+	from = strings.TrimSpace(from)
+	if from == "" {
+		// No --from specified: look up the primary account's send-as settings
+		// to get the display name. If lookup fails, use the plain address.
+		sa, err := svc.Users.Settings.SendAs.Get("me", account).Context(ctx).Do()
+		if err == nil && sa.DisplayName != "" {
+			return sa.DisplayName + " <" + account + ">", account, nil
+		}
+		return account, account, nil
+	}
+
+	sa, err := svc.Users.Settings.SendAs.Get("me", from).Context(ctx).Do()
+	if err != nil {
+		return "", "", fmt.Errorf("invalid --from address %q: %w", from, err)
+	}
+	if sa.VerificationStatus != gmailVerificationAccepted {
+		return "", "", fmt.Errorf("--from address %q is not verified (status: %s)", from, sa.VerificationStatus)
+	}
+	if sa.DisplayName != "" {
+		return sa.DisplayName + " <" + from + ">", from, nil
+	}
+	return from, from, nil
+}
+
 func (c *GmailSendCmd) resolveTrackingConfig(account string, toRecipients, ccRecipients, bccRecipients []string) (*tracking.Config, error) {
 	totalRecipients := len(toRecipients) + len(ccRecipients) + len(bccRecipients)
 	if totalRecipients != 1 && !c.TrackSplit {
@@ -270,17 +288,18 @@ func sendGmailBatches(ctx context.Context, svc *gmail.Service, opts sendMessageO
 		}
 
 		raw, err := buildRFC822(mailOptions{
-			From:        opts.FromAddr,
-			To:          batch.To,
-			Cc:          batch.Cc,
-			Bcc:         batch.Bcc,
-			ReplyTo:     opts.ReplyTo,
-			Subject:     opts.Subject,
-			Body:        opts.Body,
-			BodyHTML:    htmlBody,
-			InReplyTo:   reply.InReplyTo,
-			References:  reply.References,
-			Attachments: opts.Attachments,
+			From:         opts.FromAddr,
+			To:           batch.To,
+			Cc:           batch.Cc,
+			Bcc:          batch.Bcc,
+			ReplyTo:      opts.ReplyTo,
+			Subject:      opts.Subject,
+			Body:         opts.Body,
+			BodyHTML:     htmlBody,
+			InlineImages: opts.InlineImages,
+			InReplyTo:    reply.InReplyTo,
+			References:   reply.References,
+			Attachments:  opts.Attachments,
 		}, nil)
 		if err != nil {
 			return nil, err
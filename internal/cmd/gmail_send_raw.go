@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/mail"
+	"os"
+	"strings"
+
+	"google.golang.org/api/gmail/v1"
+	gapi "google.golang.org/api/googleapi"
+
+	"github.com/steipete/gogcli/internal/config"
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+// runRaw sends a complete RFC 822 message as-is, apart from optional From/To
+// overrides. The body and MIME structure are never touched, so signed or
+// hand-built multipart messages survive intact.
+func (c *GmailSendCmd) runRaw(ctx context.Context, u *ui.UI, account string) error {
+	if conflict := c.rawConflicts(); conflict != "" {
+		return usagef("--raw cannot be combined with %s", conflict)
+	}
+
+	raw, err := readRawMessageInput(c.Raw)
+	if err != nil {
+		return err
+	}
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return usagef("invalid message: %v", err)
+	}
+
+	svc, err := newGmailService(ctx, account)
+	if err != nil {
+		return err
+	}
+
+	var overrides [][2]string
+	fromAddr := strings.TrimSpace(msg.Header.Get("From"))
+	if strings.TrimSpace(c.From) != "" || fromAddr == "" {
+		fromAddr, _, err = resolveSendFrom(ctx, svc, account, c.From)
+		if err != nil {
+			return err
+		}
+		overrides = append(overrides, [2]string{"From", fromAddr})
+	}
+	if to := splitCSV(c.To); len(to) > 0 {
+		overrides = append(overrides, [2]string{"To", strings.Join(to, ", ")})
+	}
+	for _, h := range overrides {
+		if err := validateHeaderValue(h[1]); err != nil {
+			return usagef("invalid %s: %v", h[0], err)
+		}
+	}
+	if len(overrides) > 0 {
+		raw = setRawHeaders(raw, overrides)
+		msg, err = mail.ReadMessage(bytes.NewReader(raw))
+		if err != nil {
+			return usagef("invalid message: %v", err)
+		}
+	}
+
+	if err := validateRawHeaders(msg.Header); err != nil {
+		return err
+	}
+
+	threadID := strings.TrimSpace(c.ThreadID)
+	if threadID == "" {
+		threadID, err = threadIDForInReplyTo(ctx, svc, msg.Header.Get("In-Reply-To"))
+		if err != nil {
+			return err
+		}
+	}
+
+	sent, err := svc.Users.Messages.Send("me", &gmail.Message{ThreadId: threadID}).
+		Media(bytes.NewReader(raw), gapi.ContentType("message/rfc822")).
+		Context(ctx).
+		Do()
+	if err != nil {
+		return err
+	}
+
+	return writeSendResults(ctx, u, fromAddr, []sendResult{{MessageID: sent.Id, ThreadID: sent.ThreadId}})
+}
+
+func (c *GmailSendCmd) rawConflicts() string {
+	var flags []string
+	add := func(set bool, name string) {
+		if set {
+			flags = append(flags, name)
+		}
+	}
+	add(strings.TrimSpace(c.Subject) != "", "--subject")
+	add(strings.TrimSpace(c.Body) != "", "--body")
+	add(strings.TrimSpace(c.BodyFile) != "", "--body-file")
+	add(strings.TrimSpace(c.BodyHTML) != "", "--body-html")
+	add(strings.TrimSpace(c.BodyMD) != "", "--body-md")
+	add(strings.TrimSpace(c.Cc) != "", "--cc")
+	add(strings.TrimSpace(c.Bcc) != "", "--bcc")
+	add(strings.TrimSpace(c.ReplyTo) != "", "--reply-to")
+	add(strings.TrimSpace(c.ReplyToMessageID) != "", "--reply-to-message-id")
+	add(c.ReplyAll, "--reply-all")
+	add(len(c.Attach) > 0, "--attach")
+	add(c.Track, "--track")
+	add(c.TrackSplit, "--track-split")
+	add(strings.TrimSpace(c.At) != "", "--at")
+	return strings.Join(flags, ", ")
+}
+
+func readRawMessageInput(path string) ([]byte, error) {
+	path = strings.TrimSpace(path)
+	if path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	expanded, err := config.ExpandPath(path)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(expanded) //nolint:gosec // user-provided path
+}
+
+// validateRawHeaders checks the headers Gmail needs to deliver the message:
+// a parseable From and at least one parseable recipient.
+func validateRawHeaders(h mail.Header) error {
+	if _, err := mail.ParseAddress(h.Get("From")); err != nil {
+		return usagef("invalid From header %q: %v", h.Get("From"), err)
+	}
+	recipients := 0
+	for _, name := range []string{"To", "Cc", "Bcc"} {
+		if strings.TrimSpace(h.Get(name)) == "" {
+			continue
+		}
+		addrs, err := h.AddressList(name)
+		if err != nil {
+			return usagef("invalid %s header: %v", name, err)
+		}
+		recipients += len(addrs)
+	}
+	if recipients == 0 {
+		return usage("message has no recipients (add To/Cc/Bcc headers or pass --to)")
+	}
+	return nil
+}
+
+// threadIDForInReplyTo finds the thread holding the message the raw message
+// replies to, so Gmail files the reply into the existing conversation.
+func threadIDForInReplyTo(ctx context.Context, svc *gmail.Service, inReplyTo string) (string, error) {
+	id := strings.Trim(strings.TrimSpace(inReplyTo), "<>")
+	if id == "" {
+		return "", nil
+	}
+	resp, err := svc.Users.Messages.List("me").
+		Q("rfc822msgid:" + id).
+		IncludeSpamTrash(true).
+		MaxResults(1).
+		Fields("messages(id,threadId)").
+		Context(ctx).
+		Do()
+	if err != nil {
+		return "", fmt.Errorf("look up In-Reply-To thread: %w", err)
+	}
+	if len(resp.Messages) == 0 || resp.Messages[0] == nil {
+		return "", nil
+	}
+	return resp.Messages[0].ThreadId, nil
+}
+
+// setRawHeaders replaces (or adds) top-level headers in a raw message without
+// re-encoding anything else. Folded continuation lines of replaced headers are
+// dropped too; the message's line ending style is kept.
+func setRawHeaders(raw []byte, headers [][2]string) []byte {
+	eol := []byte("\n")
+	if i := bytes.IndexByte(raw, '\n'); i > 0 && raw[i-1] == '\r' {
+		eol = []byte("\r\n")
+	}
+	sep := append(append([]byte{}, eol...), eol...)
+
+	head, body := raw, []byte(nil)
+	if i := bytes.Index(raw, sep); i >= 0 {
+		head, body = raw[:i+len(eol)], raw[i+len(eol):]
+	}
+
+	replace := make(map[string]bool, len(headers))
+	for _, h := range headers {
+		replace[strings.ToLower(h[0])] = true
+	}
+
+	var out bytes.Buffer
+	skipping := false
+	for _, line := range bytes.SplitAfter(head, eol) {
+		if len(line) == 0 {
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			if !skipping {
+				out.Write(line)
+			}
+			continue
+		}
+		name, _, _ := bytes.Cut(line, []byte(":"))
+		skipping = replace[strings.ToLower(strings.TrimSpace(string(name)))]
+		if !skipping {
+			out.Write(line)
+		}
+	}
+	if out.Len() > 0 && !bytes.HasSuffix(out.Bytes(), eol) {
+		out.Write(eol)
+	}
+	for _, h := range headers {
+		out.WriteString(h[0] + ": " + h[1])
+		out.Write(eol)
+	}
+	out.Write(body)
+	return out.Bytes()
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
+
+	"github.com/steipete/gogcli/internal/outfmt"
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+func TestGmailSendRaw_OverridesToAndThreadsReply(t *testing.T) {
+	var uploaded string
+	var searched string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/users/me/messages") && r.Method == http.MethodGet:
+			searched = r.URL.Query().Get("q")
+			_ = json.NewEncoder(w).Encode(map[string]any{"messages": []map[string]any{{"id": "orig", "threadId": "t-orig"}}})
+		case strings.HasPrefix(r.URL.Path, "/upload/") && strings.HasSuffix(r.URL.Path, "/users/me/messages/send"):
+			body, _ := io.ReadAll(r.Body)
+			uploaded = string(body)
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": "sent-1", "threadId": "t-orig"})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	svc, err := gmail.NewService(context.Background(),
+		option.WithoutAuthentication(),
+		option.WithHTTPClient(srv.Client()),
+		option.WithEndpoint(srv.URL+"/"),
+	)
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	origNew := newGmailService
+	t.Cleanup(func() { newGmailService = origNew })
+	newGmailService = func(context.Context, string) (*gmail.Service, error) { return svc, nil }
+
+	eml := "From: Me <a@b.com>\r\nTo: old@example.com,\r\n  other@example.com\r\nSubject: Re: hi\r\nIn-Reply-To: <orig@mail.example.com>\r\nReferences: <orig@mail.example.com>\r\nContent-Type: multipart/signed; protocol=\"application/pgp-signature\"; boundary=\"b1\"\r\n\r\n--b1\r\nsigned body\r\n--b1--\r\n"
+	path := filepath.Join(t.TempDir(), "reply.eml")
+	if err := os.WriteFile(path, []byte(eml), 0o600); err != nil {
+		t.Fatalf("write eml: %v", err)
+	}
+
+	u, err := ui.New(ui.Options{Stdout: os.Stdout, Stderr: os.Stderr, Color: "never"})
+	if err != nil {
+		t.Fatalf("ui.New: %v", err)
+	}
+	ctx := outfmt.WithMode(ui.WithUI(context.Background(), u), outfmt.Mode{JSON: true})
+
+	out := captureStdout(t, func() {
+		if err := runKong(t, &GmailSendCmd{}, []string{"--raw", path, "--to", "new@example.com"}, ctx, &RootFlags{Account: "a@b.com"}); err != nil {
+			t.Fatalf("send --raw: %v", err)
+		}
+	})
+
+	if searched != "rfc822msgid:orig@mail.example.com" {
+		t.Fatalf("unexpected thread lookup: %q", searched)
+	}
+	if !strings.Contains(uploaded, `"threadId":"t-orig"`) {
+		t.Fatalf("threadId missing from upload metadata: %s", uploaded)
+	}
+	if strings.Contains(uploaded, "old@example.com") || strings.Contains(uploaded, "other@example.com") || !strings.Contains(uploaded, "To: new@example.com\r\n") {
+		t.Fatalf("To override not applied: %s", uploaded)
+	}
+	if !strings.Contains(uploaded, "From: Me <a@b.com>\r\n") || !strings.Contains(uploaded, "In-Reply-To: <orig@mail.example.com>\r\n") || !strings.Contains(uploaded, "\r\n\r\n--b1\r\nsigned body\r\n--b1--\r\n") {
+		t.Fatalf("headers or body not preserved: %s", uploaded)
+	}
+
+	var parsed map[string]any
+	if err := json.Unmarshal([]byte(out), &parsed); err != nil {
+		t.Fatalf("json parse: %v\nout=%q", err, out)
+	}
+	if parsed["messageId"] != "sent-1" || parsed["threadId"] != "t-orig" {
+		t.Fatalf("unexpected output: %#v", parsed)
+	}
+}
+
+func TestGmailSendRaw_Validation(t *testing.T) {
+	dir := t.TempDir()
+	noRecipients := filepath.Join(dir, "no-to.eml")
+	if err := os.WriteFile(noRecipients, []byte("From: a@b.com\nSubject: hi\n\nbody\n"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	origNew := newGmailService
+	t.Cleanup(func() { newGmailService = origNew })
+	newGmailService = func(context.Context, string) (*gmail.Service, error) {
+		return gmail.NewService(context.Background(), option.WithoutAuthentication(), option.WithEndpoint("http://127.0.0.1:0/"))
+	}
+
+	err := runKong(t, &GmailSendCmd{}, []string{"--raw", noRecipients, "--subject", "x"}, context.Background(), &RootFlags{Account: "a@b.com"})
+	if err == nil || !strings.Contains(err.Error(), "--raw cannot be combined with --subject") {
+		t.Fatalf("expected conflict error, got %v", err)
+	}
+
+	err = runKong(t, &GmailSendCmd{}, []string{"--raw", noRecipients}, context.Background(), &RootFlags{Account: "a@b.com"})
+	if err == nil || !strings.Contains(err.Error(), "no recipients") {
+		t.Fatalf("expected missing recipient error, got %v", err)
+	}
+}
+
+func TestSetRawHeaders_ReplacesFoldedAndKeepsLineEndings(t *testing.T) {
+	raw := []byte("To: a@example.com,\n\tb@example.com\nSubject: hi\n\nTo: not-a-header\n")
+	got := string(setRawHeaders(raw, [][2]string{{"To", "c@example.com"}, {"From", "me@example.com"}}))
+	want := "Subject: hi\nTo: c@example.com\nFrom: me@example.com\n\nTo: not-a-header\n"
+	if got != want {
+		t.Fatalf("setRawHeaders = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,362 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/api/gmail/v1"
+
+	"github.com/steipete/gogcli/internal/outfmt"
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+var gmailRelativeAgePattern = regexp.MustCompile(`^\d+[dmy]$`)
+
+var statsHeaders = []string{"From", "To", "Subject", "Date", "List-Id"}
+
+type GmailStatsCmd struct {
+	Query  string `name:"query" short:"q" help:"Gmail search query to analyze (combined with --since)"`
+	Since  string `name:"since" help:"Only messages newer than this (90d, 6m, 1y, or a date; empty = no limit)" default:"90d"`
+	Max    int64  `name:"max" help:"Max messages to scan" default:"5000"`
+	Top    int    `name:"top" help:"Rows per ranking" default:"10"`
+	Bucket string `name:"bucket" help:"Volume bucket" enum:"day,week" default:"day"`
+}
+
+type statsCount struct {
+	Key   string `json:"key"`
+	Count int    `json:"count"`
+}
+
+type statsThread struct {
+	ThreadID string `json:"threadId"`
+	Subject  string `json:"subject"`
+	Messages int    `json:"messages"`
+	Bytes    int64  `json:"bytes"`
+}
+
+// statsLabel totals the messages with attachments under one label. The size is
+// the whole message's size estimate, not just its attachment parts.
+type statsLabel struct {
+	Label                string `json:"label"`
+	Messages             int    `json:"messages"`
+	BytesWithAttachments int64  `json:"bytesWithAttachments"`
+}
+
+type statsLatency struct {
+	Replies       int   `json:"replies"`
+	MedianSeconds int64 `json:"medianSeconds"`
+	MeanSeconds   int64 `json:"meanSeconds"`
+	P90Seconds    int64 `json:"p90Seconds"`
+}
+
+type gmailStats struct {
+	Query        string        `json:"query"`
+	Scanned      int           `json:"scanned"`
+	Limited      bool          `json:"limited"`
+	TotalBytes   int64         `json:"totalBytes"`
+	Senders      []statsCount  `json:"senders"`
+	Domains      []statsCount  `json:"domains"`
+	Volume       []statsCount  `json:"volume"`
+	Threads      []statsThread `json:"threads"`
+	Labels       []statsLabel  `json:"labels"`
+	ReplyLatency statsLatency  `json:"replyLatency"`
+}
+
+func (c *GmailStatsCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+	if c.Max <= 0 {
+		return usage("--max must be > 0")
+	}
+	if c.Top <= 0 {
+		return usage("--top must be > 0")
+	}
+	query, err := statsQuery(c.Query, c.Since, time.Now())
+	if err != nil {
+		return err
+	}
+
+	svc, err := newGmailService(ctx, account)
+	if err != nil {
+		return err
+	}
+
+	ids, err := listGmailMessageIDs(ctx, svc, query, c.Max)
+	if err != nil {
+		return err
+	}
+	u.Err().Printf("Scanning %d messages", len(ids))
+	msgs, err := fetchMessageMetadata(ctx, svc, ids, statsHeaders...)
+	if err != nil {
+		return err
+	}
+	// Metadata carries no MIME parts, so a has:attachment listing identifies
+	// which messages count towards the per-label sizes.
+	attachIDs, err := listGmailMessageIDs(ctx, svc, strings.TrimSpace(query+" has:attachment"), c.Max)
+	if err != nil {
+		return err
+	}
+	withAttachments := make(map[string]bool, len(attachIDs))
+	for _, id := range attachIDs {
+		withAttachments[id] = true
+	}
+	idToName, err := fetchLabelIDToName(svc)
+	if err != nil {
+		return err
+	}
+
+	stats := computeGmailStats(msgs, withAttachments, idToName, c.Bucket, c.Top, time.Local)
+	stats.Query = query
+	stats.Limited = int64(len(ids)) >= c.Max
+
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(os.Stdout, stats)
+	}
+	if stats.Limited {
+		u.Err().Printf("Stopped at --max %d; results cover the newest messages only", c.Max)
+	}
+	writeGmailStatsText(ctx, u, stats, c.Bucket)
+	return nil
+}
+
+// statsQuery combines the user query with --since as newer_than: (Gmail
+// relative ages) or after: (absolute dates).
+func statsQuery(query, since string, now time.Time) (string, error) {
+	query = strings.TrimSpace(query)
+	since = strings.ToLower(strings.TrimSpace(since))
+	if since == "" {
+		return query, nil
+	}
+	var clause string
+	if gmailRelativeAgePattern.MatchString(since) {
+		clause = "newer_than:" + since
+	} else {
+		t, err := parseTimeExpr(since, now, time.Local)
+		if err != nil {
+			return "", usagef("invalid --since: %v", err)
+		}
+		clause = fmt.Sprintf("after:%d", t.Unix())
+	}
+	return strings.TrimSpace(query + " " + clause), nil
+}
+
+func computeGmailStats(msgs []*gmail.Message, withAttachments map[string]bool, idToName map[string]string, bucket string, top int, loc *time.Location) gmailStats {
+	stats := gmailStats{Scanned: len(msgs)}
+	senders := map[string]int{}
+	domains := map[string]int{}
+	volume := map[string]int{}
+	threads := map[string]*statsThread{}
+	labels := map[string]*statsLabel{}
+	byThread := map[string][]*gmail.Message{}
+
+	for _, m := range msgs {
+		if m == nil {
+			continue
+		}
+		stats.TotalBytes += m.SizeEstimate
+		sent := hasLabel(m.LabelIds, "SENT")
+		if !sent {
+			if addr := senderAddress(headerValue(m.Payload, "From")); addr != "" {
+				senders[addr]++
+				if at := strings.LastIndex(addr, "@"); at >= 0 {
+					domains[addr[at+1:]]++
+				}
+			}
+		}
+
+		when := time.UnixMilli(m.InternalDate).In(loc)
+		if bucket == "week" {
+			offset := (int(when.Weekday()) + 6) % 7
+			when = when.AddDate(0, 0, -offset)
+		}
+		volume[when.Format("2006-01-02")]++
+
+		t, ok := threads[m.ThreadId]
+		if !ok {
+			t = &statsThread{ThreadID: m.ThreadId}
+			threads[m.ThreadId] = t
+		}
+		t.Messages++
+		t.Bytes += m.SizeEstimate
+		if t.Subject == "" {
+			t.Subject = headerValue(m.Payload, "Subject")
+		}
+		byThread[m.ThreadId] = append(byThread[m.ThreadId], m)
+
+		if withAttachments[m.Id] {
+			for _, id := range m.LabelIds {
+				name := labelNameOrID(id, idToName)
+				l, ok := labels[name]
+				if !ok {
+					l = &statsLabel{Label: name}
+					labels[name] = l
+				}
+				l.Messages++
+				l.BytesWithAttachments += m.SizeEstimate
+			}
+		}
+	}
+
+	stats.Senders = topCounts(senders, top)
+	stats.Domains = topCounts(domains, top)
+
+	stats.Volume = make([]statsCount, 0, len(volume))
+	for k, v := range volume {
+		stats.Volume = append(stats.Volume, statsCount{Key: k, Count: v})
+	}
+	sort.Slice(stats.Volume, func(i, j int) bool { return stats.Volume[i].Key < stats.Volume[j].Key })
+
+	stats.Threads = make([]statsThread, 0, len(threads))
+	for _, t := range threads {
+		stats.Threads = append(stats.Threads, *t)
+	}
+	sort.Slice(stats.Threads, func(i, j int) bool {
+		a, b := stats.Threads[i], stats.Threads[j]
+		if a.Messages != b.Messages {
+			return a.Messages > b.Messages
+		}
+		if a.Bytes != b.Bytes {
+			return a.Bytes > b.Bytes
+		}
+		return a.ThreadID < b.ThreadID
+	})
+	if len(stats.Threads) > top {
+		stats.Threads = stats.Threads[:top]
+	}
+
+	stats.Labels = make([]statsLabel, 0, len(labels))
+	for _, l := range labels {
+		stats.Labels = append(stats.Labels, *l)
+	}
+	sort.Slice(stats.Labels, func(i, j int) bool {
+		if stats.Labels[i].BytesWithAttachments != stats.Labels[j].BytesWithAttachments {
+			return stats.Labels[i].BytesWithAttachments > stats.Labels[j].BytesWithAttachments
+		}
+		return stats.Labels[i].Label < stats.Labels[j].Label
+	})
+
+	stats.ReplyLatency = replyLatency(byThread)
+	return stats
+}
+
+// replyLatency measures, within each thread, the time from a received message
+// to our next sent message.
+func replyLatency(byThread map[string][]*gmail.Message) statsLatency {
+	var waits []int64
+	for _, msgs := range byThread {
+		sorted := append([]*gmail.Message(nil), msgs...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i].InternalDate < sorted[j].InternalDate })
+		var pending int64 = -1
+		for _, m := range sorted {
+			if hasLabel(m.LabelIds, "SENT") {
+				if pending >= 0 {
+					waits = append(waits, (m.InternalDate-pending)/1000)
+					pending = -1
+				}
+				continue
+			}
+			if pending < 0 {
+				pending = m.InternalDate
+			}
+		}
+	}
+	out := statsLatency{Replies: len(waits)}
+	if len(waits) == 0 {
+		return out
+	}
+	sort.Slice(waits, func(i, j int) bool { return waits[i] < waits[j] })
+	var sum int64
+	for _, w := range waits {
+		sum += w
+	}
+	out.MeanSeconds = sum / int64(len(waits))
+	out.MedianSeconds = waits[len(waits)/2]
+	if len(waits)%2 == 0 {
+		out.MedianSeconds = (waits[len(waits)/2-1] + waits[len(waits)/2]) / 2
+	}
+	out.P90Seconds = waits[(len(waits)*9+9)/10-1]
+	return out
+}
+
+func hasLabel(ids []string, label string) bool {
+	for _, id := range ids {
+		if id == label {
+			return true
+		}
+	}
+	return false
+}
+
+func topCounts(counts map[string]int, top int) []statsCount {
+	out := make([]statsCount, 0, len(counts))
+	for k, v := range counts {
+		out = append(out, statsCount{Key: k, Count: v})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Key < out[j].Key
+	})
+	if len(out) > top {
+		out = out[:top]
+	}
+	return out
+}
+
+func writeGmailStatsText(ctx context.Context, u *ui.UI, s gmailStats, bucket string) {
+	u.Out().Printf("query\t%s", s.Query)
+	u.Out().Printf("messages\t%d", s.Scanned)
+	u.Out().Printf("size\t%s", formatBytes(s.TotalBytes))
+	if s.ReplyLatency.Replies > 0 {
+		u.Out().Printf("replies\t%d (median %s, mean %s, p90 %s)", s.ReplyLatency.Replies,
+			formatLatency(s.ReplyLatency.MedianSeconds), formatLatency(s.ReplyLatency.MeanSeconds), formatLatency(s.ReplyLatency.P90Seconds))
+	}
+
+	section := func(title string, header string, rows func(w io.Writer)) {
+		u.Out().Println("")
+		u.Out().Println(title)
+		w, flush := tableWriter(ctx)
+		fmt.Fprintln(w, header)
+		rows(w)
+		flush()
+	}
+	section("Top senders", "COUNT\tSENDER", func(w io.Writer) {
+		for _, c := range s.Senders {
+			fmt.Fprintf(w, "%d\t%s\n", c.Count, sanitizeTab(c.Key))
+		}
+	})
+	section("Top domains", "COUNT\tDOMAIN", func(w io.Writer) {
+		for _, c := range s.Domains {
+			fmt.Fprintf(w, "%d\t%s\n", c.Count, sanitizeTab(c.Key))
+		}
+	})
+	section("Messages per "+bucket, strings.ToUpper(bucket)+"\tCOUNT", func(w io.Writer) {
+		for _, c := range s.Volume {
+			fmt.Fprintf(w, "%s\t%d\n", c.Key, c.Count)
+		}
+	})
+	section("Largest threads", "MESSAGES\tSIZE\tTHREAD\tSUBJECT", func(w io.Writer) {
+		for _, t := range s.Threads {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", t.Messages, formatBytes(t.Bytes), t.ThreadID, sanitizeTab(t.Subject))
+		}
+	})
+	section("Messages with attachments by label", "SIZE\tMESSAGES\tLABEL", func(w io.Writer) {
+		for _, l := range s.Labels {
+			fmt.Fprintf(w, "%s\t%d\t%s\n", formatBytes(l.BytesWithAttachments), l.Messages, sanitizeTab(l.Label))
+		}
+	})
+}
+
+func formatLatency(seconds int64) string {
+	return (time.Duration(seconds) * time.Second).String()
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/api/gmail/v1"
+)
+
+func statsMessage(id, thread, from string, at time.Time, size int64, labels ...string) *gmail.Message {
+	return &gmail.Message{
+		Id:           id,
+		ThreadId:     thread,
+		LabelIds:     labels,
+		InternalDate: at.UnixMilli(),
+		SizeEstimate: size,
+		Payload: &gmail.MessagePart{Headers: []*gmail.MessagePartHeader{
+			{Name: "From", Value: from},
+			{Name: "Subject", Value: "Subject " + thread},
+		}},
+	}
+}
+
+func TestComputeGmailStats(t *testing.T) {
+	base := time.Date(2026, 3, 4, 9, 0, 0, 0, time.UTC) // Wednesday
+	msgs := []*gmail.Message{
+		statsMessage("m1", "t1", "Ada <ada@example.com>", base, 1000, "INBOX", "Label_1"),
+		statsMessage("m2", "t1", "me@example.org", base.Add(2*time.Hour), 200, "SENT"),
+		statsMessage("m3", "t1", "ada@example.com", base.Add(24*time.Hour), 300, "INBOX"),
+		statsMessage("m4", "t1", "me@example.org", base.Add(28*time.Hour), 100, "SENT"),
+		statsMessage("m5", "t2", "news@lists.example.com", base.Add(6*24*time.Hour), 5000, "INBOX", "Label_1"),
+	}
+	stats := computeGmailStats(msgs, map[string]bool{"m1": true, "m5": true}, map[string]string{"Label_1": "Receipts"}, "week", 10, time.UTC)
+
+	if stats.Scanned != 5 || stats.TotalBytes != 6600 {
+		t.Fatalf("unexpected totals: %#v", stats)
+	}
+	if !reflect.DeepEqual(stats.Senders, []statsCount{{"ada@example.com", 2}, {"news@lists.example.com", 1}}) {
+		t.Fatalf("unexpected senders: %#v", stats.Senders)
+	}
+	if !reflect.DeepEqual(stats.Domains, []statsCount{{"example.com", 2}, {"lists.example.com", 1}}) {
+		t.Fatalf("unexpected domains: %#v", stats.Domains)
+	}
+	if !reflect.DeepEqual(stats.Volume, []statsCount{{"2026-03-02", 4}, {"2026-03-09", 1}}) {
+		t.Fatalf("unexpected weekly volume: %#v", stats.Volume)
+	}
+	if stats.Threads[0].ThreadID != "t1" || stats.Threads[0].Messages != 4 || stats.Threads[0].Bytes != 1600 {
+		t.Fatalf("unexpected threads: %#v", stats.Threads)
+	}
+	if stats.Labels[0] != (statsLabel{Label: "INBOX", Messages: 2, BytesWithAttachments: 6000}) || stats.Labels[1].Label != "Receipts" {
+		t.Fatalf("unexpected labels: %#v", stats.Labels)
+	}
+	want := statsLatency{Replies: 2, MedianSeconds: 3 * 3600, MeanSeconds: 3 * 3600, P90Seconds: 4 * 3600}
+	if stats.ReplyLatency != want {
+		t.Fatalf("unexpected latency: %#v", stats.ReplyLatency)
+	}
+}
+
+func TestStatsQuery(t *testing.T) {
+	now := time.Date(2026, 3, 4, 9, 0, 0, 0, time.UTC)
+	cases := map[[2]string]string{
+		{"from:ada", "90d"}: "from:ada newer_than:90d",
+		{"", "1y"}:          "newer_than:1y",
+		{"in:inbox", ""}:    "in:inbox",
+	}
+	for in, want := range cases {
+		got, err := statsQuery(in[0], in[1], now)
+		if err != nil || got != want {
+			t.Fatalf("statsQuery(%q, %q) = %q, %v; want %q", in[0], in[1], got, err, want)
+		}
+	}
+	if _, err := statsQuery("", "last tuesday-ish", now); err == nil {
+		t.Fatalf("expected invalid --since error")
+	}
+}
@@ -0,0 +1,591 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/api/gmail/v1"
+
+	"github.com/steipete/gogcli/internal/config"
+	"github.com/steipete/gogcli/internal/outfmt"
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+const (
+	gmailSyncIndexName   = ".gog-sync.json"
+	gmailSyncModeFull    = "full"
+	gmailSyncModeDelta   = "incremental"
+	gmailFormatMinimal   = "minimal"
+	gmailSyncHistoryPage = 500
+)
+
+type GmailSyncCmd struct {
+	Dir         string `name:"dir" required:"" help:"Local Maildir to keep in sync (created if missing)"`
+	Full        bool   `name:"full" help:"Ignore the stored history ID and do a full resync"`
+	Concurrency int    `name:"concurrency" help:"Parallel message fetches" default:"8"`
+}
+
+// gmailSyncIndex is the small state file kept next to the Maildir. HistoryID
+// is where the next incremental run starts; Pending holds messages whose fetch
+// failed and are retried on the next run.
+type gmailSyncIndex struct {
+	Account   string                         `json:"account"`
+	HistoryID string                         `json:"history_id,omitempty"`
+	Messages  map[string]gmailSyncIndexEntry `json:"messages"`
+	Pending   []string                       `json:"pending,omitempty"`
+	UpdatedAt time.Time                      `json:"updated_at"`
+}
+
+type gmailSyncIndexEntry struct {
+	ThreadID     string   `json:"thread_id,omitempty"`
+	InternalDate int64    `json:"internal_date"`
+	Labels       []string `json:"labels"`
+}
+
+type gmailSyncStats struct {
+	Mode      string
+	Added     int
+	Deleted   int
+	Relabeled int
+	Failed    map[string]string
+}
+
+func (c *GmailSyncCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+	if c.Concurrency < 1 {
+		return usage("--concurrency must be >= 1")
+	}
+
+	dir, err := config.ExpandPath(strings.TrimSpace(c.Dir))
+	if err != nil {
+		return err
+	}
+	if dir == "" {
+		return usage("missing --dir")
+	}
+
+	indexPath := filepath.Join(dir, gmailSyncIndexName)
+	index, err := loadGmailSyncIndex(indexPath, account)
+	if err != nil {
+		return err
+	}
+
+	writer, err := newMaildirExportWriter(dir)
+	if err != nil {
+		return err
+	}
+
+	svc, err := newGmailService(ctx, account)
+	if err != nil {
+		return err
+	}
+
+	s := &gmailSyncer{svc: svc, writer: writer, index: index, concurrency: c.Concurrency}
+	var stats gmailSyncStats
+	if c.Full || index.HistoryID == "" {
+		stats, err = s.full(ctx)
+	} else {
+		stats, err = s.incremental(ctx)
+		if err != nil && isNotFoundAPIError(err) {
+			u.Err().Printf("History ID %s expired; running full resync", index.HistoryID)
+			stats, err = s.full(ctx)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	index.Pending = sortedKeys(stats.Failed)
+	index.UpdatedAt = time.Now().UTC()
+	if err := writeGmailSyncIndex(indexPath, index); err != nil {
+		return err
+	}
+
+	if outfmt.IsJSON(ctx) {
+		if err := outfmt.WriteJSON(os.Stdout, map[string]any{
+			"mode":      stats.Mode,
+			"dir":       dir,
+			"historyId": index.HistoryID,
+			"messages":  len(index.Messages),
+			"added":     stats.Added,
+			"deleted":   stats.Deleted,
+			"relabeled": stats.Relabeled,
+			"failed":    len(stats.Failed),
+			"failures":  stats.Failed,
+		}); err != nil {
+			return err
+		}
+	} else {
+		u.Out().Printf("mode\t%s", stats.Mode)
+		u.Out().Printf("dir\t%s", dir)
+		u.Out().Printf("history_id\t%s", index.HistoryID)
+		u.Out().Printf("messages\t%d", len(index.Messages))
+		u.Out().Printf("added\t%d", stats.Added)
+		u.Out().Printf("deleted\t%d", stats.Deleted)
+		u.Out().Printf("relabeled\t%d", stats.Relabeled)
+		u.Out().Printf("failed\t%d", len(stats.Failed))
+	}
+
+	if len(stats.Failed) > 0 {
+		for _, id := range sortedKeys(stats.Failed) {
+			u.Err().Printf("failed %s: %s", id, stats.Failed[id])
+		}
+		return &ExitError{Code: 1, Err: fmt.Errorf("%d message(s) failed; they will be retried on the next sync", len(stats.Failed))}
+	}
+	return nil
+}
+
+func loadGmailSyncIndex(path string, account string) (*gmailSyncIndex, error) {
+	fresh := &gmailSyncIndex{Account: account, Messages: map[string]gmailSyncIndexEntry{}}
+
+	data, err := os.ReadFile(path) //nolint:gosec // user-provided sync dir
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fresh, nil
+		}
+		return nil, fmt.Errorf("read sync index: %w", err)
+	}
+
+	var index gmailSyncIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("parse sync index %s: %w", path, err)
+	}
+	if !strings.EqualFold(index.Account, account) {
+		return nil, usagef("%s is synced with %s; use another --dir", path, index.Account)
+	}
+	if index.Messages == nil {
+		index.Messages = map[string]gmailSyncIndexEntry{}
+	}
+	return &index, nil
+}
+
+func writeGmailSyncIndex(path string, index *gmailSyncIndex) error {
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode sync index: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("write sync index: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("commit sync index: %w", err)
+	}
+	return nil
+}
+
+type gmailSyncer struct {
+	svc         *gmail.Service
+	writer      *maildirExportWriter
+	index       *gmailSyncIndex
+	concurrency int
+	idToName    map[string]string
+}
+
+// full mirrors every message returned by messages.list (which excludes spam
+// and trash). The profile history ID is read first so changes made while the
+// listing runs are picked up by the next incremental sync.
+func (s *gmailSyncer) full(ctx context.Context) (gmailSyncStats, error) {
+	stats := gmailSyncStats{Mode: gmailSyncModeFull, Failed: map[string]string{}}
+
+	profile, err := s.svc.Users.GetProfile("me").Context(ctx).Do()
+	if err != nil {
+		return stats, err
+	}
+	ids, err := listGmailMessageIDs(ctx, s.svc, "", 0)
+	if err != nil {
+		return stats, err
+	}
+
+	remote := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		remote[id] = struct{}{}
+	}
+	for _, id := range s.localIDs() {
+		if _, ok := remote[id]; !ok {
+			if err := s.remove(id); err != nil {
+				return stats, err
+			}
+			stats.Deleted++
+		}
+	}
+
+	var fetch, refresh []string
+	for _, id := range ids {
+		if _, ok := s.writer.existing[id]; ok {
+			refresh = append(refresh, id)
+		} else {
+			fetch = append(fetch, id)
+		}
+	}
+
+	// Local copies only need their labels checked, which format=minimal
+	// answers without downloading the message again.
+	var loopErr error
+	refreshCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	for res := range fetchMessagesParallel(refreshCtx, refresh, s.concurrency, func(id string) rawMessageResult {
+		return fetchMinimalMessage(refreshCtx, s.svc, id)
+	}) {
+		if loopErr != nil {
+			continue
+		}
+		if res.err != nil {
+			if isNotFoundAPIError(res.err) {
+				if err := s.remove(res.id); err != nil {
+					loopErr = err
+					cancel()
+					continue
+				}
+				stats.Deleted++
+				continue
+			}
+			stats.Failed[res.id] = res.err.Error()
+			continue
+		}
+		entry, indexed := s.index.Messages[res.id]
+		if indexed && slices.Equal(entry.Labels, sortedLabelIDs(res.msg.LabelIDs)) {
+			continue
+		}
+		if err := s.relabel(res.msg); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				fetch = append(fetch, res.id)
+				continue
+			}
+			loopErr = err
+			cancel()
+			continue
+		}
+		if indexed {
+			stats.Relabeled++
+		}
+	}
+	if loopErr != nil {
+		return stats, loopErr
+	}
+
+	added, err := s.fetchAndWrite(ctx, fetch, stats.Failed)
+	stats.Added = added
+	if err != nil {
+		return stats, err
+	}
+
+	s.index.HistoryID = formatHistoryID(profile.HistoryId)
+	return stats, nil
+}
+
+// incremental replays history.list since the stored history ID. Label changes
+// are applied to the stored label set; messages that land in spam or trash are
+// removed locally, matching what a full sync would produce.
+func (s *gmailSyncer) incremental(ctx context.Context) (gmailSyncStats, error) {
+	stats := gmailSyncStats{Mode: gmailSyncModeDelta, Failed: map[string]string{}}
+
+	startID, err := parseHistoryID(s.index.HistoryID)
+	if err != nil {
+		return stats, err
+	}
+
+	touched := make(map[string][]string)
+	deleted := make(map[string]struct{})
+	for _, id := range s.index.Pending {
+		touched[id] = nil
+	}
+
+	labelsFor := func(id string, fallback []string) []string {
+		if labels, ok := touched[id]; ok && labels != nil {
+			return labels
+		}
+		if entry, ok := s.index.Messages[id]; ok {
+			return entry.Labels
+		}
+		return fallback
+	}
+
+	latest := startID
+	pageToken := ""
+	for {
+		call := s.svc.Users.History.List("me").
+			StartHistoryId(startID).
+			MaxResults(gmailSyncHistoryPage).
+			HistoryTypes("messageAdded", "messageDeleted", "labelAdded", "labelRemoved").
+			Context(ctx)
+		if pageToken != "" {
+			call = call.PageToken(pageToken)
+		}
+		resp, err := call.Do()
+		if err != nil {
+			return stats, err
+		}
+		for _, h := range resp.History {
+			if h == nil {
+				continue
+			}
+			for _, added := range h.MessagesAdded {
+				if added == nil || added.Message == nil || added.Message.Id == "" {
+					continue
+				}
+				touched[added.Message.Id] = sortedLabelIDs(added.Message.LabelIds)
+				delete(deleted, added.Message.Id)
+			}
+			for _, del := range h.MessagesDeleted {
+				if del == nil || del.Message == nil || del.Message.Id == "" {
+					continue
+				}
+				deleted[del.Message.Id] = struct{}{}
+				delete(touched, del.Message.Id)
+			}
+			for _, la := range h.LabelsAdded {
+				if la == nil || la.Message == nil || la.Message.Id == "" {
+					continue
+				}
+				id := la.Message.Id
+				touched[id] = applyLabelDelta(labelsFor(id, la.Message.LabelIds), la.LabelIds, nil)
+			}
+			for _, lr := range h.LabelsRemoved {
+				if lr == nil || lr.Message == nil || lr.Message.Id == "" {
+					continue
+				}
+				id := lr.Message.Id
+				touched[id] = applyLabelDelta(labelsFor(id, lr.Message.LabelIds), nil, lr.LabelIds)
+			}
+		}
+		if resp.HistoryId > latest {
+			latest = resp.HistoryId
+		}
+		if resp.NextPageToken == "" {
+			break
+		}
+		pageToken = resp.NextPageToken
+	}
+
+	for _, id := range sortedKeys(deleted) {
+		if _, ok := s.writer.existing[id]; !ok {
+			continue
+		}
+		if err := s.remove(id); err != nil {
+			return stats, err
+		}
+		stats.Deleted++
+	}
+
+	var fetch []string
+	for _, id := range sortedKeys(touched) {
+		labels := touched[id]
+		_, local := s.writer.existing[id]
+		switch {
+		case labels != nil && hasHiddenLabel(labels):
+			if local {
+				if err := s.remove(id); err != nil {
+					return stats, err
+				}
+				stats.Deleted++
+			}
+		case !local:
+			fetch = append(fetch, id)
+		default:
+			entry, indexed := s.index.Messages[id]
+			if !indexed {
+				// Local file without thread/date metadata: refetch it.
+				fetch = append(fetch, id)
+				continue
+			}
+			if labels == nil || slices.Equal(entry.Labels, labels) {
+				continue
+			}
+			err := s.relabel(gmailExportMessage{
+				ID:           id,
+				ThreadID:     entry.ThreadID,
+				LabelIDs:     labels,
+				InternalDate: time.UnixMilli(entry.InternalDate).UTC(),
+			})
+			if errors.Is(err, os.ErrNotExist) {
+				fetch = append(fetch, id)
+				continue
+			}
+			if err != nil {
+				return stats, err
+			}
+			stats.Relabeled++
+		}
+	}
+
+	added, err := s.fetchAndWrite(ctx, fetch, stats.Failed)
+	stats.Added = added
+	if err != nil {
+		return stats, err
+	}
+
+	s.index.HistoryID = formatHistoryID(latest)
+	return stats, nil
+}
+
+// fetchAndWrite downloads ids in raw format and delivers them into the
+// Maildir. Messages deleted or moved to spam/trash in the meantime are skipped.
+func (s *gmailSyncer) fetchAndWrite(ctx context.Context, ids []string, failed map[string]string) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	if err := s.loadLabelNames(); err != nil {
+		return 0, err
+	}
+
+	written := 0
+	var writeErr error
+	fetchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	for res := range fetchRawMessages(fetchCtx, s.svc, ids, s.concurrency) {
+		if writeErr != nil {
+			continue
+		}
+		if res.err != nil {
+			if !isNotFoundAPIError(res.err) {
+				failed[res.id] = res.err.Error()
+			}
+			continue
+		}
+		if hasHiddenLabel(res.msg.LabelIDs) {
+			if _, ok := s.writer.existing[res.id]; ok {
+				if err := s.remove(res.id); err != nil {
+					writeErr = err
+					cancel()
+				}
+			}
+			continue
+		}
+		if err := s.write(res.msg); err != nil {
+			writeErr = fmt.Errorf("write %s: %w", res.id, err)
+			cancel()
+			continue
+		}
+		written++
+	}
+	return written, writeErr
+}
+
+func (s *gmailSyncer) write(msg gmailExportMessage) error {
+	msg.LabelIDs = sortedLabelIDs(msg.LabelIDs)
+	msg.Labels = labelNames(msg.LabelIDs, s.idToName)
+	if err := s.writer.Write(msg); err != nil {
+		return err
+	}
+	s.index.Messages[msg.ID] = gmailSyncIndexEntry{
+		ThreadID:     msg.ThreadID,
+		InternalDate: msg.InternalDate.UnixMilli(),
+		Labels:       msg.LabelIDs,
+	}
+	return nil
+}
+
+// relabel rewrites the local copy of msg with new labels: the X-Gmail-Labels
+// header is regenerated and the Maildir flags in the filename follow.
+func (s *gmailSyncer) relabel(msg gmailExportMessage) error {
+	name, ok := s.writer.existing[msg.ID]
+	if !ok {
+		return os.ErrNotExist
+	}
+	data, err := os.ReadFile(filepath.Join(s.writer.dir, "cur", name)) //nolint:gosec // user-provided sync dir
+	if err != nil {
+		return err
+	}
+	if err := s.loadLabelNames(); err != nil {
+		return err
+	}
+	msg.Raw = stripExportHeaders(data)
+	return s.write(msg)
+}
+
+func (s *gmailSyncer) remove(id string) error {
+	if name, ok := s.writer.existing[id]; ok {
+		if err := os.Remove(filepath.Join(s.writer.dir, "cur", name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("remove %s: %w", id, err)
+		}
+		delete(s.writer.existing, id)
+	}
+	delete(s.index.Messages, id)
+	return nil
+}
+
+func (s *gmailSyncer) localIDs() []string {
+	ids := make(map[string]struct{}, len(s.writer.existing)+len(s.index.Messages))
+	for id := range s.writer.existing {
+		ids[id] = struct{}{}
+	}
+	for id := range s.index.Messages {
+		ids[id] = struct{}{}
+	}
+	return sortedKeys(ids)
+}
+
+func (s *gmailSyncer) loadLabelNames() error {
+	if s.idToName != nil {
+		return nil
+	}
+	idToName, err := fetchLabelIDToName(s.svc)
+	if err != nil {
+		return err
+	}
+	s.idToName = idToName
+	return nil
+}
+
+func fetchMinimalMessage(ctx context.Context, svc *gmail.Service, id string) rawMessageResult {
+	msg, err := svc.Users.Messages.Get("me", id).Format(gmailFormatMinimal).Context(ctx).Do()
+	if err != nil {
+		return rawMessageResult{id: id, err: err}
+	}
+	return rawMessageResult{id: id, msg: gmailExportMessage{
+		ID:           msg.Id,
+		ThreadID:     msg.ThreadId,
+		LabelIDs:     msg.LabelIds,
+		InternalDate: time.UnixMilli(msg.InternalDate).UTC(),
+	}}
+}
+
+// stripExportHeaders drops the metadata headers exportMessageBytes prepends,
+// returning the original message.
+func stripExportHeaders(data []byte) []byte {
+	for {
+		line, rest, ok := bytes.Cut(data, []byte("\n"))
+		if !ok || (!bytes.HasPrefix(line, []byte("X-GM-THRID: ")) && !bytes.HasPrefix(line, []byte("X-Gmail-Labels: "))) {
+			return data
+		}
+		data = rest
+	}
+}
+
+func applyLabelDelta(labels []string, add []string, remove []string) []string {
+	set := make(map[string]struct{}, len(labels)+len(add))
+	for _, l := range labels {
+		set[l] = struct{}{}
+	}
+	for _, l := range add {
+		set[l] = struct{}{}
+	}
+	for _, l := range remove {
+		delete(set, l)
+	}
+	return sortedKeys(set)
+}
+
+func sortedLabelIDs(labels []string) []string {
+	out := append([]string{}, labels...)
+	sort.Strings(out)
+	return out
+}
+
+func hasHiddenLabel(labels []string) bool {
+	return slices.Contains(labels, "SPAM") || slices.Contains(labels, "TRASH")
+}
@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
+
+	"github.com/steipete/gogcli/internal/outfmt"
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+type gmailSyncTestServer struct {
+	mu          sync.Mutex
+	listed      []string
+	labels      map[string][]string
+	history     []map[string]any
+	historyGone bool
+	rawFetches  []string
+	historyArgs []string
+}
+
+func newGmailSyncTestService(t *testing.T, state *gmailSyncTestServer) {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		state.mu.Lock()
+		defer state.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		path := r.URL.Path
+		switch {
+		case strings.HasSuffix(path, "/users/me/profile"):
+			_ = json.NewEncoder(w).Encode(map[string]any{"emailAddress": "a@b.com", "historyId": "100"})
+		case strings.HasSuffix(path, "/users/me/labels"):
+			_ = json.NewEncoder(w).Encode(map[string]any{"labels": []map[string]any{
+				{"id": "INBOX", "name": "INBOX"},
+				{"id": "Label_1", "name": "Clients"},
+			}})
+		case strings.HasSuffix(path, "/users/me/history"):
+			state.historyArgs = append(state.historyArgs, r.URL.Query().Get("startHistoryId"))
+			if state.historyGone {
+				w.WriteHeader(http.StatusNotFound)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{"code": 404, "message": "Requested entity was not found."}})
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"history": state.history, "historyId": "200"})
+		case strings.HasSuffix(path, "/users/me/messages"):
+			msgs := make([]map[string]any, 0, len(state.listed))
+			for _, id := range state.listed {
+				msgs = append(msgs, map[string]any{"id": id})
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"messages": msgs})
+		case strings.Contains(path, "/users/me/messages/"):
+			id := filepath.Base(path)
+			labels, ok := state.labels[id]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			resp := map[string]any{
+				"id":           id,
+				"threadId":     "t-" + id,
+				"labelIds":     labels,
+				"internalDate": "1735689600000",
+			}
+			if r.URL.Query().Get("format") == "raw" {
+				state.rawFetches = append(state.rawFetches, id)
+				resp["raw"] = base64.URLEncoding.EncodeToString([]byte("Subject: " + id + "\r\n\r\nbody " + id + "\r\n"))
+			}
+			_ = json.NewEncoder(w).Encode(resp)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	svc, err := gmail.NewService(context.Background(),
+		option.WithoutAuthentication(),
+		option.WithHTTPClient(srv.Client()),
+		option.WithEndpoint(srv.URL+"/"),
+	)
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+
+	origNew := newGmailService
+	t.Cleanup(func() { newGmailService = origNew })
+	newGmailService = func(context.Context, string) (*gmail.Service, error) { return svc, nil }
+}
+
+func runGmailSync(t *testing.T, dir string, extra ...string) map[string]any {
+	t.Helper()
+
+	u, err := ui.New(ui.Options{Stdout: os.Stdout, Stderr: os.Stderr, Color: "never"})
+	if err != nil {
+		t.Fatalf("ui.New: %v", err)
+	}
+	ctx := outfmt.WithMode(ui.WithUI(context.Background(), u), outfmt.Mode{JSON: true})
+
+	var out string
+	out = captureStdout(t, func() {
+		args := append([]string{"--dir", dir}, extra...)
+		if err := runKong(t, &GmailSyncCmd{}, args, ctx, &RootFlags{Account: "a@b.com"}); err != nil {
+			t.Fatalf("sync: %v", err)
+		}
+	})
+	var parsed map[string]any
+	if err := json.Unmarshal([]byte(out), &parsed); err != nil {
+		t.Fatalf("json parse: %v\nout=%q", err, out)
+	}
+	return parsed
+}
+
+func readSyncedMessage(t *testing.T, dir string, id string) (string, string) {
+	t.Helper()
+
+	entries, err := os.ReadDir(filepath.Join(dir, "cur"))
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	for _, e := range entries {
+		if maildirMessageID(e.Name()) == id {
+			data, err := os.ReadFile(filepath.Join(dir, "cur", e.Name()))
+			if err != nil {
+				t.Fatalf("ReadFile: %v", err)
+			}
+			return e.Name(), string(data)
+		}
+	}
+	return "", ""
+}
+
+func TestGmailSync_FullThenIncrementalThenExpired(t *testing.T) {
+	state := &gmailSyncTestServer{
+		listed: []string{"m1", "m2"},
+		labels: map[string][]string{
+			"m1": {"INBOX", "UNREAD"},
+			"m2": {"INBOX"},
+		},
+	}
+	newGmailSyncTestService(t, state)
+	dir := t.TempDir()
+
+	first := runGmailSync(t, dir)
+	if first["mode"] != gmailSyncModeFull || first["added"] != float64(2) || first["historyId"] != "100" {
+		t.Fatalf("unexpected first sync: %#v", first)
+	}
+
+	// m1 deleted, m3 added, m2 labelled and read state unchanged.
+	state.mu.Lock()
+	state.labels["m3"] = []string{"INBOX", "UNREAD"}
+	delete(state.labels, "m1")
+	state.labels["m2"] = []string{"INBOX", "Label_1", "STARRED"}
+	state.history = []map[string]any{
+		{"id": "150", "messagesAdded": []map[string]any{{"message": map[string]any{"id": "m3", "labelIds": []string{"INBOX", "UNREAD"}}}}},
+		{"id": "151", "messagesDeleted": []map[string]any{{"message": map[string]any{"id": "m1"}}}},
+		{"id": "152", "labelsAdded": []map[string]any{{"message": map[string]any{"id": "m2"}, "labelIds": []string{"Label_1", "STARRED"}}}},
+	}
+	state.rawFetches = nil
+	state.mu.Unlock()
+
+	second := runGmailSync(t, dir)
+	if second["mode"] != gmailSyncModeDelta || second["added"] != float64(1) || second["deleted"] != float64(1) || second["relabeled"] != float64(1) {
+		t.Fatalf("unexpected incremental sync: %#v", second)
+	}
+	if second["historyId"] != "200" || state.historyArgs[0] != "100" {
+		t.Fatalf("history ids: %#v %v", second, state.historyArgs)
+	}
+	if strings.Join(state.rawFetches, ",") != "m3" {
+		t.Fatalf("relabel should not refetch: %v", state.rawFetches)
+	}
+	if name, _ := readSyncedMessage(t, dir, "m1"); name != "" {
+		t.Fatalf("m1 should be removed, got %s", name)
+	}
+	name, body := readSyncedMessage(t, dir, "m2")
+	if !strings.HasSuffix(name, ":2,FS") {
+		t.Fatalf("expected starred+seen flags, got %s", name)
+	}
+	if !strings.HasPrefix(body, "X-GM-THRID: t-m2\nX-Gmail-Labels: INBOX,Clients,STARRED\nSubject: m2\n") {
+		t.Fatalf("unexpected relabelled body: %q", body)
+	}
+
+	// Expired history ID falls back to a full resync.
+	state.mu.Lock()
+	state.historyGone = true
+	state.listed = []string{"m2"}
+	state.rawFetches = nil
+	state.mu.Unlock()
+
+	third := runGmailSync(t, dir)
+	if third["mode"] != gmailSyncModeFull || third["deleted"] != float64(1) || third["added"] != float64(0) || third["messages"] != float64(1) {
+		t.Fatalf("unexpected resync: %#v", third)
+	}
+	if len(state.rawFetches) != 0 {
+		t.Fatalf("resync should reuse local copies, fetched %v", state.rawFetches)
+	}
+}
+
+func TestApplyLabelDeltaAndStripExportHeaders(t *testing.T) {
+	got := applyLabelDelta([]string{"INBOX", "UNREAD"}, []string{"STARRED"}, []string{"UNREAD"})
+	if strings.Join(got, ",") != "INBOX,STARRED" {
+		t.Fatalf("applyLabelDelta = %v", got)
+	}
+
+	raw := stripExportHeaders([]byte("X-GM-THRID: t1\nX-Gmail-Labels: INBOX\nSubject: hi\n\nbody\n"))
+	if string(raw) != "Subject: hi\n\nbody\n" {
+		t.Fatalf("stripExportHeaders = %q", raw)
+	}
+	if !hasHiddenLabel([]string{"INBOX", "TRASH"}) || hasHiddenLabel([]string{"INBOX"}) {
+		t.Fatal("hasHiddenLabel mismatch")
+	}
+}
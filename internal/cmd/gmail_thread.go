@@ -50,6 +50,7 @@ type GmailThreadCmd struct {
 	Get         GmailThreadGetCmd         `cmd:"" name:"get" default:"withargs" help:"Get a thread with all messages (optionally download attachments)"`
 	Modify      GmailThreadModifyCmd      `cmd:"" name:"modify" help:"Modify labels on all messages in a thread"`
 	Attachments GmailThreadAttachmentsCmd `cmd:"" name:"attachments" help:"List all attachments in a thread"`
+	Export      GmailThreadExportCmd      `cmd:"" name:"export" help:"Export a thread as a Markdown, HTML, or plain-text transcript"`
 }
 
 type GmailThreadGetCmd struct {
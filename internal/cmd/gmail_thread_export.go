@@ -0,0 +1,531 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+	"net/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	xhtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"google.golang.org/api/gmail/v1"
+
+	"github.com/steipete/gogcli/internal/config"
+	"github.com/steipete/gogcli/internal/outfmt"
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+const (
+	threadExportMarkdown = "md"
+	threadExportHTML     = "html"
+	threadExportText     = "txt"
+)
+
+type GmailThreadExportCmd struct {
+	ThreadID   string        `arg:"" name:"threadId" help:"Thread ID"`
+	Format     string        `name:"format" help:"Transcript format" enum:"md,html,txt" default:"md"`
+	Out        string        `name:"out" short:"o" help:"Write to this file instead of stdout"`
+	KeepQuotes bool          `name:"keep-quotes" help:"Keep quoted replies and signatures"`
+	Download   bool          `name:"download" help:"Download attachments and link the local files"`
+	OutputDir  OutputDirFlag `embed:""`
+}
+
+type transcriptAttachment struct {
+	Filename string `json:"filename"`
+	MimeType string `json:"mimeType"`
+	Size     int64  `json:"size"`
+	Link     string `json:"link"`
+}
+
+type transcriptMessage struct {
+	ID          string                 `json:"id"`
+	From        string                 `json:"from"`
+	To          string                 `json:"to,omitempty"`
+	Cc          string                 `json:"cc,omitempty"`
+	Date        string                 `json:"date"`
+	Subject     string                 `json:"subject"`
+	Body        string                 `json:"body"`
+	Attachments []transcriptAttachment `json:"attachments,omitempty"`
+}
+
+func (c *GmailThreadExportCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+	threadID := strings.TrimSpace(c.ThreadID)
+	if threadID == "" {
+		return usage("empty threadId")
+	}
+
+	svc, err := newGmailService(ctx, account)
+	if err != nil {
+		return err
+	}
+	thread, err := svc.Users.Threads.Get("me", threadID).Format("full").Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+
+	attachDir := ""
+	if c.Download {
+		attachDir = "."
+		if strings.TrimSpace(c.OutputDir.Dir) != "" {
+			expanded, expandErr := config.ExpandPath(c.OutputDir.Dir)
+			if expandErr != nil {
+				return expandErr
+			}
+			attachDir = filepath.Clean(expanded)
+		}
+	}
+
+	messages := make([]transcriptMessage, 0, len(thread.Messages))
+	for _, msg := range thread.Messages {
+		if msg == nil || msg.Payload == nil {
+			continue
+		}
+		tm := transcriptMessage{
+			ID:      msg.Id,
+			From:    headerValue(msg.Payload, "From"),
+			To:      headerValue(msg.Payload, "To"),
+			Cc:      headerValue(msg.Payload, "Cc"),
+			Date:    headerValue(msg.Payload, "Date"),
+			Subject: headerValue(msg.Payload, "Subject"),
+			Body:    transcriptBody(msg.Payload, c.Format, !c.KeepQuotes),
+		}
+		attachments := collectAttachments(msg.Payload)
+		link := fmt.Sprintf("https://mail.google.com/mail/?authuser=%s#all/%s", url.QueryEscape(account), msg.Id)
+		var downloads []attachmentDownloadOutput
+		if c.Download && len(attachments) > 0 {
+			downloads, err = downloadAttachmentOutputs(ctx, svc, msg.Id, attachments, attachDir)
+			if err != nil {
+				return err
+			}
+		}
+		for i, a := range attachments {
+			ta := transcriptAttachment{Filename: a.Filename, MimeType: a.MimeType, Size: a.Size, Link: link}
+			if i < len(downloads) {
+				ta.Link = downloads[i].Path
+			}
+			tm.Attachments = append(tm.Attachments, ta)
+		}
+		messages = append(messages, tm)
+	}
+
+	var doc string
+	switch c.Format {
+	case threadExportHTML:
+		doc, err = renderTranscriptHTML(messages)
+		if err != nil {
+			return err
+		}
+	case threadExportText:
+		doc = renderTranscriptText(messages)
+	default:
+		doc = renderTranscriptMarkdown(messages)
+	}
+
+	if strings.TrimSpace(c.Out) == "" {
+		if outfmt.IsJSON(ctx) {
+			return outfmt.WriteJSON(os.Stdout, map[string]any{"threadId": threadID, "format": c.Format, "messages": messages, "transcript": doc})
+		}
+		_, err = os.Stdout.WriteString(doc)
+		return err
+	}
+	path, err := config.ExpandPath(c.Out)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(doc), 0o600); err != nil {
+		return err
+	}
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(os.Stdout, map[string]any{"threadId": threadID, "format": c.Format, "path": path, "messages": len(messages)})
+	}
+	u.Out().Printf("path\t%s", path)
+	u.Out().Printf("messages\t%d", len(messages))
+	return nil
+}
+
+// transcriptBody renders a message body for the given format: Markdown (also
+// the source for HTML) prefers the HTML part, plain text prefers text/plain.
+func transcriptBody(p *gmail.MessagePart, format string, stripQuotes bool) string {
+	plain := findPartBody(p, "text/plain")
+	htmlBody := findPartBody(p, "text/html")
+	if format != threadExportText && strings.TrimSpace(htmlBody) != "" {
+		return htmlToMarkdown(htmlBody, stripQuotes)
+	}
+	if strings.TrimSpace(plain) == "" && htmlBody != "" {
+		if stripQuotes {
+			htmlBody = stripHTMLQuotes(htmlBody)
+		}
+		return htmlToPlainText(htmlBody)
+	}
+	plain = strings.TrimSpace(normalizeNewlines(plain))
+	if stripQuotes {
+		plain = stripQuotedText(plain)
+	}
+	return plain
+}
+
+var (
+	attributionLinePattern = regexp.MustCompile(`^(On|Am|Le|El|Il|Op) .+:\s*$`)
+	outlookSeparator       = regexp.MustCompile(`^(-{3,}\s*Original Message\s*-{3,}|_{10,})\s*$`)
+)
+
+// stripQuotedText drops quoted lines, reply attributions, and everything from
+// a signature delimiter ("-- ") or an Outlook separator on.
+func stripQuotedText(s string) string {
+	lines := strings.Split(s, "\n")
+	out := make([]string, 0, len(lines))
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		if line == "-- " || line == "--" || outlookSeparator.MatchString(trimmed) {
+			break
+		}
+		if strings.HasPrefix(trimmed, ">") {
+			continue
+		}
+		if attributionLinePattern.MatchString(trimmed) && nextQuoted(lines, i+1) {
+			continue
+		}
+		// Attribution lines wrapped over two lines by the sender's client.
+		if strings.HasPrefix(trimmed, "On ") && i+1 < len(lines) && strings.HasSuffix(strings.TrimSpace(lines[i+1]), "wrote:") && nextQuoted(lines, i+2) {
+			i++
+			continue
+		}
+		out = append(out, line)
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
+
+func nextQuoted(lines []string, from int) bool {
+	for _, l := range lines[min(from, len(lines)):] {
+		if t := strings.TrimSpace(l); t != "" {
+			return strings.HasPrefix(t, ">")
+		}
+	}
+	return true
+}
+
+// isQuoteNode matches the containers mail clients put quoted history and
+// signatures in.
+func isQuoteNode(n *xhtml.Node) bool {
+	for _, a := range n.Attr {
+		switch a.Key {
+		case "class":
+			for _, cls := range strings.Fields(a.Val) {
+				switch cls {
+				case "gmail_quote", "gmail_signature", "gmail_extra", "yahoo_quoted", "moz-cite-prefix", "moz-signature":
+					return true
+				}
+			}
+		case "type":
+			if n.DataAtom == atom.Blockquote && strings.EqualFold(a.Val, "cite") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isQuoteTail marks where Outlook starts the quoted history; everything after
+// it is dropped.
+func isQuoteTail(n *xhtml.Node) bool {
+	for _, a := range n.Attr {
+		if a.Key == "id" && (a.Val == "divRplyFwdMsg" || a.Val == "appendonsend") {
+			return true
+		}
+	}
+	return false
+}
+
+func stripHTMLQuotes(src string) string {
+	doc, err := xhtml.Parse(strings.NewReader(src))
+	if err != nil {
+		return src
+	}
+	removeQuoteNodes(doc)
+	var buf bytes.Buffer
+	if err := xhtml.Render(&buf, doc); err != nil {
+		return src
+	}
+	return buf.String()
+}
+
+func removeQuoteNodes(n *xhtml.Node) bool {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == xhtml.ElementNode && isQuoteTail(c) {
+			for r := c; r != nil; {
+				rn := r.NextSibling
+				n.RemoveChild(r)
+				r = rn
+			}
+			return true
+		}
+		if c.Type == xhtml.ElementNode && isQuoteNode(c) {
+			n.RemoveChild(c)
+		} else if removeQuoteNodes(c) {
+			for r := next; r != nil; {
+				rn := r.NextSibling
+				n.RemoveChild(r)
+				r = rn
+			}
+			return true
+		}
+		c = next
+	}
+	return false
+}
+
+var (
+	mdSpacePattern        = regexp.MustCompile(`[ \t\r\n\f]+`)
+	mdTrailingWSPattern   = regexp.MustCompile(`[ \t]+\n`)
+	mdBlankLinesPattern   = regexp.MustCompile(`\n{3,}`)
+	mdLeadingSpacePattern = regexp.MustCompile(`\n ([^ ])`)
+)
+
+// htmlToMarkdown converts an HTML mail body to Markdown, covering the
+// elements mail clients actually emit.
+func htmlToMarkdown(src string, stripQuotes bool) string {
+	doc, err := xhtml.Parse(strings.NewReader(src))
+	if err != nil {
+		return htmlToPlainText(src)
+	}
+	if stripQuotes {
+		removeQuoteNodes(doc)
+	}
+	out := mdChildren(doc)
+	out = mdTrailingWSPattern.ReplaceAllString(out, "\n")
+	out = mdLeadingSpacePattern.ReplaceAllString(out, "\n$1")
+	out = mdBlankLinesPattern.ReplaceAllString(out, "\n\n")
+	return strings.TrimSpace(out)
+}
+
+func mdChildren(n *xhtml.Node) string {
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(mdNode(c))
+	}
+	return b.String()
+}
+
+func mdNode(n *xhtml.Node) string {
+	switch n.Type {
+	case xhtml.TextNode:
+		return mdSpacePattern.ReplaceAllString(n.Data, " ")
+	case xhtml.DocumentNode:
+		return mdChildren(n)
+	case xhtml.ElementNode:
+	default:
+		return ""
+	}
+
+	switch n.DataAtom {
+	case atom.Script, atom.Style, atom.Head, atom.Title, atom.Meta, atom.Link:
+		return ""
+	case atom.Br:
+		return "\n"
+	case atom.Hr:
+		return "\n\n---\n\n"
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level, _ := strconv.Atoi(n.Data[1:])
+		return "\n\n" + strings.Repeat("#", level) + " " + strings.TrimSpace(mdChildren(n)) + "\n\n"
+	case atom.Strong, atom.B:
+		return mdWrap(mdChildren(n), "**")
+	case atom.Em, atom.I:
+		return mdWrap(mdChildren(n), "*")
+	case atom.Code:
+		return mdWrap(mdChildren(n), "`")
+	case atom.Pre:
+		return "\n\n```\n" + strings.TrimRight(nodeText(n), "\n") + "\n```\n\n"
+	case atom.A:
+		text := strings.TrimSpace(mdChildren(n))
+		href := strings.TrimSpace(attrValue(n, "href"))
+		switch {
+		case href == "" || strings.HasPrefix(href, "#"):
+			return text
+		case text == "" || text == href || "mailto:"+text == href:
+			return "<" + strings.TrimPrefix(href, "mailto:") + ">"
+		default:
+			return "[" + text + "](" + href + ")"
+		}
+	case atom.Img:
+		alt := strings.TrimSpace(attrValue(n, "alt"))
+		src := attrValue(n, "src")
+		if strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") {
+			return "![" + alt + "](" + src + ")"
+		}
+		return alt
+	case atom.Ul, atom.Ol:
+		var b strings.Builder
+		i := 0
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != xhtml.ElementNode || c.DataAtom != atom.Li {
+				continue
+			}
+			i++
+			marker := "- "
+			if n.DataAtom == atom.Ol {
+				marker = strconv.Itoa(i) + ". "
+			}
+			item := strings.TrimSpace(mdBlankLinesPattern.ReplaceAllString(mdChildren(c), "\n\n"))
+			b.WriteString(marker + strings.ReplaceAll(item, "\n", "\n"+strings.Repeat(" ", len(marker))) + "\n")
+		}
+		return "\n\n" + b.String() + "\n"
+	case atom.Blockquote:
+		inner := strings.TrimSpace(mdBlankLinesPattern.ReplaceAllString(mdChildren(n), "\n\n"))
+		lines := strings.Split(inner, "\n")
+		for i, l := range lines {
+			lines[i] = strings.TrimRight("> "+strings.TrimSpace(l), " ")
+		}
+		return "\n\n" + strings.Join(lines, "\n") + "\n\n"
+	case atom.Tr:
+		return "\n" + strings.TrimSpace(mdChildren(n))
+	case atom.Td, atom.Th:
+		return strings.TrimSpace(mdChildren(n)) + " "
+	case atom.P, atom.Div, atom.Table, atom.Section, atom.Article, atom.Header, atom.Footer, atom.Center, atom.Li:
+		return "\n\n" + strings.TrimSpace(mdChildren(n)) + "\n\n"
+	}
+	return mdChildren(n)
+}
+
+func mdWrap(s, marker string) string {
+	trimmed := strings.TrimSpace(s)
+	if trimmed == "" {
+		return s
+	}
+	lead := s[:len(s)-len(strings.TrimLeft(s, " "))]
+	trail := s[len(strings.TrimRight(s, " ")):]
+	return lead + marker + trimmed + marker + trail
+}
+
+func nodeText(n *xhtml.Node) string {
+	if n.Type == xhtml.TextNode {
+		return n.Data
+	}
+	if n.DataAtom == atom.Br {
+		return "\n"
+	}
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(nodeText(c))
+	}
+	return b.String()
+}
+
+func attrValue(n *xhtml.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func transcriptTitle(messages []transcriptMessage) string {
+	for _, m := range messages {
+		if s := strings.TrimSpace(m.Subject); s != "" {
+			return s
+		}
+	}
+	return "(no subject)"
+}
+
+func transcriptSender(from string) string {
+	if addr, err := mail.ParseAddress(from); err == nil && addr.Name != "" {
+		return addr.Name
+	}
+	return from
+}
+
+func renderTranscriptMarkdown(messages []transcriptMessage) string {
+	var b strings.Builder
+	b.WriteString("# " + transcriptTitle(messages) + "\n")
+	for _, m := range messages {
+		b.WriteString("\n## " + transcriptSender(m.From))
+		if m.Date != "" {
+			b.WriteString(" — " + m.Date)
+		}
+		b.WriteString("\n\n")
+		b.WriteString("**From:** " + m.From + "  \n")
+		if m.To != "" {
+			b.WriteString("**To:** " + m.To + "  \n")
+		}
+		if m.Cc != "" {
+			b.WriteString("**Cc:** " + m.Cc + "  \n")
+		}
+		b.WriteString("**Date:** " + m.Date + "\n\n")
+		if m.Body != "" {
+			b.WriteString(m.Body + "\n\n")
+		}
+		if len(m.Attachments) > 0 {
+			b.WriteString("**Attachments:**\n\n")
+			for _, a := range m.Attachments {
+				fmt.Fprintf(&b, "- [%s](%s) (%s, %s)\n", a.Filename, markdownLinkTarget(a.Link), a.MimeType, formatBytes(a.Size))
+			}
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// markdownLinkTarget wraps targets containing spaces or parentheses in <>.
+func markdownLinkTarget(s string) string {
+	if strings.ContainsAny(s, " ()") {
+		return "<" + s + ">"
+	}
+	return s
+}
+
+func renderTranscriptText(messages []transcriptMessage) string {
+	var b strings.Builder
+	b.WriteString(transcriptTitle(messages) + "\n")
+	for i, m := range messages {
+		fmt.Fprintf(&b, "\n=== Message %d/%d ===\n", i+1, len(messages))
+		b.WriteString("From: " + m.From + "\n")
+		if m.To != "" {
+			b.WriteString("To: " + m.To + "\n")
+		}
+		if m.Cc != "" {
+			b.WriteString("Cc: " + m.Cc + "\n")
+		}
+		b.WriteString("Date: " + m.Date + "\n\n")
+		if m.Body != "" {
+			b.WriteString(m.Body + "\n")
+		}
+		if len(m.Attachments) > 0 {
+			b.WriteString("\nAttachments:\n")
+			for _, a := range m.Attachments {
+				fmt.Fprintf(&b, "  %s (%s, %s) %s\n", a.Filename, a.MimeType, formatBytes(a.Size), a.Link)
+			}
+		}
+	}
+	return b.String()
+}
+
+// renderTranscriptHTML renders the Markdown transcript; raw HTML from the
+// original mail is not passed through.
+func renderTranscriptHTML(messages []transcriptMessage) (string, error) {
+	var body bytes.Buffer
+	md := goldmark.New(goldmark.WithExtensions(extension.GFM))
+	if err := md.Convert([]byte(renderTranscriptMarkdown(messages)), &body); err != nil {
+		return "", err
+	}
+	title := html.EscapeString(transcriptTitle(messages))
+	return "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>" + title + "</title>\n" +
+		"<style>body{font-family:sans-serif;max-width:50em;margin:2em auto;line-height:1.5}h2{border-top:1px solid #ddd;padding-top:1em}blockquote{color:#555;border-left:3px solid #ddd;margin-left:0;padding-left:1em}</style>\n" +
+		"</head>\n<body>\n" + body.String() + "</body>\n</html>\n", nil
+}
@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
+)
+
+func TestHTMLToMarkdown(t *testing.T) {
+	src := `<html><head><style>p{}</style></head><body>
+<div dir="ltr"><p>Hi <b>team</b>,</p>
+<p>See <a href="https://example.com/doc">the doc</a> and <a href="mailto:ada@example.com">ada@example.com</a>.</p>
+<ul><li>one</li><li><i>two</i></li></ul>
+<ol><li>first</li><li>second</li></ol>
+<pre>go test ./...
+ok</pre>
+<div class="gmail_signature">-- <br>Ada</div></div>
+<div class="gmail_quote"><div>On Mon, Bob wrote:</div><blockquote>old stuff</blockquote></div>
+</body></html>`
+
+	got := htmlToMarkdown(src, true)
+	want := "Hi **team**,\n\nSee [the doc](https://example.com/doc) and <ada@example.com>.\n\n- one\n- *two*\n\n1. first\n2. second\n\n```\ngo test ./...\nok\n```"
+	if got != want {
+		t.Fatalf("unexpected markdown:\n%s\n--- want ---\n%s", got, want)
+	}
+
+	kept := htmlToMarkdown(src, false)
+	if !strings.Contains(kept, "> old stuff") || !strings.Contains(kept, "Ada") {
+		t.Fatalf("--keep-quotes should keep quoted history: %q", kept)
+	}
+}
+
+func TestHTMLToMarkdown_OutlookReplyTail(t *testing.T) {
+	src := `<div><p>Sounds good.</p><div id="appendonsend"></div><hr><div id="divRplyFwdMsg"><b>From:</b> Bob</div><p>original</p></div>`
+	if got := htmlToMarkdown(src, true); got != "Sounds good." {
+		t.Fatalf("unexpected markdown: %q", got)
+	}
+}
+
+func TestStripQuotedText(t *testing.T) {
+	cases := map[string]string{
+		"Thanks!\n\nOn Mon, Jan 5, 2026 at 9:00 AM Bob <bob@x.com> wrote:\n> hello\n> there": "Thanks!",
+		"Sure.\nOn Mon, Jan 5, 2026 at 9:00 AM Bob\n<bob@x.com> wrote:\n\n> hello":           "Sure.",
+		"Yes\n\n-- \nAda Lovelace\nAnalyst":                                                  "Yes",
+		"Done\n\n-----Original Message-----\nFrom: Bob":                                      "Done",
+		"On Tuesday we ship:\n- a\n- b":                                                      "On Tuesday we ship:\n- a\n- b",
+	}
+	for in, want := range cases {
+		if got := stripQuotedText(in); got != want {
+			t.Fatalf("stripQuotedText(%q) = %q; want %q", in, got, want)
+		}
+	}
+}
+
+func newThreadExportTestService(t *testing.T) {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/users/me/threads/t1"):
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"id": "t1",
+				"messages": []map[string]any{
+					{"id": "m1", "threadId": "t1", "payload": map[string]any{
+						"mimeType": "multipart/mixed",
+						"headers": []map[string]any{
+							{"name": "From", "value": "Ada <ada@example.com>"},
+							{"name": "To", "value": "bob@example.com"},
+							{"name": "Subject", "value": "Launch plan"},
+							{"name": "Date", "value": "Mon, 5 Jan 2026 09:00:00 +0000"},
+						},
+						"parts": []map[string]any{
+							{"mimeType": "text/html", "body": map[string]any{"data": b64url("<p>Plan <b>attached</b>.</p><script>alert(1)</script>")}},
+							{"mimeType": "application/pdf", "filename": "plan.pdf", "body": map[string]any{"attachmentId": "a1", "size": 2048}},
+						},
+					}},
+					{"id": "m2", "threadId": "t1", "payload": map[string]any{
+						"mimeType": "text/plain",
+						"headers": []map[string]any{
+							{"name": "From", "value": "bob@example.com"},
+							{"name": "To", "value": "Ada <ada@example.com>"},
+							{"name": "Subject", "value": "Re: Launch plan"},
+							{"name": "Date", "value": "Mon, 5 Jan 2026 10:00:00 +0000"},
+						},
+						"body": map[string]any{"data": b64url("LGTM\n\nOn Mon, Ada wrote:\n> Plan attached.\n")},
+					}},
+				},
+			})
+		case strings.Contains(r.URL.Path, "/attachments/a1"):
+			_ = json.NewEncoder(w).Encode(map[string]any{"data": b64url("%PDF")})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	svc, err := gmail.NewService(context.Background(), option.WithoutAuthentication(), option.WithHTTPClient(srv.Client()), option.WithEndpoint(srv.URL+"/"))
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	origNew := newGmailService
+	t.Cleanup(func() { newGmailService = origNew })
+	newGmailService = func(context.Context, string) (*gmail.Service, error) { return svc, nil }
+}
+
+func TestGmailThreadExport_Markdown(t *testing.T) {
+	newThreadExportTestService(t)
+
+	out := runSendLike(t, &GmailThreadExportCmd{}, "t1")
+	doc, _ := out["transcript"].(string)
+	for _, want := range []string{
+		"# Launch plan\n",
+		"## Ada — Mon, 5 Jan 2026 09:00:00 +0000",
+		"**To:** bob@example.com",
+		"Plan **attached**.",
+		"- [plan.pdf](https://mail.google.com/mail/?authuser=a%40b.com#all/m1) (application/pdf, 2.0 KB)",
+		"LGTM",
+	} {
+		if !strings.Contains(doc, want) {
+			t.Fatalf("transcript missing %q:\n%s", want, doc)
+		}
+	}
+	if strings.Contains(doc, "alert") || strings.Contains(doc, "> Plan attached.") {
+		t.Fatalf("transcript should drop scripts and quoted replies:\n%s", doc)
+	}
+}
+
+func TestGmailThreadExport_HTMLWithDownloads(t *testing.T) {
+	newThreadExportTestService(t)
+	dir := t.TempDir()
+	outPath := filepath.Join(dir, "thread.html")
+
+	out := runSendLike(t, &GmailThreadExportCmd{}, "t1", "--format", "html", "--out", outPath, "--download", "--out-dir", dir)
+	if out["path"] != outPath || out["messages"] != float64(2) {
+		t.Fatalf("unexpected output: %#v", out)
+	}
+	data, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatalf("read export: %v", err)
+	}
+	doc := string(data)
+	if !strings.HasPrefix(doc, "<!DOCTYPE html>") || !strings.Contains(doc, "<title>Launch plan</title>") || !strings.Contains(doc, "<strong>attached</strong>") {
+		t.Fatalf("unexpected html:\n%s", doc)
+	}
+	if !strings.Contains(doc, filepath.ToSlash(dir)) || strings.Contains(doc, "mail.google.com") {
+		t.Fatalf("downloaded attachments should link local paths:\n%s", doc)
+	}
+}
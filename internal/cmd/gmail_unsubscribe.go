@@ -0,0 +1,540 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/netip"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"google.golang.org/api/gmail/v1"
+
+	"github.com/steipete/gogcli/internal/outfmt"
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+// unsubscribeHTTPClient performs RFC 8058 one-click POSTs. Redirects are not
+// followed: the POST must reach the advertised URL itself. The URLs come from
+// senders, so connections to non-public addresses are refused and no proxy is
+// used (it would hide the address actually dialed).
+var unsubscribeHTTPClient = &http.Client{
+	Timeout: 30 * time.Second,
+	Transport: &http.Transport{
+		DialContext:         (&net.Dialer{Timeout: 10 * time.Second, Control: rejectNonPublicAddress}).DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+	},
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// rejectNonPublicAddress refuses loopback, private, link-local, multicast and
+// unspecified addresses. It runs on the resolved IP, so a sender's DNS can't
+// point a one-click URL at the local network.
+func rejectNonPublicAddress(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	ip = ip.Unmap()
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("refusing to contact non-public address %s", ip)
+	}
+	return nil
+}
+
+const (
+	unsubscribeOneClick = "one-click"
+	unsubscribeMailto   = "mailto"
+	unsubscribeManual   = "manual"
+)
+
+// listUnsubscribe is the parsed List-Unsubscribe / List-Unsubscribe-Post pair.
+type listUnsubscribe struct {
+	HTTPS    string
+	Mailto   string
+	OneClick bool
+}
+
+func parseListUnsubscribeHeaders(header, post string) listUnsubscribe {
+	var out listUnsubscribe
+	for _, link := range parseListUnsubscribe(header) {
+		lower := strings.ToLower(link)
+		switch {
+		case strings.HasPrefix(lower, "https://") && out.HTTPS == "":
+			out.HTTPS = link
+		case strings.HasPrefix(lower, "mailto:") && out.Mailto == "":
+			out.Mailto = link
+		}
+	}
+	out.OneClick = out.HTTPS != "" && strings.Contains(strings.ToLower(post), "list-unsubscribe=one-click")
+	return out
+}
+
+// method reports how the unsubscribe would be performed.
+func (l listUnsubscribe) method() string {
+	switch {
+	case l.OneClick:
+		return unsubscribeOneClick
+	case l.Mailto != "":
+		return unsubscribeMailto
+	case l.HTTPS != "":
+		return unsubscribeManual
+	}
+	return ""
+}
+
+// parseUnsubscribeMailto splits a mailto: URI into recipient, subject and body.
+func parseUnsubscribeMailto(raw string) (to, subject, body string, err error) {
+	u, err := url.Parse(raw)
+	if err != nil || !strings.EqualFold(u.Scheme, "mailto") {
+		return "", "", "", fmt.Errorf("invalid mailto URI %q", raw)
+	}
+	to, err = url.PathUnescape(u.Opaque)
+	if err != nil || strings.TrimSpace(to) == "" {
+		return "", "", "", fmt.Errorf("invalid mailto URI %q", raw)
+	}
+	q := u.Query()
+	subject = q.Get("subject")
+	if subject == "" {
+		subject = "unsubscribe"
+	}
+	body = q.Get("body")
+	if body == "" {
+		body = "unsubscribe"
+	}
+	return to, subject, body, nil
+}
+
+func postOneClickUnsubscribe(ctx context.Context, target string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, strings.NewReader("List-Unsubscribe=One-Click"))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := unsubscribeHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("one-click unsubscribe: HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// subscriptionHeaders are the metadata headers unsubscribe and subscriptions read.
+var subscriptionHeaders = []string{"From", "Subject", "Date", "List-Id", "List-Unsubscribe", "List-Unsubscribe-Post"}
+
+// fetchMessageMetadata gets the given headers, label IDs, date and size of each
+// message, preserving order.
+func fetchMessageMetadata(ctx context.Context, svc *gmail.Service, ids []string, headers ...string) ([]*gmail.Message, error) {
+	const maxConcurrency = 10
+	sem := make(chan struct{}, maxConcurrency)
+	out := make([]*gmail.Message, len(ids))
+	errs := make([]error, len(ids))
+
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func(idx int, messageID string) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				errs[idx] = ctx.Err()
+				return
+			}
+			msg, err := svc.Users.Messages.Get("me", messageID).
+				Format("metadata").
+				MetadataHeaders(headers...).
+				Fields("id,threadId,labelIds,internalDate,sizeEstimate,payload(headers)").
+				Context(ctx).
+				Do()
+			if err != nil {
+				errs[idx] = fmt.Errorf("message %s: %w", messageID, err)
+				return
+			}
+			out[idx] = msg
+		}(i, id)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// senderAddress returns the bare, lowercased address of a From header.
+func senderAddress(from string) string {
+	if addr, err := mail.ParseAddress(from); err == nil {
+		return strings.ToLower(addr.Address)
+	}
+	return strings.ToLower(strings.Trim(strings.TrimSpace(from), "<>"))
+}
+
+// subscriptionKey groups mail by List-Id, falling back to the sender.
+func subscriptionKey(listID, from string) string {
+	if id := strings.TrimSpace(listID); id != "" {
+		if start, end := strings.LastIndex(id, "<"), strings.LastIndex(id, ">"); start >= 0 && end > start {
+			id = id[start+1 : end]
+		}
+		return strings.ToLower(id)
+	}
+	return senderAddress(from)
+}
+
+type GmailUnsubscribeCmd struct {
+	MessageID string `arg:"" name:"messageId" optional:"" help:"Message to unsubscribe from"`
+	Query     string `name:"query" short:"q" help:"Unsubscribe from every list matching this Gmail search query"`
+	Max       int64  `name:"max" help:"Max messages to scan with --query" default:"100"`
+	Filter    bool   `name:"filter" help:"Also create a filter archiving future mail from senders that were unsubscribed"`
+	DryRun    bool   `name:"dry-run" help:"Show what would be done without unsubscribing"`
+	From      string `name:"from" help:"Send-as address for mailto unsubscribes"`
+}
+
+type unsubscribeResult struct {
+	Sender    string `json:"sender"`
+	ListID    string `json:"listId,omitempty"`
+	MessageID string `json:"messageId"`
+	Method    string `json:"method,omitempty"`
+	Target    string `json:"target,omitempty"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+	FilterID  string `json:"filterId,omitempty"`
+}
+
+func (c *GmailUnsubscribeCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+	messageID := strings.TrimSpace(c.MessageID)
+	query := strings.TrimSpace(c.Query)
+	if (messageID == "") == (query == "") {
+		return usage("pass a messageId or --query (not both)")
+	}
+	if c.Max <= 0 {
+		return usage("--max must be > 0")
+	}
+
+	svc, err := newGmailService(ctx, account)
+	if err != nil {
+		return err
+	}
+
+	ids := []string{messageID}
+	if query != "" {
+		ids, err = listGmailMessageIDs(ctx, svc, query, c.Max)
+		if err != nil {
+			return err
+		}
+	}
+	msgs, err := fetchMessageMetadata(ctx, svc, ids, subscriptionHeaders...)
+	if err != nil {
+		return err
+	}
+
+	// One unsubscribe per list, using the newest message (search order).
+	results := []unsubscribeResult{}
+	var targets []listUnsubscribe
+	seen := map[string]bool{}
+	for _, m := range msgs {
+		from := headerValue(m.Payload, "From")
+		listID := headerValue(m.Payload, "List-Id")
+		key := subscriptionKey(listID, from)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		lu := parseListUnsubscribeHeaders(headerValue(m.Payload, "List-Unsubscribe"), headerValue(m.Payload, "List-Unsubscribe-Post"))
+		results = append(results, unsubscribeResult{Sender: senderAddress(from), ListID: strings.TrimSpace(listID), MessageID: m.Id, Method: lu.method()})
+		targets = append(targets, lu)
+	}
+
+	if !c.DryRun {
+		actionable := 0
+		for _, r := range results {
+			if r.Method == unsubscribeOneClick || r.Method == unsubscribeMailto {
+				actionable++
+			}
+		}
+		// Each target is sender-controlled: a POST to their URL or a mail
+		// from this account, so a batch needs a yes first.
+		if actionable > 1 {
+			if confirmErr := confirmDestructive(ctx, flags, fmt.Sprintf("unsubscribe from %d lists (one-click POSTs and mailto sends)", actionable)); confirmErr != nil {
+				return confirmErr
+			}
+		}
+	}
+
+	var fromAddr string
+	var filterKeys map[string]bool
+	failed := 0
+	for i := range results {
+		r := &results[i]
+		lu := targets[i]
+		switch r.Method {
+		case unsubscribeOneClick:
+			r.Target = lu.HTTPS
+		case unsubscribeMailto:
+			r.Target = lu.Mailto
+		case unsubscribeManual:
+			r.Target = lu.HTTPS
+		}
+
+		switch {
+		case r.Method == "":
+			r.Status = "no-header"
+		case c.DryRun:
+			r.Status = "dry-run"
+		case r.Method == unsubscribeManual:
+			// Without List-Unsubscribe-Post the link may need a browser.
+			r.Status = "manual"
+		case r.Method == unsubscribeOneClick:
+			if postErr := postOneClickUnsubscribe(ctx, lu.HTTPS); postErr != nil {
+				r.Status, r.Error = "failed", postErr.Error()
+			} else {
+				r.Status = "unsubscribed"
+			}
+		case r.Method == unsubscribeMailto:
+			if fromAddr == "" {
+				fromAddr, _, err = resolveSendFrom(ctx, svc, account, c.From)
+				if err != nil {
+					return err
+				}
+			}
+			if sendErr := sendUnsubscribeMailto(ctx, svc, fromAddr, lu.Mailto); sendErr != nil {
+				r.Status, r.Error = "failed", sendErr.Error()
+			} else {
+				r.Status = "sent"
+			}
+		}
+		if r.Status == "failed" {
+			failed++
+			if !outfmt.IsJSON(ctx) {
+				u.Err().Errorf("%s: %s", r.Sender, r.Error)
+			}
+		}
+
+		// Only archive senders we actually unsubscribed from.
+		if c.Filter && (r.Status == "unsubscribed" || r.Status == "sent") && r.Sender != "" {
+			if filterKeys == nil {
+				filterKeys, err = existingFilterKeys(ctx, svc)
+				if err != nil {
+					return err
+				}
+			}
+			spec := filterSpec{From: r.Sender, Archive: true}
+			if !filterKeys[spec.key()] {
+				f, buildErr := spec.toGmail(nil)
+				if buildErr != nil {
+					return buildErr
+				}
+				created, createErr := svc.Users.Settings.Filters.Create("me", f).Context(ctx).Do()
+				if createErr != nil {
+					return fmt.Errorf("create filter for %s: %w", r.Sender, createErr)
+				}
+				filterKeys[spec.key()] = true
+				r.FilterID = created.Id
+			}
+		}
+	}
+
+	if outfmt.IsJSON(ctx) {
+		if err := outfmt.WriteJSON(os.Stdout, map[string]any{"dryRun": c.DryRun, "results": results}); err != nil {
+			return err
+		}
+	} else {
+		if len(results) == 0 {
+			u.Err().Println("No messages")
+		}
+		for _, r := range results {
+			line := fmt.Sprintf("%s\t%s\t%s", r.Sender, r.Status, r.Method)
+			if r.Target != "" && (r.Status == "manual" || c.DryRun) {
+				line += "\t" + r.Target
+			}
+			if r.FilterID != "" {
+				line += "\tfilter:" + r.FilterID
+			}
+			u.Out().Println(line)
+		}
+	}
+	if failed > 0 {
+		return &ExitError{Code: 1, Err: fmt.Errorf("%d unsubscribe(s) failed", failed)}
+	}
+	return nil
+}
+
+func sendUnsubscribeMailto(ctx context.Context, svc *gmail.Service, fromAddr, mailto string) error {
+	to, subject, body, err := parseUnsubscribeMailto(mailto)
+	if err != nil {
+		return err
+	}
+	_, err = sendGmailBatches(ctx, svc, sendMessageOptions{
+		FromAddr: fromAddr,
+		Subject:  subject,
+		Body:     body,
+	}, []sendBatch{{To: []string{to}}})
+	return err
+}
+
+func existingFilterKeys(ctx context.Context, svc *gmail.Service) (map[string]bool, error) {
+	resp, err := svc.Users.Settings.Filters.List("me").Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[string]bool, len(resp.Filter))
+	for _, f := range resp.Filter {
+		if f != nil {
+			keys[filterSpecFromGmail(f, nil).key()] = true
+		}
+	}
+	return keys, nil
+}
+
+type GmailSubscriptionsCmd struct {
+	Query string `name:"query" short:"q" help:"Gmail search query to scan" default:"newer_than:90d"`
+	Max   int64  `name:"max" help:"Max messages to scan" default:"1000"`
+	Top   int    `name:"top" help:"Only show the N biggest senders (0 = all)" default:"0"`
+}
+
+type subscriptionItem struct {
+	ListID      string `json:"listId,omitempty"`
+	Sender      string `json:"sender"`
+	Name        string `json:"name,omitempty"`
+	Messages    int    `json:"messages"`
+	Unread      int    `json:"unread"`
+	Last        string `json:"last,omitempty"`
+	Unsubscribe string `json:"unsubscribe,omitempty"`
+	MessageID   string `json:"messageId"`
+	lastMillis  int64
+}
+
+func (c *GmailSubscriptionsCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+	if c.Max <= 0 {
+		return usage("--max must be > 0")
+	}
+	if c.Top < 0 {
+		return usage("--top must be >= 0")
+	}
+
+	svc, err := newGmailService(ctx, account)
+	if err != nil {
+		return err
+	}
+	ids, err := listGmailMessageIDs(ctx, svc, c.Query, c.Max)
+	if err != nil {
+		return err
+	}
+	msgs, err := fetchMessageMetadata(ctx, svc, ids, subscriptionHeaders...)
+	if err != nil {
+		return err
+	}
+
+	items := summarizeSubscriptions(msgs)
+	if c.Top > 0 && len(items) > c.Top {
+		items = items[:c.Top]
+	}
+
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(os.Stdout, map[string]any{
+			"query":         c.Query,
+			"scanned":       len(msgs),
+			"subscriptions": items,
+		})
+	}
+	if len(items) == 0 {
+		u.Err().Printf("No mailing lists in %d messages", len(msgs))
+		return nil
+	}
+	w, flush := tableWriter(ctx)
+	defer flush()
+	fmt.Fprintln(w, "MESSAGES\tUNREAD\tSENDER\tLIST\tLAST\tUNSUBSCRIBE\tMESSAGE")
+	for _, it := range items {
+		fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\t%s\t%s\n", it.Messages, it.Unread, sanitizeTab(it.Sender), sanitizeTab(orDash(it.ListID)), it.Last, orDash(it.Unsubscribe), it.MessageID)
+	}
+	return nil
+}
+
+// summarizeSubscriptions groups list mail (List-Id or List-Unsubscribe) by
+// list, most messages first.
+func summarizeSubscriptions(msgs []*gmail.Message) []subscriptionItem {
+	byKey := map[string]*subscriptionItem{}
+	var order []string
+	for _, m := range msgs {
+		if m == nil {
+			continue
+		}
+		listID := strings.TrimSpace(headerValue(m.Payload, "List-Id"))
+		unsub := headerValue(m.Payload, "List-Unsubscribe")
+		if listID == "" && strings.TrimSpace(unsub) == "" {
+			continue
+		}
+		from := headerValue(m.Payload, "From")
+		key := subscriptionKey(listID, from)
+		it, ok := byKey[key]
+		if !ok {
+			it = &subscriptionItem{ListID: listID, Sender: senderAddress(from)}
+			if addr, err := mail.ParseAddress(from); err == nil {
+				it.Name = addr.Name
+			}
+			byKey[key] = it
+			order = append(order, key)
+		}
+		it.Messages++
+		for _, l := range m.LabelIds {
+			if l == "UNREAD" {
+				it.Unread++
+				break
+			}
+		}
+		if m.InternalDate >= it.lastMillis {
+			it.lastMillis = m.InternalDate
+			it.MessageID = m.Id
+			it.Last = time.UnixMilli(m.InternalDate).Format("2006-01-02")
+			if method := parseListUnsubscribeHeaders(unsub, headerValue(m.Payload, "List-Unsubscribe-Post")).method(); method != "" {
+				it.Unsubscribe = method
+			}
+		}
+	}
+
+	items := make([]subscriptionItem, 0, len(order))
+	for _, k := range order {
+		items = append(items, *byKey[k])
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Messages > items[j].Messages })
+	return items
+}
+
+func orDash(s string) string {
+	if strings.TrimSpace(s) == "" {
+		return "-"
+	}
+	return s
+}
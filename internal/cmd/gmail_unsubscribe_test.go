@@ -0,0 +1,250 @@
+package cmd
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
+
+	"github.com/steipete/gogcli/internal/outfmt"
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+func TestParseListUnsubscribeHeaders(t *testing.T) {
+	lu := parseListUnsubscribeHeaders("<mailto:leave@lists.example.com?subject=stop>, <https://example.com/u/123>", "List-Unsubscribe=One-Click")
+	if !lu.OneClick || lu.HTTPS != "https://example.com/u/123" || lu.method() != unsubscribeOneClick {
+		t.Fatalf("unexpected parse: %#v", lu)
+	}
+	lu = parseListUnsubscribeHeaders("<https://example.com/u/123>, <mailto:leave@lists.example.com>", "")
+	if lu.OneClick || lu.method() != unsubscribeMailto {
+		t.Fatalf("without List-Unsubscribe-Post mailto should win: %#v", lu)
+	}
+	if m := parseListUnsubscribeHeaders("<https://example.com/u>", "").method(); m != unsubscribeManual {
+		t.Fatalf("expected manual, got %q", m)
+	}
+
+	to, subject, body, err := parseUnsubscribeMailto("mailto:leave%2Bx@lists.example.com?subject=Remove%20me")
+	if err != nil || to != "leave+x@lists.example.com" || subject != "Remove me" || body != "unsubscribe" {
+		t.Fatalf("unexpected mailto parse: %q %q %q %v", to, subject, body, err)
+	}
+}
+
+func newUnsubscribeTestService(t *testing.T, messages map[string][]map[string]any) (sent *[]string, filters *[]gmail.Filter) {
+	t.Helper()
+
+	var sentRaw []string
+	var created []gmail.Filter
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/users/me/messages") && r.Method == http.MethodGet:
+			list := []map[string]any{}
+			for _, id := range []string{"m1", "m2", "m3"} {
+				if _, ok := messages[id]; ok {
+					list = append(list, map[string]any{"id": id})
+				}
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"messages": list})
+		case strings.Contains(r.URL.Path, "/users/me/messages/") && r.Method == http.MethodGet:
+			id := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+			headers, ok := messages[id]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			labels := []string{"INBOX"}
+			if id == "m1" {
+				labels = append(labels, "UNREAD")
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": id, "labelIds": labels, "internalDate": "1700000000000", "payload": map[string]any{"headers": headers}})
+		case strings.HasSuffix(r.URL.Path, "/users/me/messages/send"):
+			var msg gmail.Message
+			_ = json.NewDecoder(r.Body).Decode(&msg)
+			raw, _ := base64.RawURLEncoding.DecodeString(msg.Raw)
+			sentRaw = append(sentRaw, string(raw))
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": "s1", "threadId": "t1"})
+		case strings.Contains(r.URL.Path, "/settings/sendAs/"):
+			http.NotFound(w, r)
+		case strings.HasSuffix(r.URL.Path, "/settings/filters") && r.Method == http.MethodGet:
+			_ = json.NewEncoder(w).Encode(map[string]any{"filter": []any{}})
+		case strings.HasSuffix(r.URL.Path, "/settings/filters") && r.Method == http.MethodPost:
+			var f gmail.Filter
+			_ = json.NewDecoder(r.Body).Decode(&f)
+			created = append(created, f)
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": "f1"})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	svc, err := gmail.NewService(context.Background(), option.WithoutAuthentication(), option.WithHTTPClient(srv.Client()), option.WithEndpoint(srv.URL+"/"))
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	origNew := newGmailService
+	t.Cleanup(func() { newGmailService = origNew })
+	newGmailService = func(context.Context, string) (*gmail.Service, error) { return svc, nil }
+	return &sentRaw, &created
+}
+
+func TestGmailUnsubscribe_OneClickMailtoAndFilter(t *testing.T) {
+	var posts []string
+	oneClick := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		posts = append(posts, r.Method+" "+r.URL.Path+" "+string(body)+" "+r.Header.Get("Content-Type"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer oneClick.Close()
+	origClient := unsubscribeHTTPClient
+	t.Cleanup(func() { unsubscribeHTTPClient = origClient })
+	unsubscribeHTTPClient = oneClick.Client()
+
+	sent, filters := newUnsubscribeTestService(t, map[string][]map[string]any{
+		"m1": {
+			{"name": "From", "value": "News <news@shop.example.com>"},
+			{"name": "List-Id", "value": "Shop News <news.shop.example.com>"},
+			{"name": "List-Unsubscribe", "value": "<" + oneClick.URL + "/u/1>"},
+			{"name": "List-Unsubscribe-Post", "value": "List-Unsubscribe=One-Click"},
+		},
+		"m2": {
+			{"name": "From", "value": "News <news@shop.example.com>"},
+			{"name": "List-Id", "value": "<news.shop.example.com>"},
+			{"name": "List-Unsubscribe", "value": "<" + oneClick.URL + "/u/2>"},
+			{"name": "List-Unsubscribe-Post", "value": "List-Unsubscribe=One-Click"},
+		},
+		"m3": {
+			{"name": "From", "value": "digest@forum.example.org"},
+			{"name": "List-Unsubscribe", "value": "<mailto:leave@forum.example.org?subject=unsubscribe%20me>"},
+		},
+	})
+
+	args := []string{"--query", "category:promotions", "--filter"}
+	if err := runKong(t, &GmailUnsubscribeCmd{}, args, context.Background(), &RootFlags{Account: "a@b.com"}); err == nil || !strings.Contains(err.Error(), "unsubscribe from 2 lists") {
+		t.Fatalf("expected a batch to need confirmation, got %v", err)
+	}
+	if len(posts) != 0 || len(*sent) != 0 {
+		t.Fatalf("nothing may be sent before confirming: posts=%v sent=%d", posts, len(*sent))
+	}
+
+	u, err := ui.New(ui.Options{Stdout: os.Stdout, Stderr: os.Stderr, Color: "never"})
+	if err != nil {
+		t.Fatalf("ui.New: %v", err)
+	}
+	ctx := outfmt.WithMode(ui.WithUI(context.Background(), u), outfmt.Mode{JSON: true})
+	var out map[string]any
+	stdout := captureStdout(t, func() {
+		if err := runKong(t, &GmailUnsubscribeCmd{}, args, ctx, &RootFlags{Account: "a@b.com", Force: true}); err != nil {
+			t.Fatalf("unsubscribe: %v", err)
+		}
+	})
+	if err := json.Unmarshal([]byte(stdout), &out); err != nil {
+		t.Fatalf("json: %v (%q)", err, stdout)
+	}
+	results := out["results"].([]any)
+	if len(results) != 2 {
+		t.Fatalf("expected one result per list, got %#v", results)
+	}
+	first, second := results[0].(map[string]any), results[1].(map[string]any)
+	if first["status"] != "unsubscribed" || first["method"] != unsubscribeOneClick || second["status"] != "sent" || second["method"] != unsubscribeMailto {
+		t.Fatalf("unexpected results: %#v", results)
+	}
+	if len(posts) != 1 || posts[0] != "POST /u/1 List-Unsubscribe=One-Click application/x-www-form-urlencoded" {
+		t.Fatalf("unexpected one-click requests: %v", posts)
+	}
+	raw := strings.ReplaceAll((*sent)[0], "\r\n", "\n")
+	if len(*sent) != 1 || !strings.Contains(raw, "To: leave@forum.example.org\n") || !strings.Contains(raw, "Subject: unsubscribe me\n") {
+		t.Fatalf("unexpected mailto message:\n%s", raw)
+	}
+	if len(*filters) != 2 || (*filters)[0].Criteria.From != "news@shop.example.com" || (*filters)[0].Action.RemoveLabelIds[0] != "INBOX" {
+		t.Fatalf("unexpected filters: %#v", *filters)
+	}
+}
+
+func TestGmailUnsubscribe_DryRunAndValidation(t *testing.T) {
+	sent, filters := newUnsubscribeTestService(t, map[string][]map[string]any{
+		"m3": {
+			{"name": "From", "value": "digest@forum.example.org"},
+			{"name": "List-Unsubscribe", "value": "<mailto:leave@forum.example.org>"},
+		},
+	})
+	out := runSendLike(t, &GmailUnsubscribeCmd{}, "m3", "--dry-run", "--filter")
+	r := out["results"].([]any)[0].(map[string]any)
+	if r["status"] != "dry-run" || r["target"] != "mailto:leave@forum.example.org" || len(*sent)+len(*filters) != 0 {
+		t.Fatalf("dry run must not act: %#v", out)
+	}
+
+	for _, args := range [][]string{{}, {"m3", "--query", "x"}} {
+		if err := runKong(t, &GmailUnsubscribeCmd{}, args, context.Background(), &RootFlags{Account: "a@b.com"}); err == nil {
+			t.Fatalf("expected usage error for %v", args)
+		}
+	}
+}
+
+func TestGmailSubscriptions_GroupsByList(t *testing.T) {
+	newUnsubscribeTestService(t, map[string][]map[string]any{
+		"m1": {
+			{"name": "From", "value": "News <news@shop.example.com>"},
+			{"name": "List-Id", "value": "<news.shop.example.com>"},
+			{"name": "List-Unsubscribe", "value": "<https://shop.example.com/u>"},
+			{"name": "List-Unsubscribe-Post", "value": "List-Unsubscribe=One-Click"},
+		},
+		"m2": {
+			{"name": "From", "value": "Other Sender <deals@shop.example.com>"},
+			{"name": "List-Id", "value": "Shop <news.shop.example.com>"},
+		},
+		"m3": {
+			{"name": "From", "value": "friend@example.net"},
+		},
+	})
+
+	out := runSendLike(t, &GmailSubscriptionsCmd{})
+	subs := out["subscriptions"].([]any)
+	if out["scanned"] != float64(3) || len(subs) != 1 {
+		t.Fatalf("unexpected report: %#v", out)
+	}
+	s := subs[0].(map[string]any)
+	if s["messages"] != float64(2) || s["unread"] != float64(1) || s["sender"] != "news@shop.example.com" || s["unsubscribe"] != unsubscribeOneClick {
+		t.Fatalf("unexpected subscription: %#v", s)
+	}
+}
+
+func TestGmailUnsubscribe_FilterOnlyAfterUnsubscribing(t *testing.T) {
+	_, filters := newUnsubscribeTestService(t, map[string][]map[string]any{
+		"m1": {
+			{"name": "From", "value": "promo@shop.example.com"},
+			{"name": "List-Unsubscribe", "value": "<https://shop.example.com/u>"},
+		},
+	})
+	out := runSendLike(t, &GmailUnsubscribeCmd{}, "m1", "--filter")
+	results := out["results"].([]any)
+	if len(results) != 1 || results[0].(map[string]any)["status"] != "manual" || len(*filters) != 0 {
+		t.Fatalf("manual unsubscribes must not create filters: %#v %#v", results, *filters)
+	}
+}
+
+func TestUnsubscribeHTTPClient_RejectsNonPublicAddresses(t *testing.T) {
+	for _, addr := range []string{"127.0.0.1:443", "[::1]:443", "10.1.2.3:443", "192.168.0.10:80", "169.254.169.254:80", "[::ffff:127.0.0.1]:443", "0.0.0.0:443"} {
+		if err := rejectNonPublicAddress("tcp", addr, nil); err == nil {
+			t.Fatalf("expected %s to be rejected", addr)
+		}
+	}
+	if err := rejectNonPublicAddress("tcp", "93.184.216.34:443", nil); err != nil {
+		t.Fatalf("public address rejected: %v", err)
+	}
+
+	hit := false
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { hit = true }))
+	defer srv.Close()
+	if err := postOneClickUnsubscribe(context.Background(), srv.URL+"/u"); err == nil || !strings.Contains(err.Error(), "non-public") || hit {
+		t.Fatalf("expected loopback one-click target to be refused, got %v (hit=%v)", err, hit)
+	}
+}
@@ -3,11 +3,15 @@ package cmd
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"sort"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/alecthomas/kong"
@@ -30,6 +34,8 @@ type GmailWatchCmd struct {
 	Renew  GmailWatchRenewCmd  `cmd:"" name:"renew" help:"Renew Gmail watch using stored config"`
 	Stop   GmailWatchStopCmd   `cmd:"" name:"stop" help:"Stop Gmail watch and clear stored state"`
 	Serve  GmailWatchServeCmd  `cmd:"" name:"serve" help:"Run Pub/Sub push handler"`
+
+	Deliveries GmailWatchDeliveriesCmd `cmd:"" name:"deliveries" help:"Inspect and replay undelivered hook payloads"`
 }
 
 type GmailWatchStartCmd struct {
@@ -38,6 +44,7 @@ type GmailWatchStartCmd struct {
 	TTL         string   `name:"ttl" help:"Renew after duration (seconds or Go duration)"`
 	HookURL     string   `name:"hook-url" help:"Webhook URL to forward messages"`
 	HookToken   string   `name:"hook-token" help:"Webhook bearer token"`
+	HookSecret  string   `name:"hook-secret" help:"Secret for HMAC-SHA256 signatures (X-Gog-Signature) on hook requests"`
 	IncludeBody bool     `name:"include-body" help:"Include text/plain body in hook payload"`
 	MaxBytes    int      `name:"max-bytes" help:"Max bytes of body to include" default:"20000"`
 }
@@ -63,6 +70,9 @@ func (c *GmailWatchStartCmd) Run(ctx context.Context, kctx *kong.Context, flags
 			return err
 		}
 	}
+	if err := setHookSecret(hook, c.HookSecret); err != nil {
+		return err
+	}
 
 	svc, err := newGmailService(ctx, account)
 	if err != nil {
@@ -191,28 +201,30 @@ func (c *GmailWatchStopCmd) Run(ctx context.Context, flags *RootFlags) error {
 }
 
 type GmailWatchServeCmd struct {
-	Bind         string `name:"bind" help:"Bind address" default:"127.0.0.1"`
-	Port         int    `name:"port" help:"Listen port" default:"8788"`
-	Path         string `name:"path" help:"Push handler path" default:"/gmail-pubsub"`
-	Timezone     string `name:"timezone" short:"z" help:"Output timezone (IANA name, e.g. America/New_York, UTC). Default: local"`
-	Local        bool   `name:"local" help:"Use local timezone (default behavior, useful to override --timezone)"`
-	VerifyOIDC   bool   `name:"verify-oidc" help:"Verify Pub/Sub OIDC tokens"`
-	OIDCEmail    string `name:"oidc-email" help:"Expected service account email"`
-	OIDCAudience string `name:"oidc-audience" help:"Expected OIDC audience"`
-	SharedToken  string `name:"token" help:"Shared token for x-gog-token or ?token="`
-	HookURL      string `name:"hook-url" help:"Webhook URL to forward messages"`
-	HookToken    string `name:"hook-token" help:"Webhook bearer token"`
-	IncludeBody  bool   `name:"include-body" help:"Include text/plain body in hook payload"`
-	MaxBytes     int    `name:"max-bytes" help:"Max bytes of body to include" default:"20000"`
-	SaveHook     bool   `name:"save-hook" help:"Persist hook settings to watch state"`
+	Bind            string        `name:"bind" help:"Bind address" default:"127.0.0.1"`
+	Port            int           `name:"port" help:"Listen port" default:"8788"`
+	Path            string        `name:"path" help:"Push handler path" default:"/gmail-pubsub"`
+	Timezone        string        `name:"timezone" short:"z" help:"Output timezone (IANA name, e.g. America/New_York, UTC). Default: local"`
+	Local           bool          `name:"local" help:"Use local timezone (default behavior, useful to override --timezone)"`
+	VerifyOIDC      bool          `name:"verify-oidc" help:"Verify Pub/Sub OIDC tokens"`
+	OIDCEmail       string        `name:"oidc-email" help:"Expected service account email"`
+	OIDCAudience    string        `name:"oidc-audience" help:"Expected OIDC audience"`
+	SharedToken     string        `name:"token" help:"Shared token for x-gog-token or ?token="`
+	HookURL         string        `name:"hook-url" help:"Webhook URL to forward messages"`
+	HookToken       string        `name:"hook-token" help:"Webhook bearer token"`
+	HookSecret      string        `name:"hook-secret" help:"Secret for HMAC-SHA256 signatures (X-Gog-Signature) on hook requests"`
+	HookRetries     int           `name:"hook-retries" help:"Retries with exponential backoff before a payload is spooled" default:"3"`
+	Exec            string        `name:"exec" help:"Run this shell command per new message instead of posting to a URL (payload JSON on stdin)"`
+	ExecTimeout     time.Duration `name:"exec-timeout" help:"Kill the --exec command after this long" default:"1m"`
+	ExecConcurrency int           `name:"exec-concurrency" help:"Max hook deliveries (--exec commands or hook posts) running at once" default:"4"`
+	IncludeBody     bool          `name:"include-body" help:"Include text/plain body in hook payload"`
+	MaxBytes        int           `name:"max-bytes" help:"Max bytes of body to include" default:"20000"`
+	SaveHook        bool          `name:"save-hook" help:"Persist hook settings to watch state"`
+	AllAccounts     bool          `name:"all-accounts" help:"Serve every account with stored watch state, routing pushes by emailAddress"`
 }
 
 func (c *GmailWatchServeCmd) Run(ctx context.Context, kctx *kong.Context, flags *RootFlags) error {
 	u := ui.FromContext(ctx)
-	account, err := requireAccount(flags)
-	if err != nil {
-		return err
-	}
 	if !strings.HasPrefix(c.Path, "/") {
 		return usage("--path must start with '/'")
 	}
@@ -228,28 +240,149 @@ func (c *GmailWatchServeCmd) Run(ctx context.Context, kctx *kong.Context, flags
 	if c.OIDCAudience != "" && !c.VerifyOIDC {
 		return usage("--oidc-audience requires --verify-oidc")
 	}
+	if c.HookRetries < 0 {
+		return usage("--hook-retries must be >= 0")
+	}
+	if c.Exec != "" && (c.HookURL != "" || c.HookToken != "" || c.HookSecret != "") {
+		return usage("--exec cannot be combined with --hook-url, --hook-token or --hook-secret")
+	}
+	if c.ExecTimeout <= 0 {
+		return usage("--exec-timeout must be > 0")
+	}
+	if c.ExecConcurrency <= 0 {
+		return usage("--exec-concurrency must be > 0")
+	}
+	if c.AllAccounts && c.SaveHook {
+		return usage("--save-hook cannot be combined with --all-accounts; run it per account")
+	}
 
 	loc, err := resolveOutputLocation(c.Timezone, c.Local)
 	if err != nil {
 		return err
 	}
 
-	store, err := loadGmailWatchStore(account)
+	validator := (*idtoken.Validator)(nil)
+	if c.VerifyOIDC {
+		validator, err = newOIDCValidator(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	base := gmailWatchServeConfig{
+		Bind:         c.Bind,
+		Port:         c.Port,
+		Path:         c.Path,
+		VerifyOIDC:   c.VerifyOIDC,
+		OIDCEmail:    c.OIDCEmail,
+		OIDCAudience: c.OIDCAudience,
+		SharedToken:  c.SharedToken,
+		HookTimeout:  defaultHookRequestTimeoutSec * time.Second,
+		HookRetries:  c.HookRetries,
+		ExecTimeout:  c.ExecTimeout,
+		HistoryMax:   defaultHistoryMaxResults,
+		ResyncMax:    defaultHistoryResyncMax,
+		DateLocation: loc,
+	}
+	hookClient := &http.Client{Timeout: base.HookTimeout}
+	hooks := newGmailHookQueue(ctx, c.ExecConcurrency)
+	newServer := func(cfg gmailWatchServeConfig, store *gmailWatchStore) *gmailWatchServer {
+		return &gmailWatchServer{
+			cfg:        cfg,
+			store:      store,
+			validator:  validator,
+			newService: newGmailService,
+			hookClient: hookClient,
+			hooks:      hooks,
+			logf:       u.Err().Printf,
+			warnf:      u.Err().Printf,
+		}
+	}
+
+	var server *gmailWatchServer
+	if c.AllAccounts {
+		server, err = c.multiAccountServer(kctx, base, newServer)
+	} else {
+		server, err = c.singleAccountServer(kctx, flags, base, newServer)
+	}
 	if err != nil {
 		return err
 	}
+
+	addr := net.JoinHostPort(c.Bind, strconv.Itoa(c.Port))
+	u.Err().Printf("watch: listening on %s%s", addr, c.Path)
+	if server.accounts != nil {
+		accounts := make([]string, 0, len(server.accounts))
+		for _, srv := range server.accounts {
+			accounts = append(accounts, srv.cfg.Account)
+		}
+		sort.Strings(accounts)
+		u.Err().Printf("watch: serving %d accounts: %s", len(accounts), strings.Join(accounts, ", "))
+	}
+
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           server,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	return serveGmailWatch(ctx, httpServer, hooks)
+}
+
+// serveGmailWatch runs the push server until it fails or SIGINT/SIGTERM, then
+// finishes in-flight pushes and drains the hook queue. Payloads that are not
+// delivered in time stay in the spool for `watch deliveries replay`.
+func serveGmailWatch(ctx context.Context, httpServer *http.Server, hooks *gmailHookQueue) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- listenAndServe(httpServer) }()
+
+	var err error
+	select {
+	case err = <-serveErr:
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), gmailHookDrainTimeout)
+		err = httpServer.Shutdown(shutdownCtx)
+		cancel()
+		if listenErr := <-serveErr; err == nil {
+			err = listenErr
+		}
+	}
+	hooks.close(gmailHookDrainTimeout)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+func (c *GmailWatchServeCmd) singleAccountServer(kctx *kong.Context, flags *RootFlags, base gmailWatchServeConfig, newServer func(gmailWatchServeConfig, *gmailWatchStore) *gmailWatchServer) (*gmailWatchServer, error) {
+	account, err := requireAccount(flags)
+	if err != nil {
+		return nil, err
+	}
+	store, err := loadGmailWatchStore(account)
+	if err != nil {
+		return nil, err
+	}
 	state := store.Get()
 
 	hookURL := c.HookURL
+	hookExec := c.Exec
 	hookToken := c.HookToken
+	hookSecret := c.HookSecret
 	includeBody := c.IncludeBody
 	maxBytes := c.MaxBytes
 
-	if hookURL == "" && state.Hook != nil {
+	if hookURL == "" && hookExec == "" && state.Hook != nil {
 		hookURL = state.Hook.URL
+		hookExec = state.Hook.Exec
 		if !flagProvided(kctx, "hook-token") {
 			hookToken = state.Hook.Token
 		}
+		if !flagProvided(kctx, "hook-secret") {
+			hookSecret = state.Hook.Secret
+		}
 		if !flagProvided(kctx, "include-body") {
 			includeBody = state.Hook.IncludeBody
 		}
@@ -259,12 +392,20 @@ func (c *GmailWatchServeCmd) Run(ctx context.Context, kctx *kong.Context, flags
 	}
 
 	maxChanged := flagProvided(kctx, "max-bytes")
-	hook, err := hookFromFlags(hookURL, hookToken, includeBody, maxBytes, maxChanged, true)
-	if err != nil {
-		if errors.Is(err, errNoHookConfigured) {
-			hook = nil
-		} else {
-			return err
+	var hook *gmailWatchHook
+	if hookExec != "" {
+		hook = execHookFromFlags(hookExec, includeBody, maxBytes)
+	} else {
+		hook, err = hookFromFlags(hookURL, hookToken, includeBody, maxBytes, maxChanged, true)
+		if err != nil {
+			if errors.Is(err, errNoHookConfigured) {
+				hook = nil
+			} else {
+				return nil, err
+			}
+		}
+		if err := setHookSecret(hook, hookSecret); err != nil {
+			return nil, err
 		}
 	}
 	if c.SaveHook && hook != nil {
@@ -273,66 +414,73 @@ func (c *GmailWatchServeCmd) Run(ctx context.Context, kctx *kong.Context, flags
 			s.UpdatedAtMs = time.Now().UnixMilli()
 			return nil
 		}); updateErr != nil {
-			return updateErr
+			return nil, updateErr
 		}
 	}
 
-	validator := (*idtoken.Validator)(nil)
-	if c.VerifyOIDC {
-		validator, err = newOIDCValidator(ctx)
-		if err != nil {
-			return err
+	return newServer(accountServeConfig(base, account, hook, includeBody, maxBytes), store), nil
+}
+
+// multiAccountServer serves every account with stored watch state. Each
+// account uses its own stored hook; the --hook-* flags are the fallback for
+// accounts without one.
+func (c *GmailWatchServeCmd) multiAccountServer(kctx *kong.Context, base gmailWatchServeConfig, newServer func(gmailWatchServeConfig, *gmailWatchStore) *gmailWatchServer) (*gmailWatchServer, error) {
+	stores, err := loadAllGmailWatchStores()
+	if err != nil {
+		return nil, err
+	}
+	if len(stores) == 0 {
+		return nil, errors.New("no watch state found; run gmail watch start for each account")
+	}
+
+	var fallback *gmailWatchHook
+	if c.Exec != "" {
+		fallback = execHookFromFlags(c.Exec, c.IncludeBody, c.MaxBytes)
+	} else {
+		fallback, err = hookFromFlags(c.HookURL, c.HookToken, c.IncludeBody, c.MaxBytes, flagProvided(kctx, "max-bytes"), true)
+		if err != nil && !errors.Is(err, errNoHookConfigured) {
+			return nil, err
+		}
+		if err := setHookSecret(fallback, c.HookSecret); err != nil {
+			return nil, err
 		}
 	}
 
-	cfg := gmailWatchServeConfig{
-		Account:      account,
-		Bind:         c.Bind,
-		Port:         c.Port,
-		Path:         c.Path,
-		VerifyOIDC:   c.VerifyOIDC,
-		OIDCEmail:    c.OIDCEmail,
-		OIDCAudience: c.OIDCAudience,
-		SharedToken:  c.SharedToken,
-		HookTimeout:  defaultHookRequestTimeoutSec * time.Second,
-		HistoryMax:   defaultHistoryMaxResults,
-		ResyncMax:    defaultHistoryResyncMax,
-		AllowNoHook:  hook == nil,
-		IncludeBody:  includeBody,
-		MaxBodyBytes: maxBytes,
-		DateLocation: loc,
+	front := newServer(base, nil)
+	front.accounts = make(map[string]*gmailWatchServer, len(stores))
+	for _, store := range stores {
+		state := store.Get()
+		key := strings.ToLower(strings.TrimSpace(state.Account))
+		if _, dup := front.accounts[key]; dup {
+			return nil, fmt.Errorf("duplicate watch state for %s", state.Account)
+		}
+		hook := state.Hook
+		if hook == nil {
+			hook = fallback
+		}
+		front.accounts[key] = newServer(accountServeConfig(base, state.Account, hook, c.IncludeBody, c.MaxBytes), store)
 	}
+	return front, nil
+}
+
+func accountServeConfig(base gmailWatchServeConfig, account string, hook *gmailWatchHook, includeBody bool, maxBytes int) gmailWatchServeConfig {
+	cfg := base
+	cfg.Account = account
+	cfg.AllowNoHook = hook == nil
+	cfg.IncludeBody = includeBody
+	cfg.MaxBodyBytes = maxBytes
 	if hook != nil {
 		cfg.HookURL = hook.URL
+		cfg.HookExec = hook.Exec
 		cfg.HookToken = hook.Token
+		cfg.HookSecret = hook.Secret
 		cfg.IncludeBody = hook.IncludeBody
 		cfg.MaxBodyBytes = hook.MaxBytes
 	}
-
 	if cfg.MaxBodyBytes <= 0 {
 		cfg.MaxBodyBytes = defaultHookMaxBytes
 	}
-
-	hookClient := &http.Client{Timeout: cfg.HookTimeout}
-	server := &gmailWatchServer{
-		cfg:        cfg,
-		store:      store,
-		validator:  validator,
-		newService: newGmailService,
-		hookClient: hookClient,
-		logf:       u.Err().Printf,
-		warnf:      u.Err().Printf,
-	}
-
-	addr := net.JoinHostPort(c.Bind, strconv.Itoa(c.Port))
-	u.Err().Printf("watch: listening on %s%s", addr, c.Path)
-
-	httpServer := &http.Server{
-		Addr:              addr,
-		Handler:           server,
-		ReadHeaderTimeout: 5 * time.Second,
-	}
-	return listenAndServe(httpServer)
+	return cfg
 }
 
 func writeWatchState(ctx context.Context, state gmailWatchState) error {
@@ -359,7 +507,11 @@ func writeWatchState(ctx context.Context, state gmailWatchState) error {
 		u.Out().Printf("updated_at\t%s", formatUnixMillis(state.UpdatedAtMs))
 	}
 	if state.Hook != nil {
-		u.Out().Printf("hook_url\t%s", state.Hook.URL)
+		if state.Hook.Exec != "" {
+			u.Out().Printf("hook_exec\t%s", state.Hook.Exec)
+		} else {
+			u.Out().Printf("hook_url\t%s", state.Hook.URL)
+		}
 		if state.Hook.IncludeBody {
 			u.Out().Printf("hook_include_body\ttrue")
 		}
@@ -369,6 +521,9 @@ func writeWatchState(ctx context.Context, state gmailWatchState) error {
 		if state.Hook.Token != "" {
 			u.Out().Printf("hook_token\t%s", state.Hook.Token)
 		}
+		if state.Hook.Secret != "" {
+			u.Out().Printf("hook_signed\ttrue")
+		}
 	}
 	if state.LastDeliveryStatus != "" {
 		u.Out().Printf("last_delivery_status\t%s", state.LastDeliveryStatus)
@@ -443,6 +598,30 @@ func hookFromFlags(url, token string, includeBody bool, maxBytes int, maxBytesCh
 	}, nil
 }
 
+// execHookFromFlags builds a hook that pipes each new message to a local
+// command instead of posting it.
+func execHookFromFlags(command string, includeBody bool, maxBytes int) *gmailWatchHook {
+	if maxBytes <= 0 && includeBody {
+		maxBytes = defaultHookMaxBytes
+	}
+	return &gmailWatchHook{
+		Exec:        command,
+		IncludeBody: includeBody,
+		MaxBytes:    maxBytes,
+	}
+}
+
+func setHookSecret(hook *gmailWatchHook, secret string) error {
+	if hook == nil {
+		if secret != "" {
+			return usage("--hook-url required when using --hook-secret")
+		}
+		return nil
+	}
+	hook.Secret = secret
+	return nil
+}
+
 func isLoopbackHost(host string) bool {
 	trimmed := strings.TrimSpace(host)
 	if trimmed == "" {
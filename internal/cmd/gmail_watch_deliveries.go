@@ -0,0 +1,297 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/steipete/gogcli/internal/config"
+	"github.com/steipete/gogcli/internal/outfmt"
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+const gmailWatchSpoolDirName = "spool"
+
+var errDeliveryNotFound = errors.New("spooled delivery not found")
+
+type GmailWatchDeliveriesCmd struct {
+	List   GmailWatchDeliveriesListCmd   `cmd:"" name:"list" default:"withargs" help:"List undelivered hook payloads"`
+	Replay GmailWatchDeliveriesReplayCmd `cmd:"" name:"replay" help:"Re-send undelivered hook payloads (all if no IDs given)"`
+}
+
+// gmailWatchDelivery is a hook payload that could not be delivered. Each one
+// is a file in state/gmail-watch/spool/<account>/ until a replay succeeds.
+type gmailWatchDelivery struct {
+	ID              string          `json:"id"`
+	Account         string          `json:"account"`
+	HookURL         string          `json:"hookUrl,omitempty"`
+	Exec            string          `json:"exec,omitempty"`
+	CreatedAtMs     int64           `json:"createdAtMs"`
+	Attempts        int             `json:"attempts"`
+	LastAttemptAtMs int64           `json:"lastAttemptAtMs,omitempty"`
+	LastError       string          `json:"lastError,omitempty"`
+	Payload         json.RawMessage `json:"payload"`
+}
+
+func gmailWatchSpoolDir(account string) (string, error) {
+	dir, err := config.EnsureGmailWatchDir()
+	if err != nil {
+		return "", err
+	}
+	spool := filepath.Join(dir, gmailWatchSpoolDirName, sanitizeAccountForPath(account))
+	if err := os.MkdirAll(spool, 0o700); err != nil {
+		return "", fmt.Errorf("ensure watch spool dir: %w", err)
+	}
+	return spool, nil
+}
+
+func spoolGmailWatchDelivery(account, hookURL, hookExec string, payload []byte, attempts int, deliveryErr error) (*gmailWatchDelivery, error) {
+	var b [4]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	d := &gmailWatchDelivery{
+		ID:              strconv.FormatInt(now.UnixNano(), 10) + "-" + hex.EncodeToString(b[:]),
+		Account:         account,
+		HookURL:         hookURL,
+		Exec:            hookExec,
+		CreatedAtMs:     now.UnixMilli(),
+		Attempts:        attempts,
+		LastAttemptAtMs: now.UnixMilli(),
+		Payload:         json.RawMessage(payload),
+	}
+	if deliveryErr != nil {
+		d.LastError = deliveryErr.Error()
+	}
+	return d, writeGmailWatchDelivery(d)
+}
+
+func writeGmailWatchDelivery(d *gmailWatchDelivery) error {
+	dir, err := gmailWatchSpoolDir(d.Account)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, d.ID+".json")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("write spooled delivery: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("commit spooled delivery: %w", err)
+	}
+	return nil
+}
+
+func listGmailWatchDeliveries(account string) ([]*gmailWatchDelivery, error) {
+	dir, err := gmailWatchSpoolDir(account)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*gmailWatchDelivery, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name())) //nolint:gosec // config dir
+		if err != nil {
+			return nil, err
+		}
+		var d gmailWatchDelivery
+		if err := json.Unmarshal(data, &d); err != nil {
+			return nil, fmt.Errorf("parse spooled delivery %s: %w", entry.Name(), err)
+		}
+		out = append(out, &d)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].CreatedAtMs != out[j].CreatedAtMs {
+			return out[i].CreatedAtMs < out[j].CreatedAtMs
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out, nil
+}
+
+func removeGmailWatchDelivery(account, id string) error {
+	dir, err := gmailWatchSpoolDir(account)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(filepath.Join(dir, id+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return errDeliveryNotFound
+	}
+	return err
+}
+
+// deliveryMessageCount reports how many messages a spooled payload carries.
+func deliveryMessageCount(d *gmailWatchDelivery) int {
+	var payload gmailHookPayload
+	if err := json.Unmarshal(d.Payload, &payload); err != nil {
+		return 0
+	}
+	return len(payload.Messages)
+}
+
+type GmailWatchDeliveriesListCmd struct{}
+
+func (c *GmailWatchDeliveriesListCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+	deliveries, err := listGmailWatchDeliveries(account)
+	if err != nil {
+		return err
+	}
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(os.Stdout, map[string]any{"deliveries": deliveries})
+	}
+	if len(deliveries) == 0 {
+		u.Err().Println("No undelivered payloads")
+		return nil
+	}
+
+	w, flush := tableWriter(ctx)
+	defer flush()
+	_, _ = fmt.Fprintln(w, "ID\tCREATED\tATTEMPTS\tMESSAGES\tHOOK\tERROR")
+	for _, d := range deliveries {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\t%s\n",
+			d.ID, formatUnixMillis(d.CreatedAtMs), d.Attempts, deliveryMessageCount(d), orEmpty(d.HookURL, d.Exec), sanitizeTab(d.LastError))
+	}
+	return nil
+}
+
+type GmailWatchDeliveriesReplayCmd struct {
+	IDs     []string `arg:"" name:"id" optional:"" help:"Delivery IDs (default: all)"`
+	HookURL string   `name:"hook-url" help:"Send to this URL instead of the stored hook"`
+	Exec    string   `name:"exec" help:"Pipe to this command instead of the stored hook"`
+}
+
+type deliveryReplayResult struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+func (c *GmailWatchDeliveriesReplayCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+	hookURL, hookExec := strings.TrimSpace(c.HookURL), strings.TrimSpace(c.Exec)
+	if hookURL != "" && hookExec != "" {
+		return usage("--hook-url and --exec are mutually exclusive")
+	}
+	deliveries, err := listGmailWatchDeliveries(account)
+	if err != nil {
+		return err
+	}
+	if len(c.IDs) > 0 {
+		byID := make(map[string]*gmailWatchDelivery, len(deliveries))
+		for _, d := range deliveries {
+			byID[d.ID] = d
+		}
+		selected := make([]*gmailWatchDelivery, 0, len(c.IDs))
+		for _, id := range c.IDs {
+			d, ok := byID[strings.TrimSpace(id)]
+			if !ok {
+				return fmt.Errorf("%w: %s", errDeliveryNotFound, id)
+			}
+			selected = append(selected, d)
+		}
+		deliveries = selected
+	}
+
+	// Replays use the current hook settings (URL or command, token, secret)
+	// so payloads spooled before a receiver moved still reach it.
+	var hook gmailWatchHook
+	store, storeErr := loadGmailWatchStore(account)
+	if storeErr == nil && store.Get().Hook != nil {
+		hook = *store.Get().Hook
+	}
+	if hookURL != "" || hookExec != "" {
+		hook.URL, hook.Exec = hookURL, hookExec
+	}
+	server := &gmailWatchServer{
+		cfg: gmailWatchServeConfig{
+			Account:     account,
+			HookToken:   hook.Token,
+			HookSecret:  hook.Secret,
+			ExecTimeout: defaultHookExecTimeout,
+		},
+		store:      store,
+		hookClient: &http.Client{Timeout: defaultHookRequestTimeoutSec * time.Second},
+		logf:       u.Err().Printf,
+		warnf:      u.Err().Printf,
+	}
+
+	results := make([]deliveryReplayResult, 0, len(deliveries))
+	failed := 0
+	for _, d := range deliveries {
+		server.cfg.HookURL, server.cfg.HookExec = hook.URL, hook.Exec
+		if server.cfg.HookURL == "" && server.cfg.HookExec == "" {
+			server.cfg.HookURL, server.cfg.HookExec = d.HookURL, d.Exec
+		}
+		var payload bytes.Buffer
+		if err := json.Compact(&payload, d.Payload); err != nil {
+			return fmt.Errorf("parse spooled delivery %s: %w", d.ID, err)
+		}
+		_, sendErr := server.deliverHook(ctx, payload.Bytes(), 0)
+		if sendErr == nil {
+			if err := removeGmailWatchDelivery(account, d.ID); err != nil && !errors.Is(err, errDeliveryNotFound) {
+				return err
+			}
+			results = append(results, deliveryReplayResult{ID: d.ID, Status: "delivered"})
+			continue
+		}
+		failed++
+		d.Attempts++
+		d.LastAttemptAtMs = time.Now().UnixMilli()
+		d.LastError = sendErr.Error()
+		if err := writeGmailWatchDelivery(d); err != nil {
+			return err
+		}
+		results = append(results, deliveryReplayResult{ID: d.ID, Status: "failed", Error: sendErr.Error()})
+	}
+
+	if outfmt.IsJSON(ctx) {
+		if err := outfmt.WriteJSON(os.Stdout, map[string]any{"results": results, "delivered": len(results) - failed, "failed": failed}); err != nil {
+			return err
+		}
+	} else if len(results) == 0 {
+		u.Err().Println("No undelivered payloads")
+	} else {
+		w, flush := tableWriter(ctx)
+		_, _ = fmt.Fprintln(w, "ID\tSTATUS\tERROR")
+		for _, r := range results {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", r.ID, r.Status, sanitizeTab(r.Error))
+		}
+		flush()
+	}
+	if failed > 0 {
+		return &ExitError{Code: 1, Err: fmt.Errorf("%d of %d deliveries failed", failed, len(results))}
+	}
+	return nil
+}
@@ -0,0 +1,308 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
+
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+func newHookTestServer(t *testing.T, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1))
+		status := http.StatusOK
+		if n <= len(statuses) {
+			status = statuses[n-1]
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestGmailWatchServer_SendHook_RetriesAndSigns(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	origDelay := hookRetryBaseDelay
+	t.Cleanup(func() { hookRetryBaseDelay = origDelay })
+	hookRetryBaseDelay = time.Millisecond
+
+	var gotSig, gotTS string
+	var gotBody []byte
+	var calls int
+	hookSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		gotSig, gotTS = r.Header.Get(gmailWatchSignatureHeader), r.Header.Get(gmailWatchTimestampHeader)
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer hookSrv.Close()
+
+	store := seedWatchState(t, "a@b.com", "1", nil)
+	server := &gmailWatchServer{
+		cfg:        gmailWatchServeConfig{Account: "a@b.com", HookURL: hookSrv.URL, HookSecret: "s3cret", HookRetries: 3},
+		store:      store,
+		hookClient: hookSrv.Client(),
+		logf:       func(string, ...any) {},
+		warnf:      func(string, ...any) {},
+	}
+	if err := server.sendHookPayload(context.Background(), &gmailHookPayload{Source: "gmail", Account: "a@b.com", HistoryID: "2"}); err != nil {
+		t.Fatalf("sendHookPayload: %v", err)
+	}
+	if calls != 3 || store.Get().LastDeliveryStatus != "ok" {
+		t.Fatalf("expected success on third attempt, calls=%d state=%#v", calls, store.Get())
+	}
+	if gotSig != "sha256="+hookSignature("s3cret", gotTS, gotBody) {
+		t.Fatalf("signature mismatch: %q", gotSig)
+	}
+	if ts, err := strconv.ParseInt(gotTS, 10, 64); err != nil || time.Since(time.Unix(ts, 0)) > time.Minute {
+		t.Fatalf("unexpected timestamp %q", gotTS)
+	}
+	if deliveries, _ := listGmailWatchDeliveries("a@b.com"); len(deliveries) != 0 {
+		t.Fatalf("delivered payloads should not be spooled: %#v", deliveries)
+	}
+}
+
+func TestGmailWatchServer_SendHook_SpoolsAndReplays(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	origDelay := hookRetryBaseDelay
+	t.Cleanup(func() { hookRetryBaseDelay = origDelay })
+	hookRetryBaseDelay = time.Millisecond
+
+	failing, failCalls := newHookTestServer(t, 500, 500, 500)
+	rejecting, rejectCalls := newHookTestServer(t, 400)
+	store := seedWatchState(t, "a@b.com", "1", nil)
+	server := &gmailWatchServer{
+		cfg:        gmailWatchServeConfig{Account: "a@b.com", HookURL: failing.URL, HookRetries: 1},
+		store:      store,
+		hookClient: failing.Client(),
+		logf:       func(string, ...any) {},
+		warnf:      func(string, ...any) {},
+	}
+	if err := server.sendHookPayload(context.Background(), &gmailHookPayload{Source: "gmail", Account: "a@b.com", HistoryID: "2"}); err == nil {
+		t.Fatalf("expected delivery error")
+	}
+	if failCalls.Load() != 2 || store.Get().LastDeliveryStatus != gmailWatchStatusHTTPError {
+		t.Fatalf("expected one retry, calls=%d state=%#v", failCalls.Load(), store.Get())
+	}
+
+	server.cfg.HookURL = rejecting.URL
+	if err := server.sendHookPayload(context.Background(), &gmailHookPayload{Source: "gmail", Account: "a@b.com", HistoryID: "3", Messages: []gmailHookMessage{{ID: "m1"}}}); err == nil {
+		t.Fatalf("expected delivery error")
+	}
+	if rejectCalls.Load() != 1 {
+		t.Fatalf("4xx responses should not be retried, calls=%d", rejectCalls.Load())
+	}
+
+	deliveries, err := listGmailWatchDeliveries("a@b.com")
+	if err != nil || len(deliveries) != 2 {
+		t.Fatalf("expected two spooled payloads: %v %#v", err, deliveries)
+	}
+	if deliveries[0].Attempts != 2 || deliveries[0].HookURL != failing.URL || deliveries[1].LastError != "hook status 400" || deliveryMessageCount(deliveries[1]) != 1 {
+		t.Fatalf("unexpected spooled payloads: %#v %#v", deliveries[0], deliveries[1])
+	}
+
+	// Replay one payload to a receiver that still fails, then all to one that works.
+	u, err := ui.New(ui.Options{Stdout: io.Discard, Stderr: io.Discard, Color: "never"})
+	if err != nil {
+		t.Fatalf("ui.New: %v", err)
+	}
+	err = runKong(t, &GmailWatchDeliveriesReplayCmd{}, []string{deliveries[1].ID, "--hook-url", failing.URL}, ui.WithUI(context.Background(), u), &RootFlags{Account: "a@b.com"})
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 1 {
+		t.Fatalf("expected exit 1 for failed replay, got %v", err)
+	}
+	deliveries, _ = listGmailWatchDeliveries("a@b.com")
+	if deliveries[1].Attempts != 2 {
+		t.Fatalf("failed replay should count an attempt: %#v", deliveries[1])
+	}
+
+	ok, okCalls := newHookTestServer(t)
+	if err := store.Update(func(s *gmailWatchState) error {
+		s.Hook = &gmailWatchHook{URL: ok.URL}
+		return nil
+	}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	out := runSendLike(t, &GmailWatchDeliveriesReplayCmd{})
+	if out["delivered"] != float64(2) || okCalls.Load() != 2 {
+		t.Fatalf("unexpected replay output: %#v", out)
+	}
+	if deliveries, _ := listGmailWatchDeliveries("a@b.com"); len(deliveries) != 0 {
+		t.Fatalf("replayed payloads should be removed: %#v", deliveries)
+	}
+}
+
+func TestHookRetryDelay(t *testing.T) {
+	origDelay := hookRetryBaseDelay
+	t.Cleanup(func() { hookRetryBaseDelay = origDelay })
+	hookRetryBaseDelay = time.Second
+
+	for n, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 10: 30 * time.Second} {
+		if got := hookRetryDelay(n); got != want {
+			t.Fatalf("hookRetryDelay(%d) = %s; want %s", n, got, want)
+		}
+	}
+}
+
+func TestGmailWatchServer_Push_AcksBeforeHookRetries(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	origDelay := hookRetryBaseDelay
+	t.Cleanup(func() { hookRetryBaseDelay = origDelay })
+	hookRetryBaseDelay = 50 * time.Millisecond
+
+	gmailSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.Contains(r.URL.Path, "/users/me/history"):
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"historyId": "300",
+				"history":   []map[string]any{{"messagesAdded": []map[string]any{{"message": map[string]any{"id": "m1"}}}}},
+			})
+		case strings.Contains(r.URL.Path, "/users/me/messages/m1"):
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": "m1", "threadId": "t1", "payload": map[string]any{}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer gmailSrv.Close()
+	gsvc, err := gmail.NewService(context.Background(), option.WithoutAuthentication(), option.WithHTTPClient(gmailSrv.Client()), option.WithEndpoint(gmailSrv.URL+"/"))
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	hookSrv, calls := newHookTestServer(t, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+
+	store := seedWatchState(t, "a@b.com", "100", nil)
+	server := &gmailWatchServer{
+		cfg:        gmailWatchServeConfig{Account: "a@b.com", Path: "/gmail-pubsub", HookURL: hookSrv.URL, HookRetries: 3, HistoryMax: 100, ResyncMax: 10},
+		store:      store,
+		newService: func(context.Context, string) (*gmail.Service, error) { return gsvc, nil },
+		hookClient: hookSrv.Client(),
+		hooks:      newGmailHookQueue(context.Background(), 1),
+		logf:       func(string, ...any) {},
+		warnf:      func(string, ...any) {},
+	}
+
+	env := pubsubPushEnvelope{}
+	env.Message.Data = base64.StdEncoding.EncodeToString([]byte(`{"emailAddress":"a@b.com","historyId":"300"}`))
+	body, _ := json.Marshal(env)
+	reqCtx, cancel := context.WithCancel(context.Background())
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/gmail-pubsub", bytes.NewReader(body)).WithContext(reqCtx))
+	// Pub/Sub dropping the connection must not stop the retries.
+	cancel()
+
+	if rr.Code != http.StatusOK || store.Get().HistoryID != "300" {
+		t.Fatalf("push should be acknowledged after the history sync: %d %#v", rr.Code, store.Get())
+	}
+	if store.Get().LastDeliveryStatus == "ok" {
+		t.Fatalf("hook retries should still be running after the push was acknowledged")
+	}
+	if deliveries, _ := listGmailWatchDeliveries("a@b.com"); len(deliveries) != 1 || deliveries[0].Attempts != 0 {
+		t.Fatalf("payload should be spooled before the push is acknowledged: %#v", deliveries)
+	}
+	server.hooks.wait()
+	if calls.Load() != 3 || store.Get().LastDeliveryStatus != "ok" {
+		t.Fatalf("expected delivery on the third attempt, calls=%d state=%#v", calls.Load(), store.Get())
+	}
+	if deliveries, _ := listGmailWatchDeliveries("a@b.com"); len(deliveries) != 0 {
+		t.Fatalf("delivered payloads should not be spooled: %#v", deliveries)
+	}
+}
+
+func TestGmailHookQueue_CloseLeavesUndeliveredPayloadsSpooled(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	release := make(chan struct{})
+	var calls atomic.Int32
+	hookSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer hookSrv.Close()
+	defer close(release)
+
+	server := &gmailWatchServer{
+		cfg:        gmailWatchServeConfig{Account: "a@b.com", HookURL: hookSrv.URL},
+		store:      seedWatchState(t, "a@b.com", "1", nil),
+		hookClient: hookSrv.Client(),
+		hooks:      newGmailHookQueue(context.Background(), 1),
+		logf:       func(string, ...any) {},
+		warnf:      func(string, ...any) {},
+	}
+	for _, id := range []string{"2", "3"} {
+		delivery, err := server.spoolHookPayload(&gmailHookPayload{Source: "gmail", Account: "a@b.com", HistoryID: id})
+		if err != nil {
+			t.Fatalf("spool: %v", err)
+		}
+		server.hooks.push(server, delivery)
+	}
+
+	server.hooks.close(50 * time.Millisecond)
+	if calls.Load() != 1 {
+		t.Fatalf("queued payload should not be sent after the drain timeout, calls=%d", calls.Load())
+	}
+	deliveries, err := listGmailWatchDeliveries("a@b.com")
+	if err != nil || len(deliveries) != 2 {
+		t.Fatalf("undelivered payloads should stay spooled: %v %#v", err, deliveries)
+	}
+	if deliveries[0].Attempts != 1 || deliveries[0].LastError == "" || deliveries[1].Attempts != 0 {
+		t.Fatalf("unexpected spooled payloads: %#v %#v", deliveries[0], deliveries[1])
+	}
+}
+
+func TestServeGmailWatch_ShutsDownAndDrains(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	hookSrv, calls := newHookTestServer(t)
+	server := &gmailWatchServer{
+		cfg:        gmailWatchServeConfig{Account: "a@b.com", HookURL: hookSrv.URL},
+		store:      seedWatchState(t, "a@b.com", "1", nil),
+		hookClient: hookSrv.Client(),
+		hooks:      newGmailHookQueue(context.Background(), 1),
+		logf:       func(string, ...any) {},
+		warnf:      func(string, ...any) {},
+	}
+	delivery, err := server.spoolHookPayload(&gmailHookPayload{Source: "gmail", Account: "a@b.com", HistoryID: "2"})
+	if err != nil {
+		t.Fatalf("spool: %v", err)
+	}
+
+	origListen := listenAndServe
+	t.Cleanup(func() { listenAndServe = origListen })
+	listenAndServe = func(srv *http.Server) error {
+		server.hooks.push(server, delivery)
+		return srv.ListenAndServe()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := serveGmailWatch(ctx, &http.Server{Addr: "127.0.0.1:0", ReadHeaderTimeout: time.Second}, server.hooks); err != nil {
+		t.Fatalf("serve: %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("queued payload should be delivered on shutdown, calls=%d", calls.Load())
+	}
+	if deliveries, _ := listGmailWatchDeliveries("a@b.com"); len(deliveries) != 0 {
+		t.Fatalf("delivered payload should leave the spool: %#v", deliveries)
+	}
+}
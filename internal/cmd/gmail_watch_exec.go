@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	gmailWatchStatusExecError = "exec_error"
+
+	defaultHookExecTimeout = time.Minute
+	hookExecStderrLimit    = 2048
+)
+
+// hookExecError is a hook command that exited non-zero or ran out of time.
+type hookExecError struct {
+	Code     int
+	TimedOut bool
+	Stderr   string
+}
+
+func (e *hookExecError) Error() string {
+	msg := fmt.Sprintf("hook command exited %d", e.Code)
+	if e.TimedOut {
+		msg = "hook command timed out"
+	}
+	if e.Stderr != "" {
+		msg += ": " + e.Stderr
+	}
+	return msg
+}
+
+// runHookExec runs the hook command once for a single-message payload. The
+// payload is written to stdin and the message is described in GOG_* env vars,
+// so scripts can call back into gog for the same account.
+func (s *gmailWatchServer) runHookExec(ctx context.Context, data []byte) error {
+	timeout := s.cfg.ExecTimeout
+	if timeout <= 0 {
+		timeout = defaultHookExecTimeout
+	}
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := hookExecCommand(runCtx, s.cfg.HookExec)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Env = append(os.Environ(), hookExecEnv(data)...)
+	cmd.WaitDelay = 5 * time.Second
+	var stderr bytes.Buffer
+	cmd.Stderr = &limitedWriter{w: &stderr, n: hookExecStderrLimit}
+
+	err := cmd.Run()
+	if err == nil {
+		return nil
+	}
+	execErr := &hookExecError{Code: -1, Stderr: strings.TrimSpace(stderr.String())}
+	var exitErr *exec.ExitError
+	switch {
+	case errors.Is(runCtx.Err(), context.DeadlineExceeded):
+		execErr.TimedOut = true
+	case errors.As(err, &exitErr):
+		execErr.Code = exitErr.ExitCode()
+	default:
+		return fmt.Errorf("hook command: %w", err)
+	}
+	return execErr
+}
+
+// hookExecEnv describes the payload's first message for the hook command.
+func hookExecEnv(data []byte) []string {
+	var payload gmailHookPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil
+	}
+	env := []string{
+		"GOG_ACCOUNT=" + payload.Account,
+		"GOG_HISTORY_ID=" + payload.HistoryID,
+	}
+	if len(payload.Messages) > 0 {
+		env = append(env,
+			"GOG_MESSAGE_ID="+payload.Messages[0].ID,
+			"GOG_THREAD_ID="+payload.Messages[0].ThreadID,
+		)
+	}
+	return env
+}
+
+// limitedWriter keeps the first n bytes and silently drops the rest.
+type limitedWriter struct {
+	w *bytes.Buffer
+	n int
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if room := l.n - l.w.Len(); room > 0 {
+		l.w.Write(p[:min(len(p), room)])
+	}
+	return len(p), nil
+}
@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+func newExecTestServer(t *testing.T, command string) (*gmailWatchServer, *gmailWatchStore) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("hook command tests use sh")
+	}
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	origDelay := hookRetryBaseDelay
+	t.Cleanup(func() { hookRetryBaseDelay = origDelay })
+	hookRetryBaseDelay = time.Millisecond
+
+	store := seedWatchState(t, "a@b.com", "1", nil)
+	return &gmailWatchServer{
+		cfg:   gmailWatchServeConfig{Account: "a@b.com", HookExec: command, ExecTimeout: 5 * time.Second},
+		store: store,
+		hooks: newGmailHookQueue(context.Background(), 2),
+		logf:  func(string, ...any) {},
+		warnf: func(string, ...any) {},
+	}, store
+}
+
+func TestGmailWatchServer_SendHook_ExecPerMessage(t *testing.T) {
+	dir := t.TempDir()
+	server, store := newExecTestServer(t, `cat > "`+dir+`/$GOG_MESSAGE_ID.json"; echo "$GOG_ACCOUNT $GOG_HISTORY_ID $GOG_THREAD_ID" > "`+dir+`/$GOG_MESSAGE_ID.env"`)
+
+	payload := &gmailHookPayload{Source: "gmail", Account: "a@b.com", HistoryID: "7", Messages: []gmailHookMessage{{ID: "m1", ThreadID: "t1"}, {ID: "m2", ThreadID: "t2"}}}
+	for _, single := range server.hookPayloads(payload) {
+		if err := server.sendHookPayload(context.Background(), single); err != nil {
+			t.Fatalf("sendHookPayload: %v", err)
+		}
+	}
+	for _, id := range []string{"m1", "m2"} {
+		data, err := os.ReadFile(filepath.Join(dir, id+".json"))
+		if err != nil {
+			t.Fatalf("read stdin copy: %v", err)
+		}
+		var got gmailHookPayload
+		if err := json.Unmarshal(data, &got); err != nil || len(got.Messages) != 1 || got.Messages[0].ID != id {
+			t.Fatalf("expected single-message payload for %s: %v %q", id, err, data)
+		}
+		env, _ := os.ReadFile(filepath.Join(dir, id+".env"))
+		if want := "a@b.com 7 t" + strings.TrimPrefix(id, "m"); strings.TrimSpace(string(env)) != want {
+			t.Fatalf("unexpected env for %s: %q", id, env)
+		}
+	}
+	if store.Get().LastDeliveryStatus != "ok" {
+		t.Fatalf("unexpected state: %#v", store.Get())
+	}
+}
+
+func TestGmailWatchServer_Push_ExecRunsMessagesConcurrently(t *testing.T) {
+	dir := t.TempDir()
+	// Each command waits until both messages' commands have started, so this
+	// only succeeds when one push's messages run in parallel.
+	server, store := newExecTestServer(t, `touch "`+dir+`/$GOG_MESSAGE_ID"; for i in $(seq 100); do [ -e "`+dir+`/m1" ] && [ -e "`+dir+`/m2" ] && exit 0; sleep 0.02; done; exit 1`)
+
+	result := &gmailHookPayload{Source: "gmail", Account: "a@b.com", HistoryID: "7", Messages: []gmailHookMessage{{ID: "m1"}, {ID: "m2"}}}
+	for _, payload := range server.hookPayloads(result) {
+		delivery, err := server.spoolHookPayload(payload)
+		if err != nil {
+			t.Fatalf("spool: %v", err)
+		}
+		server.hooks.push(server, delivery)
+	}
+	server.hooks.wait()
+	if state := store.Get(); state.LastDeliveryStatus != "ok" {
+		t.Fatalf("expected both commands to run at once: %#v", state)
+	}
+	if deliveries, _ := listGmailWatchDeliveries("a@b.com"); len(deliveries) != 0 {
+		t.Fatalf("unexpected spool: %#v", deliveries)
+	}
+}
+
+func TestGmailWatchServer_SendHook_ExecFailureSpools(t *testing.T) {
+	server, store := newExecTestServer(t, "echo boom >&2; exit 3")
+	server.cfg.HookRetries = 1
+
+	err := server.sendHookPayload(context.Background(), &gmailHookPayload{Source: "gmail", Account: "a@b.com", HistoryID: "2", Messages: []gmailHookMessage{{ID: "m1"}}})
+	if err == nil || err.Error() != "hook command exited 3: boom" {
+		t.Fatalf("expected exit error, got %v", err)
+	}
+	if state := store.Get(); state.LastDeliveryStatus != gmailWatchStatusExecError {
+		t.Fatalf("unexpected state: %#v", state)
+	}
+	deliveries, err := listGmailWatchDeliveries("a@b.com")
+	if err != nil || len(deliveries) != 1 || deliveries[0].Attempts != 2 || deliveries[0].Exec != "echo boom >&2; exit 3" || deliveries[0].HookURL != "" {
+		t.Fatalf("unexpected spool: %v %#v", err, deliveries)
+	}
+
+	u, err := ui.New(ui.Options{Stdout: io.Discard, Stderr: io.Discard, Color: "never"})
+	if err != nil {
+		t.Fatalf("ui.New: %v", err)
+	}
+	if err := runKong(t, &GmailWatchDeliveriesReplayCmd{}, []string{"--exec", "cat >/dev/null", "--hook-url", "http://example.com"}, ui.WithUI(context.Background(), u), &RootFlags{Account: "a@b.com"}); err == nil {
+		t.Fatalf("expected --exec and --hook-url to conflict")
+	}
+	out := runSendLike(t, &GmailWatchDeliveriesReplayCmd{}, "--exec", "cat >/dev/null")
+	if out["delivered"] != float64(1) {
+		t.Fatalf("unexpected replay output: %#v", out)
+	}
+}
+
+func TestGmailWatchServer_SendHook_ExecTimeout(t *testing.T) {
+	server, _ := newExecTestServer(t, "sleep 5")
+	server.cfg.ExecTimeout = 50 * time.Millisecond
+
+	start := time.Now()
+	err := server.sendHookPayload(context.Background(), &gmailHookPayload{Account: "a@b.com", Messages: []gmailHookMessage{{ID: "m1"}}})
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("expected timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Fatalf("command was not killed in time: %s", elapsed)
+	}
+}
+
+func TestGmailWatchServeCmd_ExecFlags(t *testing.T) {
+	origListen := listenAndServe
+	t.Cleanup(func() { listenAndServe = origListen })
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	seedWatchState(t, "a@b.com", "1", nil)
+
+	u, err := ui.New(ui.Options{Stdout: io.Discard, Stderr: io.Discard, Color: "never"})
+	if err != nil {
+		t.Fatalf("ui.New: %v", err)
+	}
+	ctx := ui.WithUI(context.Background(), u)
+	flags := &RootFlags{Account: "a@b.com"}
+	if err := runKong(t, &GmailWatchServeCmd{}, []string{"--exec", "./on-mail.sh", "--hook-url", "http://example.com"}, ctx, flags); err == nil {
+		t.Fatalf("expected --exec and --hook-url to conflict")
+	}
+
+	var got *gmailWatchServer
+	listenAndServe = func(srv *http.Server) error {
+		got, _ = srv.Handler.(*gmailWatchServer)
+		return nil
+	}
+	if err := runKong(t, &GmailWatchServeCmd{}, []string{"--exec", "./on-mail.sh", "--exec-timeout", "10s", "--exec-concurrency", "2", "--save-hook"}, ctx, flags); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if got == nil || got.cfg.HookExec != "./on-mail.sh" || got.cfg.ExecTimeout != 10*time.Second || got.hooks.workers != 2 || got.cfg.AllowNoHook {
+		t.Fatalf("unexpected server: %#v", got)
+	}
+	reloaded, err := loadGmailWatchStore("a@b.com")
+	if err != nil || reloaded.Get().Hook == nil || reloaded.Get().Hook.Exec != "./on-mail.sh" {
+		t.Fatalf("expected saved exec hook: %v", err)
+	}
+
+	// A stored exec hook is used when no hook flags are given.
+	got = nil
+	if err := runKong(t, &GmailWatchServeCmd{}, nil, ctx, flags); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if got == nil || got.cfg.HookExec != "./on-mail.sh" || got.cfg.HookURL != "" {
+		t.Fatalf("expected stored exec hook: %#v", got)
+	}
+}
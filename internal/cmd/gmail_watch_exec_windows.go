@@ -0,0 +1,13 @@
+//go:build windows
+
+package cmd
+
+import (
+	"context"
+	"os/exec"
+)
+
+// hookExecCommand runs command via cmd.exe.
+func hookExecCommand(ctx context.Context, command string) *exec.Cmd {
+	return exec.CommandContext(ctx, "cmd", "/C", command) //nolint:gosec // user-configured hook command
+}
@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
+
+	"github.com/steipete/gogcli/internal/ui"
+)
+
+func seedWatchState(t *testing.T, account, historyID string, hook *gmailWatchHook) *gmailWatchStore {
+	t.Helper()
+	store, err := newGmailWatchStore(account)
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	if err := store.Update(func(s *gmailWatchState) error {
+		s.Account = account
+		s.HistoryID = historyID
+		s.Hook = hook
+		return nil
+	}); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	return store
+}
+
+func TestGmailWatchServeCmd_AllAccounts(t *testing.T) {
+	origListen := listenAndServe
+	t.Cleanup(func() { listenAndServe = origListen })
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	seedWatchState(t, "b@x.com", "10", &gmailWatchHook{URL: "http://example.com/b", Token: "btok"})
+	seedWatchState(t, "A@x.com", "20", nil)
+
+	var got *gmailWatchServer
+	listenAndServe = func(srv *http.Server) error {
+		got, _ = srv.Handler.(*gmailWatchServer)
+		return nil
+	}
+	u, err := ui.New(ui.Options{Stdout: io.Discard, Stderr: io.Discard, Color: "never"})
+	if err != nil {
+		t.Fatalf("ui.New: %v", err)
+	}
+	ctx := ui.WithUI(context.Background(), u)
+	if err := runKong(t, &GmailWatchServeCmd{}, []string{"--all-accounts", "--hook-url", "http://example.com/default"}, ctx, &RootFlags{}); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if got == nil || len(got.accounts) != 2 {
+		t.Fatalf("expected two routed accounts, got %#v", got)
+	}
+	if cfg := got.accounts["b@x.com"].cfg; cfg.HookURL != "http://example.com/b" || cfg.HookToken != "btok" || cfg.Account != "b@x.com" {
+		t.Fatalf("stored hook should win: %#v", cfg)
+	}
+	if cfg := got.accounts["a@x.com"].cfg; cfg.HookURL != "http://example.com/default" || cfg.Account != "A@x.com" {
+		t.Fatalf("--hook-url should be the fallback: %#v", cfg)
+	}
+
+	if err := runKong(t, &GmailWatchServeCmd{}, []string{"--all-accounts", "--save-hook"}, ctx, &RootFlags{}); err == nil {
+		t.Fatalf("expected --save-hook to be rejected")
+	}
+}
+
+func TestGmailWatchServer_RoutesByEmailAddress(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.Contains(r.URL.Path, "/users/me/history"):
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"historyId": "300",
+				"history":   []map[string]any{{"messagesAdded": []map[string]any{{"message": map[string]any{"id": "m1"}}}}},
+			})
+		case strings.Contains(r.URL.Path, "/users/me/messages/m1"):
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": "m1", "threadId": "t1", "payload": map[string]any{}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	gsvc, err := gmail.NewService(context.Background(), option.WithoutAuthentication(), option.WithHTTPClient(srv.Client()), option.WithEndpoint(srv.URL+"/"))
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+
+	var serviceAccounts []string
+	newServer := func(account string, store *gmailWatchStore) *gmailWatchServer {
+		return &gmailWatchServer{
+			cfg:   gmailWatchServeConfig{Account: account, Path: "/gmail-pubsub", AllowNoHook: true, HistoryMax: 100, ResyncMax: 10},
+			store: store,
+			newService: func(_ context.Context, account string) (*gmail.Service, error) {
+				serviceAccounts = append(serviceAccounts, account)
+				return gsvc, nil
+			},
+			logf:  func(string, ...any) {},
+			warnf: func(string, ...any) {},
+		}
+	}
+	front := newServer("", nil)
+	front.accounts = map[string]*gmailWatchServer{
+		"a@x.com": newServer("a@x.com", seedWatchState(t, "a@x.com", "100", nil)),
+		"b@x.com": newServer("b@x.com", seedWatchState(t, "b@x.com", "200", nil)),
+	}
+
+	push := func(email string) *httptest.ResponseRecorder {
+		env := pubsubPushEnvelope{}
+		env.Message.Data = base64.StdEncoding.EncodeToString([]byte(`{"emailAddress":"` + email + `","historyId":"300"}`))
+		body, _ := json.Marshal(env)
+		rr := httptest.NewRecorder()
+		front.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/gmail-pubsub", bytes.NewReader(body)))
+		return rr
+	}
+
+	rr := push("B@x.com")
+	var payload gmailHookPayload
+	if rr.Code != http.StatusOK || json.Unmarshal(rr.Body.Bytes(), &payload) != nil || payload.Account != "b@x.com" {
+		t.Fatalf("unexpected routed response: %d %q", rr.Code, rr.Body.String())
+	}
+	if len(serviceAccounts) != 1 || serviceAccounts[0] != "b@x.com" {
+		t.Fatalf("push should use b@x.com's service, got %v", serviceAccounts)
+	}
+	if front.accounts["b@x.com"].store.Get().HistoryID != "300" || front.accounts["a@x.com"].store.Get().HistoryID != "100" {
+		t.Fatalf("only b@x.com's state should advance")
+	}
+
+	if rr := push("other@x.com"); rr.Code != http.StatusAccepted || len(serviceAccounts) != 1 {
+		t.Fatalf("unknown accounts should be ignored: %d", rr.Code)
+	}
+
+	// Without auth, /healthz must not list the watched addresses.
+	front.cfg.SharedToken = "tok"
+	for _, token := range []string{"", "wrong"} {
+		rr = httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+		req.Header.Set("x-gog-token", token)
+		front.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK || strings.TrimSpace(rr.Body.String()) != `{"ok":true}` {
+			t.Fatalf("unauthenticated healthz leaked state: %d %q", rr.Code, rr.Body.String())
+		}
+	}
+
+	rr = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	req.Header.Set("x-gog-token", "tok")
+	front.ServeHTTP(rr, req)
+	var health struct {
+		OK       bool               `json:"ok"`
+		Accounts []gmailWatchHealth `json:"accounts"`
+	}
+	if rr.Code != http.StatusOK || json.Unmarshal(rr.Body.Bytes(), &health) != nil {
+		t.Fatalf("unexpected healthz: %d %q", rr.Code, rr.Body.String())
+	}
+	if !health.OK || len(health.Accounts) != 2 || health.Accounts[0].Account != "a@x.com" || health.Accounts[1].HistoryID != "300" {
+		t.Fatalf("unexpected health: %#v", health)
+	}
+}
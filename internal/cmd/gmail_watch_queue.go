@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	gmailHookQueueSize    = 1024
+	gmailHookDrainTimeout = 30 * time.Second
+)
+
+// gmailHookQueue delivers spooled hook payloads on a fixed pool of background
+// workers (--exec-concurrency), one job per hook command or URL post, so a
+// push is acknowledged as soon as the history cursor is saved and its
+// payloads are spooled. Retries, hook commands and spool updates run with the
+// queue's context rather than the push request's, which ends when Pub/Sub
+// gives up on a slow response.
+type gmailHookQueue struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	workers int
+	jobs    chan gmailHookJob
+	pending sync.WaitGroup
+}
+
+type gmailHookJob struct {
+	server   *gmailWatchServer
+	delivery *gmailWatchDelivery
+}
+
+func newGmailHookQueue(ctx context.Context, workers int) *gmailHookQueue {
+	// Deliveries outlive the serve context so shutdown can drain the queue.
+	qctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	q := &gmailHookQueue{
+		ctx:     qctx,
+		cancel:  cancel,
+		workers: max(workers, 1),
+		jobs:    make(chan gmailHookJob, gmailHookQueueSize),
+	}
+	for range q.workers {
+		go q.work()
+	}
+	return q
+}
+
+// push queues a spooled delivery. It only blocks when the queue is full.
+func (q *gmailHookQueue) push(server *gmailWatchServer, delivery *gmailWatchDelivery) {
+	q.pending.Add(1)
+	q.jobs <- gmailHookJob{server: server, delivery: delivery}
+}
+
+func (q *gmailHookQueue) work() {
+	for job := range q.jobs {
+		// After the drain timeout, queued payloads stay spooled for replay.
+		if q.ctx.Err() == nil {
+			if err := job.server.sendSpooledDelivery(q.ctx, job.delivery); err != nil {
+				job.server.warnf("watch: hook failed: %v", err)
+			}
+		}
+		q.pending.Done()
+	}
+}
+
+// wait blocks until every queued delivery has finished.
+func (q *gmailHookQueue) wait() {
+	q.pending.Wait()
+}
+
+// close stops accepting deliveries and waits up to timeout for the queue to
+// drain. Deliveries still running then are cancelled; they and anything not
+// yet started remain in the spool.
+func (q *gmailHookQueue) close(timeout time.Duration) {
+	close(q.jobs)
+	done := make(chan struct{})
+	go func() {
+		q.pending.Wait()
+		close(done)
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+		q.cancel()
+		<-done
+	}
+	q.cancel()
+}
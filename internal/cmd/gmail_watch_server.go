@@ -3,13 +3,18 @@ package cmd
 import (
 	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -23,19 +28,37 @@ var errNoNewMessages = errors.New("no new messages")
 const (
 	gmailWatchFormatMetadata  = "metadata"
 	gmailWatchStatusHTTPError = "http_error"
+	gmailWatchStatusError     = "error"
+
+	gmailWatchSignatureHeader = "X-Gog-Signature"
+	gmailWatchTimestampHeader = "X-Gog-Timestamp"
 )
 
+var hookRetryBaseDelay = time.Second
+
 type gmailWatchServer struct {
 	cfg        gmailWatchServeConfig
 	store      *gmailWatchStore
 	validator  *idtoken.Validator
 	newService func(context.Context, string) (*gmail.Service, error)
 	hookClient *http.Client
-	logf       func(string, ...any)
-	warnf      func(string, ...any)
+	// hooks delivers to the hook URL or command in the background; it is
+	// shared by all accounts, so its workers also bound concurrent --exec
+	// commands.
+	hooks *gmailHookQueue
+	logf  func(string, ...any)
+	warnf func(string, ...any)
+	// accounts routes pushes by lowercased emailAddress when one server
+	// handles several mailboxes; cfg and validator then only carry the shared
+	// listener and auth settings.
+	accounts map[string]*gmailWatchServer
 }
 
 func (s *gmailWatchServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == gmailWatchHealthPath {
+		s.serveHealth(w, r)
+		return
+	}
 	if !pathMatches(s.cfg.Path, r.URL.Path) {
 		w.WriteHeader(http.StatusNotFound)
 		return
@@ -62,12 +85,27 @@ func (s *gmailWatchServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
-	if payload.EmailAddress != "" && !strings.EqualFold(payload.EmailAddress, s.cfg.Account) {
+	target := s
+	if s.accounts != nil {
+		target = s.accounts[strings.ToLower(strings.TrimSpace(payload.EmailAddress))]
+		if target == nil {
+			s.warnf("watch: ignoring push for %s", orEmpty(payload.EmailAddress, "(no emailAddress)"))
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+	} else if payload.EmailAddress != "" && !strings.EqualFold(payload.EmailAddress, s.cfg.Account) {
 		s.warnf("watch: ignoring push for %s", payload.EmailAddress)
 		w.WriteHeader(http.StatusAccepted)
 		return
 	}
+	target.deliver(w, r, payload)
+}
 
+// deliver runs the history sync for one account and queues the result for
+// its hook. The push is acknowledged once the history cursor is saved and the
+// hook payloads are spooled, so a restart before delivery leaves them for
+// replay; hook failures are retried by the queue, not redelivered by Pub/Sub.
+func (s *gmailWatchServer) deliver(w http.ResponseWriter, r *http.Request, payload gmailPushPayload) {
 	result, err := s.handlePush(r.Context(), payload)
 	if err != nil {
 		if errors.Is(err, errNoNewMessages) {
@@ -83,7 +121,7 @@ func (s *gmailWatchServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if s.cfg.HookURL == "" {
+	if s.cfg.HookURL == "" && s.cfg.HookExec == "" {
 		if s.cfg.AllowNoHook {
 			_ = json.NewEncoder(w).Encode(result)
 			return
@@ -92,14 +130,66 @@ func (s *gmailWatchServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.sendHook(r.Context(), result); err != nil {
-		s.warnf("watch: hook failed: %v", err)
-		w.WriteHeader(http.StatusOK)
+	spoolFailed := false
+	for _, payload := range s.hookPayloads(result) {
+		delivery, err := s.spoolHookPayload(payload)
+		if err != nil {
+			s.warnf("watch: failed to spool hook payload: %v", err)
+			spoolFailed = true
+			continue
+		}
+		s.hooks.push(s, delivery)
+	}
+	if spoolFailed {
+		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
 }
 
+type gmailWatchHealth struct {
+	Account            string `json:"account"`
+	HistoryID          string `json:"historyId,omitempty"`
+	LastDeliveryStatus string `json:"lastDeliveryStatus,omitempty"`
+	LastDeliveryAtMs   int64  `json:"lastDeliveryAtMs,omitempty"`
+}
+
+// serveHealth reports liveness. Per-account watch state (addresses, history
+// IDs, delivery status) is only added for requests that pass the push auth,
+// so a server without --token/--verify-oidc never lists it.
+func (s *gmailWatchServer) serveHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if (s.cfg.SharedToken == "" && !s.cfg.VerifyOIDC) || !s.authorize(r) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"ok": true})
+		return
+	}
+	servers := []*gmailWatchServer{s}
+	if s.accounts != nil {
+		servers = make([]*gmailWatchServer, 0, len(s.accounts))
+		for _, srv := range s.accounts {
+			servers = append(servers, srv)
+		}
+		sort.Slice(servers, func(i, j int) bool { return servers[i].cfg.Account < servers[j].cfg.Account })
+	}
+	accounts := make([]gmailWatchHealth, 0, len(servers))
+	for _, srv := range servers {
+		item := gmailWatchHealth{Account: srv.cfg.Account}
+		if srv.store != nil {
+			state := srv.store.Get()
+			item.HistoryID = state.HistoryID
+			item.LastDeliveryStatus = state.LastDeliveryStatus
+			item.LastDeliveryAtMs = state.LastDeliveryAtMs
+		}
+		accounts = append(accounts, item)
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{"ok": true, "accounts": accounts})
+}
+
 func (s *gmailWatchServer) authorize(r *http.Request) bool {
 	if s.cfg.VerifyOIDC {
 		bearer := bearerToken(r)
@@ -306,46 +396,183 @@ func (s *gmailWatchServer) fetchMessages(ctx context.Context, svc *gmail.Service
 	return messages, nil
 }
 
-func (s *gmailWatchServer) sendHook(ctx context.Context, payload *gmailHookPayload) error {
+// hookPayloads splits a push result into hook deliveries. Hook commands run
+// once per message so scripts see a single message on stdin and in
+// GOG_MESSAGE_ID / GOG_THREAD_ID; a hook URL gets the whole batch.
+func (s *gmailWatchServer) hookPayloads(payload *gmailHookPayload) []*gmailHookPayload {
+	if s.cfg.HookExec == "" {
+		return []*gmailHookPayload{payload}
+	}
+	payloads := make([]*gmailHookPayload, 0, len(payload.Messages))
+	for _, msg := range payload.Messages {
+		single := *payload
+		single.Messages = []gmailHookMessage{msg}
+		payloads = append(payloads, &single)
+	}
+	return payloads
+}
+
+// spoolHookPayload writes a payload to the spool before it is delivered.
+func (s *gmailWatchServer) spoolHookPayload(payload *gmailHookPayload) (*gmailWatchDelivery, error) {
 	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return spoolGmailWatchDelivery(s.cfg.Account, s.cfg.HookURL, s.cfg.HookExec, data, 0, nil)
+}
+
+// sendHookPayload spools a payload and delivers it.
+func (s *gmailWatchServer) sendHookPayload(ctx context.Context, payload *gmailHookPayload) error {
+	delivery, err := s.spoolHookPayload(payload)
 	if err != nil {
 		return err
 	}
+	return s.sendSpooledDelivery(ctx, delivery)
+}
+
+// sendSpooledDelivery delivers a spooled payload and removes it from the
+// spool. A failed delivery stays spooled with its attempts and last error.
+func (s *gmailWatchServer) sendSpooledDelivery(ctx context.Context, d *gmailWatchDelivery) error {
+	attempts, err := s.deliverHook(ctx, d.Payload, s.cfg.HookRetries)
+	if err == nil {
+		if rmErr := removeGmailWatchDelivery(d.Account, d.ID); rmErr != nil && !errors.Is(rmErr, errDeliveryNotFound) {
+			s.warnf("watch: failed to remove delivered payload %s: %v", d.ID, rmErr)
+		}
+		return nil
+	}
+	d.Attempts += attempts
+	d.LastAttemptAtMs = time.Now().UnixMilli()
+	d.LastError = err.Error()
+	if spoolErr := writeGmailWatchDelivery(d); spoolErr != nil {
+		s.warnf("watch: failed to spool undelivered payload %s: %v", d.ID, spoolErr)
+	} else {
+		s.warnf("watch: spooled undelivered payload %s after %d attempt(s)", d.ID, d.Attempts)
+	}
+	return err
+}
+
+// deliverHook delivers data to the hook URL or command, retrying network
+// errors, 408, 429, 5xx responses, and failed commands with exponential
+// backoff. It records the final outcome in the watch state and returns the
+// number of attempts made.
+func (s *gmailWatchServer) deliverHook(ctx context.Context, data []byte, retries int) (int, error) {
+	var (
+		attempts int
+		err      error
+	)
+retry:
+	for attempt := 0; attempt <= max(retries, 0); attempt++ {
+		if attempt > 0 {
+			timer := time.NewTimer(hookRetryDelay(attempt))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				break retry
+			case <-timer.C:
+			}
+		}
+		attempts++
+		retryable := true
+		if s.cfg.HookExec != "" {
+			err = s.runHookExec(ctx, data)
+		} else {
+			retryable, err = s.postHookOnce(ctx, data)
+		}
+		if err == nil {
+			s.recordDelivery("ok", "")
+			return attempts, nil
+		}
+		if !retryable {
+			break
+		}
+		if attempt < retries {
+			s.logf("watch: hook attempt %d failed: %v", attempts, err)
+		}
+	}
+	status := gmailWatchStatusError
+	note := err.Error()
+	var statusErr *hookStatusError
+	var execErr *hookExecError
+	switch {
+	case errors.As(err, &statusErr):
+		status = gmailWatchStatusHTTPError
+		note = fmt.Sprintf("status %d", statusErr.Code)
+	case errors.As(err, &execErr):
+		status = gmailWatchStatusExecError
+	}
+	s.recordDelivery(status, note)
+	return attempts, err
+}
+
+type hookStatusError struct {
+	Code int
+}
+
+func (e *hookStatusError) Error() string {
+	return fmt.Sprintf("hook status %d", e.Code)
+}
+
+func (s *gmailWatchServer) postHookOnce(ctx context.Context, data []byte) (bool, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.HookURL, bytes.NewReader(data))
 	if err != nil {
-		return err
+		return false, err
 	}
 	req.Header.Set("Content-Type", "application/json")
 	if s.cfg.HookToken != "" {
 		req.Header.Set("Authorization", "Bearer "+s.cfg.HookToken)
 	}
+	if s.cfg.HookSecret != "" {
+		signHookRequest(req, s.cfg.HookSecret, data, time.Now())
+	}
 	resp, err := s.hookClient.Do(req)
 	if err != nil {
-		_ = s.store.Update(func(state *gmailWatchState) error {
-			state.LastDeliveryStatus = "error"
-			state.LastDeliveryAtMs = time.Now().UnixMilli()
-			state.LastDeliveryStatusNote = err.Error()
-			return nil
-		})
-		return err
+		return true, err
 	}
 	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		_ = s.store.Update(func(state *gmailWatchState) error {
-			state.LastDeliveryStatus = gmailWatchStatusHTTPError
-			state.LastDeliveryAtMs = time.Now().UnixMilli()
-			state.LastDeliveryStatusNote = fmt.Sprintf("status %d", resp.StatusCode)
-			return nil
-		})
-		return fmt.Errorf("hook status %d", resp.StatusCode)
+		retryable := resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return retryable, &hookStatusError{Code: resp.StatusCode}
+	}
+	return false, nil
+}
+
+func (s *gmailWatchServer) recordDelivery(status, note string) {
+	if s.store == nil {
+		return
 	}
 	_ = s.store.Update(func(state *gmailWatchState) error {
-		state.LastDeliveryStatus = "ok"
+		state.LastDeliveryStatus = status
 		state.LastDeliveryAtMs = time.Now().UnixMilli()
-		state.LastDeliveryStatusNote = ""
+		state.LastDeliveryStatusNote = note
 		return nil
 	})
-	return nil
+}
+
+// hookRetryDelay is the wait before retry n (1-based): base, 2×base, 4×base…,
+// capped at 30s.
+func hookRetryDelay(n int) time.Duration {
+	delay := hookRetryBaseDelay
+	for i := 1; i < n && delay < 30*time.Second; i++ {
+		delay *= 2
+	}
+	return min(delay, 30*time.Second)
+}
+
+// signHookRequest adds an HMAC-SHA256 signature over "<timestamp>.<body>" so
+// receivers can verify the payload without a bearer token.
+func signHookRequest(req *http.Request, secret string, body []byte, now time.Time) {
+	ts := strconv.FormatInt(now.Unix(), 10)
+	req.Header.Set(gmailWatchTimestampHeader, ts)
+	req.Header.Set(gmailWatchSignatureHeader, "sha256="+hookSignature(secret, ts, body))
+}
+
+func hookSignature(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
 }
 
 func parsePubSubPush(r *http.Request) (*pubsubPushEnvelope, error) {
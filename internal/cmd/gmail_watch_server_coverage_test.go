@@ -46,7 +46,7 @@ func TestGmailWatchServer_SendHook_TransportError(t *testing.T) {
 		warnf: func(string, ...any) {},
 	}
 
-	err = server.sendHook(context.Background(), &gmailHookPayload{Source: "gmail", Account: "a@b.com", HistoryID: "1"})
+	err = server.sendHookPayload(context.Background(), &gmailHookPayload{Source: "gmail", Account: "a@b.com", HistoryID: "1"})
 	if err == nil || !strings.Contains(err.Error(), "dial failed") {
 		t.Fatalf("expected transport error, got: %v", err)
 	}
@@ -217,6 +217,7 @@ func TestGmailWatchServer_ServeHTTP_HookSuccess(t *testing.T) {
 		store:      store,
 		newService: func(context.Context, string) (*gmail.Service, error) { return gsvc, nil },
 		hookClient: hookSrv.Client(),
+		hooks:      newGmailHookQueue(context.Background(), 1),
 		logf:       func(string, ...any) {},
 		warnf:      func(string, ...any) {},
 	}
@@ -231,6 +232,10 @@ func TestGmailWatchServer_ServeHTTP_HookSuccess(t *testing.T) {
 	if rr.Code != http.StatusOK {
 		t.Fatalf("status: %d", rr.Code)
 	}
+	server.hooks.wait()
+	if store.Get().LastDeliveryStatus != "ok" {
+		t.Fatalf("unexpected state: %#v", store.Get())
+	}
 }
 
 func TestGmailWatchServer_HandlePush_NewServiceError(t *testing.T) {
@@ -425,7 +425,7 @@ func TestGmailWatchServer_SendHook_UpdatesState(t *testing.T) {
 		warnf:      func(string, ...any) {},
 	}
 
-	err = server.sendHook(context.Background(), &gmailHookPayload{Source: "gmail", Account: "a@b.com", HistoryID: "1"})
+	err = server.sendHookPayload(context.Background(), &gmailHookPayload{Source: "gmail", Account: "a@b.com", HistoryID: "1"})
 	if err == nil || !strings.Contains(err.Error(), "hook status") {
 		t.Fatalf("expected http error, got: %v", err)
 	}
@@ -433,7 +433,7 @@ func TestGmailWatchServer_SendHook_UpdatesState(t *testing.T) {
 		t.Fatalf("unexpected state: %#v", store.Get())
 	}
 
-	if err := server.sendHook(context.Background(), &gmailHookPayload{Source: "gmail", Account: "a@b.com", HistoryID: "1"}); err != nil {
+	if err := server.sendHookPayload(context.Background(), &gmailHookPayload{Source: "gmail", Account: "a@b.com", HistoryID: "1"}); err != nil {
 		t.Fatalf("expected ok, got: %v", err)
 	}
 	if store.Get().LastDeliveryStatus != "ok" {
@@ -508,6 +508,7 @@ func TestGmailWatchServer_ServeHTTP_HookError(t *testing.T) {
 		store:      store,
 		newService: func(context.Context, string) (*gmail.Service, error) { return gsvc, nil },
 		hookClient: hookSrv.Client(),
+		hooks:      newGmailHookQueue(context.Background(), 1),
 		logf:       func(string, ...any) {},
 		warnf:      func(string, ...any) {},
 	}
@@ -522,6 +523,7 @@ func TestGmailWatchServer_ServeHTTP_HookError(t *testing.T) {
 	if rr.Code != http.StatusOK {
 		t.Fatalf("status: %d", rr.Code)
 	}
+	server.hooks.wait()
 	if store.Get().LastDeliveryStatus != "http_error" {
 		t.Fatalf("unexpected state: %#v", store.Get())
 	}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -63,17 +64,57 @@ func newGmailWatchStore(account string) (*gmailWatchStore, error) {
 }
 
 func loadGmailWatchStore(account string) (*gmailWatchStore, error) {
-	store, err := newGmailWatchStore(account)
+	path, err := gmailWatchStatePath(account)
 	if err != nil {
 		return nil, err
 	}
-	data, err := os.ReadFile(store.path)
+	store, err := readGmailWatchStore(path)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
 			return nil, errors.New("watch state not found; run gmail watch start")
 		}
 		return nil, err
 	}
+	return store, nil
+}
+
+// loadAllGmailWatchStores loads the watch state of every account in the
+// gmail-watch state dir, sorted by account.
+func loadAllGmailWatchStores() ([]*gmailWatchStore, error) {
+	dir, err := config.EnsureGmailWatchDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	stores := make([]*gmailWatchStore, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		store, err := readGmailWatchStore(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("load watch state %s: %w", entry.Name(), err)
+		}
+		if strings.TrimSpace(store.state.Account) == "" {
+			return nil, fmt.Errorf("watch state %s has no account", entry.Name())
+		}
+		stores = append(stores, store)
+	}
+	sort.Slice(stores, func(i, j int) bool {
+		return strings.ToLower(stores[i].state.Account) < strings.ToLower(stores[j].state.Account)
+	})
+	return stores, nil
+}
+
+func readGmailWatchStore(path string) (*gmailWatchStore, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	store := &gmailWatchStore{path: path}
 	if err := json.Unmarshal(data, &store.state); err != nil {
 		return nil, err
 	}
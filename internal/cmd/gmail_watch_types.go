@@ -8,6 +8,7 @@ import (
 
 const (
 	defaultWatchPath             = "/gmail-pubsub"
+	gmailWatchHealthPath         = "/healthz"
 	defaultWatchPort             = 8788
 	defaultHookMaxBytes          = 20000
 	defaultHistoryMaxResults     = 100
@@ -17,8 +18,10 @@ const (
 )
 
 type gmailWatchHook struct {
-	URL         string `json:"url"`
+	URL         string `json:"url,omitempty"`
+	Exec        string `json:"exec,omitempty"`
 	Token       string `json:"token,omitempty"`
+	Secret      string `json:"secret,omitempty"`
 	IncludeBody bool   `json:"includeBody,omitempty"`
 	MaxBytes    int    `json:"maxBytes,omitempty"`
 }
@@ -50,6 +53,10 @@ type gmailWatchServeConfig struct {
 	SharedToken   string
 	HookURL       string
 	HookToken     string
+	HookSecret    string
+	HookRetries   int
+	HookExec      string
+	ExecTimeout   time.Duration
 	IncludeBody   bool
 	MaxBodyBytes  int
 	HistoryMax    int64
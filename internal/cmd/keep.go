@@ -12,6 +12,7 @@ import (
 
 	"github.com/steipete/gogcli/internal/config"
 	"github.com/steipete/gogcli/internal/googleapi"
+	"github.com/steipete/gogcli/internal/googleauth"
 	"github.com/steipete/gogcli/internal/outfmt"
 	"github.com/steipete/gogcli/internal/ui"
 )
@@ -313,5 +314,15 @@ func getKeepService(ctx context.Context, flags *RootFlags, keepCmd *KeepCmd) (*k
 		}
 	}
 
+	if cfg, cfgErr := config.ReadConfig(); cfgErr == nil {
+		if key, ok := config.ServiceAccountKeyForAccount(cfg, account, string(googleauth.ServiceKeep)); ok {
+			routedPath, pathErr := config.ServiceAccountKeyPath(key)
+			if pathErr != nil {
+				return nil, pathErr
+			}
+			return newKeepServiceWithSA(ctx, routedPath, account)
+		}
+	}
+
 	return nil, usage("Keep is Workspace-only and requires a service account. Configure it with: gog auth service-account set <email> --key <service-account.json> (or legacy: gog auth keep <email> --key <service-account.json>)")
 }
@@ -181,6 +181,50 @@ func ClientForDomain(cfg File, domain string) (string, bool) {
 	return normalizedClient, true
 }
 
+// SetClientTesting records whether an OAuth client's consent screen is in
+// "Testing" publishing status (refresh tokens expire after 7 days).
+func SetClientTesting(cfg *File, client string, testing bool) error {
+	normalized, err := NormalizeClientNameOrDefault(client)
+	if err != nil {
+		return err
+	}
+
+	out := make([]string, 0, len(cfg.TestingClients)+1)
+	for _, c := range cfg.TestingClients {
+		if existing, err := NormalizeClientNameOrDefault(c); err == nil && existing != normalized {
+			out = append(out, existing)
+		}
+	}
+
+	if testing {
+		out = append(out, normalized)
+	}
+
+	sort.Strings(out)
+
+	if len(out) == 0 {
+		out = nil
+	}
+	cfg.TestingClients = out
+
+	return nil
+}
+
+func ClientIsTesting(cfg File, client string) bool {
+	normalized, err := NormalizeClientNameOrDefault(client)
+	if err != nil {
+		return false
+	}
+
+	for _, c := range cfg.TestingClients {
+		if existing, err := NormalizeClientNameOrDefault(c); err == nil && existing == normalized {
+			return true
+		}
+	}
+
+	return false
+}
+
 type ClientCredentialsInfo struct {
 	Client  string `json:"client"`
 	Path    string `json:"path"`
@@ -152,6 +152,38 @@ func TestAccountAndDomainMappings(t *testing.T) {
 	}
 }
 
+func TestClientTesting(t *testing.T) {
+	cfg := File{}
+
+	if ClientIsTesting(cfg, "") {
+		t.Fatalf("expected default client not testing")
+	}
+
+	if err := SetClientTesting(&cfg, "Work", true); err != nil {
+		t.Fatalf("SetClientTesting: %v", err)
+	}
+
+	if err := SetClientTesting(&cfg, "", true); err != nil {
+		t.Fatalf("SetClientTesting: %v", err)
+	}
+
+	if !ClientIsTesting(cfg, "work") || !ClientIsTesting(cfg, "default") {
+		t.Fatalf("expected testing clients, got %v", cfg.TestingClients)
+	}
+
+	if err := SetClientTesting(&cfg, "work", false); err != nil {
+		t.Fatalf("SetClientTesting: %v", err)
+	}
+
+	if ClientIsTesting(cfg, "work") || len(cfg.TestingClients) != 1 {
+		t.Fatalf("expected work cleared, got %v", cfg.TestingClients)
+	}
+
+	if err := SetClientTesting(&cfg, "bad!", true); err == nil {
+		t.Fatalf("expected invalid client error")
+	}
+}
+
 func TestResolveClientForAccount(t *testing.T) {
 	dir := withTempConfigDir(t)
 
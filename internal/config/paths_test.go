@@ -61,6 +61,15 @@ func TestPaths_CreateDirs(t *testing.T) {
 		t.Fatalf("expected watch dir: %v", statErr)
 	}
 
+	outboxDir, err := EnsureGmailOutboxDir()
+	if err != nil {
+		t.Fatalf("EnsureGmailOutboxDir: %v", err)
+	}
+
+	if _, statErr := os.Stat(outboxDir); statErr != nil {
+		t.Fatalf("expected outbox dir: %v", statErr)
+	}
+
 	credsPath, err := ClientCredentialsPath()
 	if err != nil {
 		t.Fatalf("ClientCredentialsPath: %v", err)
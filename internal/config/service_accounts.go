@@ -0,0 +1,240 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var errInvalidKeyName = errors.New("invalid service account key name")
+
+// ServiceAccountRoute maps a Workspace domain to a named service account key.
+// Services overrides the key for individual services (e.g. keep).
+type ServiceAccountRoute struct {
+	Key      string            `json:"key,omitempty"`
+	Services map[string]string `json:"services,omitempty"`
+}
+
+type ServiceAccountKeyInfo struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+const serviceAccountKeyPrefix = "service-account-"
+
+func NormalizeServiceAccountKeyName(raw string) (string, error) {
+	name := strings.ToLower(strings.TrimSpace(raw))
+	if name == "" {
+		return "", fmt.Errorf("%w: empty", errInvalidKeyName)
+	}
+
+	for _, r := range name {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' || r == '_' || r == '.' {
+			continue
+		}
+
+		return "", fmt.Errorf("%w: %q", errInvalidKeyName, raw)
+	}
+
+	return name, nil
+}
+
+func ServiceAccountKeyPath(name string) (string, error) {
+	normalized, err := NormalizeServiceAccountKeyName(name)
+	if err != nil {
+		return "", err
+	}
+
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, serviceAccountKeyPrefix+normalized+".json"), nil
+}
+
+func ListServiceAccountKeys() ([]ServiceAccountKeyInfo, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("read config dir: %w", err)
+	}
+
+	out := make([]ServiceAccountKeyInfo, 0, len(entries))
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, serviceAccountKeyPrefix) || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+
+		keyName, err := NormalizeServiceAccountKeyName(strings.TrimSuffix(strings.TrimPrefix(name, serviceAccountKeyPrefix), ".json"))
+		if err != nil {
+			continue
+		}
+
+		out = append(out, ServiceAccountKeyInfo{Name: keyName, Path: filepath.Join(dir, name)})
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+
+	return out, nil
+}
+
+// SetServiceAccountRoute routes domain (or one service within it, when service
+// is non-empty) to the named key.
+func SetServiceAccountRoute(cfg *File, domain string, service string, key string) error {
+	normalizedDomain, err := NormalizeDomain(domain)
+	if err != nil {
+		return err
+	}
+
+	normalizedKey, err := NormalizeServiceAccountKeyName(key)
+	if err != nil {
+		return err
+	}
+
+	if cfg.ServiceAccountDomains == nil {
+		cfg.ServiceAccountDomains = make(map[string]ServiceAccountRoute)
+	}
+
+	route := cfg.ServiceAccountDomains[normalizedDomain]
+	service = strings.ToLower(strings.TrimSpace(service))
+
+	if service == "" {
+		route.Key = normalizedKey
+	} else {
+		if route.Services == nil {
+			route.Services = make(map[string]string)
+		}
+		route.Services[service] = normalizedKey
+	}
+	cfg.ServiceAccountDomains[normalizedDomain] = route
+
+	return nil
+}
+
+// UnsetServiceAccountRoute removes a domain route (or a single service
+// override). It reports whether anything was removed.
+func UnsetServiceAccountRoute(cfg *File, domain string, service string) (bool, error) {
+	normalizedDomain, err := NormalizeDomain(domain)
+	if err != nil {
+		return false, err
+	}
+
+	route, ok := cfg.ServiceAccountDomains[normalizedDomain]
+	if !ok {
+		return false, nil
+	}
+
+	service = strings.ToLower(strings.TrimSpace(service))
+	removed := false
+
+	if service == "" {
+		removed = route.Key != ""
+		route.Key = ""
+	} else if _, exists := route.Services[service]; exists {
+		delete(route.Services, service)
+		removed = true
+	}
+
+	if len(route.Services) == 0 {
+		route.Services = nil
+	}
+
+	if route.Key == "" && route.Services == nil {
+		delete(cfg.ServiceAccountDomains, normalizedDomain)
+	} else {
+		cfg.ServiceAccountDomains[normalizedDomain] = route
+	}
+
+	if len(cfg.ServiceAccountDomains) == 0 {
+		cfg.ServiceAccountDomains = nil
+	}
+
+	return removed, nil
+}
+
+// RemoveServiceAccountKeyRoutes drops every route pointing at key and returns
+// how many were removed.
+func RemoveServiceAccountKeyRoutes(cfg *File, key string) int {
+	normalizedKey, err := NormalizeServiceAccountKeyName(key)
+	if err != nil {
+		return 0
+	}
+
+	removed := 0
+	for domain, route := range cfg.ServiceAccountDomains {
+		for service, k := range route.Services {
+			if k == normalizedKey {
+				delete(route.Services, service)
+				removed++
+			}
+		}
+
+		if route.Key == normalizedKey {
+			route.Key = ""
+			removed++
+		}
+
+		if len(route.Services) == 0 {
+			route.Services = nil
+		}
+
+		if route.Key == "" && route.Services == nil {
+			delete(cfg.ServiceAccountDomains, domain)
+		} else {
+			cfg.ServiceAccountDomains[domain] = route
+		}
+	}
+
+	if len(cfg.ServiceAccountDomains) == 0 {
+		cfg.ServiceAccountDomains = nil
+	}
+
+	return removed
+}
+
+// ServiceAccountKeyForAccount resolves the routed key for email's domain.
+// A per-service override wins over the domain default.
+func ServiceAccountKeyForAccount(cfg File, email string, service string) (string, bool) {
+	domain := DomainFromEmail(email)
+	if domain == "" {
+		return "", false
+	}
+
+	route, ok := cfg.ServiceAccountDomains[domain]
+	if !ok {
+		return "", false
+	}
+
+	service = strings.ToLower(strings.TrimSpace(service))
+	if service != "" {
+		if key, ok := route.Services[service]; ok && strings.TrimSpace(key) != "" {
+			if normalized, err := NormalizeServiceAccountKeyName(key); err == nil {
+				return normalized, true
+			}
+		}
+	}
+
+	if strings.TrimSpace(route.Key) == "" {
+		return "", false
+	}
+
+	normalized, err := NormalizeServiceAccountKeyName(route.Key)
+	if err != nil {
+		return "", false
+	}
+
+	return normalized, true
+}
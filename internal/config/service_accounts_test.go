@@ -0,0 +1,93 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestServiceAccountRoutes(t *testing.T) {
+	var cfg File
+
+	if err := SetServiceAccountRoute(&cfg, "@Example.com", "", "Tenant-A"); err != nil {
+		t.Fatalf("SetServiceAccountRoute: %v", err)
+	}
+
+	if err := SetServiceAccountRoute(&cfg, "example.com", "Keep", "keep-key"); err != nil {
+		t.Fatalf("SetServiceAccountRoute service: %v", err)
+	}
+
+	if key, ok := ServiceAccountKeyForAccount(cfg, "User@Example.com", "gmail"); !ok || key != "tenant-a" {
+		t.Fatalf("gmail route = %q, %v", key, ok)
+	}
+
+	if key, ok := ServiceAccountKeyForAccount(cfg, "user@example.com", "keep"); !ok || key != "keep-key" {
+		t.Fatalf("keep route = %q, %v", key, ok)
+	}
+
+	if _, ok := ServiceAccountKeyForAccount(cfg, "user@other.com", "gmail"); ok {
+		t.Fatalf("expected no route for other domain")
+	}
+
+	if err := SetServiceAccountRoute(&cfg, "example.com", "", "bad/name"); err == nil {
+		t.Fatalf("expected invalid key name error")
+	}
+
+	removed, err := UnsetServiceAccountRoute(&cfg, "example.com", "")
+	if err != nil || !removed {
+		t.Fatalf("UnsetServiceAccountRoute = %v, %v", removed, err)
+	}
+
+	if _, ok := ServiceAccountKeyForAccount(cfg, "user@example.com", "gmail"); ok {
+		t.Fatalf("expected domain default removed")
+	}
+
+	if key, ok := ServiceAccountKeyForAccount(cfg, "user@example.com", "keep"); !ok || key != "keep-key" {
+		t.Fatalf("keep override should survive, got %q, %v", key, ok)
+	}
+
+	if n := RemoveServiceAccountKeyRoutes(&cfg, "keep-key"); n != 1 {
+		t.Fatalf("RemoveServiceAccountKeyRoutes = %d", n)
+	}
+
+	if cfg.ServiceAccountDomains != nil {
+		t.Fatalf("expected empty routes, got %#v", cfg.ServiceAccountDomains)
+	}
+}
+
+func TestListServiceAccountKeys(t *testing.T) {
+	dir := withTempConfigDir(t)
+
+	path, err := ServiceAccountKeyPath("Tenant-A")
+	if err != nil {
+		t.Fatalf("ServiceAccountKeyPath: %v", err)
+	}
+
+	if filepath.Base(path) != "service-account-tenant-a.json" {
+		t.Fatalf("unexpected path %q", path)
+	}
+
+	for _, name := range []string{filepath.Base(path), "sa-dXNlckBleGFtcGxlLmNvbQ.json", "credentials.json"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("{}"), 0o600); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	keys, err := ListServiceAccountKeys()
+	if err != nil {
+		t.Fatalf("ListServiceAccountKeys: %v", err)
+	}
+
+	if len(keys) != 1 || keys[0].Name != "tenant-a" || keys[0].Path != path {
+		t.Fatalf("unexpected keys: %#v", keys)
+	}
+
+	emails, err := ListServiceAccountEmails()
+	if err != nil {
+		t.Fatalf("ListServiceAccountEmails: %v", err)
+	}
+
+	if len(emails) != 1 || emails[0] != "user@example.com" {
+		t.Fatalf("named keys must not show up as accounts: %#v", emails)
+	}
+}
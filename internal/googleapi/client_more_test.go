@@ -257,3 +257,74 @@ func TestOptionsForAccountScopes_ServiceAccountPreferred(t *testing.T) {
 		t.Fatalf("expected client options")
 	}
 }
+
+func TestOptionsForAccountScopes_RoutedServiceAccount(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	if _, err := config.EnsureDir(); err != nil {
+		t.Fatalf("EnsureDir: %v", err)
+	}
+
+	for name, body := range map[string]string{"tenant": `{"key":"tenant"}`, "groups-only": `{"key":"groups-only"}`} {
+		path, err := config.ServiceAccountKeyPath(name)
+		if err != nil {
+			t.Fatalf("ServiceAccountKeyPath: %v", err)
+		}
+
+		if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+			t.Fatalf("write key: %v", err)
+		}
+	}
+
+	var cfg config.File
+	if err := config.SetServiceAccountRoute(&cfg, "example.com", "", "tenant"); err != nil {
+		t.Fatalf("route: %v", err)
+	}
+
+	if err := config.SetServiceAccountRoute(&cfg, "example.com", "groups", "groups-only"); err != nil {
+		t.Fatalf("route: %v", err)
+	}
+
+	origConfig := readConfig
+	origOpen := openSecretsStore
+	origSA := newServiceAccountTokenSource
+
+	t.Cleanup(func() {
+		readConfig = origConfig
+		openSecretsStore = origOpen
+		newServiceAccountTokenSource = origSA
+	})
+
+	readConfig = func() (config.File, error) { return cfg, nil }
+	openSecretsStore = func() (secrets.Store, error) {
+		t.Fatalf("openSecretsStore should not be called")
+		return nil, errBoom
+	}
+
+	var gotKey, gotSubject string
+
+	newServiceAccountTokenSource = func(_ context.Context, keyJSON []byte, subject string, _ []string) (oauth2.TokenSource, error) {
+		gotKey = string(keyJSON)
+		gotSubject = subject
+
+		return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "t"}), nil
+	}
+
+	if _, err := optionsForAccountScopes(context.Background(), "gmail", "someone@example.com", []string{"s1"}); err != nil {
+		t.Fatalf("gmail: %v", err)
+	}
+
+	if gotKey != `{"key":"tenant"}` || gotSubject != "someone@example.com" {
+		t.Fatalf("gmail used key %q subject %q", gotKey, gotSubject)
+	}
+
+	if _, err := optionsForAccountScopes(context.Background(), "cloudidentity", "someone@example.com", []string{"s1"}); err != nil {
+		t.Fatalf("cloudidentity: %v", err)
+	}
+
+	if gotKey != `{"key":"groups-only"}` {
+		t.Fatalf("cloudidentity should use the groups override, got %q", gotKey)
+	}
+}
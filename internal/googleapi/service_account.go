@@ -10,6 +10,7 @@ import (
 	"golang.org/x/oauth2/google"
 
 	"github.com/steipete/gogcli/internal/config"
+	"github.com/steipete/gogcli/internal/googleauth"
 )
 
 var newServiceAccountTokenSource = func(ctx context.Context, keyJSON []byte, subject string, scopes []string) (oauth2.TokenSource, error) {
@@ -25,7 +26,22 @@ var newServiceAccountTokenSource = func(ctx context.Context, keyJSON []byte, sub
 	return cfg.TokenSource(ctx), nil
 }
 
-func tokenSourceForServiceAccountScopes(ctx context.Context, email string, scopes []string) (oauth2.TokenSource, string, bool, error) {
+// CheckServiceAccountScopes mints a token impersonating subject to verify the
+// key's domain-wide delegation covers scopes.
+func CheckServiceAccountScopes(ctx context.Context, keyJSON []byte, subject string, scopes []string) error {
+	ts, err := newServiceAccountTokenSource(ctx, keyJSON, subject, scopes)
+	if err != nil {
+		return err
+	}
+
+	if _, err := ts.Token(); err != nil {
+		return fmt.Errorf("mint token: %w", err)
+	}
+
+	return nil
+}
+
+func tokenSourceForServiceAccountScopes(ctx context.Context, serviceLabel string, email string, scopes []string) (oauth2.TokenSource, string, bool, error) {
 	saPath, err := config.ServiceAccountPath(email)
 	if err != nil {
 		return nil, "", false, fmt.Errorf("service account path: %w", err)
@@ -80,5 +96,46 @@ func tokenSourceForServiceAccountScopes(ctx context.Context, email string, scope
 		}
 	}
 
-	return nil, "", false, nil
+	return tokenSourceForRoutedServiceAccount(ctx, serviceLabel, email, scopes)
+}
+
+// tokenSourceForRoutedServiceAccount uses the key routed for email's domain
+// (service_account_domains in config.json), if any.
+func tokenSourceForRoutedServiceAccount(ctx context.Context, serviceLabel string, email string, scopes []string) (oauth2.TokenSource, string, bool, error) {
+	cfg, err := readConfig()
+	if err != nil {
+		return nil, "", false, fmt.Errorf("read config: %w", err)
+	}
+
+	key, ok := config.ServiceAccountKeyForAccount(cfg, email, routeServiceForLabel(serviceLabel))
+	if !ok {
+		return nil, "", false, nil
+	}
+
+	keyPath, err := config.ServiceAccountKeyPath(key)
+	if err != nil {
+		return nil, "", false, err
+	}
+
+	data, err := os.ReadFile(keyPath) //nolint:gosec // stored in user config dir
+	if err != nil {
+		return nil, "", false, fmt.Errorf("read service account key %q for %s: %w", key, email, err)
+	}
+
+	ts, err := newServiceAccountTokenSource(ctx, data, email, scopes)
+	if err != nil {
+		return nil, "", false, err
+	}
+
+	return ts, keyPath, true, nil
+}
+
+// routeServiceForLabel maps client labels that aren't googleauth service
+// names onto the service used for routing.
+func routeServiceForLabel(label string) string {
+	if label == "cloudidentity" {
+		return string(googleauth.ServiceGroups)
+	}
+
+	return label
 }
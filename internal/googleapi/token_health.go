@@ -0,0 +1,85 @@
+package googleapi
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/steipete/gogcli/internal/config"
+	"github.com/steipete/gogcli/internal/googleauth"
+	"github.com/steipete/gogcli/internal/secrets"
+)
+
+// refreshRecordInterval throttles keyring writes for LastRefreshedAt.
+const refreshRecordInterval = time.Hour
+
+var (
+	tokenWarnWriter io.Writer = os.Stderr
+	nowFunc                   = time.Now
+	readConfig                = config.ReadConfig
+)
+
+// refreshRecordingTokenSource records the first successful token exchange
+// in the stored token's LastRefreshedAt metadata.
+type refreshRecordingTokenSource struct {
+	base   oauth2.TokenSource
+	store  secrets.Store
+	client string
+	email  string
+	tok    secrets.Token
+	once   sync.Once
+}
+
+func newRefreshRecordingTokenSource(base oauth2.TokenSource, store secrets.Store, client string, email string, tok secrets.Token) oauth2.TokenSource {
+	return &refreshRecordingTokenSource{base: base, store: store, client: client, email: email, tok: tok}
+}
+
+func (s *refreshRecordingTokenSource) Token() (*oauth2.Token, error) {
+	t, err := s.base.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	s.once.Do(s.record)
+
+	return t, nil
+}
+
+func (s *refreshRecordingTokenSource) record() {
+	now := nowFunc().UTC()
+	if !s.tok.LastRefreshedAt.IsZero() && now.Sub(s.tok.LastRefreshedAt) < refreshRecordInterval {
+		return
+	}
+
+	updated := s.tok
+	updated.LastRefreshedAt = now
+
+	if err := s.store.SetToken(s.client, s.email, updated); err != nil {
+		slog.Debug("record token refresh failed", "email", s.email, "err", err)
+	}
+}
+
+// warnIfTestingTokenExpiring prints a stderr warning when the client is marked
+// as Testing and the refresh token is close to (or past) its 7-day limit.
+func warnIfTestingTokenExpiring(client string, email string, tok secrets.Token) {
+	cfg, err := readConfig()
+	if err != nil || !config.ClientIsTesting(cfg, client) {
+		return
+	}
+
+	exp := googleauth.TestingTokenExpiryAt(tok.CreatedAt, nowFunc())
+
+	switch {
+	case exp.Expired:
+		_, _ = fmt.Fprintf(tokenWarnWriter, "WARNING: refresh token for %s (client %s, Testing mode) passed its 7-day limit at %s; re-run: gog auth add %s --force-consent\n",
+			email, client, exp.ExpiresAt.UTC().Format(time.RFC3339), email)
+	case exp.ExpiringSoon:
+		_, _ = fmt.Fprintf(tokenWarnWriter, "WARNING: refresh token for %s (client %s, Testing mode) expires in %s (%s); re-run: gog auth add %s --force-consent\n",
+			email, client, exp.Remaining.Round(time.Hour), exp.ExpiresAt.UTC().Format(time.RFC3339), email)
+	}
+}
@@ -0,0 +1,135 @@
+package googleapi
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/steipete/gogcli/internal/config"
+	"github.com/steipete/gogcli/internal/secrets"
+)
+
+type recordingStore struct {
+	stubStore
+	sets []secrets.Token
+}
+
+func (s *recordingStore) SetToken(_ string, _ string, tok secrets.Token) error {
+	s.sets = append(s.sets, tok)
+	return nil
+}
+
+type countingTokenSource struct {
+	calls int
+	err   error
+}
+
+func (s *countingTokenSource) Token() (*oauth2.Token, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	return &oauth2.Token{AccessToken: "at"}, nil
+}
+
+func TestRefreshRecordingTokenSource(t *testing.T) {
+	origNow := nowFunc
+
+	t.Cleanup(func() { nowFunc = origNow })
+
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	nowFunc = func() time.Time { return now }
+
+	store := &recordingStore{}
+	base := &countingTokenSource{}
+	ts := newRefreshRecordingTokenSource(base, store, "default", "a@b.com", secrets.Token{RefreshToken: "rt"})
+
+	for range 3 {
+		if _, err := ts.Token(); err != nil {
+			t.Fatalf("Token: %v", err)
+		}
+	}
+
+	if base.calls != 3 || len(store.sets) != 1 {
+		t.Fatalf("expected one record after 3 calls, got calls=%d sets=%d", base.calls, len(store.sets))
+	}
+
+	if !store.sets[0].LastRefreshedAt.Equal(now) || store.sets[0].RefreshToken != "rt" {
+		t.Fatalf("unexpected recorded token: %#v", store.sets[0])
+	}
+
+	// Recently recorded: skip the keyring write.
+	store2 := &recordingStore{}
+	ts2 := newRefreshRecordingTokenSource(&countingTokenSource{}, store2, "default", "a@b.com", secrets.Token{RefreshToken: "rt", LastRefreshedAt: now.Add(-time.Minute)})
+
+	if _, err := ts2.Token(); err != nil {
+		t.Fatalf("Token: %v", err)
+	}
+
+	if len(store2.sets) != 0 {
+		t.Fatalf("expected throttled record, got %d", len(store2.sets))
+	}
+
+	// Failed exchange: nothing recorded.
+	store3 := &recordingStore{}
+	ts3 := newRefreshRecordingTokenSource(&countingTokenSource{err: errBoom}, store3, "default", "a@b.com", secrets.Token{RefreshToken: "rt"})
+
+	if _, err := ts3.Token(); !errors.Is(err, errBoom) {
+		t.Fatalf("expected boom, got %v", err)
+	}
+
+	if len(store3.sets) != 0 {
+		t.Fatalf("expected no record on failure")
+	}
+}
+
+func TestWarnIfTestingTokenExpiring(t *testing.T) {
+	origNow := nowFunc
+	origWriter := tokenWarnWriter
+	origRead := readConfig
+
+	t.Cleanup(func() {
+		nowFunc = origNow
+		tokenWarnWriter = origWriter
+		readConfig = origRead
+	})
+
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	nowFunc = func() time.Time { return now }
+
+	var buf bytes.Buffer
+	tokenWarnWriter = &buf
+
+	cfg := config.File{TestingClients: []string{"work"}}
+	readConfig = func() (config.File, error) { return cfg, nil }
+
+	warnIfTestingTokenExpiring("default", "a@b.com", secrets.Token{CreatedAt: now.Add(-6 * 24 * time.Hour)})
+
+	if buf.Len() != 0 {
+		t.Fatalf("expected no warning for non-testing client: %q", buf.String())
+	}
+
+	warnIfTestingTokenExpiring("work", "a@b.com", secrets.Token{CreatedAt: now.Add(-24 * time.Hour)})
+
+	if buf.Len() != 0 {
+		t.Fatalf("expected no warning for fresh token: %q", buf.String())
+	}
+
+	warnIfTestingTokenExpiring("work", "a@b.com", secrets.Token{CreatedAt: now.Add(-6 * 24 * time.Hour)})
+
+	if !strings.Contains(buf.String(), "expires in 24h") {
+		t.Fatalf("expected expiring warning, got %q", buf.String())
+	}
+
+	buf.Reset()
+	warnIfTestingTokenExpiring("work", "a@b.com", secrets.Token{CreatedAt: now.Add(-8 * 24 * time.Hour)})
+
+	if !strings.Contains(buf.String(), "passed its 7-day limit") {
+		t.Fatalf("expected expired warning, got %q", buf.String())
+	}
+}
@@ -0,0 +1,38 @@
+package googleauth
+
+import "time"
+
+const (
+	// TestingRefreshTokenLifetime is how long Google honors refresh tokens
+	// issued to OAuth clients whose consent screen is in "Testing" status.
+	TestingRefreshTokenLifetime = 7 * 24 * time.Hour
+	// TestingRefreshTokenWarnWindow is how far ahead of that limit we warn.
+	TestingRefreshTokenWarnWindow = 48 * time.Hour
+)
+
+// TestingTokenExpiry describes where a Testing-mode refresh token stands
+// relative to its 7-day lifetime.
+type TestingTokenExpiry struct {
+	ExpiresAt    time.Time
+	Remaining    time.Duration
+	Expired      bool
+	ExpiringSoon bool
+}
+
+// TestingTokenExpiryAt reports the expiry state at now for a token created
+// at createdAt. A zero createdAt yields a zero value (unknown age).
+func TestingTokenExpiryAt(createdAt time.Time, now time.Time) TestingTokenExpiry {
+	if createdAt.IsZero() {
+		return TestingTokenExpiry{}
+	}
+
+	expiresAt := createdAt.Add(TestingRefreshTokenLifetime)
+	remaining := expiresAt.Sub(now)
+
+	return TestingTokenExpiry{
+		ExpiresAt:    expiresAt,
+		Remaining:    remaining,
+		Expired:      remaining <= 0,
+		ExpiringSoon: remaining > 0 && remaining <= TestingRefreshTokenWarnWindow,
+	}
+}
@@ -0,0 +1,29 @@
+package googleauth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTestingTokenExpiryAt(t *testing.T) {
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+
+	if got := TestingTokenExpiryAt(time.Time{}, now); !got.ExpiresAt.IsZero() || got.Expired || got.ExpiringSoon {
+		t.Fatalf("unexpected zero result: %#v", got)
+	}
+
+	fresh := TestingTokenExpiryAt(now.Add(-24*time.Hour), now)
+	if fresh.Expired || fresh.ExpiringSoon || fresh.Remaining != 6*24*time.Hour {
+		t.Fatalf("unexpected fresh result: %#v", fresh)
+	}
+
+	soon := TestingTokenExpiryAt(now.Add(-6*24*time.Hour), now)
+	if soon.Expired || !soon.ExpiringSoon {
+		t.Fatalf("unexpected soon result: %#v", soon)
+	}
+
+	expired := TestingTokenExpiryAt(now.Add(-8*24*time.Hour), now)
+	if !expired.Expired || expired.ExpiringSoon {
+		t.Fatalf("unexpected expired result: %#v", expired)
+	}
+}
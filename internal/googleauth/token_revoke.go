@@ -0,0 +1,81 @@
+package googleauth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var revokeURL = "https://oauth2.googleapis.com/revoke"
+
+var (
+	// ErrTokenAlreadyInvalid is returned when Google reports the token as
+	// already revoked or expired. Callers usually treat it as success.
+	ErrTokenAlreadyInvalid = errors.New("token already invalid")
+	errRevokeFailed        = errors.New("token revocation failed")
+)
+
+// RevokeToken revokes a refresh (or access) token at Google. Revoking a
+// refresh token also invalidates every access token minted from it.
+func RevokeToken(ctx context.Context, token string, timeout time.Duration) error {
+	if strings.TrimSpace(token) == "" {
+		return errMissingToken
+	}
+
+	if timeout <= 0 {
+		timeout = 15 * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	form := url.Values{"token": {token}}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, revokeURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("create revoke request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	client := &http.Client{Timeout: timeout}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("revoke token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+
+	var body struct {
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if json.Unmarshal(raw, &body) == nil && body.Error != "" {
+		if body.Error == "invalid_token" {
+			return ErrTokenAlreadyInvalid
+		}
+
+		return fmt.Errorf("%w: status %d: %s %s", errRevokeFailed, resp.StatusCode, body.Error, body.ErrorDescription)
+	}
+
+	msg := readHTTPBodySnippet(bytes.NewReader(raw), 512)
+
+	if msg != "" {
+		return fmt.Errorf("%w: status %d: %s", errRevokeFailed, resp.StatusCode, msg)
+	}
+
+	return fmt.Errorf("%w: status %d", errRevokeFailed, resp.StatusCode)
+}
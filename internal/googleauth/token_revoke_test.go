@@ -0,0 +1,55 @@
+package googleauth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRevokeToken(t *testing.T) {
+	origURL := revokeURL
+	t.Cleanup(func() { revokeURL = origURL })
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Fatalf("unexpected method: %s", r.Method)
+		}
+
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("parse form: %v", err)
+		}
+
+		switch r.Form.Get("token") {
+		case "good":
+			w.WriteHeader(http.StatusOK)
+		case "gone":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_token","error_description":"Token expired or revoked"}`))
+		default:
+			http.Error(w, "boom", http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	revokeURL = srv.URL
+
+	if err := RevokeToken(context.Background(), "good", time.Second); err != nil {
+		t.Fatalf("RevokeToken good: %v", err)
+	}
+
+	if err := RevokeToken(context.Background(), "gone", time.Second); !errors.Is(err, ErrTokenAlreadyInvalid) {
+		t.Fatalf("expected ErrTokenAlreadyInvalid, got %v", err)
+	}
+
+	if err := RevokeToken(context.Background(), "other", time.Second); !errors.Is(err, errRevokeFailed) {
+		t.Fatalf("expected errRevokeFailed, got %v", err)
+	}
+
+	if err := RevokeToken(context.Background(), " ", time.Second); !errors.Is(err, errMissingToken) {
+		t.Fatalf("expected errMissingToken, got %v", err)
+	}
+}
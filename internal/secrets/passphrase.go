@@ -0,0 +1,152 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	sealedFormat  = "gog-sealed"
+	sealedVersion = 1
+	sealedKDF     = "scrypt"
+
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 32
+	scryptSalt   = 16
+)
+
+var (
+	errEmptyPassphrase   = errors.New("empty passphrase")
+	errInvalidSealedBlob = errors.New("invalid sealed data")
+	errWrongPassphrase   = errors.New("decrypt failed (wrong passphrase or corrupted data)")
+)
+
+// sealedEnvelope is the on-disk format produced by SealWithPassphrase.
+// KDF parameters are stored alongside the ciphertext so they can be raised
+// later without breaking older files.
+type sealedEnvelope struct {
+	Format     string `json:"format"`
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	N          int    `json:"n"`
+	R          int    `json:"r"`
+	P          int    `json:"p"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// SealWithPassphrase encrypts plaintext with a key derived from passphrase
+// (scrypt + AES-256-GCM) and returns a self-describing JSON envelope.
+func SealWithPassphrase(plaintext []byte, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, errEmptyPassphrase
+	}
+
+	salt := make([]byte, scryptSalt)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("salt: %w", err)
+	}
+
+	aead, err := passphraseAEAD(passphrase, salt, scryptN, scryptR, scryptP)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("nonce: %w", err)
+	}
+
+	env := sealedEnvelope{
+		Format:  sealedFormat,
+		Version: sealedVersion,
+		KDF:     sealedKDF,
+		N:       scryptN,
+		R:       scryptR,
+		P:       scryptP,
+		Salt:    salt,
+		Nonce:   nonce,
+	}
+	env.Ciphertext = aead.Seal(nil, nonce, plaintext, sealedAAD(env))
+
+	b, err := json.MarshalIndent(env, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encode sealed data: %w", err)
+	}
+
+	return append(b, '\n'), nil
+}
+
+// OpenWithPassphrase reverses SealWithPassphrase.
+func OpenWithPassphrase(data []byte, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, errEmptyPassphrase
+	}
+
+	var env sealedEnvelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidSealedBlob, err)
+	}
+
+	if env.Format != sealedFormat || env.KDF != sealedKDF {
+		return nil, fmt.Errorf("%w: unexpected format %q/%q", errInvalidSealedBlob, env.Format, env.KDF)
+	}
+
+	if env.Version != sealedVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", errInvalidSealedBlob, env.Version)
+	}
+
+	if len(env.Salt) == 0 || len(env.Nonce) == 0 || len(env.Ciphertext) == 0 {
+		return nil, fmt.Errorf("%w: missing fields", errInvalidSealedBlob)
+	}
+
+	aead, err := passphraseAEAD(passphrase, env.Salt, env.N, env.R, env.P)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(env.Nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("%w: bad nonce", errInvalidSealedBlob)
+	}
+
+	plaintext, err := aead.Open(nil, env.Nonce, env.Ciphertext, sealedAAD(env))
+	if err != nil {
+		return nil, errWrongPassphrase
+	}
+
+	return plaintext, nil
+}
+
+func passphraseAEAD(passphrase string, salt []byte, n, r, p int) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, n, r, p, scryptKeyLen)
+	if err != nil {
+		return nil, fmt.Errorf("derive key: %w", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("new cipher: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("new gcm: %w", err)
+	}
+
+	return aead, nil
+}
+
+// sealedAAD binds the KDF parameters to the ciphertext so they can't be
+// tampered with independently.
+func sealedAAD(env sealedEnvelope) []byte {
+	return fmt.Appendf(nil, "%s/%d/%s/%d/%d/%d", env.Format, env.Version, env.KDF, env.N, env.R, env.P)
+}
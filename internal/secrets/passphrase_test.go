@@ -0,0 +1,74 @@
+package secrets
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestSealWithPassphrase_RoundTrip(t *testing.T) {
+	plaintext := []byte(`{"hello":"world"}`)
+
+	sealed, err := SealWithPassphrase(plaintext, "correct horse")
+	if err != nil {
+		t.Fatalf("SealWithPassphrase: %v", err)
+	}
+
+	if bytes.Contains(sealed, []byte("world")) {
+		t.Fatalf("sealed output leaks plaintext: %s", sealed)
+	}
+
+	got, err := OpenWithPassphrase(sealed, "correct horse")
+	if err != nil {
+		t.Fatalf("OpenWithPassphrase: %v", err)
+	}
+
+	if !bytes.Equal(got, plaintext) {
+		t.Fatalf("unexpected plaintext: %q", got)
+	}
+}
+
+func TestOpenWithPassphrase_WrongPassphrase(t *testing.T) {
+	sealed, err := SealWithPassphrase([]byte("secret"), "one")
+	if err != nil {
+		t.Fatalf("SealWithPassphrase: %v", err)
+	}
+
+	if _, err := OpenWithPassphrase(sealed, "two"); !errors.Is(err, errWrongPassphrase) {
+		t.Fatalf("expected wrong passphrase error, got %v", err)
+	}
+}
+
+func TestOpenWithPassphrase_TamperedParams(t *testing.T) {
+	sealed, err := SealWithPassphrase([]byte("secret"), "pw")
+	if err != nil {
+		t.Fatalf("SealWithPassphrase: %v", err)
+	}
+
+	var env sealedEnvelope
+	if err := json.Unmarshal(sealed, &env); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	env.R = 1
+
+	tampered, err := json.Marshal(env)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	if _, err := OpenWithPassphrase(tampered, "pw"); err == nil {
+		t.Fatalf("expected error for tampered params")
+	}
+}
+
+func TestSealWithPassphrase_Empty(t *testing.T) {
+	if _, err := SealWithPassphrase([]byte("x"), ""); !errors.Is(err, errEmptyPassphrase) {
+		t.Fatalf("expected empty passphrase error, got %v", err)
+	}
+
+	if _, err := OpenWithPassphrase([]byte("{}"), "pw"); !errors.Is(err, errInvalidSealedBlob) {
+		t.Fatalf("expected invalid blob error, got %v", err)
+	}
+}
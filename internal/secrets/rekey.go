@@ -0,0 +1,286 @@
+package secrets
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/99designs/keyring"
+
+	"github.com/steipete/gogcli/internal/config"
+)
+
+const (
+	rekeyStagingSuffix = ".rekey-new"
+	rekeyBackupSuffix  = ".rekey-old"
+	migrateProbePrefix = "gogcli-migrate-probe-"
+)
+
+var (
+	errEmptyKeyringPassword = errors.New("empty keyring password")
+	errEmptyFileKeyring     = errors.New("file keyring is empty; nothing to verify the current password against")
+	errRekeyInProgress      = errors.New("previous rekey did not finish")
+	errSameKeyringBackend   = errors.New("source and destination keyring are the same store")
+	errVerifyKeyringItem    = errors.New("verify keyring item")
+)
+
+// RekeyFileKeyring re-encrypts every item of the encrypted file keyring with
+// newPassword. Items are written to a staging directory and verified before it
+// replaces the live directory, so a failure at any step leaves the old keyring
+// untouched. It returns the number of items re-encrypted.
+func RekeyFileKeyring(oldPassword string, newPassword string) (int, error) {
+	if oldPassword == "" || newPassword == "" {
+		return 0, errEmptyKeyringPassword
+	}
+
+	dir, err := config.EnsureKeyringDir()
+	if err != nil {
+		return 0, fmt.Errorf("ensure keyring dir: %w", err)
+	}
+
+	staging := dir + rekeyStagingSuffix
+	backup := dir + rekeyBackupSuffix
+
+	if _, statErr := os.Stat(backup); statErr == nil {
+		return 0, fmt.Errorf("%w: %s exists (restore or remove it first)", errRekeyInProgress, backup)
+	}
+
+	src, err := openFileKeyring(dir, oldPassword)
+	if err != nil {
+		return 0, err
+	}
+
+	items, err := readAllItems(src)
+	if err != nil {
+		return 0, fmt.Errorf("decrypt with current password: %w", err)
+	}
+
+	if len(items) == 0 {
+		return 0, errEmptyFileKeyring
+	}
+
+	if err := os.RemoveAll(staging); err != nil {
+		return 0, fmt.Errorf("clear staging dir: %w", err)
+	}
+
+	if err := os.MkdirAll(staging, 0o700); err != nil {
+		return 0, fmt.Errorf("create staging dir: %w", err)
+	}
+
+	if err := writeVerifiedItems(staging, newPassword, items); err != nil {
+		_ = os.RemoveAll(staging)
+		return 0, err
+	}
+
+	if err := os.Rename(dir, backup); err != nil {
+		_ = os.RemoveAll(staging)
+		return 0, fmt.Errorf("move old keyring aside: %w", err)
+	}
+
+	if err := os.Rename(staging, dir); err != nil {
+		if rollbackErr := os.Rename(backup, dir); rollbackErr != nil {
+			return 0, fmt.Errorf("install rekeyed keyring: %w (rollback failed: %v; old keyring is at %s)", err, rollbackErr, backup)
+		}
+
+		_ = os.RemoveAll(staging)
+
+		return 0, fmt.Errorf("install rekeyed keyring: %w", err)
+	}
+
+	if err := os.RemoveAll(backup); err != nil {
+		return len(items), fmt.Errorf("remove old keyring backup %s: %w", backup, err)
+	}
+
+	return len(items), nil
+}
+
+func writeVerifiedItems(dir string, password string, items []keyring.Item) error {
+	dst, err := openFileKeyring(dir, password)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		if err := dst.Set(item); err != nil {
+			return fmt.Errorf("re-encrypt %q: %w", item.Key, err)
+		}
+	}
+
+	// Re-open so verification decrypts from disk with the new password.
+	check, err := openFileKeyring(dir, password)
+	if err != nil {
+		return err
+	}
+
+	return verifyItems(check, items)
+}
+
+func openFileKeyring(dir string, password string) (keyring.Keyring, error) {
+	ring, err := keyringOpenFunc(newKeyringConfig(dir, []keyring.BackendType{keyring.FileBackend}, keyring.FixedStringPrompt(password)))
+	if err != nil {
+		return nil, fmt.Errorf("open file keyring: %w", err)
+	}
+
+	return ring, nil
+}
+
+// MigrateResult summarizes a keyring backend migration.
+type MigrateResult struct {
+	Copied        int      `json:"copied"`
+	Removed       int      `json:"removed"`
+	RemoveFailed  []string `json:"remove_failed,omitempty"`
+	SourceRemoved bool     `json:"source_removed"`
+}
+
+// MigrateKeyring copies every item from the currently configured keyring into
+// the target backend ("keychain" or "file"). If any copy or verification
+// fails, items already written to the target are restored to their previous
+// state. Unless keepSource is set, items are then removed from the source.
+func MigrateKeyring(target string, keepSource bool) (MigrateResult, error) {
+	backends, err := allowedBackends(KeyringBackendInfo{Value: normalizeKeyringBackend(target)})
+	if err != nil || len(backends) != 1 {
+		return MigrateResult{}, fmt.Errorf("%w: %q (expected keychain or file)", errInvalidKeyringBackend, target)
+	}
+
+	src, err := openKeyringFunc()
+	if err != nil {
+		return MigrateResult{}, err
+	}
+
+	dir, err := config.EnsureKeyringDir()
+	if err != nil {
+		return MigrateResult{}, fmt.Errorf("ensure keyring dir: %w", err)
+	}
+
+	dst, err := keyringOpenFunc(newKeyringConfig(dir, backends, fileKeyringPasswordFunc()))
+	if err != nil {
+		return MigrateResult{}, fmt.Errorf("open %s keyring: %w", target, wrapKeychainError(err))
+	}
+
+	if same, err := sameKeyring(src, dst); err != nil {
+		return MigrateResult{}, err
+	} else if same {
+		return MigrateResult{}, fmt.Errorf("%w (already using %s?)", errSameKeyringBackend, target)
+	}
+
+	items, err := readAllItems(src)
+	if err != nil {
+		return MigrateResult{}, err
+	}
+
+	if err := copyItemsWithRollback(dst, items); err != nil {
+		return MigrateResult{}, err
+	}
+
+	res := MigrateResult{Copied: len(items)}
+	if keepSource {
+		return res, nil
+	}
+
+	for _, item := range items {
+		if err := src.Remove(item.Key); err != nil && !errors.Is(err, keyring.ErrKeyNotFound) {
+			res.RemoveFailed = append(res.RemoveFailed, item.Key)
+			continue
+		}
+		res.Removed++
+	}
+	res.SourceRemoved = len(res.RemoveFailed) == 0
+
+	return res, nil
+}
+
+func copyItemsWithRollback(dst keyring.Keyring, items []keyring.Item) (err error) {
+	type prior struct {
+		item   keyring.Item
+		exists bool
+	}
+
+	written := make([]prior, 0, len(items))
+
+	defer func() {
+		if err == nil {
+			return
+		}
+
+		for i := len(written) - 1; i >= 0; i-- {
+			p := written[i]
+			if p.exists {
+				_ = dst.Set(p.item)
+			} else {
+				_ = dst.Remove(p.item.Key)
+			}
+		}
+	}()
+
+	for _, item := range items {
+		p := prior{item: keyring.Item{Key: item.Key}}
+		if existing, getErr := dst.Get(item.Key); getErr == nil {
+			p = prior{item: existing, exists: true}
+		}
+
+		if setErr := dst.Set(item); setErr != nil {
+			return fmt.Errorf("copy %q: %w", item.Key, wrapKeychainError(setErr))
+		}
+
+		written = append(written, p)
+	}
+
+	return verifyItems(dst, items)
+}
+
+// sameKeyring detects when source and destination resolve to the same store
+// (e.g. "auto" already picked the target backend) by writing a probe item.
+func sameKeyring(src keyring.Keyring, dst keyring.Keyring) (bool, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return false, fmt.Errorf("probe keyring: %w", err)
+	}
+
+	key := migrateProbePrefix + hex.EncodeToString(buf)
+	if err := dst.Set(keyring.Item{Key: key, Data: buf}); err != nil {
+		return false, fmt.Errorf("probe destination keyring: %w", wrapKeychainError(err))
+	}
+
+	defer func() { _ = dst.Remove(key) }()
+
+	item, err := src.Get(key)
+
+	return err == nil && bytes.Equal(item.Data, buf), nil
+}
+
+func readAllItems(ring keyring.Keyring) ([]keyring.Item, error) {
+	keys, err := ring.Keys()
+	if err != nil {
+		return nil, fmt.Errorf("list keyring keys: %w", err)
+	}
+
+	items := make([]keyring.Item, 0, len(keys))
+	for _, key := range keys {
+		item, err := ring.Get(key)
+		if err != nil {
+			return nil, fmt.Errorf("read %q: %w", key, wrapKeychainError(err))
+		}
+
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+func verifyItems(ring keyring.Keyring, items []keyring.Item) error {
+	for _, item := range items {
+		got, err := ring.Get(item.Key)
+		if err != nil {
+			return fmt.Errorf("%w %q: %w", errVerifyKeyringItem, item.Key, err)
+		}
+
+		if !bytes.Equal(got.Data, item.Data) {
+			return fmt.Errorf("%w %q: content mismatch", errVerifyKeyringItem, item.Key)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,213 @@
+package secrets
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/99designs/keyring"
+
+	"github.com/steipete/gogcli/internal/config"
+)
+
+var errTestSetFailed = errors.New("set failed")
+
+func withTempKeyringDir(t *testing.T) string {
+	t.Helper()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	dir, err := config.EnsureKeyringDir()
+	if err != nil {
+		t.Fatalf("EnsureKeyringDir: %v", err)
+	}
+
+	return dir
+}
+
+func TestRekeyFileKeyring(t *testing.T) {
+	dir := withTempKeyringDir(t)
+
+	ring, err := openFileKeyring(dir, "old-pass")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+
+	for _, key := range []string{"token:default:a@b.com", "default_account"} {
+		if err := ring.Set(keyring.Item{Key: key, Data: []byte("v-" + key)}); err != nil {
+			t.Fatalf("Set: %v", err)
+		}
+	}
+
+	if _, err := RekeyFileKeyring("wrong", "new-pass"); err == nil {
+		t.Fatalf("expected wrong old password to fail")
+	}
+
+	if _, err := os.Stat(dir + rekeyStagingSuffix); !os.IsNotExist(err) {
+		t.Fatalf("staging dir left behind: %v", err)
+	}
+
+	n, err := RekeyFileKeyring("old-pass", "new-pass")
+	if err != nil {
+		t.Fatalf("RekeyFileKeyring: %v", err)
+	}
+
+	if n != 2 {
+		t.Fatalf("rekeyed %d items, want 2", n)
+	}
+
+	if _, err := os.Stat(dir + rekeyBackupSuffix); !os.IsNotExist(err) {
+		t.Fatalf("backup dir left behind: %v", err)
+	}
+
+	oldRing, err := openFileKeyring(dir, "old-pass")
+	if err != nil {
+		t.Fatalf("open old: %v", err)
+	}
+
+	if _, err := oldRing.Get("default_account"); err == nil {
+		t.Fatalf("old password still decrypts")
+	}
+
+	newRing, err := openFileKeyring(dir, "new-pass")
+	if err != nil {
+		t.Fatalf("open new: %v", err)
+	}
+
+	item, err := newRing.Get("token:default:a@b.com")
+	if err != nil || string(item.Data) != "v-token:default:a@b.com" {
+		t.Fatalf("unexpected item %q err=%v", item.Data, err)
+	}
+}
+
+func TestRekeyFileKeyring_RefusesUnfinishedRekey(t *testing.T) {
+	dir := withTempKeyringDir(t)
+
+	if err := os.MkdirAll(dir+rekeyBackupSuffix, 0o700); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	if _, err := RekeyFileKeyring("a", "b"); !errors.Is(err, errRekeyInProgress) {
+		t.Fatalf("expected errRekeyInProgress, got %v", err)
+	}
+}
+
+func TestRekeyFileKeyring_RefusesEmptyKeyring(t *testing.T) {
+	withTempKeyringDir(t)
+
+	if _, err := RekeyFileKeyring("any", "new-pass"); !errors.Is(err, errEmptyFileKeyring) {
+		t.Fatalf("expected errEmptyFileKeyring, got %v", err)
+	}
+}
+
+type failingSetKeyring struct {
+	*keyring.ArrayKeyring
+	failKey string
+}
+
+func (k *failingSetKeyring) Set(item keyring.Item) error {
+	if item.Key == k.failKey {
+		return errTestSetFailed
+	}
+
+	return k.ArrayKeyring.Set(item)
+}
+
+func stubMigrateKeyrings(t *testing.T, src keyring.Keyring, dst keyring.Keyring) {
+	t.Helper()
+	withTempKeyringDir(t)
+
+	origOpen := openKeyringFunc
+	origKeyringOpen := keyringOpenFunc
+
+	t.Cleanup(func() {
+		openKeyringFunc = origOpen
+		keyringOpenFunc = origKeyringOpen
+	})
+
+	openKeyringFunc = func() (keyring.Keyring, error) { return src, nil }
+	keyringOpenFunc = func(cfg keyring.Config) (keyring.Keyring, error) {
+		if len(cfg.AllowedBackends) != 1 || cfg.AllowedBackends[0] != keyring.FileBackend {
+			t.Fatalf("unexpected backends: %v", cfg.AllowedBackends)
+		}
+
+		return dst, nil
+	}
+}
+
+func TestMigrateKeyring(t *testing.T) {
+	src := keyring.NewArrayKeyring([]keyring.Item{
+		{Key: "token:default:a@b.com", Data: []byte("a")},
+		{Key: "default_account", Data: []byte("b")},
+	})
+	dst := keyring.NewArrayKeyring(nil)
+	stubMigrateKeyrings(t, src, dst)
+
+	res, err := MigrateKeyring("file", false)
+	if err != nil {
+		t.Fatalf("MigrateKeyring: %v", err)
+	}
+
+	if res.Copied != 2 || res.Removed != 2 || !res.SourceRemoved {
+		t.Fatalf("unexpected result: %#v", res)
+	}
+
+	if keys, _ := src.Keys(); len(keys) != 0 {
+		t.Fatalf("source not emptied: %v", keys)
+	}
+
+	if keys, _ := dst.Keys(); len(keys) != 2 {
+		t.Fatalf("unexpected destination keys (probe left behind?): %v", keys)
+	}
+}
+
+func TestMigrateKeyring_SameStore(t *testing.T) {
+	ring := keyring.NewArrayKeyring([]keyring.Item{{Key: "k", Data: []byte("v")}})
+	stubMigrateKeyrings(t, ring, ring)
+
+	if _, err := MigrateKeyring("file", false); !errors.Is(err, errSameKeyringBackend) {
+		t.Fatalf("expected errSameKeyringBackend, got %v", err)
+	}
+
+	if item, err := ring.Get("k"); err != nil || string(item.Data) != "v" {
+		t.Fatalf("item lost: %v", err)
+	}
+}
+
+func TestMigrateKeyring_RollsBackOnFailure(t *testing.T) {
+	src := keyring.NewArrayKeyring([]keyring.Item{
+		{Key: "a", Data: []byte("new-a")},
+		{Key: "b", Data: []byte("new-b")},
+		{Key: "c", Data: []byte("new-c")},
+	})
+	dst := &failingSetKeyring{
+		ArrayKeyring: keyring.NewArrayKeyring([]keyring.Item{{Key: "a", Data: []byte("old-a")}}),
+		failKey:      "c",
+	}
+	stubMigrateKeyrings(t, src, dst)
+
+	if _, err := MigrateKeyring("file", false); !errors.Is(err, errTestSetFailed) {
+		t.Fatalf("expected set failure, got %v", err)
+	}
+
+	if item, err := dst.Get("a"); err != nil || string(item.Data) != "old-a" {
+		t.Fatalf("prior destination item not restored: %q err=%v", item.Data, err)
+	}
+
+	if _, err := dst.Get("b"); err == nil {
+		t.Fatalf("new destination item not rolled back")
+	}
+
+	if keys, _ := src.Keys(); len(keys) != 3 {
+		t.Fatalf("source modified on failure: %v", keys)
+	}
+}
+
+func TestMigrateKeyring_InvalidTarget(t *testing.T) {
+	if _, err := MigrateKeyring("auto", false); !errors.Is(err, errInvalidKeyringBackend) {
+		t.Fatalf("expected errInvalidKeyringBackend, got %v", err)
+	}
+}
@@ -29,13 +29,16 @@ type KeyringStore struct {
 	ring keyring.Keyring
 }
 
+// Token is a stored refresh token plus metadata. LastRefreshedAt records the
+// last successful exchange for an access token (updated at most hourly).
 type Token struct {
-	Client       string    `json:"client,omitempty"`
-	Email        string    `json:"email"`
-	Services     []string  `json:"services,omitempty"`
-	Scopes       []string  `json:"scopes,omitempty"`
-	CreatedAt    time.Time `json:"created_at,omitempty"`
-	RefreshToken string    `json:"-"`
+	Client          string    `json:"client,omitempty"`
+	Email           string    `json:"email"`
+	Services        []string  `json:"services,omitempty"`
+	Scopes          []string  `json:"scopes,omitempty"`
+	CreatedAt       time.Time `json:"created_at,omitempty"`
+	LastRefreshedAt time.Time `json:"last_refreshed_at,omitempty"`
+	RefreshToken    string    `json:"-"`
 }
 
 const (
@@ -85,6 +88,21 @@ func ResolveKeyringBackendInfo() (KeyringBackendInfo, error) {
 	return KeyringBackendInfo{Value: keyringBackendAuto, Source: keyringBackendSourceDefault}, nil
 }
 
+// UsesFileKeyring reports whether the default store resolves to the encrypted
+// file backend, either configured or forced on Linux without D-Bus.
+func UsesFileKeyring() (bool, error) {
+	info, err := ResolveKeyringBackendInfo()
+	if err != nil {
+		return false, err
+	}
+
+	if info.Value == "file" {
+		return true, nil
+	}
+
+	return shouldForceFileBackend(runtime.GOOS, info, os.Getenv("DBUS_SESSION_BUS_ADDRESS")), nil
+}
+
 func allowedBackends(info KeyringBackendInfo) ([]keyring.BackendType, error) {
 	switch info.Value {
 	case "", keyringBackendAuto:
@@ -173,19 +191,7 @@ func openKeyring() (keyring.Keyring, error) {
 		backends = []keyring.BackendType{keyring.FileBackend}
 	}
 
-	cfg := keyring.Config{
-		ServiceName: config.AppName,
-		// KeychainTrustApplication is intentionally false to support Homebrew upgrades.
-		// When true, macOS Keychain ties access control to the specific binary hash.
-		// Homebrew upgrades install a new binary with a different hash, causing the
-		// new binary to lose access to existing keychain items. With false, users may
-		// see a one-time keychain prompt after upgrade (click "Always Allow"), but
-		// tokens survive across upgrades. See: https://github.com/steipete/gogcli/issues/86
-		KeychainTrustApplication: false,
-		AllowedBackends:          backends,
-		FileDir:                  keyringDir,
-		FilePasswordFunc:         fileKeyringPasswordFunc(),
-	}
+	cfg := newKeyringConfig(keyringDir, backends, fileKeyringPasswordFunc())
 
 	// On Linux with D-Bus present, keyring.Open() can still hang if SecretService
 	// is unresponsive (e.g., gnome-keyring installed but not running).
@@ -202,6 +208,22 @@ func openKeyring() (keyring.Keyring, error) {
 	return ring, nil
 }
 
+func newKeyringConfig(keyringDir string, backends []keyring.BackendType, passwordFunc keyring.PromptFunc) keyring.Config {
+	return keyring.Config{
+		ServiceName: config.AppName,
+		// KeychainTrustApplication is intentionally false to support Homebrew upgrades.
+		// When true, macOS Keychain ties access control to the specific binary hash.
+		// Homebrew upgrades install a new binary with a different hash, causing the
+		// new binary to lose access to existing keychain items. With false, users may
+		// see a one-time keychain prompt after upgrade (click "Always Allow"), but
+		// tokens survive across upgrades. See: https://github.com/steipete/gogcli/issues/86
+		KeychainTrustApplication: false,
+		AllowedBackends:          backends,
+		FileDir:                  keyringDir,
+		FilePasswordFunc:         passwordFunc,
+	}
+}
+
 type keyringResult struct {
 	ring keyring.Keyring
 	err  error
@@ -295,10 +317,11 @@ func (s *KeyringStore) Keys() ([]string, error) {
 }
 
 type storedToken struct {
-	RefreshToken string    `json:"refresh_token"`
-	Services     []string  `json:"services,omitempty"`
-	Scopes       []string  `json:"scopes,omitempty"`
-	CreatedAt    time.Time `json:"created_at,omitempty"`
+	RefreshToken    string    `json:"refresh_token"`
+	Services        []string  `json:"services,omitempty"`
+	Scopes          []string  `json:"scopes,omitempty"`
+	CreatedAt       time.Time `json:"created_at,omitempty"`
+	LastRefreshedAt time.Time `json:"last_refreshed_at,omitempty"`
 }
 
 func (s *KeyringStore) SetToken(client string, email string, tok Token) error {
@@ -321,10 +344,11 @@ func (s *KeyringStore) SetToken(client string, email string, tok Token) error {
 	}
 
 	payload, err := json.Marshal(storedToken{
-		RefreshToken: tok.RefreshToken,
-		Services:     tok.Services,
-		Scopes:       tok.Scopes,
-		CreatedAt:    tok.CreatedAt,
+		RefreshToken:    tok.RefreshToken,
+		Services:        tok.Services,
+		Scopes:          tok.Scopes,
+		CreatedAt:       tok.CreatedAt,
+		LastRefreshedAt: tok.LastRefreshedAt,
 	})
 	if err != nil {
 		return fmt.Errorf("encode token: %w", err)
@@ -385,12 +409,13 @@ func (s *KeyringStore) GetToken(client string, email string) (Token, error) {
 	}
 
 	return Token{
-		Client:       normalizedClient,
-		Email:        email,
-		Services:     st.Services,
-		Scopes:       st.Scopes,
-		CreatedAt:    st.CreatedAt,
-		RefreshToken: st.RefreshToken,
+		Client:          normalizedClient,
+		Email:           email,
+		Services:        st.Services,
+		Scopes:          st.Scopes,
+		CreatedAt:       st.CreatedAt,
+		LastRefreshedAt: st.LastRefreshedAt,
+		RefreshToken:    st.RefreshToken,
 	}, nil
 }
 
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -121,6 +122,36 @@ func LoadConfig(account string) (*Config, error) {
 	return hydrateConfig(account, &legacy)
 }
 
+// ListAccounts returns the accounts that have a tracking configuration entry.
+// Legacy single-account files carry no account name and yield no entries.
+func ListAccounts() ([]string, error) {
+	path, err := ConfigPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, ok, err := readConfigBytes(path)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	var fileCfg fileConfig
+	if err := json.Unmarshal(data, &fileCfg); err != nil {
+		return nil, fmt.Errorf("parse tracking config: %w", err)
+	}
+
+	out := make([]string, 0, len(fileCfg.Accounts))
+	for account := range fileCfg.Accounts {
+		if account = normalizeAccount(account); account != "" {
+			out = append(out, account)
+		}
+	}
+
+	sort.Strings(out)
+
+	return out, nil
+}
+
 // SaveConfig saves tracking configuration to disk for the specified account.
 func SaveConfig(account string, cfg *Config) error {
 	account = normalizeAccount(account)